@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runGrantAdmin gives or takes administrator rights:
+//
+//	go run ./cmd/app grant-admin -user alice
+//	go run ./cmd/app grant-admin -user alice -revoke
+func runGrantAdmin(args []string) {
+	flags := flag.NewFlagSet("grant-admin", flag.ExitOnError)
+	username := flags.String("user", "", "username of the account")
+	revoke := flags.Bool("revoke", false, "take administrator rights away instead")
+	flags.Parse(args)
+
+	if *username == "" {
+		fmt.Fprintln(os.Stderr, "usage: app grant-admin -user <username> [-revoke]")
+		os.Exit(2)
+	}
+
+	a, err := newApp()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	user, err := a.userRepo.GetUserByUsername(*username)
+	if err != nil {
+		log.Fatalf("user %s not found: %v", *username, err)
+	}
+
+	if err := a.userRepo.SetAdmin(user.ID, !*revoke); err != nil {
+		log.Fatal(err)
+	}
+
+	if *revoke {
+		fmt.Printf("%s is no longer an administrator\n", user.Username)
+	} else {
+		fmt.Printf("%s is now an administrator\n", user.Username)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"final-project/pkg/apikey"
+	"final-project/pkg/auth"
+	"final-project/pkg/block"
+	"final-project/pkg/comment"
+	"final-project/pkg/crypto"
+	"final-project/pkg/digest"
+	"final-project/pkg/domain"
+	"final-project/pkg/event"
+	"final-project/pkg/export"
+	"final-project/pkg/graphql"
+	"final-project/pkg/http/rest"
+	"final-project/pkg/importer"
+	"final-project/pkg/loginguard"
+	"final-project/pkg/mailer"
+	"final-project/pkg/message"
+	"final-project/pkg/notification"
+	"final-project/pkg/oidc"
+	"final-project/pkg/photo"
+	"final-project/pkg/ratelimit"
+	"final-project/pkg/rpc"
+	"final-project/pkg/session"
+	"final-project/pkg/socialmedia"
+	"final-project/pkg/storage/localfs"
+	"final-project/pkg/storage/sqldb"
+	"final-project/pkg/stream"
+	"final-project/pkg/twofactor"
+	"final-project/pkg/user"
+	"final-project/pkg/webhook"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// app holds the storage and services shared by the server and the CLI subcommands
+type app struct {
+	port       string
+	grpcPort   string
+	restConfig rest.Config
+	grpcConfig rpc.Config
+	storage    *sqldb.Storage
+
+	userRepo domain.UserRepository
+
+	authService         domain.AuthService
+	userService         domain.UserService
+	photoService        domain.PhotoService
+	commentService      domain.CommentService
+	socialMediaService  domain.SocialMediaService
+	exportService       domain.ExportService
+	importService       domain.ImportService
+	twoFactorService    domain.TwoFactorService
+	loginGuard          domain.LoginGuard
+	rateLimiter         domain.RateLimiter
+	apiKeyService       domain.APIKeyService
+	oidcService         domain.OIDCService
+	sessionService      domain.SessionService
+	webhookService      domain.WebhookService
+	streamHub           domain.StreamHub
+	notificationService domain.NotificationService
+	digestService       domain.DigestService
+	messageService      domain.MessageService
+	blockService        domain.BlockService
+}
+
+func newApp() (*app, error) {
+	PORT := "8080"
+	GRPC_PORT := "9090"
+	// This sensitive information is written here for the convenience of this assignment
+	dsn := "falfal:Pasword!2@tcp(mysql-dev-db.airy.my.id:3306)/fga_go_final?charset=utf8mb4&parseTime=True&loc=Local"
+	os.Setenv("JWT_SECRET", "supersecret1287401bnf9147ehfn9r247")
+
+	userConfig := user.Config{
+		DeletionGracePeriod:  14 * 24 * time.Hour,
+		PasswordResetTTL:     time.Hour,
+		PasswordResetURL:     "http://localhost:" + PORT + "/reset-password",
+		EmailVerificationTTL: 48 * time.Hour,
+		EmailVerificationURL: "http://localhost:" + PORT + "/users/verify",
+	}
+	// Existing bcrypt hashes are upgraded to Argon2id on the next login
+	cryptoConfig := crypto.Config{
+		Algorithm:     crypto.AlgorithmArgon2id,
+		BcryptCost:    14,
+		Argon2:        crypto.DefaultArgon2Params,
+		MaxConcurrent: runtime.NumCPU(),
+	}
+	loginGuardConfig := loginguard.Config{
+		UsernameFreeAttempts: 5,
+		IPFreeAttempts:       50,
+		BaseLockout:          time.Minute,
+		MaxLockout:           24 * time.Hour,
+		ResetAfter:           24 * time.Hour,
+	}
+	apiKeyConfig := apikey.Config{
+		MaxKeysPerUser:   20,
+		MaxExpiresIn:     365 * 24 * time.Hour,
+		LastUsedInterval: time.Minute,
+	}
+	// Failed deliveries are retried for about a day before they become dead letters
+	webhookConfig := webhook.Config{
+		MaxWebhooksPerUser: 10,
+		MaxAttempts:        10,
+		InitialBackoff:     time.Minute,
+		MaxBackoff:         6 * time.Hour,
+		Timeout:            10 * time.Second,
+		BatchSize:          100,
+		// Receivers on localhost or a private network, for development
+		AllowPrivateNetworks: os.Getenv("WEBHOOKS_ALLOW_PRIVATE_NETWORKS") == "true",
+	}
+	// Providers are enabled by setting their client credentials
+	oidcConfig := oidc.Config{
+		Providers: map[string]oidc.ProviderConfig{},
+		StateTTL:  10 * time.Minute,
+	}
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		oidcConfig.Providers["google"] = oidc.ProviderConfig{
+			Issuer:       "https://accounts.google.com",
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  "http://localhost:" + PORT + "/auth/oidc/google/callback",
+		}
+	}
+	exportConfig := export.Config{
+		Dir: "data/exports",
+		TTL: 7 * 24 * time.Hour,
+	}
+	smtpConfig := mailer.SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     "MyGram <no-reply@mygram.local>",
+	}
+	if port, err := strconv.Atoi(os.Getenv("SMTP_PORT")); err == nil {
+		smtpConfig.Port = port
+	}
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		smtpConfig.From = from
+	}
+	// Unsubscribe links are signed with the JWT secret, they need no login
+	digestConfig := digest.Config{
+		DefaultDigest:  domain.DigestWeekly,
+		UnsubscribeURL: "http://localhost:" + PORT + "/users/unsubscribe",
+		Secret:         []byte(os.Getenv("JWT_SECRET")),
+		MaxPhotos:      10,
+		// A digest failing to send is tried again after 1, 2, 4 and 8 hours, then skipped
+		MaxAttempts:  5,
+		RetryBackoff: time.Hour,
+	}
+	// Comma separated addresses or CIDRs of the reverse proxies in front of the server
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	mediaDir := "data/media"
+	mediaBaseURL := "http://localhost:" + PORT + "/media"
+
+	// Create storage
+	storage, err := sqldb.NewStorage(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create repository
+	userRepo := sqldb.NewUserRepository(storage.DB)
+	photoRepo := sqldb.NewPhotoRepository(storage.DB)
+	commentRepo := sqldb.NewCommentRepository(storage.DB)
+	socialMediaRepo := sqldb.NewSocialMediaRepository(storage.DB)
+	auditLogRepo := sqldb.NewAuditLogRepository(storage.DB)
+	exportRepo := sqldb.NewExportRepository(storage.DB)
+	importRepo := sqldb.NewImportRepository(storage.DB)
+	userTokenRepo := sqldb.NewUserTokenRepository(storage.DB)
+	twoFactorRepo := sqldb.NewTwoFactorRepository(storage.DB)
+	loginAttemptRepo := sqldb.NewLoginAttemptRepository(storage.DB)
+	apiKeyRepo := sqldb.NewAPIKeyRepository(storage.DB)
+	linkedIdentityRepo := sqldb.NewLinkedIdentityRepository(storage.DB)
+	sessionRepo := sqldb.NewSessionRepository(storage.DB)
+	webhookRepo := sqldb.NewWebhookRepository(storage.DB)
+	notificationRepo := sqldb.NewNotificationRepository(storage.DB)
+	digestRepo := sqldb.NewDigestRepository(storage.DB)
+	messageRepo := sqldb.NewMessageRepository(storage.DB)
+	blockRepo := sqldb.NewBlockRepository(storage.DB)
+	// Counters are kept in the database so every instance enforces the same limits,
+	// memory.NewRateLimitStore() is enough for a single instance
+	rateLimitStore := sqldb.NewRateLimitStore(storage.DB)
+	mediaStore := localfs.NewMediaStore(mediaDir, mediaBaseURL)
+	// Emails are dropped in data/mail unless an SMTP server is configured
+	emailSender := mailer.NewFileMailer("data/mail")
+	if smtpConfig.Host != "" {
+		emailSender = mailer.NewSMTPMailer(smtpConfig)
+	}
+	emailTemplates := mailer.NewTemplates()
+
+	// Shared by the REST and gRPC APIs, a gRPC method counts against the rules of the matching REST route
+	rateLimits := []domain.RateLimitRule{
+		{Group: "users", Policy: domain.RateLimitPolicy{Limit: 60, Window: time.Minute}},
+		{Group: "photos", Methods: []string{"POST", "PUT"}, Policy: domain.RateLimitPolicy{Limit: 30, Window: time.Hour}},
+		{Group: "comments", Methods: []string{"POST", "PUT"}, Policy: domain.RateLimitPolicy{Limit: 10, Window: time.Minute}},
+		{Group: "socialmedias", Methods: []string{"POST", "PUT"}, Policy: domain.RateLimitPolicy{Limit: 10, Window: time.Minute}},
+		{Group: "photos", Policy: domain.RateLimitPolicy{Limit: 300, Window: time.Minute}},
+		{Group: "comments", Policy: domain.RateLimitPolicy{Limit: 300, Window: time.Minute}},
+		{Group: "socialmedias", Policy: domain.RateLimitPolicy{Limit: 300, Window: time.Minute}},
+		{Group: "graphql", Policy: domain.RateLimitPolicy{Limit: 300, Window: time.Minute}},
+		{Group: "messages", Methods: []string{"POST"}, Policy: domain.RateLimitPolicy{Limit: 60, Window: time.Minute}},
+		// Counts connections, a stream stays open
+		{Group: "stream", Policy: domain.RateLimitPolicy{Limit: 30, Window: time.Minute}},
+	}
+
+	// Create service
+	authService := auth.NewAuthService()
+	cryptoService := crypto.NewCryptoService(cryptoConfig)
+	twoFactorService := twofactor.NewService(twoFactorRepo, userRepo, cryptoService, twofactor.Config{Issuer: "MyGram"})
+	loginGuard := loginguard.NewService(loginAttemptRepo, userRepo, auditLogRepo, loginGuardConfig)
+	// Sessions last as long as the access tokens issued for them
+	sessionService := session.NewService(sessionRepo, session.Config{TTL: 72 * time.Hour})
+	// Events of the photo, comment and user services are delivered to the webhooks, the streams and
+	// turned into notifications
+	webhookService := webhook.NewService(webhookRepo, userRepo, cryptoService, webhookConfig)
+	streamHub := stream.NewHub(stream.Config{BufferSize: 64, MaxTopics: 20, MaxSubscriptionsPerUser: 5})
+	notificationService := notification.NewService(notificationRepo, commentRepo, photoRepo, userRepo, blockRepo, notification.Config{QueueSize: 1024, Workers: 2})
+	events := event.NewDispatcher(webhookService, streamHub, notificationService)
+	userService := user.NewService(userRepo, cryptoService, authService, auditLogRepo, userTokenRepo, emailSender, emailTemplates, twoFactorService, loginGuard, sessionService, apiKeyRepo, events, userConfig)
+	photoService := photo.NewService(photoRepo, events)
+	commentService := comment.NewService(commentRepo, photoRepo, blockRepo, events)
+	socialMediaService := socialmedia.NewService(socialMediaRepo)
+	digestService := digest.NewService(digestRepo, userRepo, emailSender, emailTemplates, digestConfig)
+	blockService := block.NewService(blockRepo, userRepo)
+	messageService := message.NewService(messageRepo, userRepo, photoRepo, blockRepo, message.Config{MaxParticipants: 10, MaxBodyLength: 4096})
+	exportService := export.NewService(exportRepo, userRepo, photoRepo, commentRepo, socialMediaRepo, mediaStore, exportConfig)
+	importService := importer.NewService(importRepo, photoService, commentService, mediaStore)
+	rateLimiter := ratelimit.NewService(rateLimitStore)
+	apiKeyService := apikey.NewService(apiKeyRepo, cryptoService, apiKeyConfig)
+	oidcService := oidc.NewService(linkedIdentityRepo, userRepo, userTokenRepo, userService, cryptoService, oidcConfig)
+
+	return &app{
+		port:     PORT,
+		grpcPort: GRPC_PORT,
+		restConfig: rest.Config{
+			MediaDir: mediaDir,
+			// Unverified accounts can't post photos
+			VerifiedEmailRequired: []string{"photos"},
+			RateLimits:            rateLimits,
+			// Existing clients keep the unwrapped responses until they send the version 2 Accept header
+			DefaultAPIVersion: rest.APIVersion1,
+			// GIN_MODE=test also checks the responses against the OpenAPI document
+			ValidateResponses: gin.Mode() == gin.TestMode,
+			// Enough for a page of photos with their comments, authors and social medias
+			GraphQL: graphql.Config{MaxDepth: 8, MaxComplexity: 10000},
+			// X-Forwarded-For is ignored unless the server runs behind the proxies in TRUSTED_PROXIES
+			TrustedProxies: trustedProxies,
+		},
+		grpcConfig: rpc.Config{
+			VerifiedEmailRequired: []string{"photos"},
+			RateLimits:            rateLimits,
+		},
+		storage:             storage,
+		userRepo:            userRepo,
+		authService:         authService,
+		userService:         userService,
+		photoService:        photoService,
+		commentService:      commentService,
+		socialMediaService:  socialMediaService,
+		exportService:       exportService,
+		importService:       importService,
+		twoFactorService:    twoFactorService,
+		loginGuard:          loginGuard,
+		rateLimiter:         rateLimiter,
+		apiKeyService:       apiKeyService,
+		oidcService:         oidcService,
+		sessionService:      sessionService,
+		webhookService:      webhookService,
+		streamHub:           streamHub,
+		notificationService: notificationService,
+		digestService:       digestService,
+		messageService:      messageService,
+		blockService:        blockService,
+	}, nil
+}
+
+func (a *app) Close() error {
+	// The queued comments get their notifications before the database goes away
+	a.notificationService.Close()
+	return a.storage.Close()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"final-project/pkg/domain"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runImport imports an Instagram export for an existing user:
+//
+//	go run ./cmd/app import -user alice instagram.zip
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	username := flags.String("user", "", "username of the account to import into")
+	flags.Parse(args)
+
+	if *username == "" || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: app import -user <username> <archive.zip>")
+		os.Exit(2)
+	}
+
+	a, err := newApp()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	user, err := a.userRepo.GetUserByUsername(*username)
+	if err != nil {
+		log.Fatalf("user %s not found: %v", *username, err)
+	}
+
+	job, err := a.importService.RunImport(user.ID, flags.Arg(0), func(job *domain.ImportJob) {
+		fmt.Printf("\r%s: %d/%d processed, %d imported, %d failed", job.Status, job.Processed, job.Total, job.Imported, job.Failed)
+	})
+	fmt.Println()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, itemError := range job.Errors {
+		fmt.Printf("%s: %s\n", itemError.Item, itemError.Message)
+	}
+}
@@ -1,57 +1,175 @@
 package main
 
 import (
-	"final-project/pkg/auth"
-	"final-project/pkg/comment"
-	"final-project/pkg/crypto"
+	"context"
+	"errors"
 	"final-project/pkg/http/rest"
-	"final-project/pkg/photo"
-	"final-project/pkg/socialmedia"
-	"final-project/pkg/storage/sqldb"
-	"final-project/pkg/user"
+	"final-project/pkg/job"
+	"final-project/pkg/rpc"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"log"
 )
 
 func main() {
-	PORT := "8080"
-	// This sensitive information is written here for the convenience of this assignment
-	dsn := "falfal:Pasword!2@tcp(mysql-dev-db.airy.my.id:3306)/fga_go_final?charset=utf8mb4&parseTime=True&loc=Local"
-	os.Setenv("JWT_SECRET", "supersecret1287401bnf9147ehfn9r247")
+	// Subcommands, the server is started when none is given
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "serve":
+		serve()
+	case "import":
+		runImport(os.Args[2:])
+	case "grant-admin":
+		runGrantAdmin(os.Args[2:])
+	case "migrate-identities":
+		runMigrateIdentities()
+	case "openapi":
+		runOpenAPI(os.Args[2:])
+	default:
+		log.Fatalf("unknown command %q, expected serve, import, grant-admin, migrate-identities or openapi", command)
+	}
+}
 
-	// Create storage
-	storage, err := sqldb.NewStorage(dsn)
+func serve() {
+	a, err := newApp()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer storage.Close()
-
-	// Create repository
-	userRepo := sqldb.NewUserRepository(storage.DB)
-	photoRepo := sqldb.NewPhotoRepository(storage.DB)
-	commentRepo := sqldb.NewCommentRepository(storage.DB)
-	socialMediaRepo := sqldb.NewSocialMediaRepository(storage.DB)
-
-	// Create service
-	authService := auth.NewAuthService()
-	cryptoService := crypto.NewCryptoService()
-	userService := user.NewService(userRepo, cryptoService, authService)
-	photoService := photo.NewService(photoRepo)
-	commentService := comment.NewService(commentRepo)
-	socialMediaService := socialmedia.NewService(socialMediaRepo)
+	defer a.Close()
 
 	// Create router
 	router := rest.NewRouter(
-		&userService,
-		&authService,
-		&photoService,
-		&commentService,
-		&socialMediaService,
+		&a.userService,
+		&a.authService,
+		&a.photoService,
+		&a.commentService,
+		&a.socialMediaService,
+		&a.exportService,
+		&a.importService,
+		&a.twoFactorService,
+		&a.loginGuard,
+		&a.rateLimiter,
+		&a.apiKeyService,
+		&a.oidcService,
+		&a.sessionService,
+		&a.webhookService,
+		&a.streamHub,
+		&a.notificationService,
+		&a.digestService,
+		&a.messageService,
+		&a.blockService,
+		a.restConfig,
 	)
 
-	// Start server
-	log.Println("Starting server on port " + PORT)
-	http.ListenAndServe(":"+PORT, router)
+	// Exports interrupted by the last shutdown are built again
+	resumed, err := a.exportService.ResumePendingExports()
+	if err != nil {
+		log.Printf("failed to resume pending exports: %v", err)
+	} else if resumed > 0 {
+		log.Printf("resumed %d pending exports", resumed)
+	}
+
+	// Start scheduled jobs
+	stopPurge := job.Every("purge-deleted-users", time.Hour, func() error {
+		purged, err := a.userService.PurgeScheduledDeletions()
+		if purged > 0 {
+			log.Printf("purged %d accounts pending deletion", purged)
+		}
+		return err
+	})
+	defer stopPurge()
+
+	stopExportPurge := job.Every("purge-expired-exports", time.Hour, func() error {
+		_, err := a.exportService.PurgeExpiredExports()
+		return err
+	})
+	defer stopExportPurge()
+
+	stopRateLimitPurge := job.Every("purge-expired-rate-limits", time.Hour, func() error {
+		_, err := a.rateLimiter.PurgeExpired()
+		return err
+	})
+	defer stopRateLimitPurge()
+
+	stopLastSeenFlush := job.Every("flush-session-last-seen", time.Minute, a.sessionService.FlushLastSeen)
+	defer stopLastSeenFlush()
+
+	stopSessionPurge := job.Every("purge-expired-sessions", time.Hour, func() error {
+		_, err := a.sessionService.PurgeExpiredSessions()
+		return err
+	})
+	defer stopSessionPurge()
+
+	// Deliveries are sent as soon as they are queued, the job retries the failed ones
+	stopWebhookDelivery := job.Every("deliver-webhooks", 15*time.Second, func() error {
+		_, err := a.webhookService.DeliverDue()
+		return err
+	})
+	defer stopWebhookDelivery()
+
+	// Daily and weekly digests go out in the first run of each UTC day they are due
+	stopDigests := job.Every("send-digests", time.Hour, func() error {
+		sent, err := a.digestService.SendDigests()
+		if sent > 0 {
+			log.Printf("sent %d digests", sent)
+		}
+		return err
+	})
+	defer stopDigests()
+
+	// Start gRPC server for internal consumers
+	grpcServer := rpc.NewServer(
+		a.userService,
+		a.authService,
+		a.photoService,
+		a.commentService,
+		a.socialMediaService,
+		a.apiKeyService,
+		a.blockService,
+		a.rateLimiter,
+		a.grpcConfig,
+	)
+	lis, err := net.Listen("tcp", ":"+a.grpcPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer grpcServer.Stop()
+	go func() {
+		log.Println("Starting gRPC server on port " + a.grpcPort)
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	// Start server, it stops on SIGINT and SIGTERM once the requests in flight are answered so the
+	// deferred shutdown of the jobs and the app runs
+	server := &http.Server{Addr: ":" + a.port, Handler: router}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		// Streams stay open until the timeout
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Println("Starting server on port " + a.port)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("server stopped: %v", err)
+		return
+	}
+	<-shutdown
+	log.Println("Server stopped")
 }
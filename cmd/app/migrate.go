@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// runMigrateIdentities fills the canonical usernames and emails of existing accounts and reports
+// the accounts that only differ by case or width, they have to be renamed by hand:
+//
+//	go run ./cmd/app migrate-identities
+func runMigrateIdentities() {
+	a, err := newApp()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	collisions, err := a.userRepo.MigrateCanonicalIdentities()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(*collisions) == 0 {
+		fmt.Println("canonical identities migrated, no collisions")
+		return
+	}
+
+	fmt.Printf("canonical identities migrated, %d collisions need attention:\n", len(*collisions))
+	for _, collision := range *collisions {
+		accounts := make([]string, len(collision.UserIDs))
+		for i, userID := range collision.UserIDs {
+			accounts[i] = fmt.Sprintf("user %d (%q)", userID, collision.Values[i])
+		}
+		fmt.Printf("  %s %q: %s, only the first one keeps it\n", collision.Field, collision.Canonical, strings.Join(accounts, ", "))
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"final-project/pkg/domain"
+	"final-project/pkg/http/rest"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runOpenAPI prints the OpenAPI document served at /openapi.json, or with -check fails when routes
+// and their documentation drifted apart:
+//
+//	go run ./cmd/app openapi > openapi.json
+//	go run ./cmd/app openapi -check
+func runOpenAPI(args []string) {
+	flags := flag.NewFlagSet("openapi", flag.ExitOnError)
+	check := flags.Bool("check", false, "only report routes missing from the document and the other way round")
+	flags.Parse(args)
+
+	// The routes are only listed, no service is called
+	gin.SetMode(gin.ReleaseMode)
+	var (
+		userService         domain.UserService
+		authService         domain.AuthService
+		photoService        domain.PhotoService
+		commentService      domain.CommentService
+		socialMediaService  domain.SocialMediaService
+		exportService       domain.ExportService
+		importService       domain.ImportService
+		twoFactorService    domain.TwoFactorService
+		loginGuard          domain.LoginGuard
+		rateLimiter         domain.RateLimiter
+		apiKeyService       domain.APIKeyService
+		oidcService         domain.OIDCService
+		sessionService      domain.SessionService
+		webhookService      domain.WebhookService
+		streamHub           domain.StreamHub
+		notificationService domain.NotificationService
+		digestService       domain.DigestService
+		messageService      domain.MessageService
+		blockService        domain.BlockService
+	)
+	router := rest.NewRouter(
+		&userService,
+		&authService,
+		&photoService,
+		&commentService,
+		&socialMediaService,
+		&exportService,
+		&importService,
+		&twoFactorService,
+		&loginGuard,
+		&rateLimiter,
+		&apiKeyService,
+		&oidcService,
+		&sessionService,
+		&webhookService,
+		&streamHub,
+		&notificationService,
+		&digestService,
+		&messageService,
+		&blockService,
+		rest.Config{},
+	)
+
+	spec, drift := rest.BuildOpenAPI(router.Routes())
+	if *check {
+		for _, problem := range drift {
+			fmt.Println(problem)
+		}
+		if len(drift) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("the OpenAPI document matches the routes")
+		return
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(spec); err != nil {
+		log.Fatal(err)
+	}
+}
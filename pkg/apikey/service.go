@@ -0,0 +1,177 @@
+package apikey
+
+import (
+	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// keyPrefix marks API keys so they can be told apart from JWTs in the Authorization header
+const keyPrefix = "mgp_"
+
+var errInvalidKey = domain.NewUnauthorizedError("invalid_api_key", "invalid or expired API key")
+
+type Config struct {
+	// MaxKeysPerUser caps the number of keys of an account
+	MaxKeysPerUser int
+	// MaxExpiresIn is the longest lifetime of a key, 0 allows keys that don't expire
+	MaxExpiresIn time.Duration
+	// LastUsedInterval throttles the last used updates to one per interval per key
+	LastUsedInterval time.Duration
+}
+
+type service struct {
+	repo          domain.APIKeyRepository
+	cryptoService domain.CryptoService
+	config        Config
+}
+
+func NewService(repo domain.APIKeyRepository, cryptoService domain.CryptoService, config Config) domain.APIKeyService {
+	return &service{
+		repo:          repo,
+		cryptoService: cryptoService,
+		config:        config,
+	}
+}
+
+func (s *service) CreateAPIKey(userID uint, req *domain.CreateAPIKeyRequest) (*domain.APIKey, string, error) {
+	if err := s.validate(req); err != nil {
+		return nil, "", err
+	}
+
+	keys, err := s.repo.GetAPIKeysByUserID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	// expired keys can't be used anymore, they don't take up a place
+	active := 0
+	for _, key := range *keys {
+		if key.ExpiresAt == nil || key.ExpiresAt.After(time.Now()) {
+			active++
+		}
+	}
+	if s.config.MaxKeysPerUser > 0 && active >= s.config.MaxKeysPerUser {
+		return nil, "", domain.NewConflictError("api_key_limit_reached", fmt.Sprintf("an account can have at most %d API keys", s.config.MaxKeysPerUser))
+	}
+
+	token, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := keyPrefix + token
+
+	key := domain.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  plaintext[:len(keyPrefix)+6],
+		KeyHash: s.cryptoService.HashToken(plaintext),
+		Scopes:  uniqueScopes(req.Scopes),
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(req.ExpiresIn)
+		key.ExpiresAt = &expiresAt
+	}
+
+	saved, err := s.repo.SaveAPIKey(&key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return saved, plaintext, nil
+}
+
+func (s *service) GetAPIKeys(userID uint) (*[]domain.APIKey, error) {
+	return s.repo.GetAPIKeysByUserID(userID)
+}
+
+func (s *service) RevokeAPIKey(userID uint, keyID uint) error {
+	deleted, err := s.repo.DeleteAPIKey(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return domain.NewNotFoundError("api_key_not_found", "API key not found")
+	}
+
+	return nil
+}
+
+func (s *service) Authenticate(plaintext string) (*domain.APIKey, error) {
+	if !s.IsAPIKey(plaintext) {
+		return nil, errInvalidKey
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(s.cryptoService.HashToken(plaintext))
+	if err != nil {
+		return nil, errInvalidKey
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, errInvalidKey
+	}
+
+	// Don't write on every request of a busy script
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.config.LastUsedInterval {
+		if err := s.repo.UpdateAPIKeyLastUsed(key.ID, now); err != nil {
+			log.Printf("failed to update last use of API key %d: %v", key.ID, err)
+		}
+		key.LastUsedAt = &now
+	}
+
+	return key, nil
+}
+
+func (s *service) IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, keyPrefix)
+}
+
+func (s *service) validate(req *domain.CreateAPIKeyRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return domain.NewFieldValidationError("name", "is required")
+	}
+	if len(req.Name) > 100 {
+		return domain.NewFieldValidationError("name", "must be at most 100 characters")
+	}
+
+	if len(req.Scopes) == 0 {
+		return domain.NewFieldValidationError("scopes", "must have at least one scope")
+	}
+	for _, scope := range req.Scopes {
+		if !isKnownScope(scope) {
+			return domain.NewFieldValidationError("scopes", fmt.Sprintf("has unknown scope %q, expected one of %s", scope, strings.Join(domain.APIKeyScopes, ", ")))
+		}
+	}
+
+	if req.ExpiresIn < 0 {
+		return domain.NewFieldValidationError("expires_in_days", "must be in the future")
+	}
+	if s.config.MaxExpiresIn > 0 && (req.ExpiresIn == 0 || req.ExpiresIn > s.config.MaxExpiresIn) {
+		return domain.NewFieldValidationError("expires_in_days", fmt.Sprintf("must be within %d days", int(s.config.MaxExpiresIn.Hours()/24)))
+	}
+
+	return nil
+}
+
+func isKnownScope(scope string) bool {
+	for _, known := range domain.APIKeyScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
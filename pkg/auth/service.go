@@ -8,8 +8,25 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
+const (
+	// purposeTwoFactorChallenge marks tokens that only allow completing a two-factor login
+	purposeTwoFactorChallenge = "2fa_challenge"
+	// purposeStreamTicket marks tokens that only allow opening a stream
+	purposeStreamTicket = "stream_ticket"
+)
+
+// streamTicketTTL is short since the tickets travel in URLs, which end up in logs
+const streamTicketTTL = time.Minute
+
 type JwtCustomClaims struct {
-	UserID uint
+	UserID       uint
+	TokenVersion uint
+	SessionID    uint `json:",omitempty"`
+	// Purpose is empty for access tokens
+	Purpose string `json:",omitempty"`
+	// APIKey tells whether a stream ticket was requested with an API key, which has Scopes
+	APIKey bool     `json:",omitempty"`
+	Scopes []string `json:",omitempty"`
 	jwt.StandardClaims
 }
 
@@ -21,10 +38,12 @@ func NewAuthService() domain.AuthService {
 }
 
 // GenerateToken is a function to generate JWT token
-func (s *service) GenerateToken(userID uint) (string, error) {
+func (s *service) GenerateToken(tokenClaims *domain.TokenClaims) (string, error) {
 	// Set custom claims
 	claims := JwtCustomClaims{
-		UserID: userID,
+		UserID:       tokenClaims.UserID,
+		TokenVersion: tokenClaims.TokenVersion,
+		SessionID:    tokenClaims.SessionID,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(time.Hour * 72).Unix(),
 		},
@@ -36,7 +55,7 @@ func (s *service) GenerateToken(userID uint) (string, error) {
 }
 
 // ValidateToken is a function to validate JWT token
-func (s *service) ValidateToken(tokenString string) (uint, error) {
+func (s *service) ValidateToken(tokenString string) (*domain.TokenClaims, error) {
 	claims := &JwtCustomClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -44,12 +63,97 @@ func (s *service) ValidateToken(tokenString string) (uint, error) {
 	})
 
 	if err != nil {
-		return 0, err
+		return nil, domain.NewUnauthorizedError("invalid_token", err.Error())
+	}
+
+	if !token.Valid || claims.Purpose != "" {
+		return nil, domain.NewUnauthorizedError("invalid_token", "invalid token")
+	}
+
+	return &domain.TokenClaims{
+		UserID:       claims.UserID,
+		TokenVersion: claims.TokenVersion,
+		SessionID:    claims.SessionID,
+	}, nil
+}
+
+// GenerateChallengeToken is a function to generate the short lived token exchanged for an access token with a two-factor code
+func (s *service) GenerateChallengeToken(userID uint) (string, error) {
+	claims := JwtCustomClaims{
+		UserID:  userID,
+		Purpose: purposeTwoFactorChallenge,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Minute * 5).Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// ValidateChallengeToken is a function to validate a two-factor challenge token
+func (s *service) ValidateChallengeToken(tokenString string) (uint, error) {
+	claims := &JwtCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil {
+		return 0, domain.NewUnauthorizedError("invalid_challenge_token", err.Error())
 	}
 
-	if !token.Valid {
-		return 0, err
+	if !token.Valid || claims.Purpose != purposeTwoFactorChallenge {
+		return 0, domain.NewUnauthorizedError("invalid_challenge_token", "invalid challenge token")
 	}
 
 	return claims.UserID, nil
 }
+
+// GenerateStreamTicket is a function to generate the short lived token which opens a stream from its URL
+func (s *service) GenerateStreamTicket(ticket *domain.StreamTicket) (string, time.Time, error) {
+	expiresAt := time.Now().Add(streamTicketTTL)
+	claims := JwtCustomClaims{
+		UserID:       ticket.UserID,
+		TokenVersion: ticket.TokenVersion,
+		SessionID:    ticket.SessionID,
+		Purpose:      purposeStreamTicket,
+		APIKey:       ticket.Scopes != nil,
+		Scopes:       ticket.Scopes,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signed, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return signed, expiresAt, err
+}
+
+// ValidateStreamTicket is a function to validate a stream ticket
+func (s *service) ValidateStreamTicket(tokenString string) (*domain.StreamTicket, error) {
+	claims := &JwtCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil {
+		return nil, domain.NewUnauthorizedError("invalid_ticket", err.Error())
+	}
+
+	if !token.Valid || claims.Purpose != purposeStreamTicket {
+		return nil, domain.NewUnauthorizedError("invalid_ticket", "invalid stream ticket")
+	}
+
+	ticket := &domain.StreamTicket{
+		UserID:       claims.UserID,
+		TokenVersion: claims.TokenVersion,
+		SessionID:    claims.SessionID,
+	}
+	// A key without scopes keeps none
+	if claims.APIKey {
+		ticket.Scopes = append([]string{}, claims.Scopes...)
+	}
+	return ticket, nil
+}
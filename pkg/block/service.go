@@ -0,0 +1,84 @@
+// Package block keeps the blocks and mutes between users. The other services ask it which users
+// can't interact and whose content to hide.
+package block
+
+import (
+	"final-project/pkg/domain"
+	"time"
+)
+
+type service struct {
+	repo     domain.BlockRepository
+	userRepo domain.UserRepository
+}
+
+func NewService(repo domain.BlockRepository, userRepo domain.UserRepository) domain.BlockService {
+	return &service{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *service) AddRelation(userID uint, targetID uint, relationType string) (*domain.UserRelation, error) {
+	if err := checkType(relationType); err != nil {
+		return nil, err
+	}
+	if targetID == userID {
+		return nil, domain.NewFieldValidationError("user_id", "must be another user")
+	}
+
+	if _, err := s.userRepo.GetUserByID(targetID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveRelation(&domain.UserRelation{
+		UserID:    userID,
+		TargetID:  targetID,
+		Type:      relationType,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *service) RemoveRelation(userID uint, targetID uint, relationType string) error {
+	if err := checkType(relationType); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteRelation(userID, targetID, relationType)
+}
+
+func (s *service) GetRelations(userID uint, relationType string, page domain.PageRequest) (*[]domain.UserRelation, int64, error) {
+	if err := checkType(relationType); err != nil {
+		return nil, 0, err
+	}
+
+	return s.repo.GetRelationsByUserID(userID, relationType, page)
+}
+
+func (s *service) IsBlocked(userID uint, otherUserID uint) (bool, error) {
+	if userID == otherUserID {
+		return false, nil
+	}
+
+	return s.repo.IsBlocked(userID, otherUserID)
+}
+
+func (s *service) HiddenUserIDs(userID uint) (map[uint]bool, error) {
+	ids, err := s.repo.GetHiddenUserIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+func checkType(relationType string) error {
+	if relationType != domain.RelationBlock && relationType != domain.RelationMute {
+		return domain.NewFieldValidationError("type", "must be block or mute")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// Package canonical normalizes identifiers so that variants a person would read as the
+// same name, like "Alice", "alice" or "ａｌｉｃｅ", compare equal.
+package canonical
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Username returns the NFKC case-folded form of a username
+func Username(username string) string {
+	return fold(username)
+}
+
+// Email returns the NFKC case-folded form of an email, the local part included
+// since providers treat it case-insensitively in practice
+func Email(email string) string {
+	return fold(email)
+}
+
+// fold follows the NFKC_Casefold recipe: normalize, fold, normalize again
+// since folding can produce denormalized text
+func fold(s string) string {
+	s = norm.NFKC.String(strings.TrimSpace(s))
+	s = cases.Fold().String(s)
+	return norm.NFKC.String(s)
+}
@@ -2,34 +2,79 @@ package comment
 
 import (
 	"final-project/pkg/domain"
+	"time"
 )
 
 type service struct {
-	repo domain.CommentRepository
+	repo      domain.CommentRepository
+	photoRepo domain.PhotoRepository
+	blockRepo domain.BlockRepository
+	events    domain.EventPublisher
 }
 
-func NewService(repo domain.CommentRepository) domain.CommentService {
+func NewService(repo domain.CommentRepository, photoRepo domain.PhotoRepository, blockRepo domain.BlockRepository, events domain.EventPublisher) domain.CommentService {
 	return &service{
-		repo: repo,
+		repo:      repo,
+		photoRepo: photoRepo,
+		blockRepo: blockRepo,
+		events:    events,
 	}
 }
 
 func (s *service) AddComment(userID uint, photoID uint, message string) (*domain.Comment, error) {
+	photo, err := s.photoRepo.GetPhotoByID(photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The photos of a user who blocked the author, or whom the author blocked, don't exist for them
+	blocked, err := s.blockRepo.IsBlocked(userID, photo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, domain.ErrPhotoNotFound
+	}
+
 	comment := &domain.Comment{
 		UserID:  userID,
 		PhotoID: photoID,
 		Message: message,
 	}
 
-	return s.repo.SaveComment(comment)
+	saved, err := s.repo.SaveComment(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	// The event concerns the author and the owner of the photo, unless the owner muted the author
+	userIDs := []uint{userID}
+	if photo.UserID != userID {
+		hiding, err := s.blockRepo.GetHidingUserIDs(userID, []uint{photo.UserID})
+		if err == nil && len(hiding) == 0 {
+			userIDs = append(userIDs, photo.UserID)
+		}
+	}
+	s.events.Publish(&domain.Event{
+		Type:       domain.EventCommentCreated,
+		ActorID:    userID,
+		UserIDs:    userIDs,
+		Data:       saved,
+		OccurredAt: time.Now(),
+	})
+	return saved, nil
 }
 
 func (s *service) GetCommentByID(commentID uint) (*domain.Comment, error) {
 	return s.repo.GetCommentByID(commentID)
 }
 
-func (s *service) GetCommentsByUserID(userID uint) (*[]domain.Comment, error) {
-	return s.repo.GetCommentsByUserID(userID)
+func (s *service) GetCommentsByUserID(userID uint, page domain.PageRequest) (*[]domain.Comment, int64, error) {
+	return s.repo.GetCommentsByUserID(userID, page)
+}
+
+func (s *service) GetCommentsByPhotoIDs(photoIDs []uint) (*[]domain.Comment, error) {
+	return s.repo.GetCommentsByPhotoIDs(photoIDs)
 }
 
 func (s *service) UpdateComment(commentID uint, message string) (*domain.Comment, error) {
@@ -1,23 +1,176 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"final-project/pkg/domain"
+	"fmt"
+	"runtime"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var errInvalidPassword = errors.New("invalid password")
+
+type Config struct {
+	// Algorithm hashes new passwords, hashes of the other algorithm are still verified
+	// and reported by NeedsRehash
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+	// MaxConcurrent caps the hashes computed at the same time, defaults to the number of CPUs
+	MaxConcurrent int
+}
+
 type service struct {
+	config Config
+	// slots is the bounded pool the password hashing runs in
+	slots chan struct{}
 }
 
-func NewCryptoService() domain.CryptoService {
-	return &service{}
+func NewCryptoService(config Config) domain.CryptoService {
+	if config.Algorithm == "" {
+		config.Algorithm = AlgorithmArgon2id
+	}
+	if config.BcryptCost == 0 {
+		config.BcryptCost = bcrypt.DefaultCost
+	}
+	if config.Argon2 == (Argon2Params{}) {
+		config.Argon2 = DefaultArgon2Params
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = runtime.NumCPU()
+	}
+
+	return &service{
+		config: config,
+		slots:  make(chan struct{}, config.MaxConcurrent),
+	}
 }
 
 func (s *service) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	s.acquire()
+	defer s.release()
+
+	switch s.config.Algorithm {
+	case AlgorithmBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), s.config.BcryptCost)
+		return string(bytes), err
+	case AlgorithmArgon2id:
+		return hashArgon2id(password, s.config.Argon2)
+	}
+	return "", fmt.Errorf("unknown password hashing algorithm %q", s.config.Algorithm)
 }
 
+// VerifyPassword checks plaintext against a hash of any supported algorithm, identified by its prefix
 func (s *service) VerifyPassword(plaintext string, hashed string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plaintext))
+	s.acquire()
+	defer s.release()
+
+	switch algorithmOf(hashed) {
+	case AlgorithmBcrypt:
+		if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plaintext)); err != nil {
+			return errInvalidPassword
+		}
+		return nil
+	case AlgorithmArgon2id:
+		return verifyArgon2id(plaintext, hashed)
+	}
+	return errors.New("unsupported password hash")
+}
+
+// NeedsRehash tells whether hashed was made with another algorithm or other parameters than configured
+func (s *service) NeedsRehash(hashed string) bool {
+	if algorithmOf(hashed) != s.config.Algorithm {
+		return true
+	}
+
+	switch s.config.Algorithm {
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(hashed))
+		return err != nil || cost != s.config.BcryptCost
+	case AlgorithmArgon2id:
+		params, _, _, err := decodeArgon2id(hashed)
+		return err != nil || params != s.config.Argon2
+	}
+	return false
+}
+
+// GenerateRandomToken returns a url safe random token with 256 bits of entropy
+func (s *service) GenerateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest of a random token, used to store tokens at rest
+func (s *service) HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// acquire waits for a free slot so concurrent logins queue up instead of exhausting CPU and memory
+func (s *service) acquire() {
+	s.slots <- struct{}{}
+}
+
+func (s *service) release() {
+	<-s.slots
+}
+
+// Algorithms returns the configured algorithm followed by the other supported ones
+func (s *service) Algorithms() []string {
+	algorithms := []string{s.config.Algorithm}
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt} {
+		if algorithm != s.config.Algorithm {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
+
+func (s *service) HashPrefixes(algorithm string) []string {
+	return hashPrefixes[algorithm]
+}
+
+func (s *service) DummyHash(algorithm string) (string, error) {
+	s.acquire()
+	defer s.release()
+
+	switch algorithm {
+	case AlgorithmBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte("not a real password"), s.config.BcryptCost)
+		return string(bytes), err
+	case AlgorithmArgon2id:
+		return hashArgon2id("not a real password", s.config.Argon2)
+	}
+	return "", fmt.Errorf("unknown password hashing algorithm %q", algorithm)
+}
+
+// hashPrefixes identify the algorithm of a hash
+var hashPrefixes = map[string][]string{
+	AlgorithmBcrypt:   {"$2a$", "$2b$", "$2y$"},
+	AlgorithmArgon2id: {"$argon2id$"},
+}
+
+func algorithmOf(hashed string) string {
+	for algorithm, prefixes := range hashPrefixes {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(hashed, prefix) {
+				return algorithm
+			}
+		}
+	}
+	return ""
 }
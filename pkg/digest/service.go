@@ -0,0 +1,288 @@
+// Package digest emails users a summary of the comments on their photos and keeps their email
+// preferences, which the signed unsubscribe links of the digests change.
+package digest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"final-project/pkg/domain"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// DefaultDigest is the digest frequency of users who never chose one, DigestWeekly when empty
+	DefaultDigest string
+	// UnsubscribeURL is the unsubscribe endpoint, the token is added as query parameter
+	UnsubscribeURL string
+	// Secret signs the unsubscribe tokens, a random one is used when empty and the links of the
+	// sent digests stop working on restart
+	Secret []byte
+	// MaxPhotos is the number of photos listed in a digest, 10 when zero
+	MaxPhotos int
+	// BatchSize is the number of recipients loaded at once, 100 when zero
+	BatchSize int
+	// MaxAttempts is the number of failed attempts after which a digest is skipped, 5 when zero
+	MaxAttempts int
+	// RetryBackoff is the wait after the first failed attempt, doubled after each, 1 hour when zero
+	RetryBackoff time.Duration
+}
+
+type service struct {
+	repo      domain.DigestRepository
+	userRepo  domain.UserRepository
+	mailer    domain.Mailer
+	templates domain.EmailTemplates
+	config    Config
+}
+
+func NewService(repo domain.DigestRepository, userRepo domain.UserRepository, mailer domain.Mailer, templates domain.EmailTemplates, config Config) domain.DigestService {
+	if config.DefaultDigest == "" {
+		config.DefaultDigest = domain.DigestWeekly
+	}
+	if len(config.Secret) == 0 {
+		log.Println("digest: no secret configured, unsubscribe links won't survive a restart")
+		config.Secret = make([]byte, 32)
+		if _, err := rand.Read(config.Secret); err != nil {
+			// crypto/rand doesn't fail on the supported platforms
+			panic(err)
+		}
+	}
+	if config.MaxPhotos <= 0 {
+		config.MaxPhotos = 10
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Hour
+	}
+
+	return &service{
+		repo:      repo,
+		userRepo:  userRepo,
+		mailer:    mailer,
+		templates: templates,
+		config:    config,
+	}
+}
+
+func (s *service) GetEmailPreferences(userID uint) (*domain.EmailPreferences, error) {
+	preferences, err := s.repo.GetEmailPreferences(userID)
+	if errors.Is(err, domain.ErrEmailPreferencesNotFound) {
+		return &domain.EmailPreferences{
+			UserID: userID,
+			Digest: s.config.DefaultDigest,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (s *service) UpdateEmailPreferences(userID uint, digest string) (*domain.EmailPreferences, error) {
+	if !isDigestFrequency(digest) {
+		return nil, domain.NewFieldValidationError("digest", "must be one of "+strings.Join(domain.DigestFrequencies, ", "))
+	}
+
+	if err := s.repo.SaveEmailPreferences(&domain.EmailPreferences{UserID: userID, Digest: digest}); err != nil {
+		return nil, err
+	}
+
+	return s.GetEmailPreferences(userID)
+}
+
+func (s *service) CheckUnsubscribeToken(token string) (*domain.EmailPreferences, error) {
+	userID, ok := s.verifyToken(token)
+	if !ok {
+		return nil, domain.ErrInvalidUnsubscribeToken
+	}
+
+	// The links of deleted accounts are dead
+	if _, err := s.userRepo.GetUserByID(userID); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrInvalidUnsubscribeToken
+		}
+		return nil, err
+	}
+
+	return s.GetEmailPreferences(userID)
+}
+
+func (s *service) Unsubscribe(token string) (*domain.EmailPreferences, error) {
+	preferences, err := s.CheckUnsubscribeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.UpdateEmailPreferences(preferences.UserID, domain.DigestOff)
+}
+
+// SendDigests sends the digests once per day or per 7 days, counted in UTC days so the time of
+// the runs doesn't shift the digests. Users without activity on their photos get no email. A digest
+// that fails to send is retried later rather than holding up the others.
+func (s *service) SendDigests() (int, error) {
+	now := time.Now()
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	sent := 0
+	for _, schedule := range []struct {
+		digest     string
+		sentBefore time.Time
+		period     time.Duration
+	}{
+		{domain.DigestDaily, today, 24 * time.Hour},
+		{domain.DigestWeekly, today.AddDate(0, 0, -6), 7 * 24 * time.Hour},
+	} {
+		for {
+			recipients, err := s.repo.GetDigestRecipients(schedule.digest, s.config.DefaultDigest, schedule.sentBefore, now, s.config.BatchSize)
+			if err != nil {
+				return sent, err
+			}
+
+			for _, recipient := range recipients {
+				since := now.Add(-schedule.period)
+				if recipient.LastDigestAt != nil {
+					since = *recipient.LastDigestAt
+				}
+
+				email, err := s.compose(&recipient, schedule.digest, since)
+				if err != nil {
+					return sent, err
+				}
+				if email != nil {
+					if err := s.mailer.Send(email); err != nil {
+						if err := s.retryLater(&recipient, schedule.digest, now, err); err != nil {
+							return sent, err
+						}
+						continue
+					}
+					sent++
+				}
+
+				// Recipients are marked even without activity so they aren't loaded again
+				if err := s.repo.MarkDigestSent(recipient.UserID, schedule.digest, now); err != nil {
+					return sent, err
+				}
+			}
+
+			if len(recipients) < s.config.BatchSize {
+				break
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// retryLater backs off the digest of a recipient which failed to send, and skips it after
+// MaxAttempts so the address doesn't fail forever
+func (s *service) retryLater(recipient *domain.DigestRecipient, digest string, now time.Time, sendErr error) error {
+	failures := recipient.DigestFailures + 1
+	if failures >= s.config.MaxAttempts {
+		log.Printf("digest: skipped the %s digest of user %d after %d failed attempts: %v", digest, recipient.UserID, failures, sendErr)
+		return s.repo.MarkDigestSent(recipient.UserID, digest, now)
+	}
+
+	retryAt := now.Add(s.config.RetryBackoff << (failures - 1))
+	log.Printf("digest: failed to send the %s digest of user %d, retrying at %s: %v", digest, recipient.UserID, retryAt.Format(time.RFC3339), sendErr)
+	return s.repo.MarkDigestFailed(recipient.UserID, digest, retryAt)
+}
+
+// compose writes the digest of the activity since a time, nil when there was none
+func (s *service) compose(recipient *domain.DigestRecipient, digest string, since time.Time) (*domain.Email, error) {
+	activity, err := s.repo.GetPhotoActivity(recipient.UserID, since)
+	if err != nil {
+		return nil, err
+	}
+	if len(activity) == 0 {
+		return nil, nil
+	}
+
+	var comments int64
+	for _, photo := range activity {
+		comments += photo.Comments
+	}
+
+	data := map[string]interface{}{
+		"Username":       recipient.Username,
+		"Comments":       comments,
+		"Photos":         activity,
+		"More":           0,
+		"Period":         "this week",
+		"Frequency":      "every week",
+		"UnsubscribeURL": s.unsubscribeURL(recipient.UserID),
+	}
+	if len(activity) > s.config.MaxPhotos {
+		data["Photos"] = activity[:s.config.MaxPhotos]
+		data["More"] = len(activity) - s.config.MaxPhotos
+	}
+	if digest == domain.DigestDaily {
+		data["Period"] = "today"
+		data["Frequency"] = "every day"
+	}
+
+	email, err := s.templates.Render("digest", data)
+	if err != nil {
+		return nil, err
+	}
+
+	email.To = recipient.Email
+	// One-click unsubscribe from the mail client, RFC 8058
+	email.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + data["UnsubscribeURL"].(string) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+
+	return email, nil
+}
+
+func (s *service) unsubscribeURL(userID uint) string {
+	token := strconv.FormatUint(uint64(userID), 10) + "." + s.sign(userID)
+	return s.config.UnsubscribeURL + "?token=" + url.QueryEscape(token)
+}
+
+// verifyToken returns the user of an unsubscribe token, tokens don't expire
+func (s *service) verifyToken(token string) (uint, bool) {
+	id, signature, found := strings.Cut(token, ".")
+	if !found {
+		return 0, false
+	}
+
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(uint(userID)))) {
+		return 0, false
+	}
+
+	return uint(userID), true
+}
+
+func (s *service) sign(userID uint) string {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte("unsubscribe:" + strconv.FormatUint(uint64(userID), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isDigestFrequency(digest string) bool {
+	for _, frequency := range domain.DigestFrequencies {
+		if frequency == digest {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package digest
+
+import (
+	"errors"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"final-project/pkg/mailer"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDigestRepo holds weekly recipients who all have activity, filtered like the query does
+type fakeDigestRepo struct {
+	domain.DigestRepository
+	recipients  []domain.DigestRecipient
+	retryAt     map[uint]time.Time
+	preferences map[uint]string
+}
+
+func (r *fakeDigestRepo) GetDigestRecipients(digest string, defaultDigest string, sentBefore time.Time, now time.Time, limit int) ([]domain.DigestRecipient, error) {
+	due := []domain.DigestRecipient{}
+	for _, recipient := range r.recipients {
+		if digest != defaultDigest || (recipient.LastDigestAt != nil && !recipient.LastDigestAt.Before(sentBefore)) {
+			continue
+		}
+		if retryAt, ok := r.retryAt[recipient.UserID]; ok && retryAt.After(now) {
+			continue
+		}
+		if len(due) < limit {
+			due = append(due, recipient)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeDigestRepo) MarkDigestSent(userID uint, digest string, sentAt time.Time) error {
+	recipient := r.recipient(userID)
+	recipient.LastDigestAt = &sentAt
+	recipient.DigestFailures = 0
+	delete(r.retryAt, userID)
+	return nil
+}
+
+func (r *fakeDigestRepo) MarkDigestFailed(userID uint, digest string, retryAt time.Time) error {
+	r.recipient(userID).DigestFailures++
+	r.retryAt[userID] = retryAt
+	return nil
+}
+
+func (r *fakeDigestRepo) GetPhotoActivity(userID uint, since time.Time) ([]domain.PhotoActivity, error) {
+	return []domain.PhotoActivity{{PhotoID: 1, Title: "Sunset", Comments: 2, Commenters: 1}}, nil
+}
+
+func (r *fakeDigestRepo) GetEmailPreferences(userID uint) (*domain.EmailPreferences, error) {
+	digest, ok := r.preferences[userID]
+	if !ok {
+		return nil, domain.ErrEmailPreferencesNotFound
+	}
+	return &domain.EmailPreferences{UserID: userID, Digest: digest}, nil
+}
+
+func (r *fakeDigestRepo) SaveEmailPreferences(preferences *domain.EmailPreferences) error {
+	r.preferences[preferences.UserID] = preferences.Digest
+	return nil
+}
+
+func (r *fakeDigestRepo) recipient(userID uint) *domain.DigestRecipient {
+	for i := range r.recipients {
+		if r.recipients[i].UserID == userID {
+			return &r.recipients[i]
+		}
+	}
+	panic("unknown recipient")
+}
+
+// newTestService sends the digests of users 1 to 3, the mailer fails for the addresses of
+// bounce.example
+func newTestService(recipients ...domain.DigestRecipient) (*service, *fakeDigestRepo, *fake.Mailer) {
+	repo := &fakeDigestRepo{recipients: recipients, retryAt: map[uint]time.Time{}, preferences: map[uint]string{}}
+	users := fake.NewUserRepo(domain.User{ID: 1}, domain.User{ID: 2}, domain.User{ID: 3})
+	m := &fake.Mailer{}
+	s := NewService(repo, users, m, mailer.NewTemplates(), Config{
+		UnsubscribeURL: "https://mygram.example/users/unsubscribe",
+		Secret:         []byte("secret"),
+		BatchSize:      2,
+		MaxAttempts:    3,
+		RetryBackoff:   time.Hour,
+	}).(*service)
+	return s, repo, m
+}
+
+func TestSendDigestsContinuesAfterAFailure(t *testing.T) {
+	s, repo, m := newTestService(
+		domain.DigestRecipient{UserID: 1, Username: "alice", Email: "alice@example.com"},
+		domain.DigestRecipient{UserID: 2, Username: "bob", Email: "bob@bounce.example"},
+		domain.DigestRecipient{UserID: 3, Username: "carol", Email: "carol@example.com"},
+	)
+
+	sent, err := s.SendDigests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipients := m.Recipients(); sent != 2 || strings.Join(recipients, ",") != "alice@example.com,carol@example.com" {
+		t.Errorf("sent %d digests to %v, want the ones of alice and carol", sent, recipients)
+	}
+
+	bob := repo.recipient(2)
+	if bob.LastDigestAt != nil || bob.DigestFailures != 1 {
+		t.Errorf("got %+v, want one failure and no digest", bob)
+	}
+	if wait := time.Until(repo.retryAt[2]); wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("retried in %v, want an hour", wait)
+	}
+
+	// the next run within the hour leaves bob alone
+	if sent, err := s.SendDigests(); err != nil || sent != 0 {
+		t.Errorf("second run sent %d, %v", sent, err)
+	}
+}
+
+func TestSendDigestsBacksOffThenSkips(t *testing.T) {
+	s, repo, _ := newTestService(domain.DigestRecipient{UserID: 2, Username: "bob", Email: "bob@bounce.example"})
+
+	var waits []time.Duration
+	for attempt := 1; attempt <= 3; attempt++ {
+		start := time.Now()
+		if _, err := s.SendDigests(); err != nil {
+			t.Fatal(err)
+		}
+		if retryAt, ok := repo.retryAt[2]; ok {
+			waits = append(waits, retryAt.Sub(start).Round(time.Hour))
+			// the retry falls due
+			repo.retryAt[2] = start
+		}
+	}
+
+	if len(waits) != 2 || waits[0] != time.Hour || waits[1] != 2*time.Hour {
+		t.Errorf("got waits %v, want 1h then 2h", waits)
+	}
+	bob := repo.recipient(2)
+	if bob.LastDigestAt == nil || bob.DigestFailures != 0 {
+		t.Errorf("got %+v, want the digest skipped after 3 attempts", bob)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	s, repo, _ := newTestService()
+	token := strings.TrimPrefix(s.unsubscribeURL(1), "https://mygram.example/users/unsubscribe?token=")
+
+	preferences, err := s.CheckUnsubscribeToken(token)
+	if err != nil || preferences.Digest != domain.DigestWeekly {
+		t.Fatalf("got %+v, %v", preferences, err)
+	}
+	if _, ok := repo.preferences[1]; ok {
+		t.Error("checking the token changed the preferences")
+	}
+
+	if preferences, err := s.Unsubscribe(token); err != nil || preferences.Digest != domain.DigestOff {
+		t.Errorf("got %+v, %v", preferences, err)
+	}
+
+	for _, token := range []string{"1.forged", "2." + s.sign(1), "9." + s.sign(9), "nope"} {
+		if _, err := s.CheckUnsubscribeToken(token); !errors.Is(err, domain.ErrInvalidUnsubscribeToken) {
+			t.Errorf("%s: got %v, want ErrInvalidUnsubscribeToken", token, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package domain
+
+import "time"
+
+const (
+	ScopeProfileRead        = "profile:read"
+	ScopePhotosRead         = "photos:read"
+	ScopePhotosWrite        = "photos:write"
+	ScopeCommentsRead       = "comments:read"
+	ScopeCommentsWrite      = "comments:write"
+	ScopeSocialMediasRead   = "socialmedias:read"
+	ScopeSocialMediasWrite  = "socialmedias:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+	ScopeMessagesRead       = "messages:read"
+	ScopeMessagesWrite      = "messages:write"
+)
+
+// APIKeyScopes lists the scopes a personal API key can be granted
+var APIKeyScopes = []string{
+	ScopeProfileRead,
+	ScopePhotosRead,
+	ScopePhotosWrite,
+	ScopeCommentsRead,
+	ScopeCommentsWrite,
+	ScopeSocialMediasRead,
+	ScopeSocialMediasWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+	ScopeMessagesRead,
+	ScopeMessagesWrite,
+}
+
+// APIKey is a personal access token for scripts, only its hash is stored
+type APIKey struct {
+	ID     uint
+	UserID uint
+	Name   string
+	// Prefix is the start of the key, shown so the user can tell keys apart
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// HasScope tells whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string
+	Scopes []string
+	// ExpiresIn is the lifetime of the key, 0 for a key that doesn't expire
+	ExpiresIn time.Duration
+}
+
+type APIKeyService interface {
+	// CreateAPIKey returns the stored key and the plaintext key, which is shown only this once
+	CreateAPIKey(userID uint, req *CreateAPIKeyRequest) (*APIKey, string, error)
+	GetAPIKeys(userID uint) (*[]APIKey, error)
+	RevokeAPIKey(userID uint, keyID uint) error
+	// Authenticate returns the key matching the plaintext key unless it is expired
+	Authenticate(key string) (*APIKey, error)
+	// IsAPIKey tells whether a bearer token looks like an API key rather than a JWT
+	IsAPIKey(token string) bool
+}
+
+type APIKeyRepository interface {
+	SaveAPIKey(key *APIKey) (*APIKey, error)
+	GetAPIKeysByUserID(userID uint) (*[]APIKey, error)
+	GetAPIKeyByHash(keyHash string) (*APIKey, error)
+	// DeleteAPIKey reports whether a key of the user was deleted
+	DeleteAPIKey(userID uint, keyID uint) (bool, error)
+	// DeleteAPIKeysByUserID revokes every key of the user and returns how many there were
+	DeleteAPIKeysByUserID(userID uint) (int64, error)
+	UpdateAPIKeyLastUsed(keyID uint, usedAt time.Time) error
+}
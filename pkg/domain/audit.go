@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	AuditActionDeletionRequested = "user.deletion_requested"
+	AuditActionDeletionCancelled = "user.deletion_cancelled"
+	AuditActionUserPurged        = "user.purged"
+)
+
+type AuditLog struct {
+	ID        uint
+	Action    string
+	UserID    uint
+	Detail    string
+	CreatedAt time.Time
+}
+
+type AuditLogRepository interface {
+	SaveAuditLog(auditLog *AuditLog) (*AuditLog, error)
+	// GetAuditLogsByAction returns the latest logs of action first
+	GetAuditLogsByAction(action string, limit int) (*[]AuditLog, error)
+}
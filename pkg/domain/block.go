@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+const (
+	// RelationBlock stops the blocked user from seeing the photos of the user, commenting on them
+	// and messaging the user, and hides their content from the user
+	RelationBlock = "block"
+	// RelationMute hides the content of the muted user from the user, the muted user isn't told
+	RelationMute = "mute"
+)
+
+var ErrRelationNotFound = NewNotFoundError("relation_not_found", "user is not blocked or muted")
+
+// UserRelation is a block or a mute of TargetID by UserID
+type UserRelation struct {
+	UserID   uint
+	TargetID uint
+	// Type is RelationBlock or RelationMute
+	Type      string
+	CreatedAt time.Time
+}
+
+type BlockService interface {
+	// AddRelation blocks or mutes the target, adding an existing relation again keeps it as is
+	AddRelation(userID uint, targetID uint, relationType string) (*UserRelation, error)
+	RemoveRelation(userID uint, targetID uint, relationType string) error
+	// GetRelations returns the blocks or the mutes of the user, newest first
+	GetRelations(userID uint, relationType string, page PageRequest) (*[]UserRelation, int64, error)
+	// IsBlocked tells whether either user blocked the other, they can't interact then
+	IsBlocked(userID uint, otherUserID uint) (bool, error)
+	// HiddenUserIDs returns the set of users whose content is hidden from the user: the ones they
+	// blocked or muted and the ones who blocked them. Every read path leaves their content out.
+	HiddenUserIDs(userID uint) (map[uint]bool, error)
+}
+
+type BlockRepository interface {
+	// SaveRelation stores the relation unless it exists already
+	SaveRelation(relation *UserRelation) (*UserRelation, error)
+	// DeleteRelation returns ErrRelationNotFound when there was no such relation
+	DeleteRelation(userID uint, targetID uint, relationType string) error
+	GetRelationsByUserID(userID uint, relationType string, page PageRequest) (*[]UserRelation, int64, error)
+	IsBlocked(userID uint, otherUserID uint) (bool, error)
+	GetHiddenUserIDs(userID uint) ([]uint, error)
+	// GetHidingUserIDs returns the users among userIDs from whom the content of actorID is hidden
+	GetHidingUserIDs(actorID uint, userIDs []uint) ([]uint, error)
+}
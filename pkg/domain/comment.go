@@ -12,17 +12,24 @@ type Comment struct {
 }
 
 type CommentService interface {
+	// AddComment returns ErrPhotoNotFound when the photo is missing or either user blocked the other
 	AddComment(userID uint, photoID uint, message string) (*Comment, error)
-	GetCommentsByUserID(userID uint) (*[]Comment, error)
+	GetCommentsByUserID(userID uint, page PageRequest) (*[]Comment, int64, error)
+	// GetCommentsByPhotoIDs loads the comments of several photos in one query
+	GetCommentsByPhotoIDs(photoIDs []uint) (*[]Comment, error)
 	UpdateComment(commentID uint, message string) (*Comment, error)
 	DeleteComment(commentID uint) error
 	GetCommentByID(commentID uint) (*Comment, error)
 }
 
+// CommentRepository leaves out the comments of accounts pending deletion
 type CommentRepository interface {
 	SaveComment(comment *Comment) (*Comment, error)
 	GetCommentByID(commentID uint) (*Comment, error)
-	GetCommentsByUserID(userID uint) (*[]Comment, error)
+	GetCommentsByUserID(userID uint, page PageRequest) (*[]Comment, int64, error)
+	GetCommentsByPhotoIDs(photoIDs []uint) (*[]Comment, error)
+	// GetCommenterIDs returns the distinct users who commented on the photo
+	GetCommenterIDs(photoID uint) ([]uint, error)
 	UpdateComment(comment *Comment) (*Comment, error)
 	DeleteCommentByID(commentID uint) error
 }
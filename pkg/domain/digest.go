@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestFrequencies lists the values of EmailPreferences.Digest
+var DigestFrequencies = []string{
+	DigestOff,
+	DigestDaily,
+	DigestWeekly,
+}
+
+var (
+	ErrEmailPreferencesNotFound = NewNotFoundError("email_preferences_not_found", "email preferences not found")
+	ErrInvalidUnsubscribeToken  = NewValidationError("invalid_token", "invalid unsubscribe link")
+)
+
+// EmailPreferences are the choices of a user about the emails that aren't needed to use the account
+type EmailPreferences struct {
+	UserID uint
+	// Digest is how often the activity digest is sent, one of DigestFrequencies
+	Digest       string
+	LastDigestAt *time.Time
+}
+
+// DigestRecipient is a user whose digest is due
+type DigestRecipient struct {
+	UserID       uint
+	Username     string
+	Email        string
+	LastDigestAt *time.Time
+	// DigestFailures counts the failed attempts at the due digest
+	DigestFailures int
+}
+
+// PhotoActivity sums up the comments other users posted on a photo
+type PhotoActivity struct {
+	PhotoID    uint
+	Title      string
+	Comments   int64
+	Commenters int64
+}
+
+type DigestService interface {
+	// GetEmailPreferences returns the preferences of the user, the defaults when they never changed them
+	GetEmailPreferences(userID uint) (*EmailPreferences, error)
+	UpdateEmailPreferences(userID uint, digest string) (*EmailPreferences, error)
+	// CheckUnsubscribeToken returns the preferences of the user a signed unsubscribe token was
+	// issued to, without changing them
+	CheckUnsubscribeToken(token string) (*EmailPreferences, error)
+	// Unsubscribe turns the digest off for the user a signed unsubscribe token was issued to
+	Unsubscribe(token string) (*EmailPreferences, error)
+	// SendDigests sends the daily and weekly digests which are due and returns how many were sent
+	SendDigests() (int, error)
+}
+
+type DigestRepository interface {
+	// GetEmailPreferences returns ErrEmailPreferencesNotFound for users who never changed them
+	GetEmailPreferences(userID uint) (*EmailPreferences, error)
+	SaveEmailPreferences(preferences *EmailPreferences) error
+	// GetDigestRecipients returns at most limit users with a verified email whose digest setting,
+	// defaultDigest when not stored, is digest, whose last digest was sent before sentBefore and
+	// whose failed digest isn't retried after now
+	GetDigestRecipients(digest string, defaultDigest string, sentBefore time.Time, now time.Time, limit int) ([]DigestRecipient, error)
+	// MarkDigestSent records the digest of the user and clears their failures, storing digest as
+	// their setting when they had none
+	MarkDigestSent(userID uint, digest string, sentAt time.Time) error
+	// MarkDigestFailed counts a failed attempt at the digest of the user, which is retried at retryAt
+	MarkDigestFailed(userID uint, digest string, retryAt time.Time) error
+	// GetPhotoActivity returns the comments of other users on the photos of the user since a time,
+	// the busiest photos first, without the comments of the users hidden from the user
+	GetPhotoActivity(userID uint, since time.Time) ([]PhotoActivity, error)
+}
@@ -0,0 +1,96 @@
+package domain
+
+// ErrorKind is the category of an Error, the HTTP layer maps it to a status code
+type ErrorKind string
+
+const (
+	ErrorKindValidation      ErrorKind = "validation"
+	ErrorKindUnauthorized    ErrorKind = "unauthorized"
+	ErrorKindForbidden       ErrorKind = "forbidden"
+	ErrorKindNotFound        ErrorKind = "not_found"
+	ErrorKindConflict        ErrorKind = "conflict"
+	ErrorKindGone            ErrorKind = "gone"
+	ErrorKindTooManyRequests ErrorKind = "too_many_requests"
+	ErrorKindNotAcceptable   ErrorKind = "not_acceptable"
+	ErrorKindTooLarge        ErrorKind = "too_large"
+)
+
+// Error is an error meant for the client. Code is stable and machine-readable,
+// Message is human-readable and may change. Errors that aren't an *Error are
+// internal and never shown to clients.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Fields details which request fields failed validation
+	Fields []FieldError
+}
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors with the same code so errors.Is works with the Err* values
+// whatever message the instance carries
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func NewValidationError(code string, message string, fields ...FieldError) *Error {
+	return &Error{Kind: ErrorKindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// NewFieldValidationError reports one invalid request field, the message reads "<field> <message>"
+func NewFieldValidationError(field string, message string) *Error {
+	return NewValidationError(ErrCodeValidationFailed, field+" "+message, FieldError{Field: field, Message: message})
+}
+
+func NewUnauthorizedError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindUnauthorized, Code: code, Message: message}
+}
+
+func NewForbiddenError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindForbidden, Code: code, Message: message}
+}
+
+func NewNotFoundError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindNotFound, Code: code, Message: message}
+}
+
+func NewConflictError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindConflict, Code: code, Message: message}
+}
+
+func NewGoneError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindGone, Code: code, Message: message}
+}
+
+func NewTooManyRequestsError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindTooManyRequests, Code: code, Message: message}
+}
+
+func NewNotAcceptableError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindNotAcceptable, Code: code, Message: message}
+}
+
+func NewTooLargeError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindTooLarge, Code: code, Message: message}
+}
+
+// ErrCodeValidationFailed is the code of invalid request fields
+const ErrCodeValidationFailed = "validation_failed"
+
+var (
+	ErrUserNotFound        = NewNotFoundError("user_not_found", "user not found")
+	ErrPhotoNotFound       = NewNotFoundError("photo_not_found", "photo not found")
+	ErrCommentNotFound     = NewNotFoundError("comment_not_found", "comment not found")
+	ErrSocialMediaNotFound = NewNotFoundError("social_media_not_found", "social media not found")
+	// ErrNotOwner is returned when acting on a resource of another user
+	ErrNotOwner = NewForbiddenError("not_owner", "insufficient privileges")
+)
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	EventPhotoCreated   = "photo.created"
+	EventCommentCreated = "comment.created"
+	EventUserDeleted    = "user.deleted"
+)
+
+var ErrSlowConsumer = NewTooManyRequestsError("slow_consumer", "subscription fell too far behind and was closed, subscribe again")
+
+// Event is something that happened to the content of accounts
+type Event struct {
+	// ID is set by the dispatcher when empty, subscribers use it to tell deliveries apart
+	ID   string
+	Type string
+	// ActorID is the user who caused the event
+	ActorID uint
+	// UserIDs are the accounts the event concerns
+	UserIDs []uint
+	// Data is the subject of the event, a *Photo, *Comment or *User
+	Data       interface{}
+	OccurredAt time.Time
+}
+
+// EventPublisher is given the events of the services, Publish must not block on the subscribers
+type EventPublisher interface {
+	Publish(event *Event)
+}
+
+// PhotoCommentsTopic is the stream topic of the comments posted on a photo
+func PhotoCommentsTopic(photoID uint) string {
+	return fmt.Sprintf("photos/%d/comments", photoID)
+}
+
+// NotificationsTopic is the stream topic of the events other users caused on the content of a user
+func NotificationsTopic(userID uint) string {
+	return fmt.Sprintf("users/%d/notifications", userID)
+}
+
+// StreamMessage is an event sent to the subscribers of a topic
+type StreamMessage struct {
+	// ID increases with every message of the hub
+	ID    uint64
+	Topic string
+	Type  string
+	// ActorID is the user who caused the event, subscribers leave out the ones hidden from them
+	ActorID uint
+	// Data is the JSON payload of the event
+	Data []byte
+}
+
+// StreamHub fans the events out to the live subscriptions of their topics
+type StreamHub interface {
+	EventPublisher
+	// Subscribe opens a subscription of the user, the caller checks the user may read the topics
+	Subscribe(userID uint, topics []string) (StreamSubscription, error)
+}
+
+type StreamSubscription interface {
+	// Messages is closed when the subscription ends
+	Messages() <-chan StreamMessage
+	// Err tells why the hub ended the subscription, ErrSlowConsumer when its buffer overflowed
+	Err() error
+	// Subscribe adds topics, Unsubscribe removes them
+	Subscribe(topics []string) error
+	Unsubscribe(topics []string)
+	Close()
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+var (
+	ErrExportNotFound = NewNotFoundError("export_not_found", "export not found")
+	ErrExportExpired  = NewGoneError("export_expired", "export has expired")
+	ErrExportsBusy    = NewTooManyRequestsError("exports_busy", "too many exports are being built, try again later")
+)
+
+type DataExport struct {
+	ID        uint
+	UserID    uint
+	Status    string
+	FilePath  string
+	Error     string
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ExportService interface {
+	// RequestExport starts an export, or returns the export of the user still pending or ready
+	RequestExport(userID uint) (*DataExport, error)
+	GetExport(userID uint, exportID uint) (*DataExport, error)
+	PurgeExpiredExports() (int, error)
+	// ResumePendingExports builds again the exports a restart left pending, it is called once on
+	// startup and returns how many were resumed
+	ResumePendingExports() (int, error)
+}
+
+type ExportRepository interface {
+	SaveExport(export *DataExport) (*DataExport, error)
+	GetExportByID(exportID uint) (*DataExport, error)
+	// GetLatestExportByUserID returns ErrExportNotFound when the user has no export
+	GetLatestExportByUserID(userID uint) (*DataExport, error)
+	UpdateExport(export *DataExport) (*DataExport, error)
+	GetExportsExpiredBefore(before time.Time) (*[]DataExport, error)
+	GetExportsByStatus(status string) (*[]DataExport, error)
+	DeleteExportByID(exportID uint) error
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// LinkedIdentity is an account of an external OpenID Connect provider linked to a user
+type LinkedIdentity struct {
+	ID       uint
+	UserID   uint
+	Provider string
+	// Subject is the stable user identifier of the provider
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCAuthorization is a started authorization code flow
+type OIDCAuthorization struct {
+	// URL is where the user is sent to sign in at the provider
+	URL string
+	// Binding is a secret the browser starting the flow keeps until the callback, so a flow can't
+	// be completed in another browser
+	Binding   string
+	ExpiresAt time.Time
+}
+
+// OIDCCallbackRequest holds what the provider redirected back with, and the client for the session
+type OIDCCallbackRequest struct {
+	Provider string
+	Code     string
+	State    string
+	// Binding is the secret of the OIDCAuthorization kept by the browser
+	Binding   string
+	IP        string
+	UserAgent string
+}
+
+// OIDCCallbackResult holds the login of a sign in, or the new identity when linking
+type OIDCCallbackResult struct {
+	Login          *LoginResult
+	LinkedIdentity *LinkedIdentity
+}
+
+type OIDCService interface {
+	Providers() []string
+	// AuthorizationURL starts the authorization code flow, linkUserID links the provider
+	// account to that user instead of signing in when not 0
+	AuthorizationURL(provider string, linkUserID uint) (*OIDCAuthorization, error)
+	// Callback completes the flow with the code and state the provider redirected back with, in
+	// the browser that started it
+	Callback(req *OIDCCallbackRequest) (*OIDCCallbackResult, error)
+	GetLinkedIdentities(userID uint) (*[]LinkedIdentity, error)
+	UnlinkIdentity(userID uint, identityID uint) error
+}
+
+type LinkedIdentityRepository interface {
+	SaveLinkedIdentity(identity *LinkedIdentity) (*LinkedIdentity, error)
+	GetLinkedIdentity(provider string, subject string) (*LinkedIdentity, error)
+	GetLinkedIdentitiesByUserID(userID uint) (*[]LinkedIdentity, error)
+	// DeleteLinkedIdentity reports whether an identity of the user was deleted
+	DeleteLinkedIdentity(userID uint, identityID uint) (bool, error)
+}
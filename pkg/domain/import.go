@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+var ErrImportNotFound = NewNotFoundError("import_not_found", "import not found")
+
+type ImportJob struct {
+	ID        uint
+	UserID    uint
+	Source    string
+	Status    string
+	Total     int
+	Processed int
+	Imported  int
+	Failed    int
+	Errors    []ImportItemError
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ImportItemError describes why a single post or comment of an archive was not imported
+type ImportItemError struct {
+	Item    string
+	Message string
+}
+
+type ImportService interface {
+	// StartImport imports the archive in the background and removes archivePath once done
+	StartImport(userID uint, archivePath string) (*ImportJob, error)
+	// RunImport imports the archive synchronously, progress is called after every item while the
+	// job is saved only now and then
+	RunImport(userID uint, archivePath string, progress func(job *ImportJob)) (*ImportJob, error)
+	GetImportJob(userID uint, jobID uint) (*ImportJob, error)
+}
+
+type ImportRepository interface {
+	SaveImportJob(job *ImportJob) (*ImportJob, error)
+	GetImportJobByID(jobID uint) (*ImportJob, error)
+	UpdateImportJob(job *ImportJob) (*ImportJob, error)
+}
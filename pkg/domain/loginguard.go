@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const AuditActionLoginLockout = "login.lockout"
+
+// ErrInvalidCredentials is returned for unknown usernames and wrong passwords alike
+var ErrInvalidCredentials = NewUnauthorizedError("invalid_credentials", "invalid username or password")
+
+// LockedError is returned while a username or IP address is locked out after too many failed logins
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginAttempt counts the recent failed logins of a username or IP address
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LockedUntil   *time.Time
+	LastFailureAt time.Time
+}
+
+type LoginGuard interface {
+	// Check returns a *LockedError while the username or the IP address is locked out
+	Check(username string, ip string) error
+	RecordFailure(username string, ip string) error
+	RecordSuccess(username string) error
+	GetLockoutEvents(limit int) (*[]AuditLog, error)
+}
+
+type LoginAttemptRepository interface {
+	GetLoginAttempt(key string) (*LoginAttempt, error)
+	// IncrementLoginFailures adds a failure, counting restarts when the last failure is older than resetBefore
+	IncrementLoginFailures(key string, resetBefore time.Time) (*LoginAttempt, error)
+	LockLoginAttempt(key string, lockedUntil time.Time) error
+	DeleteLoginAttempt(key string) error
+}
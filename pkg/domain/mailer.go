@@ -0,0 +1,22 @@
+package domain
+
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+	// HTMLBody is sent along Body as an alternative when set
+	HTMLBody string
+	// Headers are added to the message, e.g. List-Unsubscribe
+	Headers map[string]string
+}
+
+type Mailer interface {
+	Send(email *Email) error
+}
+
+// EmailTemplates renders the emails sent to users
+type EmailTemplates interface {
+	// Render fills the subject and the bodies of the template name with data, the caller sets the
+	// recipient
+	Render(name string, data interface{}) (*Email, error)
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrMediaNotLocal = errors.New("media is not stored locally")
+
+// MediaStore keeps photo files on local disk, they are served under a public base URL
+type MediaStore interface {
+	// Open returns the stored original of photoUrl or ErrMediaNotLocal when it is hosted elsewhere
+	Open(photoUrl string) (io.ReadCloser, error)
+	// Save stores a new file under a unique name derived from fileName and returns its public url
+	Save(fileName string, r io.Reader) (string, error)
+	// Delete removes a locally stored file, urls hosted elsewhere are ignored
+	Delete(photoUrl string) error
+}
@@ -0,0 +1,135 @@
+package domain
+
+import "time"
+
+const (
+	// AllowMessagesFromEveryone lets any user start a conversation with the user
+	AllowMessagesFromEveryone = "everyone"
+	// AllowMessagesFromContacts only lets the users the user has messaged before start one
+	AllowMessagesFromContacts = "contacts"
+)
+
+// AllowMessagesFromValues lists the values of MessageSettings.AllowFrom
+var AllowMessagesFromValues = []string{
+	AllowMessagesFromEveryone,
+	AllowMessagesFromContacts,
+}
+
+var (
+	ErrConversationNotFound = NewNotFoundError("conversation_not_found", "conversation not found")
+	ErrMessageNotFound      = NewNotFoundError("message_not_found", "message not found")
+	ErrMessagesRefused      = NewForbiddenError("messages_refused", "the user doesn't accept messages from you")
+)
+
+// Conversation is a private exchange of messages between two users or a small group
+type Conversation struct {
+	ID uint
+	// IsGroup is false for the conversations of two users, there is one per pair
+	IsGroup   bool
+	Title     string
+	CreatedBy uint
+	// Participants include the current user
+	Participants []ConversationParticipant
+	// LastMessage is the latest message the user can see, nil when there is none
+	LastMessage *Message
+	// Unread is the number of messages of the others the user hasn't read
+	Unread    int64
+	CreatedAt time.Time
+	// UpdatedAt is the time of the latest message
+	UpdatedAt time.Time
+}
+
+// ConversationParticipant carries the read receipt of a user in a conversation
+type ConversationParticipant struct {
+	UserID uint
+	// LastReadMessageID is the latest message the user has read, 0 when none
+	LastReadMessageID uint
+	LastReadAt        *time.Time
+}
+
+type Message struct {
+	ID             uint
+	ConversationID uint
+	SenderID       uint
+	Body           string
+	// PhotoID is the photo attached to the message, nil when there is none
+	PhotoID *uint
+	// Photo is loaded with the message, nil when it has been deleted since
+	Photo *Photo
+	// ReadBy lists the other participants who have read the message
+	ReadBy []uint
+	// DeletedAt is set when the sender deleted the message for everyone, its content is then erased
+	DeletedAt *time.Time
+	CreatedAt time.Time
+}
+
+// MessageSettings are the choices of a user about who can message them
+type MessageSettings struct {
+	UserID uint
+	// AllowFrom is one of AllowMessagesFromValues
+	AllowFrom string
+}
+
+type StartConversationRequest struct {
+	// ParticipantIDs are the other users, one for a 1:1 conversation
+	ParticipantIDs []uint
+	// Title names group conversations
+	Title string
+}
+
+type SendMessageRequest struct {
+	Body    string
+	PhotoID *uint
+}
+
+type MessageService interface {
+	// StartConversation creates a conversation of the user with the participants, or returns the
+	// existing one of the two users for a 1:1 conversation
+	StartConversation(userID uint, req *StartConversationRequest) (*Conversation, error)
+	// GetConversations returns the conversations of the user, the most recent activity first
+	GetConversations(userID uint, page PageRequest) (*[]Conversation, int64, error)
+	GetConversation(userID uint, conversationID uint) (*Conversation, error)
+	// GetMessages returns the messages the user can see in a conversation, newest first
+	GetMessages(userID uint, conversationID uint, page PageRequest) (*[]Message, int64, error)
+	SendMessage(userID uint, conversationID uint, req *SendMessageRequest) (*Message, error)
+	// MarkRead moves the read receipt of the user to the latest message of the conversation
+	MarkRead(userID uint, conversationID uint) (*Conversation, error)
+	// DeleteMessage hides a message from the user, or erases it for everyone when forEveryone is
+	// set, which only its sender can do
+	DeleteMessage(userID uint, conversationID uint, messageID uint, forEveryone bool) error
+	GetSettings(userID uint) (*MessageSettings, error)
+	UpdateSettings(userID uint, allowFrom string) (*MessageSettings, error)
+}
+
+type MessageRepository interface {
+	// SaveConversation creates the conversation with its participants
+	SaveConversation(conversation *Conversation) (*Conversation, error)
+	GetConversationByID(conversationID uint) (*Conversation, error)
+	// GetDirectConversation returns the 1:1 conversation of two users, ErrConversationNotFound
+	// when there is none
+	GetDirectConversation(userID uint, otherUserID uint) (*Conversation, error)
+	// GetConversationsByUserID leaves out the 1:1 conversations with users hidden from the user
+	GetConversationsByUserID(userID uint, page PageRequest) (*[]Conversation, int64, error)
+	// GetUnreadCounts returns the number of unread messages of the user per conversation
+	GetUnreadCounts(userID uint, conversationIDs []uint) (map[uint]int64, error)
+	// GetLastMessages returns the latest message the user can see per conversation
+	GetLastMessages(userID uint, conversationIDs []uint) (map[uint]Message, error)
+	UpdateReadReceipt(conversationID uint, userID uint, messageID uint, readAt time.Time) error
+
+	SaveMessage(message *Message) (*Message, error)
+	GetMessageByID(messageID uint) (*Message, error)
+	// GetMessages returns the messages of a conversation the user hasn't deleted, newest first.
+	// Like GetUnreadCounts and GetLastMessages, it leaves out the messages of users hidden from
+	// the user, see BlockService.HiddenUserIDs.
+	GetMessages(userID uint, conversationID uint, page PageRequest) (*[]Message, int64, error)
+	// DeleteMessageForUser hides a message from the user
+	DeleteMessageForUser(messageID uint, userID uint) error
+	// EraseMessage erases the content of a message for everyone
+	EraseMessage(messageID uint, deletedAt time.Time) error
+	// HasMessaged tells whether the user sent a message in a conversation with otherUserID
+	HasMessaged(userID uint, otherUserID uint) (bool, error)
+
+	// GetSettings returns settings without AllowFrom for users who never changed them
+	GetSettings(userID uint) (*MessageSettings, error)
+	SaveSettings(settings *MessageSettings) error
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+const (
+	// NotificationPhotoComment is sent to the owner of a photo when others comment on it
+	NotificationPhotoComment = "photo_comment"
+	// NotificationThreadComment is sent to the commenters of a photo when others comment after them
+	NotificationThreadComment = "thread_comment"
+)
+
+// NotificationTypes lists the types of notifications a user can turn off
+var NotificationTypes = []string{
+	NotificationPhotoComment,
+	NotificationThreadComment,
+}
+
+var ErrNotificationNotFound = NewNotFoundError("notification_not_found", "notification not found")
+
+// Notification tells a user about the activity of others on a photo. The comments of several
+// users on the same photo are gathered in one notification until it is read.
+type Notification struct {
+	ID      uint
+	UserID  uint
+	Type    string
+	PhotoID uint
+	// ActorIDs are the users who caused the notification, most recent first and without duplicates
+	ActorIDs []uint
+	// Message is written by the service when listing, e.g. "alice and 3 others commented on your photo"
+	Message   string
+	ReadAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NotificationPreference tells whether a user receives the notifications of a type
+type NotificationPreference struct {
+	Type    string
+	Enabled bool
+}
+
+type NotificationService interface {
+	// Publish queues comment events, workers generate their notifications in the background
+	EventPublisher
+	// GetNotifications returns the notifications of the user, most recently updated first
+	GetNotifications(userID uint, unreadOnly bool, page PageRequest) (*[]Notification, int64, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(userID uint, notificationID uint) (*Notification, error)
+	// MarkAllRead returns the number of notifications marked read
+	MarkAllRead(userID uint) (int64, error)
+	// GetPreferences returns a preference per type in NotificationTypes, types are enabled by default
+	GetPreferences(userID uint) ([]NotificationPreference, error)
+	UpdatePreferences(userID uint, preferences []NotificationPreference) ([]NotificationPreference, error)
+	// Close stops the workers once the queued comments have their notifications
+	Close()
+}
+
+type NotificationRepository interface {
+	SaveNotification(notification *Notification) (*Notification, error)
+	UpdateNotification(notification *Notification) error
+	GetNotificationByID(notificationID uint) (*Notification, error)
+	// AddActor puts actorID first in the unread notification of the user of type about the photo,
+	// once, or creates the notification. Concurrent calls end up in the same notification.
+	AddActor(userID uint, notificationType string, photoID uint, actorID uint) error
+	GetNotificationsByUserID(userID uint, unreadOnly bool, page PageRequest) (*[]Notification, int64, error)
+	CountUnread(userID uint) (int64, error)
+	MarkAllRead(userID uint, readAt time.Time) (int64, error)
+
+	// GetPreferences returns the stored preferences of the user, types without one are enabled
+	GetPreferences(userID uint) ([]NotificationPreference, error)
+	SavePreferences(userID uint, preferences []NotificationPreference) error
+	// GetOptedOutUserIDs returns the users among userIDs who turned off notificationType
+	GetOptedOutUserIDs(notificationType string, userIDs []uint) ([]uint, error)
+}
@@ -0,0 +1,15 @@
+package domain
+
+// PageRequest selects a page of a list, pages start at 1. PerPage 0 selects the whole list.
+type PageRequest struct {
+	Page    int
+	PerPage int
+}
+
+// Offset is the number of items before the page
+func (p PageRequest) Offset() int {
+	if p.Page <= 1 || p.PerPage <= 0 {
+		return 0
+	}
+	return (p.Page - 1) * p.PerPage
+}
@@ -17,20 +17,29 @@ type AddPhotoRequest struct {
 	Title    string
 	Caption  string
 	PhotoUrl string
+	// CreatedAt keeps the original creation time of imported photos, zero means now
+	CreatedAt time.Time
 }
 
 type PhotoService interface {
 	SavePhoto(userID uint, req *AddPhotoRequest) (*Photo, error)
 	GetPhotoByID(photoID uint) (*Photo, error)
-	GetPhotosByUserID(userID uint) (*[]Photo, error)
+	GetPhotosByUserID(userID uint, page PageRequest) (*[]Photo, int64, error)
+	// GetPhotosByIDs and GetPhotosByUserIDs load the photos of several parents in one query, ids
+	// without photos are left out
+	GetPhotosByIDs(photoIDs []uint) (*[]Photo, error)
+	GetPhotosByUserIDs(userIDs []uint) (*[]Photo, error)
 	UpdatePhoto(photoID uint, req *AddPhotoRequest) (*Photo, error)
 	DeletePhoto(photoID uint) error
 }
 
+// PhotoRepository leaves out the photos of accounts pending deletion
 type PhotoRepository interface {
 	SavePhoto(photo *Photo) (*Photo, error)
 	GetPhotoByID(photoID uint) (*Photo, error)
 	UpdatePhoto(photo *Photo) (*Photo, error)
-	GetPhotosByUserID(userID uint) (*[]Photo, error)
+	GetPhotosByUserID(userID uint, page PageRequest) (*[]Photo, int64, error)
+	GetPhotosByIDs(photoIDs []uint) (*[]Photo, error)
+	GetPhotosByUserIDs(userIDs []uint) (*[]Photo, error)
 	DeletePhotoByID(photoID uint) error
 }
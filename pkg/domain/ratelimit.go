@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RateLimitPolicy allows Limit requests per sliding Window
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult describes the state of a key after a request was counted or rejected
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the current window ends
+	Reset time.Duration
+	// RetryAfter is set when the request was rejected
+	RetryAfter time.Duration
+}
+
+// RateLimitRule applies Policy to the requests of a route group whose HTTP method it covers
+type RateLimitRule struct {
+	Group string
+	// Methods the rule applies to, all methods when empty
+	Methods []string
+	Policy  RateLimitPolicy
+}
+
+// Name names the counters of the rule, its group followed by the methods it covers
+func (r RateLimitRule) Name() string {
+	if len(r.Methods) == 0 {
+		return r.Group
+	}
+	return r.Group + ":" + strings.Join(r.Methods, ",")
+}
+
+// Covers tells whether the rule applies to requests with the HTTP method
+func (r RateLimitRule) Covers(method string) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitUserKey is the counter of a user under the counters named name. REST, GraphQL and gRPC
+// share it so a user has one budget whichever API they call.
+func RateLimitUserKey(name string, userID uint) string {
+	return fmt.Sprintf("%s:user:%d", name, userID)
+}
+
+type RateLimiter interface {
+	Allow(key string, policy RateLimitPolicy) (*RateLimitResult, error)
+	PurgeExpired() (int64, error)
+}
+
+// RateLimitStore keeps request counters per key and fixed window, a shared store
+// lets several instances of the app enforce the same limits
+type RateLimitStore interface {
+	// GetCount returns the count of the window, 0 when there is none
+	GetCount(key string, windowStart time.Time) (int, error)
+	// Increment adds one request to the window and returns the new count in one atomic step, so
+	// concurrent requests each see their own count. The counter can be dropped after expiresAt.
+	Increment(key string, windowStart time.Time, expiresAt time.Time) (int, error)
+	// Decrement takes back a request counted by Increment
+	Decrement(key string, windowStart time.Time) error
+	DeleteExpired(before time.Time) (int64, error)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// Session is a device logged into an account, access tokens carry its ID
+type Session struct {
+	ID         uint
+	UserID     uint
+	DeviceName string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
+
+type SessionService interface {
+	CreateSession(userID uint, userAgent string, ip string) (*Session, error)
+	// GetSessions returns the sessions of the user that are neither revoked nor expired
+	GetSessions(userID uint) (*[]Session, error)
+	RevokeSession(userID uint, sessionID uint) error
+	// RevokeOtherSessions revokes every session of the user but keepSessionID, all of them when it is 0
+	RevokeOtherSessions(userID uint, keepSessionID uint) error
+	// ValidateSession rejects revoked, expired and unknown sessions and records the activity
+	ValidateSession(userID uint, sessionID uint) error
+	// FlushLastSeen writes the activity recorded since the last flush
+	FlushLastSeen() error
+	PurgeExpiredSessions() (int64, error)
+}
+
+type SessionRepository interface {
+	SaveSession(session *Session) (*Session, error)
+	GetSessionByID(sessionID uint) (*Session, error)
+	GetActiveSessionsByUserID(userID uint, createdAfter time.Time) (*[]Session, error)
+	// RevokeSession reports whether an active session of the user was revoked
+	RevokeSession(userID uint, sessionID uint) (bool, error)
+	RevokeSessionsExcept(userID uint, keepSessionID uint) error
+	UpdateLastSeen(lastSeen map[uint]time.Time) error
+	DeleteSessionsCreatedBefore(before time.Time) (int64, error)
+}
@@ -14,7 +14,9 @@ type SocialMedia struct {
 type SocialMediaService interface {
 	AddSocialMedia(userID uint, name string, socialMediaUrl string) (*SocialMedia, error)
 	GetSocialMediaByID(socialMediaID uint) (*SocialMedia, error)
-	GetSocialMediasByUserID(userID uint) (*[]SocialMedia, error)
+	GetSocialMediasByUserID(userID uint, page PageRequest) (*[]SocialMedia, int64, error)
+	// GetSocialMediasByUserIDs loads the social medias of several users in one query
+	GetSocialMediasByUserIDs(userIDs []uint) (*[]SocialMedia, error)
 	UpdateSocialMedia(socialMediaID uint, name string, socialMediaUrl string) (*SocialMedia, error)
 	DeleteSocialMedia(socialMediaID uint) error
 }
@@ -22,7 +24,8 @@ type SocialMediaService interface {
 type SocialMediaRepository interface {
 	SaveSocialMedia(socialMedia *SocialMedia) (*SocialMedia, error)
 	GetSocialMediaByID(socialMediaID uint) (*SocialMedia, error)
-	GetSocialMediasByUserID(userID uint) (*[]SocialMedia, error)
+	GetSocialMediasByUserID(userID uint, page PageRequest) (*[]SocialMedia, int64, error)
+	GetSocialMediasByUserIDs(userIDs []uint) (*[]SocialMedia, error)
 	UpdateSocialMedia(socialMedia *SocialMedia) (*SocialMedia, error)
 	DeleteSocialMediaByID(socialMediaID uint) error
 }
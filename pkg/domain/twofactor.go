@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// TwoFactor is the TOTP setup of a user, it is enabled once the first code is confirmed
+type TwoFactor struct {
+	UserID       uint
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type TwoFactorEnrollment struct {
+	Secret    string
+	URI       string
+	QRCodePNG []byte
+}
+
+type TwoFactorService interface {
+	Enroll(userID uint) (*TwoFactorEnrollment, error)
+	// Confirm enables two-factor authentication and returns the recovery codes
+	Confirm(userID uint, code string) ([]string, error)
+	Disable(userID uint, password string, code string) error
+	IsEnabled(userID uint) bool
+	// VerifyCode accepts a current TOTP code or an unused recovery code
+	VerifyCode(userID uint, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+}
+
+type TwoFactorRepository interface {
+	GetTwoFactor(userID uint) (*TwoFactor, error)
+	SaveTwoFactor(twoFactor *TwoFactor) (*TwoFactor, error)
+	// UpdateLastUsedStep records a used step and reports false when a later or equal step was already used
+	UpdateLastUsedStep(userID uint, step int64) (bool, error)
+	DeleteTwoFactor(userID uint) error
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	// ConsumeRecoveryCode marks the code used and reports whether it was still unused
+	ConsumeRecoveryCode(userID uint, codeHash string) (bool, error)
+}
@@ -3,21 +3,57 @@ package domain
 import "time"
 
 type User struct {
-	ID           uint
-	Username     string
-	Email        string
-	Password     string
-	Age          int
+	ID       uint
+	Username string
+	Email    string
+	Password string
+	Age      int
+	// EmailVerified tells whether Email has been confirmed
+	EmailVerified bool
+	// PendingEmail is the new email awaiting confirmation, Email stays active until then
+	PendingEmail string
+	IsAdmin      bool
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
-	Photos       []Photo
-	Comments     []Comment
-	SocialMedias []SocialMedia
+	// TokenVersion is embedded in issued tokens, bumping it revokes all of them
+	TokenVersion uint
+	// DeletionScheduledAt is set while the account is pending deletion
+	DeletionScheduledAt *time.Time
+	Photos              []Photo
+	Comments            []Comment
+	SocialMedias        []SocialMedia
 }
 
 type LoginRequest struct {
+	// Username is the username or the email of the account
 	Username string
 	Password string
+	// IP is the client address, failed attempts are also counted per IP
+	IP string
+	// UserAgent names the device of the session
+	UserAgent string
+}
+
+// LoginResult holds either the access token or, when two-factor authentication
+// is enabled, the challenge token to exchange with a code
+type LoginResult struct {
+	Token             string
+	TwoFactorRequired bool
+	ChallengeToken    string
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string
+	Code           string
+	IP             string
+	UserAgent      string
+}
+
+// IdentityLoginRequest logs in a user authenticated by an external identity provider
+type IdentityLoginRequest struct {
+	UserID    uint
+	IP        string
+	UserAgent string
 }
 
 type RegisterRequest struct {
@@ -32,31 +68,124 @@ type UpdateUserRequest struct {
 	Email    string
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string
+	NewPassword     string
+	// SessionID is the session kept logged in, the others are revoked
+	SessionID uint
+}
+
+type ResetPasswordRequest struct {
+	Token       string
+	NewPassword string
+}
+
+// TokenClaims are the claims carried by an access token
+type TokenClaims struct {
+	UserID       uint
+	TokenVersion uint
+	// SessionID is 0 for tokens issued before sessions were recorded
+	SessionID uint
+}
+
 type UserService interface {
 	DeleteUser(userID uint) error
+	RequestDeletion(userID uint) (*User, error)
+	CancelDeletion(req *LoginRequest) error
+	PurgeScheduledDeletions() (int, error)
 	UpdateUser(userID uint, req *UpdateUserRequest) (*User, error)
 	IsUserExist(userID uint) bool
+	IsUsernameAvailable(username string) bool
 	Register(req *RegisterRequest) (*User, error)
-	Login(req *LoginRequest) (*string, error)
+	Login(req *LoginRequest) (*LoginResult, error)
+	LoginTwoFactor(req *TwoFactorLoginRequest) (*string, error)
+	LoginWithIdentity(req *IdentityLoginRequest) (*LoginResult, error)
 	GetUserByID(userID uint) (*User, error)
+	// GetUsersByIDs loads several users in one query, unknown ids and accounts pending deletion
+	// are left out
+	GetUsersByIDs(userIDs []uint) (*[]User, error)
+	VerifyTokenClaims(claims *TokenClaims) error
+	ChangePassword(userID uint, req *ChangePasswordRequest) (*string, error)
+	// RequestPasswordReset sends a reset link to the email in the background when it is registered
+	RequestPasswordReset(email string) error
+	ResetPassword(req *ResetPasswordRequest) error
+	VerifyEmail(token string) (*User, error)
+	ResendEmailVerification(userID uint) error
 }
 
 type UserRepository interface {
 	SaveUser(user *User) (*User, error)
 	GetUserByID(userID uint) (*User, error)
+	GetUsersByIDs(userIDs []uint) (*[]User, error)
 	GetUserByUsername(username string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	// UpdatePassword stores a new password hash and bumps the token version
+	UpdatePassword(userID uint, hashedPassword string) error
+	// UpdatePasswordHash replaces the hash of the same password, issued tokens stay valid
+	UpdatePasswordHash(userID uint, hashedPassword string) error
+	// HasPasswordHashWithPrefix tells whether the password hash of some account starts with one of prefixes
+	HasPasswordHashWithPrefix(prefixes []string) (bool, error)
+	MarkEmailVerified(userID uint) error
+	// ReplaceEmail makes the confirmed pending email the account email
+	ReplaceEmail(userID uint, email string) error
+	SetAdmin(userID uint, isAdmin bool) error
 	DeleteUserByID(userID uint) error
+	SetDeletionSchedule(userID uint, scheduledAt *time.Time) error
+	GetUsersScheduledForDeletion(before time.Time) (*[]User, error)
+	// PurgeUser deletes the user like DeleteUserByID, but only while their deletion is still scheduled
+	// before scheduledBefore. It reports false and deletes nothing when it was cancelled meanwhile.
+	PurgeUser(userID uint, scheduledBefore time.Time) (bool, error)
 	UpdateUser(user *User) (*User, error)
+	// IsUsernameExist, IsEmailExist and the lookups by username and email ignore case and width
 	IsUsernameExist(username string) bool
 	IsEmailExist(email string) bool
+	// MigrateCanonicalIdentities fills the canonical username and email of accounts created before
+	// they were stored. Accounts colliding with an older account are left out and reported.
+	MigrateCanonicalIdentities() (*[]IdentityCollision, error)
+}
+
+// IdentityCollision groups accounts whose usernames or emails only differ by case or width
+type IdentityCollision struct {
+	// Field is "username" or "email"
+	Field     string
+	Canonical string
+	UserIDs   []uint
+	Values    []string
+}
+
+// StreamTicket opens a stream from its URL, browsers can't set the Authorization header of
+// EventSource and WebSocket requests. It carries who requested it.
+type StreamTicket struct {
+	UserID uint
+	// Scopes are the scopes of the API key the ticket was requested with, nil for tokens
+	Scopes []string
+	// TokenVersion and SessionID are the ones of the token, revoking it revokes its tickets
+	TokenVersion uint
+	SessionID    uint
 }
 
 type AuthService interface {
-	GenerateToken(userID uint) (string, error)
-	ValidateToken(token string) (uint, error)
+	GenerateToken(claims *TokenClaims) (string, error)
+	ValidateToken(token string) (*TokenClaims, error)
+	GenerateChallengeToken(userID uint) (string, error)
+	ValidateChallengeToken(token string) (uint, error)
+	// GenerateStreamTicket returns a ticket valid for a minute and its expiry
+	GenerateStreamTicket(ticket *StreamTicket) (string, time.Time, error)
+	ValidateStreamTicket(token string) (*StreamTicket, error)
 }
 
 type CryptoService interface {
 	HashPassword(password string) (string, error)
 	VerifyPassword(plaintext string, hashed string) error
+	// NeedsRehash tells whether hashed uses an outdated algorithm or outdated parameters
+	NeedsRehash(hashed string) bool
+	GenerateRandomToken() (string, error)
+	HashToken(token string) string
+	// Algorithms returns the supported hashing algorithms, the one hashing new passwords first
+	Algorithms() []string
+	// HashPrefixes returns the prefixes the hashes of algorithm start with
+	HashPrefixes(algorithm string) []string
+	// DummyHash hashes a throwaway password with algorithm and its configured parameters, verifying
+	// against it takes as long as against a real hash of the algorithm
+	DummyHash(algorithm string) (string, error)
 }
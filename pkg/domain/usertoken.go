@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+const (
+	UserTokenPurposePasswordReset     = "password_reset"
+	UserTokenPurposeEmailVerification = "email_verification"
+	UserTokenPurposeOIDCState         = "oidc_state"
+)
+
+// UserToken is a single use token sent to the user, only its hash is stored
+type UserToken struct {
+	ID        uint
+	UserID    uint
+	Purpose   string
+	TokenHash string
+	// Data carries purpose specific data
+	Data      string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type UserTokenRepository interface {
+	SaveUserToken(token *UserToken) (*UserToken, error)
+	GetUserTokenByHash(purpose string, tokenHash string) (*UserToken, error)
+	// ConsumeUserToken marks the token used and reports whether it was still unused
+	ConsumeUserToken(tokenID uint) (bool, error)
+	// ConsumeUserTokens marks every unused token of the user for purpose as used
+	ConsumeUserTokens(userID uint, purpose string) error
+}
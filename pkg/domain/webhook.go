@@ -0,0 +1,106 @@
+package domain
+
+import "time"
+
+// WebhookEventTypes lists the events a webhook can subscribe to
+var WebhookEventTypes = []string{
+	EventPhotoCreated,
+	EventCommentCreated,
+	EventUserDeleted,
+}
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	// DeliveryStatusDead marks the dead letters, deliveries which failed every attempt
+	DeliveryStatusDead = "dead"
+)
+
+var (
+	ErrWebhookNotFound  = NewNotFoundError("webhook_not_found", "webhook not found")
+	ErrDeliveryNotFound = NewNotFoundError("delivery_not_found", "delivery not found")
+)
+
+// Webhook is a subscription to the events of an account, or of every account for the webhooks of
+// administrators with AllUsers set
+type Webhook struct {
+	ID     uint
+	UserID uint
+	URL    string
+	// Secret is the key of the HMAC-SHA256 signature of the deliveries
+	Secret    string
+	Events    []string
+	AllUsers  bool
+	CreatedAt time.Time
+}
+
+// Subscribes tells whether the webhook receives events of type eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event queued for a webhook, it is retried until it is delivered or dead
+type WebhookDelivery struct {
+	ID        uint
+	WebhookID uint
+	EventID   string
+	EventType string
+	Payload   string
+	Status    string
+	Attempts  int
+	// LastStatusCode is the response status of the last attempt, 0 when no response was received
+	LastStatusCode int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type CreateWebhookRequest struct {
+	URL string
+	// Secret is generated when empty
+	Secret   string
+	Events   []string
+	AllUsers bool
+}
+
+type WebhookService interface {
+	EventPublisher
+	// CreateWebhook returns the stored webhook, its secret is only given back here
+	CreateWebhook(userID uint, req *CreateWebhookRequest) (*Webhook, error)
+	GetWebhooks(userID uint) (*[]Webhook, error)
+	DeleteWebhook(userID uint, webhookID uint) error
+	// GetDeadLetters returns the dead deliveries of a webhook of the user, oldest first
+	GetDeadLetters(userID uint, webhookID uint, page PageRequest) (*[]WebhookDelivery, int64, error)
+	// Redeliver queues a delivered or dead delivery of a webhook of the user again
+	Redeliver(userID uint, webhookID uint, deliveryID uint) (*WebhookDelivery, error)
+	// DeliverDue sends the deliveries whose attempt is due and returns how many were delivered
+	DeliverDue() (int, error)
+}
+
+type WebhookRepository interface {
+	SaveWebhook(webhook *Webhook) (*Webhook, error)
+	GetWebhookByID(webhookID uint) (*Webhook, error)
+	GetWebhooksByUserID(userID uint) (*[]Webhook, error)
+	// GetWebhooksForEvent returns the webhooks subscribed to eventType of one of the users, and
+	// the ones of every account
+	GetWebhooksForEvent(eventType string, userIDs []uint) (*[]Webhook, error)
+	// DeleteWebhook deletes a webhook of the user and its deliveries, and reports whether it existed
+	DeleteWebhook(userID uint, webhookID uint) (bool, error)
+
+	SaveDeliveries(deliveries []WebhookDelivery) error
+	GetDeliveryByID(deliveryID uint) (*WebhookDelivery, error)
+	GetDeadDeliveries(webhookID uint, page PageRequest) (*[]WebhookDelivery, int64, error)
+	// GetDueDeliveries returns at most limit pending deliveries whose attempt is due at now
+	GetDueDeliveries(now time.Time, limit int) (*[]WebhookDelivery, error)
+	// ClaimDelivery moves the next attempt of a pending delivery from its current time to until,
+	// and reports false when another worker claimed it first
+	ClaimDelivery(delivery *WebhookDelivery, until time.Time) (bool, error)
+	UpdateDelivery(delivery *WebhookDelivery) error
+}
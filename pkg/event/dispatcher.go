@@ -0,0 +1,45 @@
+// Package event carries the events of the services to their subscribers, the webhooks and the
+// stream hub.
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"final-project/pkg/domain"
+	"time"
+)
+
+type dispatcher struct {
+	publishers []domain.EventPublisher
+}
+
+// NewDispatcher returns a publisher giving every event to each of publishers in turn, after
+// setting its id and time when they are missing
+func NewDispatcher(publishers ...domain.EventPublisher) domain.EventPublisher {
+	return &dispatcher{
+		publishers: publishers,
+	}
+}
+
+func (d *dispatcher) Publish(event *domain.Event) {
+	if event.ID == "" {
+		event.ID = NewID()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	for _, publisher := range d.publishers {
+		publisher.Publish(event)
+	}
+}
+
+// NewID returns a random event id
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand doesn't fail on the supported platforms
+		panic(err)
+	}
+	return "evt_" + hex.EncodeToString(b)
+}
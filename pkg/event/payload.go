@@ -0,0 +1,74 @@
+package event
+
+import (
+	"final-project/pkg/domain"
+	"time"
+)
+
+// Payload is the JSON form of an event sent to webhooks and stream subscribers
+type Payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type photoData struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	PhotoUrl  string    `json:"photo_url"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type commentData struct {
+	ID        uint      `json:"id"`
+	Message   string    `json:"message"`
+	PhotoID   uint      `json:"photo_id"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// userData leaves out the email, receivers may outlive the account
+type userData struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+func NewPayload(event *domain.Event) Payload {
+	return Payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.OccurredAt.UTC(),
+		Data:      formatData(event.Data),
+	}
+}
+
+func formatData(data interface{}) interface{} {
+	switch data := data.(type) {
+	case *domain.Photo:
+		return photoData{
+			ID:        data.ID,
+			Title:     data.Title,
+			Caption:   data.Caption,
+			PhotoUrl:  data.PhotoUrl,
+			UserID:    data.UserID,
+			CreatedAt: data.CreatedAt,
+		}
+	case *domain.Comment:
+		return commentData{
+			ID:        data.ID,
+			Message:   data.Message,
+			PhotoID:   data.PhotoID,
+			UserID:    data.UserID,
+			CreatedAt: data.CreatedAt,
+		}
+	case *domain.User:
+		return userData{
+			ID:       data.ID,
+			Username: data.Username,
+		}
+	}
+	return data
+}
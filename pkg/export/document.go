@@ -0,0 +1,48 @@
+package export
+
+import "time"
+
+// document is the data.json written at the root of an export archive
+type document struct {
+	ExportedAt   time.Time     `json:"exported_at"`
+	Profile      profile       `json:"profile"`
+	Photos       []photo       `json:"photos"`
+	Comments     []comment     `json:"comments"`
+	SocialMedias []socialMedia `json:"social_medias"`
+}
+
+type profile struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Age       int       `json:"age"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type photo struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	PhotoUrl  string    `json:"photo_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// OriginalFile is the path of the original inside the archive when it is stored locally
+	OriginalFile string `json:"original_file,omitempty"`
+}
+
+type comment struct {
+	ID        uint      `json:"id"`
+	PhotoID   uint      `json:"photo_id"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type socialMedia struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	SocialMediaUrl string    `json:"social_media_url"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,352 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Dir is where finished archives are written
+	Dir string
+	// TTL is how long a finished archive can be downloaded
+	TTL time.Duration
+	// QueueSize is the number of exports waiting for a worker, 64 when zero
+	QueueSize int
+	// Workers build the queued exports, 1 when zero
+	Workers int
+}
+
+type service struct {
+	repo            domain.ExportRepository
+	userRepo        domain.UserRepository
+	photoRepo       domain.PhotoRepository
+	commentRepo     domain.CommentRepository
+	socialMediaRepo domain.SocialMediaRepository
+	mediaStore      domain.MediaStore
+	config          Config
+
+	queue chan domain.DataExport
+	// requesting makes looking up the latest export of a user and saving a new one atomic
+	requesting sync.Mutex
+}
+
+func NewService(
+	repo domain.ExportRepository,
+	userRepo domain.UserRepository,
+	photoRepo domain.PhotoRepository,
+	commentRepo domain.CommentRepository,
+	socialMediaRepo domain.SocialMediaRepository,
+	mediaStore domain.MediaStore,
+	config Config,
+) domain.ExportService {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 64
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+
+	s := &service{
+		repo:            repo,
+		userRepo:        userRepo,
+		photoRepo:       photoRepo,
+		commentRepo:     commentRepo,
+		socialMediaRepo: socialMediaRepo,
+		mediaStore:      mediaStore,
+		config:          config,
+		queue:           make(chan domain.DataExport, config.QueueSize),
+	}
+	for i := 0; i < config.Workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// RequestExport registers a pending export and queues it for the workers. A user has one export at
+// a time, while it is pending or ready it is returned instead of starting another.
+func (s *service) RequestExport(userID uint) (*domain.DataExport, error) {
+	s.requesting.Lock()
+	defer s.requesting.Unlock()
+
+	latest, err := s.repo.GetLatestExportByUserID(userID)
+	if err != nil && !errors.Is(err, domain.ErrExportNotFound) {
+		return nil, err
+	}
+	if err == nil && s.inProgressOrReady(latest) {
+		return latest, nil
+	}
+
+	export, err := s.repo.SaveExport(&domain.DataExport{
+		UserID: userID,
+		Status: domain.ExportStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- *export:
+		return export, nil
+	default:
+	}
+
+	// the workers are behind, the export fails right away so the user can request it again later
+	s.finish(*export, domain.ErrExportsBusy)
+	return nil, domain.ErrExportsBusy
+}
+
+func (s *service) inProgressOrReady(export *domain.DataExport) bool {
+	switch export.Status {
+	case domain.ExportStatusPending:
+		return true
+	case domain.ExportStatusReady:
+		return export.ExpiresAt == nil || export.ExpiresAt.After(time.Now())
+	}
+	return false
+}
+
+// GetExport returns an export of the user, expired exports are reported as ErrExportExpired
+func (s *service) GetExport(userID uint, exportID uint) (*domain.DataExport, error) {
+	export, err := s.repo.GetExportByID(exportID)
+	if err != nil || export.UserID != userID {
+		return nil, domain.ErrExportNotFound
+	}
+
+	if export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now()) {
+		return nil, domain.ErrExportExpired
+	}
+
+	return export, nil
+}
+
+// PurgeExpiredExports removes expired archives from disk together with their records
+func (s *service) PurgeExpiredExports() (int, error) {
+	exports, err := s.repo.GetExportsExpiredBefore(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, export := range *exports {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return purged, err
+			}
+		}
+		if err := s.repo.DeleteExportByID(export.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// ResumePendingExports queues the exports left pending by a restart again, in the background so
+// startup doesn't wait on a full queue. Only one process builds exports so every pending export on
+// startup is stale.
+func (s *service) ResumePendingExports() (int, error) {
+	exports, err := s.repo.GetExportsByStatus(domain.ExportStatusPending)
+	if err != nil {
+		return 0, err
+	}
+
+	go func() {
+		for _, export := range *exports {
+			s.queue <- export
+		}
+	}()
+
+	return len(*exports), nil
+}
+
+func (s *service) work() {
+	for export := range s.queue {
+		s.build(export)
+	}
+}
+
+func (s *service) build(export domain.DataExport) {
+	filePath := filepath.Join(s.config.Dir, fmt.Sprintf("export-%d-%d.zip", export.UserID, export.ID))
+
+	err := s.writeArchiveFile(export.UserID, filePath)
+	if err != nil {
+		log.Printf("export %d failed: %v", export.ID, err)
+		os.Remove(filePath)
+	} else {
+		export.FilePath = filePath
+	}
+	s.finish(export, err)
+}
+
+// finish records the outcome of an export, err is why it failed
+func (s *service) finish(export domain.DataExport, err error) {
+	if err != nil {
+		export.Status = domain.ExportStatusFailed
+		export.Error = err.Error()
+	} else {
+		export.Status = domain.ExportStatusReady
+	}
+
+	// failed exports expire too so the purge job cleans them up
+	expiresAt := time.Now().Add(s.config.TTL)
+	export.ExpiresAt = &expiresAt
+
+	if _, err := s.repo.UpdateExport(&export); err != nil {
+		log.Printf("failed to update export %d: %v", export.ID, err)
+	}
+}
+
+func (s *service) writeArchiveFile(userID uint, filePath string) error {
+	if err := os.MkdirAll(s.config.Dir, 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if err := s.writeArchive(userID, file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// writeArchive writes a ZIP with data.json and the locally stored photo originals
+func (s *service) writeArchive(userID uint, w io.Writer) error {
+	doc, photoUrls, err := s.collect(userID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+
+	// Copy photo originals first, photos hosted elsewhere are only referenced by url
+	for i, photo := range doc.Photos {
+		name := fmt.Sprintf("photos/%d%s", photo.ID, path.Ext(photo.PhotoUrl))
+		copied, err := s.copyMedia(archive, name, photoUrls[i])
+		if err != nil {
+			return err
+		}
+		if copied {
+			doc.Photos[i].OriginalFile = name
+		}
+	}
+
+	dataFile, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(dataFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (s *service) copyMedia(archive *zip.Writer, name string, photoUrl string) (bool, error) {
+	media, err := s.mediaStore.Open(photoUrl)
+	if errors.Is(err, domain.ErrMediaNotLocal) || errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer media.Close()
+
+	dst, err := archive.Create(name)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = io.Copy(dst, media)
+	return err == nil, err
+}
+
+func (s *service) collect(userID uint) (*document, []string, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	photos, _, err := s.photoRepo.GetPhotosByUserID(userID, domain.PageRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comments, _, err := s.commentRepo.GetCommentsByUserID(userID, domain.PageRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	socialMedias, _, err := s.socialMediaRepo.GetSocialMediasByUserID(userID, domain.PageRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := &document{
+		ExportedAt: time.Now(),
+		Profile: profile{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Age:       user.Age,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		},
+		Photos:       make([]photo, len(*photos)),
+		Comments:     make([]comment, len(*comments)),
+		SocialMedias: make([]socialMedia, len(*socialMedias)),
+	}
+
+	photoUrls := make([]string, len(*photos))
+	for i, p := range *photos {
+		photoUrls[i] = p.PhotoUrl
+		doc.Photos[i] = photo{
+			ID:        p.ID,
+			Title:     p.Title,
+			Caption:   p.Caption,
+			PhotoUrl:  p.PhotoUrl,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		}
+	}
+
+	for i, c := range *comments {
+		doc.Comments[i] = comment{
+			ID:        c.ID,
+			PhotoID:   c.PhotoID,
+			Message:   c.Message,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		}
+	}
+
+	for i, sm := range *socialMedias {
+		doc.SocialMedias[i] = socialMedia{
+			ID:             sm.ID,
+			Name:           sm.Name,
+			SocialMediaUrl: sm.SocialMediaUrl,
+			CreatedAt:      sm.CreatedAt,
+			UpdatedAt:      sm.UpdatedAt,
+		}
+	}
+
+	return doc, photoUrls, nil
+}
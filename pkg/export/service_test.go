@@ -0,0 +1,239 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeExportRepo struct {
+	mu      sync.Mutex
+	exports map[uint]domain.DataExport
+}
+
+func newFakeExportRepo(exports ...domain.DataExport) *fakeExportRepo {
+	repo := &fakeExportRepo{exports: map[uint]domain.DataExport{}}
+	for _, export := range exports {
+		repo.exports[export.ID] = export
+	}
+	return repo
+}
+
+func (r *fakeExportRepo) SaveExport(export *domain.DataExport) (*domain.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	export.ID = uint(len(r.exports) + 1)
+	r.exports[export.ID] = *export
+	return export, nil
+}
+
+func (r *fakeExportRepo) GetExportByID(exportID uint) (*domain.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	export, ok := r.exports[exportID]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &export, nil
+}
+
+func (r *fakeExportRepo) GetLatestExportByUserID(userID uint) (*domain.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest *domain.DataExport
+	for _, export := range r.exports {
+		if export.UserID == userID && (latest == nil || export.ID > latest.ID) {
+			export := export
+			latest = &export
+		}
+	}
+	if latest == nil {
+		return nil, domain.ErrExportNotFound
+	}
+	return latest, nil
+}
+
+func (r *fakeExportRepo) UpdateExport(export *domain.DataExport) (*domain.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exports[export.ID] = *export
+	return export, nil
+}
+
+func (r *fakeExportRepo) GetExportsExpiredBefore(before time.Time) (*[]domain.DataExport, error) {
+	return &[]domain.DataExport{}, nil
+}
+
+func (r *fakeExportRepo) GetExportsByStatus(status string) (*[]domain.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exports := []domain.DataExport{}
+	for _, export := range r.exports {
+		if export.Status == status {
+			exports = append(exports, export)
+		}
+	}
+	return &exports, nil
+}
+
+func (r *fakeExportRepo) DeleteExportByID(exportID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.exports, exportID)
+	return nil
+}
+
+// newTestService exports alice, whose photos are one local file and one hosted elsewhere
+func newTestService(repo domain.ExportRepository, dir string) *service {
+	users := fake.NewUserRepo(domain.User{ID: 1, Username: "alice", Email: "alice@example.com", Age: 20})
+	photos := fake.NewPhotoRepo(
+		domain.Photo{ID: 1, Title: "Local", PhotoUrl: "/media/local.jpg", UserID: 1},
+		domain.Photo{ID: 2, Title: "Remote", PhotoUrl: "https://example.com/remote.png", UserID: 1},
+	)
+	comments := fake.NewCommentRepo(domain.Comment{ID: 3, UserID: 1, PhotoID: 9, Message: "Nice"})
+	socialMedias := fake.NewSocialMediaRepo(domain.SocialMedia{ID: 4, Name: "blog", SocialMediaUrl: "https://example.com", UserID: 1})
+	mediaStore := fake.NewMediaStore()
+	mediaStore.Files["/media/local.jpg"] = "jpeg bytes"
+
+	return NewService(repo, users, photos, comments, socialMedias, mediaStore, Config{
+		Dir: dir,
+		TTL: time.Hour,
+	}).(*service)
+}
+
+func TestWriteArchive(t *testing.T) {
+	s := newTestService(newFakeExportRepo(), t.TempDir())
+
+	var buf bytes.Buffer
+	if err := s.writeArchive(1, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		files[file.Name] = string(content)
+	}
+
+	if files["photos/1.jpg"] != "jpeg bytes" {
+		t.Errorf("local original not copied, files: %v", files)
+	}
+	if _, ok := files["photos/2.png"]; ok {
+		t.Error("remote photo should only be referenced")
+	}
+
+	var doc document
+	if err := json.Unmarshal([]byte(files["data.json"]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Profile.Username != "alice" || len(doc.Photos) != 2 || len(doc.Comments) != 1 || len(doc.SocialMedias) != 1 {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if doc.Photos[0].OriginalFile != "photos/1.jpg" || doc.Photos[1].OriginalFile != "" {
+		t.Errorf("unexpected original files: %q, %q", doc.Photos[0].OriginalFile, doc.Photos[1].OriginalFile)
+	}
+}
+
+func TestBuildFailureExpires(t *testing.T) {
+	repo := newFakeExportRepo()
+	s := newTestService(repo, t.TempDir())
+
+	s.build(domain.DataExport{ID: 1, UserID: 2, Status: domain.ExportStatusPending})
+
+	export, _ := repo.GetExportByID(1)
+	if export.Status != domain.ExportStatusFailed || export.Error == "" {
+		t.Errorf("export should have failed, got %+v", export)
+	}
+	if export.ExpiresAt == nil {
+		t.Error("failed exports should expire so they are purged")
+	}
+}
+
+func TestResumePendingExports(t *testing.T) {
+	repo := newFakeExportRepo(
+		domain.DataExport{ID: 1, UserID: 1, Status: domain.ExportStatusPending},
+		domain.DataExport{ID: 2, UserID: 1, Status: domain.ExportStatusReady},
+	)
+	s := newTestService(repo, t.TempDir())
+
+	resumed, err := s.ResumePendingExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != 1 {
+		t.Fatalf("resumed %d exports, want 1", resumed)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		export, _ := repo.GetExportByID(1)
+		if export.Status == domain.ExportStatusReady {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pending export was not built, status %q", export.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequestExportReturnsTheExportInProgress(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	repo := newFakeExportRepo(
+		domain.DataExport{ID: 1, UserID: 1, Status: domain.ExportStatusReady, ExpiresAt: &expired},
+		domain.DataExport{ID: 2, UserID: 1, Status: domain.ExportStatusFailed},
+	)
+	// without workers the export stays pending
+	s := &service{repo: repo, queue: make(chan domain.DataExport, 1)}
+
+	export, err := s.RequestExport(1)
+	if err != nil || export.ID != 3 || export.Status != domain.ExportStatusPending {
+		t.Fatalf("got %+v, %v, want a new pending export", export, err)
+	}
+	if again, err := s.RequestExport(1); err != nil || again.ID != 3 {
+		t.Errorf("got %+v, %v, want the pending export again", again, err)
+	}
+
+	// the queue is full, the export of another user fails right away
+	if _, err := s.RequestExport(2); !errors.Is(err, domain.ErrExportsBusy) {
+		t.Fatalf("got %v, want ErrExportsBusy", err)
+	}
+	if failed, _ := repo.GetExportByID(4); failed.Status != domain.ExportStatusFailed || failed.ExpiresAt == nil {
+		t.Errorf("got %+v, want a failed export which expires", failed)
+	}
+}
+
+func TestGetExport(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	repo := newFakeExportRepo(
+		domain.DataExport{ID: 1, UserID: 1, Status: domain.ExportStatusReady},
+		domain.DataExport{ID: 2, UserID: 1, Status: domain.ExportStatusReady, ExpiresAt: &expired},
+	)
+	s := newTestService(repo, t.TempDir())
+
+	if _, err := s.GetExport(1, 1); err != nil {
+		t.Errorf("own export: %v", err)
+	}
+	if _, err := s.GetExport(2, 1); !errors.Is(err, domain.ErrExportNotFound) {
+		t.Errorf("export of another user: got %v, want ErrExportNotFound", err)
+	}
+	if _, err := s.GetExport(1, 2); !errors.Is(err, domain.ErrExportExpired) {
+		t.Errorf("expired export: got %v, want ErrExportExpired", err)
+	}
+}
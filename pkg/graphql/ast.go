@@ -0,0 +1,121 @@
+package graphql
+
+// document is a parsed query document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	// kind is query, mutation or subscription
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue value
+	hasDefault   bool
+	loc          Location
+}
+
+// typeRef is a type written in a query, e.g. [ID!]!
+type typeRef struct {
+	name string
+	// elem is the item type of lists
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// selection is a *field, a *fragmentSpread or an *inlineFragment
+type selection interface {
+	location() Location
+}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// responseKey is the key of the field in the response, the alias when there is one
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	// typeCondition is empty when the fragment only groups directives
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+func (f *field) location() Location          { return f.loc }
+func (f *fragmentSpread) location() Location { return f.loc }
+func (f *inlineFragment) location() Location { return f.loc }
+
+type argument struct {
+	name  string
+	value value
+	loc   Location
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+	loc       Location
+}
+
+// value is a literal of a query: variable, enumValue, listValue, objectValue, or the Go value of a
+// scalar literal, int64, float64, string, bool or nil
+type value interface{}
+
+type variable string
+
+type enumValue string
+
+type listValue []value
+
+type objectValue []*objectField
+
+type objectField struct {
+	name  string
+	value value
+}
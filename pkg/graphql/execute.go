@@ -0,0 +1,501 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Request is the body of a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response holds the data of an executed operation and the errors raised along the way. Data is
+// absent when the request failed before execution.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error, Path is set for errors of resolvers
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	// Err is the error returned by the resolver, nil for errors of the request
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Config limits the queries executed, zero values disable the limits
+type Config struct {
+	// MaxDepth is the deepest nesting of fields allowed, root fields are at depth 1
+	MaxDepth int
+	// MaxComplexity is the highest total Cost of the fields allowed
+	MaxComplexity int
+	// QueryOnly rejects mutations, for requests that must not change anything such as HTTP GETs
+	QueryOnly bool
+}
+
+// Execute runs the operation of req. Introspection fields other than __typename don't count towards
+// the limits of config.
+func (s *Schema) Execute(ctx context.Context, req Request, config Config) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{toError(err)}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{toError(err)}}
+	}
+
+	if config.QueryOnly && op.kind != "query" {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("Only query operations are allowed here, not %s operations.", op.kind), Locations: []Location{op.loc}}}}
+	}
+
+	var root *Object
+	switch op.kind {
+	case "query":
+		root = s.query
+	case "mutation":
+		root = s.mutation
+	}
+	if root == nil {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("Schema does not support %s operations.", op.kind), Locations: []Location{op.loc}}}}
+	}
+
+	vars, errs := s.coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	if errs := s.validate(doc, op, root, vars, config); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	e := &executor{schema: s, doc: doc, vars: vars}
+	results := e.executeSelections(ctx, root, []interface{}{nil}, [][]interface{}{nil}, op.selections)
+	return &Response{Data: results[0], Errors: e.errors}
+}
+
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("Must provide operation name if query contains multiple operations.")
+		}
+		return doc.operations[0], nil
+	}
+
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown operation named %q.", name)
+}
+
+func (s *Schema) coerceVariables(op *operation, inputs map[string]interface{}) (map[string]interface{}, []*Error) {
+	vars := make(map[string]interface{}, len(op.variables))
+	var errs []*Error
+	for _, definition := range op.variables {
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{definition.loc}})
+		}
+
+		t, err := s.resolveTypeRef(definition.typ)
+		if err != nil {
+			fail("Variable \"$%s\": %v.", definition.name, err)
+			continue
+		}
+
+		input, ok := inputs[definition.name]
+		if !ok {
+			if definition.hasDefault {
+				value, _, err := valueFromLiteral(t, definition.defaultValue, nil)
+				if err != nil {
+					fail("Variable \"$%s\" has an invalid default value: %v.", definition.name, err)
+				}
+				vars[definition.name] = value
+			} else if definition.typ.nonNull {
+				fail("Variable \"$%s\" of required type \"%s\" was not provided.", definition.name, t)
+			}
+			continue
+		}
+
+		value, err := coerceInput(t, input)
+		if err != nil {
+			fail("Variable \"$%s\" got invalid value %s; %v.", definition.name, describe(input), err)
+			continue
+		}
+		vars[definition.name] = value
+	}
+	return vars, errs
+}
+
+// executor resolves the selections of an operation level by level
+type executor struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]interface{}
+	errors []*Error
+}
+
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// selections merges the selections of the fields sharing a response key
+func (g *fieldGroup) selections() []selection {
+	var selections []selection
+	for _, f := range g.fields {
+		selections = append(selections, f.selections...)
+	}
+	return selections
+}
+
+func (e *executor) addError(err error, path []interface{}, loc Location) {
+	gqlErr := &Error{Message: err.Error(), Path: path, Locations: []Location{loc}, Err: err}
+	var resolverErr *Error
+	if errors.As(err, &resolverErr) {
+		gqlErr.Message = resolverErr.Message
+		gqlErr.Extensions = resolverErr.Extensions
+		gqlErr.Err = resolverErr.Err
+	}
+	e.errors = append(e.errors, gqlErr)
+}
+
+// executeSelections resolves selections on every source object at once, paths are the response
+// paths of the sources. Results are nil for the objects nulled by a null non-null field.
+func (e *executor) executeSelections(ctx context.Context, t *Object, sources []interface{}, paths [][]interface{}, selections []selection) []*orderedMap {
+	results := make([]*orderedMap, len(sources))
+	for i := range results {
+		results[i] = &orderedMap{values: make(map[string]interface{})}
+	}
+
+	var groups []*fieldGroup
+	e.collectFields(t, selections, &groups, make(map[string]*fieldGroup), make(map[string]bool))
+	for _, group := range groups {
+		f := group.fields[0]
+		if f.name == "__typename" {
+			for _, result := range results {
+				result.set(group.key, t.Name)
+			}
+			continue
+		}
+
+		fieldPaths := make([][]interface{}, len(paths))
+		for i, path := range paths {
+			fieldPaths[i] = appendPath(path, group.key)
+		}
+
+		definition := e.schema.fieldDefinition(t, f.name)
+		args, err := argumentValues(definition.Args, f.arguments, e.vars)
+		if err != nil {
+			e.addError(err, fieldPaths[0], f.loc)
+			for _, result := range results {
+				result.set(group.key, nil)
+			}
+			continue
+		}
+
+		values, failed := e.resolve(ctx, definition, sources, fieldPaths, args, f.loc)
+		completed := e.completeValues(ctx, definition.Type, values, fieldPaths, failed, group)
+		_, nonNull := definition.Type.(*NonNull)
+		for i, result := range results {
+			if result == nil {
+				continue
+			}
+			// A null non-null field nulls its object
+			if nonNull && completed[i] == nil {
+				results[i] = nil
+				continue
+			}
+			result.set(group.key, completed[i])
+		}
+	}
+	return results
+}
+
+func (e *executor) collectFields(t *Object, selections []selection, groups *[]*fieldGroup, byKey map[string]*fieldGroup, visited map[string]bool) {
+	for _, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			if !e.included(s.directives) {
+				continue
+			}
+			group, ok := byKey[s.responseKey()]
+			if !ok {
+				group = &fieldGroup{key: s.responseKey()}
+				byKey[group.key] = group
+				*groups = append(*groups, group)
+			}
+			group.fields = append(group.fields, s)
+		case *fragmentSpread:
+			if visited[s.name] || !e.included(s.directives) {
+				continue
+			}
+			visited[s.name] = true
+			frag := e.doc.fragments[s.name]
+			if frag.typeCondition == t.Name {
+				e.collectFields(t, frag.selections, groups, byKey, visited)
+			}
+		case *inlineFragment:
+			if !e.included(s.directives) {
+				continue
+			}
+			if s.typeCondition == "" || s.typeCondition == t.Name {
+				e.collectFields(t, s.selections, groups, byKey, visited)
+			}
+		}
+	}
+}
+
+// included applies the @skip and @include directives
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		definition := directiveDefinitions[d.name]
+		if definition == nil || (d.name != "skip" && d.name != "include") {
+			continue
+		}
+		args, err := argumentValues(definition.Args, d.arguments, e.vars)
+		if err != nil {
+			continue
+		}
+		if condition, _ := args["if"].(bool); condition == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve runs the resolver of a field for every source, failed tells which ones returned an error
+func (e *executor) resolve(ctx context.Context, definition *Field, sources []interface{}, paths [][]interface{}, args map[string]interface{}, loc Location) ([]interface{}, []bool) {
+	failed := make([]bool, len(sources))
+
+	if definition.Batch != nil {
+		values, err := definition.Batch(ctx, sources, args)
+		if err == nil && len(values) != len(sources) {
+			err = fmt.Errorf("resolver returned %d values for %d objects", len(values), len(sources))
+		}
+		if err != nil {
+			e.addError(err, paths[0], loc)
+			for i := range failed {
+				failed[i] = true
+			}
+			return make([]interface{}, len(sources)), failed
+		}
+		return values, failed
+	}
+
+	values := make([]interface{}, len(sources))
+	for i, source := range sources {
+		value, err := definition.Resolve(ctx, source, args)
+		if err != nil {
+			e.addError(err, paths[i], loc)
+			failed[i] = true
+			continue
+		}
+		values[i] = value
+	}
+	return values, failed
+}
+
+// completeValues converts resolved values to their response values, the selections of objects are
+// executed once for all the objects of the level. failed is set for the values that are null because
+// of an error already reported, their null propagates through non-null types.
+func (e *executor) completeValues(ctx context.Context, t Type, values []interface{}, paths [][]interface{}, failed []bool, group *fieldGroup) []interface{} {
+	loc := group.fields[0].loc
+	results := make([]interface{}, len(values))
+
+	switch t := t.(type) {
+	case *NonNull:
+		results = e.completeValues(ctx, t.Of, values, paths, failed, group)
+		for i, result := range results {
+			if result == nil && !failed[i] {
+				e.addError(fmt.Errorf("Cannot return null for non-nullable field %q.", group.fields[0].name), paths[i], loc)
+				failed[i] = true
+			}
+		}
+	case *List:
+		// Flatten the lists so their items are completed together
+		var items []interface{}
+		var itemPaths [][]interface{}
+		counts := make([]int, len(values))
+		for i, v := range values {
+			counts[i] = -1
+			if isNull(v) {
+				continue
+			}
+			list := reflect.Indirect(reflect.ValueOf(v))
+			if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+				e.addError(fmt.Errorf("expected a list for field %q, got %T", group.fields[0].name, v), paths[i], loc)
+				failed[i] = true
+				continue
+			}
+			counts[i] = list.Len()
+			for j := 0; j < list.Len(); j++ {
+				item := list.Index(j)
+				if item.Kind() == reflect.Struct && item.CanAddr() {
+					item = item.Addr()
+				}
+				items = append(items, item.Interface())
+				itemPaths = append(itemPaths, appendPath(paths[i], j))
+			}
+		}
+
+		completed := e.completeValues(ctx, t.Of, items, itemPaths, make([]bool, len(items)), group)
+		_, nonNullItems := t.Of.(*NonNull)
+		offset := 0
+		for i, count := range counts {
+			if count < 0 {
+				continue
+			}
+			list := completed[offset : offset+count]
+			offset += count
+			if nonNullItems && containsNull(list) {
+				failed[i] = true
+				continue
+			}
+			results[i] = list
+		}
+	case *Scalar:
+		for i, v := range values {
+			if isNull(v) {
+				continue
+			}
+			serialized, err := t.Serialize(reflect.Indirect(reflect.ValueOf(v)).Interface())
+			if err != nil {
+				e.addError(err, paths[i], loc)
+				failed[i] = true
+				continue
+			}
+			results[i] = serialized
+		}
+	case *Enum:
+		for i, v := range values {
+			if isNull(v) {
+				continue
+			}
+			name := fmt.Sprint(reflect.Indirect(reflect.ValueOf(v)).Interface())
+			if !t.has(name) {
+				e.addError(fmt.Errorf("%s cannot represent %q", t.Name, name), paths[i], loc)
+				failed[i] = true
+				continue
+			}
+			results[i] = name
+		}
+	case *Object:
+		var sources []interface{}
+		var sourcePaths [][]interface{}
+		var indexes []int
+		for i, v := range values {
+			if isNull(v) {
+				continue
+			}
+			sources = append(sources, v)
+			sourcePaths = append(sourcePaths, paths[i])
+			indexes = append(indexes, i)
+		}
+		if len(sources) == 0 {
+			break
+		}
+		for i, result := range e.executeSelections(ctx, t, sources, sourcePaths, group.selections()) {
+			if result == nil {
+				failed[indexes[i]] = true
+				continue
+			}
+			results[indexes[i]] = result
+		}
+	}
+	return results
+}
+
+func containsNull(values []interface{}) bool {
+	for _, v := range values {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	result := make([]interface{}, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, key)
+}
+
+func toError(err error) *Error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+	return &Error{Message: err.Error()}
+}
+
+// orderedMap is a response object, its fields are encoded in the order of the query
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,237 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID   string
+	Name string
+}
+
+var testUsers = []*testUser{{ID: "1", Name: "alice"}, {ID: "2", Name: "bob"}, {ID: "3", Name: "carol"}}
+
+func findTestUser(id string) *testUser {
+	for _, user := range testUsers {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+func firstCost(args map[string]interface{}, children int) int {
+	first, _ := args["first"].(int)
+	return 1 + first*children
+}
+
+// newTestSchema builds a schema of users who are friends with every other user. batches counts the
+// calls of the friends resolver.
+func newTestSchema(t *testing.T, batches *int) *Schema {
+	t.Helper()
+	user := &Object{Name: "User"}
+	firstArgs := []*Argument{{Name: "first", Type: Int, Default: 2}}
+	user.Fields = []*Field{
+		{Name: "id", Type: NewNonNull(ID), Resolve: resolveSource(func(source interface{}) interface{} { return source.(*testUser).ID })},
+		{Name: "name", Type: NewNonNull(String), Resolve: resolveSource(func(source interface{}) interface{} { return source.(*testUser).Name })},
+		{
+			Name: "friends",
+			Type: NewNonNull(NewList(NewNonNull(user))),
+			Args: firstArgs,
+			Batch: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+				*batches++
+				values := make([]interface{}, len(sources))
+				for i, source := range sources {
+					var friends []*testUser
+					for _, friend := range testUsers {
+						if friend != source && len(friends) < args["first"].(int) {
+							friends = append(friends, friend)
+						}
+					}
+					values[i] = friends
+				}
+				return values, nil
+			},
+			Cost: firstCost,
+		},
+		{
+			Name: "secret",
+			Type: NewNonNull(String),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return nil, errors.New("forbidden")
+			},
+		},
+	}
+
+	query := &Object{Name: "Query", Fields: []*Field{
+		{
+			Name: "user",
+			Type: user,
+			Args: []*Argument{{Name: "id", Type: NewNonNull(ID)}},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				if user := findTestUser(args["id"].(string)); user != nil {
+					return user, nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name: "users",
+			Type: NewNonNull(NewList(NewNonNull(user))),
+			Args: firstArgs,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return testUsers[:args["first"].(int)], nil
+			},
+			Cost: firstCost,
+		},
+	}}
+	mutation := &Object{Name: "Mutation", Fields: []*Field{
+		{
+			Name: "rename",
+			Type: NewNonNull(user),
+			Args: []*Argument{{Name: "id", Type: NewNonNull(ID)}, {Name: "name", Type: NewNonNull(String)}},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return &testUser{ID: args["id"].(string), Name: args["name"].(string)}, nil
+			},
+		},
+	}}
+
+	s, err := NewSchema(query, mutation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// execute runs a query and returns the response encoded in JSON
+func execute(t *testing.T, s *Schema, req Request, config Config) string {
+	t.Helper()
+	body, err := json.Marshal(s.Execute(context.Background(), req, config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestExecute(t *testing.T) {
+	s := newTestSchema(t, new(int))
+
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "fields in the order of the query",
+			req:  Request{Query: `{ user(id: 2) { name id } }`},
+			want: `{"data":{"user":{"name":"bob","id":"2"}}}`,
+		},
+		{
+			name: "aliases",
+			req:  Request{Query: `{ a: user(id: "1") { name } b: user(id: "3") { who: name } }`},
+			want: `{"data":{"a":{"name":"alice"},"b":{"who":"carol"}}}`,
+		},
+		{
+			name: "null object",
+			req:  Request{Query: `{ user(id: 9) { name } }`},
+			want: `{"data":{"user":null}}`,
+		},
+		{
+			name: "variables and default arguments",
+			req:  Request{Query: `query Q($id: ID!, $n: Int = 1) { user(id: $id) { friends(first: $n) { name } } }`, Variables: map[string]interface{}{"id": "1"}},
+			want: `{"data":{"user":{"friends":[{"name":"bob"}]}}}`,
+		},
+		{
+			name: "fragments and fields merged",
+			req:  Request{Query: `{ user(id: 1) { ...F name ... on User { id } } } fragment F on User { name }`},
+			want: `{"data":{"user":{"name":"alice","id":"1"}}}`,
+		},
+		{
+			name: "skip and include",
+			req:  Request{Query: `query ($yes: Boolean!) { user(id: 1) { id @skip(if: $yes) name @include(if: $yes) } }`, Variables: map[string]interface{}{"yes": true}},
+			want: `{"data":{"user":{"name":"alice"}}}`,
+		},
+		{
+			name: "typename",
+			req:  Request{Query: `{ __typename user(id: 1) { __typename } }`},
+			want: `{"data":{"__typename":"Query","user":{"__typename":"User"}}}`,
+		},
+		{
+			name: "null non-null field nulls its parent",
+			req:  Request{Query: `{ user(id: 1) { name secret } }`},
+			want: `{"data":{"user":null},"errors":[{"message":"forbidden","locations":[{"line":1,"column":22}],"path":["user","secret"]}]}`,
+		},
+		{
+			name: "operation by name",
+			req:  Request{Query: `query A { user(id: 1) { name } } query B { user(id: 2) { name } }`, OperationName: "B"},
+			want: `{"data":{"user":{"name":"bob"}}}`,
+		},
+		{
+			name: "mutation",
+			req:  Request{Query: `mutation { rename(id: 1, name: "ally") { name } }`},
+			want: `{"data":{"rename":{"name":"ally"}}}`,
+		},
+		{
+			name: "missing variable",
+			req:  Request{Query: `query ($id: ID!) { user(id: $id) { name } }`},
+			want: `{"errors":[{"message":"Variable \"$id\" of required type \"ID!\" was not provided.","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name: "ambiguous operation",
+			req:  Request{Query: `query A { users { id } } query B { users { id } }`},
+			want: `{"errors":[{"message":"Must provide operation name if query contains multiple operations."}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, s, tt.req, Config{}); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteBatchesLevels(t *testing.T) {
+	batches := 0
+	s := newTestSchema(t, &batches)
+
+	got := execute(t, s, Request{Query: `{ users(first: 3) { friends { friends(first: 1) { id } } } }`}, Config{})
+	want := `{"data":{"users":[` +
+		`{"friends":[{"friends":[{"id":"1"}]},{"friends":[{"id":"1"}]}]},` +
+		`{"friends":[{"friends":[{"id":"2"}]},{"friends":[{"id":"1"}]}]},` +
+		`{"friends":[{"friends":[{"id":"2"}]},{"friends":[{"id":"1"}]}]}]}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	// one call per level, not per user
+	if batches != 2 {
+		t.Errorf("friends resolved in %d batches, want 2", batches)
+	}
+}
+
+func TestExecuteQueryOnly(t *testing.T) {
+	s := newTestSchema(t, new(int))
+
+	got := execute(t, s, Request{Query: `mutation { rename(id: 1, name: "x") { id } }`}, Config{QueryOnly: true})
+	if !strings.Contains(got, "Only query operations are allowed here, not mutation operations.") {
+		t.Errorf("mutation should be refused, got %s", got)
+	}
+}
+
+func TestExecuteIntrospection(t *testing.T) {
+	s := newTestSchema(t, new(int))
+
+	got := execute(t, s, Request{Query: `{ __type(name: "User") { name kind fields { name type { kind ofType { name } } } } }`}, Config{})
+	want := `{"data":{"__type":{"name":"User","kind":"OBJECT","fields":[` +
+		`{"name":"id","type":{"kind":"NON_NULL","ofType":{"name":"ID"}}},` +
+		`{"name":"name","type":{"kind":"NON_NULL","ofType":{"name":"String"}}},` +
+		`{"name":"friends","type":{"kind":"NON_NULL","ofType":{"name":null}}},` +
+		`{"name":"secret","type":{"kind":"NON_NULL","ofType":{"name":"String"}}}]}}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
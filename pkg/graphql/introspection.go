@@ -0,0 +1,276 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// directiveDefinition is a directive supported in queries
+type directiveDefinition struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []*Argument
+}
+
+var ifArgument = []*Argument{{Name: "if", Type: NewNonNull(Boolean)}}
+
+var directiveDefinitions = map[string]*directiveDefinition{
+	"skip": {
+		Name:        "skip",
+		Description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:        ifArgument,
+	},
+	"include": {
+		Name:        "include",
+		Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:        ifArgument,
+	},
+}
+
+// introspection holds the __schema and __type fields of the query type
+type introspection struct {
+	schema *Object
+	fields map[string]*Field
+}
+
+// resolveSource adapts a function of the source object to a resolver
+func resolveSource(f func(source interface{}) interface{}) func(context.Context, interface{}, map[string]interface{}) (interface{}, error) {
+	return func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return f(source), nil
+	}
+}
+
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func alwaysFalse(interface{}) interface{} {
+	return false
+}
+
+func alwaysNull(interface{}) interface{} {
+	return nil
+}
+
+func newIntrospection(s *Schema) *introspection {
+	typeKind := &Enum{
+		Name:        "__TypeKind",
+		Description: "An enum describing what kind of type a given `__Type` is.",
+		Values:      []string{"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL"},
+	}
+	directiveLocation := &Enum{
+		Name:        "__DirectiveLocation",
+		Description: "A Directive can be adjacent to many parts of the GraphQL language.",
+		Values: []string{"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD",
+			"INLINE_FRAGMENT", "VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION",
+			"ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION"},
+	}
+	includeDeprecated := []*Argument{{Name: "includeDeprecated", Type: Boolean, Default: false}}
+
+	typ := &Object{Name: "__Type", Description: "The fundamental unit of any GraphQL Schema is the type."}
+	field := &Object{Name: "__Field", Description: "Object and Interface types are described by a list of Fields, each of which has a name, potentially a list of arguments, and a return type."}
+	inputValue := &Object{Name: "__InputValue", Description: "Arguments provided to Fields or Directives and the input fields of an InputObject are represented as Input Values which describe their type and optionally a default value."}
+	enumValue := &Object{Name: "__EnumValue", Description: "One possible value for a given Enum."}
+	directive := &Object{Name: "__Directive", Description: "A Directive provides a way to describe alternate runtime execution and type evaluation behavior in a GraphQL document."}
+	schema := &Object{Name: "__Schema", Description: "A GraphQL Schema defines the capabilities of a GraphQL server."}
+
+	typ.Fields = []*Field{
+		{Name: "kind", Type: NewNonNull(typeKind), Resolve: resolveSource(func(source interface{}) interface{} {
+			switch source.(type) {
+			case *Scalar:
+				return "SCALAR"
+			case *Object:
+				return "OBJECT"
+			case *Enum:
+				return "ENUM"
+			case *InputObject:
+				return "INPUT_OBJECT"
+			case *List:
+				return "LIST"
+			}
+			return "NON_NULL"
+		})},
+		{Name: "name", Type: String, Resolve: resolveSource(func(source interface{}) interface{} {
+			switch source.(type) {
+			case *List, *NonNull:
+				return nil
+			}
+			return source.(Type).String()
+		})},
+		{Name: "description", Type: String, Resolve: resolveSource(func(source interface{}) interface{} {
+			switch t := source.(type) {
+			case *Scalar:
+				return optional(t.Description)
+			case *Object:
+				return optional(t.Description)
+			case *Enum:
+				return optional(t.Description)
+			case *InputObject:
+				return optional(t.Description)
+			}
+			return nil
+		})},
+		{Name: "specifiedByURL", Type: String, Resolve: resolveSource(alwaysNull)},
+		{Name: "fields", Type: NewList(NewNonNull(field)), Args: includeDeprecated, Resolve: resolveSource(func(source interface{}) interface{} {
+			if t, ok := source.(*Object); ok {
+				return t.Fields
+			}
+			return nil
+		})},
+		{Name: "interfaces", Type: NewList(NewNonNull(typ)), Resolve: resolveSource(func(source interface{}) interface{} {
+			if _, ok := source.(*Object); ok {
+				return []Type{}
+			}
+			return nil
+		})},
+		{Name: "possibleTypes", Type: NewList(NewNonNull(typ)), Resolve: resolveSource(alwaysNull)},
+		{Name: "enumValues", Type: NewList(NewNonNull(enumValue)), Args: includeDeprecated, Resolve: resolveSource(func(source interface{}) interface{} {
+			if t, ok := source.(*Enum); ok {
+				return t.Values
+			}
+			return nil
+		})},
+		{Name: "inputFields", Type: NewList(NewNonNull(inputValue)), Args: includeDeprecated, Resolve: resolveSource(func(source interface{}) interface{} {
+			if t, ok := source.(*InputObject); ok {
+				return t.Fields
+			}
+			return nil
+		})},
+		{Name: "ofType", Type: typ, Resolve: resolveSource(func(source interface{}) interface{} {
+			switch t := source.(type) {
+			case *List:
+				return t.Of
+			case *NonNull:
+				return t.Of
+			}
+			return nil
+		})},
+	}
+
+	field.Fields = []*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*Field).Name
+		})},
+		{Name: "description", Type: String, Resolve: resolveSource(func(source interface{}) interface{} {
+			return optional(source.(*Field).Description)
+		})},
+		{Name: "args", Type: NewNonNull(NewList(NewNonNull(inputValue))), Args: includeDeprecated, Resolve: resolveSource(func(source interface{}) interface{} {
+			if args := source.(*Field).Args; args != nil {
+				return args
+			}
+			return []*Argument{}
+		})},
+		{Name: "type", Type: NewNonNull(typ), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*Field).Type
+		})},
+		{Name: "isDeprecated", Type: NewNonNull(Boolean), Resolve: resolveSource(alwaysFalse)},
+		{Name: "deprecationReason", Type: String, Resolve: resolveSource(alwaysNull)},
+	}
+
+	inputValue.Fields = []*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*Argument).Name
+		})},
+		{Name: "description", Type: String, Resolve: resolveSource(func(source interface{}) interface{} {
+			return optional(source.(*Argument).Description)
+		})},
+		{Name: "type", Type: NewNonNull(typ), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*Argument).Type
+		})},
+		{Name: "defaultValue", Type: String, Resolve: resolveSource(func(source interface{}) interface{} {
+			arg := source.(*Argument)
+			if arg.Default == nil {
+				return nil
+			}
+			b, err := json.Marshal(arg.Default)
+			if err != nil {
+				return nil
+			}
+			return string(b)
+		})},
+		{Name: "isDeprecated", Type: NewNonNull(Boolean), Resolve: resolveSource(alwaysFalse)},
+		{Name: "deprecationReason", Type: String, Resolve: resolveSource(alwaysNull)},
+	}
+
+	enumValue.Fields = []*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source
+		})},
+		{Name: "description", Type: String, Resolve: resolveSource(alwaysNull)},
+		{Name: "isDeprecated", Type: NewNonNull(Boolean), Resolve: resolveSource(alwaysFalse)},
+		{Name: "deprecationReason", Type: String, Resolve: resolveSource(alwaysNull)},
+	}
+
+	directive.Fields = []*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*directiveDefinition).Name
+		})},
+		{Name: "description", Type: String, Resolve: resolveSource(func(source interface{}) interface{} {
+			return optional(source.(*directiveDefinition).Description)
+		})},
+		{Name: "isRepeatable", Type: NewNonNull(Boolean), Resolve: resolveSource(alwaysFalse)},
+		{Name: "locations", Type: NewNonNull(NewList(NewNonNull(directiveLocation))), Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*directiveDefinition).Locations
+		})},
+		{Name: "args", Type: NewNonNull(NewList(NewNonNull(inputValue))), Args: includeDeprecated, Resolve: resolveSource(func(source interface{}) interface{} {
+			return source.(*directiveDefinition).Args
+		})},
+	}
+
+	schema.Fields = []*Field{
+		{Name: "description", Type: String, Resolve: resolveSource(alwaysNull)},
+		{Name: "types", Type: NewNonNull(NewList(NewNonNull(typ))), Resolve: resolveSource(func(interface{}) interface{} {
+			names := s.typeNames()
+			types := make([]Type, len(names))
+			for i, name := range names {
+				types[i] = s.types[name]
+			}
+			return types
+		})},
+		{Name: "queryType", Type: NewNonNull(typ), Resolve: resolveSource(func(interface{}) interface{} {
+			return s.query
+		})},
+		{Name: "mutationType", Type: typ, Resolve: resolveSource(func(interface{}) interface{} {
+			if s.mutation == nil {
+				return nil
+			}
+			return s.mutation
+		})},
+		{Name: "subscriptionType", Type: typ, Resolve: resolveSource(alwaysNull)},
+		{Name: "directives", Type: NewNonNull(NewList(NewNonNull(directive))), Resolve: resolveSource(func(interface{}) interface{} {
+			return []*directiveDefinition{directiveDefinitions["include"], directiveDefinitions["skip"]}
+		})},
+	}
+
+	return &introspection{
+		schema: schema,
+		fields: map[string]*Field{
+			"__schema": {
+				Name:        "__schema",
+				Description: "Access the current type schema of this server.",
+				Type:        NewNonNull(schema),
+				Resolve: resolveSource(func(interface{}) interface{} {
+					return s
+				}),
+			},
+			"__type": {
+				Name:        "__type",
+				Description: "Request the type information of a single type.",
+				Type:        typ,
+				Args:        []*Argument{{Name: "name", Type: NewNonNull(String)}},
+				Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					if t, ok := s.types[args["name"].(string)]; ok {
+						return t, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	}
+}
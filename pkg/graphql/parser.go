@@ -0,0 +1,431 @@
+package graphql
+
+import (
+	"strconv"
+)
+
+// parser is a recursive descent parser of executable documents, type system definitions aren't supported
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{lexer: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections, loc: selections[0].location()})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, &Error{Message: "There can be only one fragment named \"" + frag.name + "\".", Locations: []Location{frag.loc}}
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, &Error{Message: "Syntax Error: the document has no operation"}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = t
+	return nil
+}
+
+func (p *parser) peek(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.token.kind == tokenName && p.token.value == name
+}
+
+func (p *parser) unexpected() error {
+	return p.lexer.errorf(p.token.loc, "unexpected %s", p.token)
+}
+
+// skip consumes the punctuator when it is next
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(punctuator) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return p.lexer.errorf(p.token.loc, "expected %q, found %s", punctuator, p.token)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.lexer.errorf(p.token.loc, "expected a name, found %s", p.token)
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.token.value, loc: p.token.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.token.kind == tokenName {
+		if op.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if op.variables, err = p.variableDefinitions(); err != nil {
+		return nil, err
+	}
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*variableDefinition, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	var definitions []*variableDefinition
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return definitions, err
+		}
+
+		definition := &variableDefinition{loc: p.token.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if definition.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if definition.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if definition.defaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+			definition.hasDefault = true
+		}
+		if _, err := p.directives(); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	nonNull, err := p.skip("!")
+	t.nonNull = nonNull
+	return t, err
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.token.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+	for {
+		if ok, err := p.skip("}"); err != nil {
+			return nil, err
+		} else if ok {
+			if len(selections) == 0 {
+				return nil, p.lexer.errorf(p.token.loc, "empty selection set")
+			}
+			return selections, nil
+		}
+
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+}
+
+func (p *parser) selection() (selection, error) {
+	if !p.peek("...") {
+		return p.field()
+	}
+
+	loc := p.token.loc
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.token.kind == tokenName && p.token.value != "on" {
+		spread := &fragmentSpread{loc: loc}
+		var err error
+		if spread.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if spread.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		return spread, nil
+	}
+
+	inline := &inlineFragment{loc: loc}
+	var err error
+	if p.peekName("on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if inline.typeCondition, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) field() (*field, error) {
+	f := &field{loc: p.token.loc}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = f.name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments() ([]*argument, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	var arguments []*argument
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return arguments, err
+		}
+
+		arg := &argument{loc: p.token.loc}
+		var err error
+		if arg.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.value, err = p.value(false); err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, arg)
+	}
+}
+
+// value parses a literal, constant ones can't hold variables
+func (p *parser) value(constant bool) (value, error) {
+	t := p.token
+	switch t.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, p.lexer.errorf(t.loc, "invalid integer %s", t.value)
+		}
+		return n, p.advance()
+	case tokenFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.lexer.errorf(t.loc, "invalid float %s", t.value)
+		}
+		return f, p.advance()
+	case tokenString:
+		return t.value, p.advance()
+	case tokenName:
+		var v value
+		switch t.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = enumValue(t.value)
+		}
+		return v, p.advance()
+	}
+
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := listValue{}
+		for {
+			if ok, err := p.skip("]"); ok || err != nil {
+				return list, err
+			}
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := objectValue{}
+		for {
+			if ok, err := p.skip("}"); ok || err != nil {
+				return object, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, &objectField{name: name, value: item})
+		}
+	}
+
+	return nil, p.unexpected()
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	frag := &fragment{loc: p.token.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if frag.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.name == "on" {
+		return nil, p.lexer.errorf(frag.loc, "a fragment can't be named \"on\"")
+	}
+	if !p.peekName("on") {
+		return nil, p.lexer.errorf(p.token.loc, "expected \"on\", found %s", p.token)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if frag.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
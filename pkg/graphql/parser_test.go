@@ -0,0 +1,124 @@
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# the comments and commas are ignored,
+		query Photos($id: ID! = "1", $tags: [String!]) @include(if: true) {
+			me: user(id: $id, filter: {name: "a", tags: [B, null]}, limit: -1.5e2) {
+				...Fields @skip(if: false)
+				... on User { id }
+				... @include(if: $yes) { name }
+			}
+		}
+
+		fragment Fields on User { name }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Photos" || len(op.directives) != 1 {
+		t.Fatalf("unexpected operation: %+v", op)
+	}
+	if len(op.variables) != 2 || op.variables[0].typ.String() != "ID!" || op.variables[0].defaultValue != "1" ||
+		op.variables[1].typ.String() != "[String!]" || op.variables[1].hasDefault {
+		t.Errorf("unexpected variables: %+v %+v", op.variables[0], op.variables[1])
+	}
+
+	f := op.selections[0].(*field)
+	if f.alias != "me" || f.name != "user" || f.responseKey() != "me" {
+		t.Errorf("unexpected field: %+v", f)
+	}
+	wantArgs := []value{
+		variable("id"),
+		objectValue{{name: "name", value: "a"}, {name: "tags", value: listValue{enumValue("B"), nil}}},
+		-150.0,
+	}
+	for i, arg := range f.arguments {
+		if !reflect.DeepEqual(arg.value, wantArgs[i]) {
+			t.Errorf("argument %s is %#v, want %#v", arg.name, arg.value, wantArgs[i])
+		}
+	}
+
+	if spread := f.selections[0].(*fragmentSpread); spread.name != "Fields" || spread.directives[0].name != "skip" {
+		t.Errorf("unexpected spread: %+v", spread)
+	}
+	if inline := f.selections[1].(*inlineFragment); inline.typeCondition != "User" {
+		t.Errorf("unexpected inline fragment: %+v", inline)
+	}
+	if inline := f.selections[2].(*inlineFragment); inline.typeCondition != "" || inline.directives[0].name != "include" {
+		t.Errorf("unexpected inline fragment: %+v", inline)
+	}
+	if frag := doc.fragments["Fields"]; frag == nil || frag.typeCondition != "User" {
+		t.Errorf("unexpected fragment: %+v", frag)
+	}
+	if want := (Location{Line: 4, Column: 4}); f.loc != want {
+		t.Errorf("field at %+v, want %+v", f.loc, want)
+	}
+}
+
+func TestParseStrings(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    string
+	}{
+		{`"plain"`, "plain"},
+		{`"esc\"aped\\\/\n\tend"`, "esc\"aped\\/\n\tend"},
+		{`"été"`, "été"},
+		{`"""
+			block
+			  indented
+		"""`, "block\n  indented"},
+		{`"""a \""" b"""`, `a """ b`},
+	}
+	for _, tt := range tests {
+		doc, err := parse(`{ f(s: ` + tt.literal + `) }`)
+		if err != nil {
+			t.Errorf("%s: %v", tt.literal, err)
+			continue
+		}
+		if got := doc.operations[0].selections[0].(*field).arguments[0].value; got != tt.want {
+			t.Errorf("%s parsed as %q, want %q", tt.literal, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		loc   Location
+	}{
+		{`{ user `, `Syntax Error: expected a name, found <EOF>`, Location{Line: 1, Column: 8}},
+		{`{ }`, `Syntax Error: empty selection set`, Location{Line: 1, Column: 4}},
+		{"{\n  f(a: 01x) }", `Syntax Error: invalid number`, Location{Line: 2, Column: 8}},
+		{`{ f(a: "open) }`, `Syntax Error: unterminated string`, Location{Line: 1, Column: 8}},
+		{`{ f(a: "\q") }`, `Syntax Error: invalid escape \q`, Location{Line: 1, Column: 8}},
+		{`{ f ; }`, `Syntax Error: unexpected character ';'`, Location{Line: 1, Column: 5}},
+		{`fragment on on User { id } { id }`, `Syntax Error: a fragment can't be named "on"`, Location{Line: 1, Column: 1}},
+		{`fragment F User { id }`, `Syntax Error: expected "on", found User`, Location{Line: 1, Column: 12}},
+		{`fragment F on User { id }`, `Syntax Error: the document has no operation`, Location{}},
+		{`{ a } fragment F on User { id } fragment F on User { id }`, `There can be only one fragment named "F".`, Location{Line: 1, Column: 33}},
+		{`query ($v: Int = $w) { a }`, `Syntax Error: unexpected $`, Location{Line: 1, Column: 18}},
+	}
+	for _, tt := range tests {
+		_, err := parse(tt.query)
+		gqlErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%s: got %v, want a syntax error", tt.query, err)
+			continue
+		}
+		if gqlErr.Message != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, gqlErr.Message, tt.want)
+		}
+		if tt.loc != (Location{}) && (len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != tt.loc) {
+			t.Errorf("%s: error at %+v, want %+v", tt.query, gqlErr.Locations, tt.loc)
+		}
+	}
+}
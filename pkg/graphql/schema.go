@@ -0,0 +1,380 @@
+// Package graphql executes GraphQL queries against a schema defined in Go. Fields resolve for every
+// parent object of a level at once, so resolvers can load the children of a whole list in a single
+// query instead of one per parent. Only the object types of the schema are supported, interfaces,
+// unions and subscriptions aren't.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Type is a *Scalar, *Enum, *Object, *InputObject, *List or *NonNull
+type Type interface {
+	// String is the type as written in queries, e.g. [Photo!]!
+	String() string
+}
+
+// Scalar is a leaf type
+type Scalar struct {
+	Name        string
+	Description string
+	// Serialize converts a resolved value to its JSON value
+	Serialize func(value interface{}) (interface{}, error)
+	// Parse converts an input, a JSON value of the variables or a literal of the query, to the value
+	// given to resolvers. Literals are int64, float64, string or bool.
+	Parse func(value interface{}) (interface{}, error)
+}
+
+// Enum is a leaf type whose values are strings
+type Enum struct {
+	Name        string
+	Description string
+	Values      []string
+}
+
+// Object is an output type with fields
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+// InputObject is an argument type with fields, resolvers get it as a map[string]interface{}
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*Argument
+}
+
+// List is a list of Of, resolvers return slices
+type List struct {
+	Of Type
+}
+
+// NonNull is a value of Of that is never null
+type NonNull struct {
+	Of Type
+}
+
+func (t *Scalar) String() string      { return t.Name }
+func (t *Enum) String() string        { return t.Name }
+func (t *Object) String() string      { return t.Name }
+func (t *InputObject) String() string { return t.Name }
+func (t *List) String() string        { return "[" + t.Of.String() + "]" }
+func (t *NonNull) String() string     { return t.Of.String() + "!" }
+
+// NewList is a shorthand for &List{Of: t}
+func NewList(t Type) *List {
+	return &List{Of: t}
+}
+
+// NewNonNull is a shorthand for &NonNull{Of: t}
+func NewNonNull(t Type) *NonNull {
+	return &NonNull{Of: t}
+}
+
+// Field is a field of an Object, resolved with Resolve or Batch
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Argument
+	// Resolve resolves the field of one parent object, nil for root fields
+	Resolve func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+	// Batch resolves the field of every parent object of a level at once and returns a value per
+	// parent, in the same order
+	Batch func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error)
+	// Cost is the complexity of the field from its arguments and the complexity of its selections,
+	// 1 + children when nil. Lists should multiply children by the number of items they can return.
+	Cost func(args map[string]interface{}, children int) int
+}
+
+// Argument is an argument of a field or a field of an InputObject
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	// Default is used when the argument is omitted, nil when there is none
+	Default interface{}
+}
+
+func (t *Object) field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// namedType unwraps lists and non-null types
+func namedType(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.Of
+		case *NonNull:
+			t = wrapper.Of
+		default:
+			return t
+		}
+	}
+}
+
+func typeName(t Type) string {
+	return namedType(t).String()
+}
+
+// Schema is the entry point of queries and mutations
+type Schema struct {
+	query    *Object
+	mutation *Object
+	types    map[string]Type
+
+	// meta are the introspection fields of the query type
+	meta map[string]*Field
+}
+
+// NewSchema checks the types reachable from query and mutation, which may be nil
+func NewSchema(query *Object, mutation *Object) (*Schema, error) {
+	s := &Schema{
+		query:    query,
+		mutation: mutation,
+		types:    make(map[string]Type),
+	}
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+
+	introspection := newIntrospection(s)
+	roots := []Type{query, introspection.schema}
+	if mutation != nil {
+		roots = append(roots, mutation)
+	}
+	for _, root := range roots {
+		if err := s.collect(root); err != nil {
+			return nil, err
+		}
+	}
+	s.meta = introspection.fields
+	return s, nil
+}
+
+func (s *Schema) collect(t Type) error {
+	named := namedType(t)
+	name := named.String()
+	if existing, ok := s.types[name]; ok {
+		if existing != named {
+			return fmt.Errorf("graphql: two types are named %s", name)
+		}
+		return nil
+	}
+	s.types[name] = named
+
+	switch named := named.(type) {
+	case *Object:
+		for _, f := range named.Fields {
+			if f.Resolve == nil && f.Batch == nil {
+				return fmt.Errorf("graphql: field %s.%s has no resolver", named.Name, f.Name)
+			}
+			if err := s.collect(f.Type); err != nil {
+				return err
+			}
+			for _, arg := range f.Args {
+				if err := s.collect(arg.Type); err != nil {
+					return err
+				}
+			}
+		}
+	case *InputObject:
+		for _, f := range named.Fields {
+			if err := s.collect(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeNames lists the named types in alphabetical order
+func (s *Schema) typeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Int is a signed 32 bit integer
+var Int = &Scalar{
+	Name:        "Int",
+	Description: "The `Int` scalar type represents non-fractional signed whole numeric values between -2^31 and 2^31-1.",
+	Serialize: func(value interface{}) (interface{}, error) {
+		n, ok := toInt(value)
+		if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("Int cannot represent %v", value)
+		}
+		return n, nil
+	},
+	Parse: func(value interface{}) (interface{}, error) {
+		n, ok := toInt(value)
+		if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("Int cannot represent %s", describe(value))
+		}
+		return int(n), nil
+	},
+}
+
+// Float is a double precision number
+var Float = &Scalar{
+	Name:        "Float",
+	Description: "The `Float` scalar type represents signed double-precision fractional values.",
+	Serialize: func(value interface{}) (interface{}, error) {
+		f, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("Float cannot represent %v", value)
+		}
+		return f, nil
+	},
+	Parse: func(value interface{}) (interface{}, error) {
+		f, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("Float cannot represent %s", describe(value))
+		}
+		return f, nil
+	},
+}
+
+// String is a UTF-8 string
+var String = &Scalar{
+	Name:        "String",
+	Description: "The `String` scalar type represents textual data, represented as UTF-8 character sequences.",
+	Serialize: func(value interface{}) (interface{}, error) {
+		switch value := value.(type) {
+		case string:
+			return value, nil
+		case fmt.Stringer:
+			return value.String(), nil
+		}
+		return nil, fmt.Errorf("String cannot represent %v", value)
+	},
+	Parse: func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("String cannot represent %s", describe(value))
+		}
+		return s, nil
+	},
+}
+
+// Boolean is true or false
+var Boolean = &Scalar{
+	Name:        "Boolean",
+	Description: "The `Boolean` scalar type represents `true` or `false`.",
+	Serialize: func(value interface{}) (interface{}, error) {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("Boolean cannot represent %v", value)
+		}
+		return b, nil
+	},
+	Parse: func(value interface{}) (interface{}, error) {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("Boolean cannot represent %s", describe(value))
+		}
+		return b, nil
+	},
+}
+
+// ID is an identifier serialized as a string, integers are accepted as input. Resolvers get a string.
+var ID = &Scalar{
+	Name:        "ID",
+	Description: "The `ID` scalar type represents a unique identifier, serialized as a string.",
+	Serialize: func(value interface{}) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		if n, ok := toInt(value); ok {
+			return strconv.FormatInt(n, 10), nil
+		}
+		return nil, fmt.Errorf("ID cannot represent %v", value)
+	},
+	Parse: func(value interface{}) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		if n, ok := toInt(value); ok {
+			return strconv.FormatInt(n, 10), nil
+		}
+		return nil, fmt.Errorf("ID cannot represent %s", describe(value))
+	},
+}
+
+func toInt(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), n <= math.MaxInt64
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float64:
+		return int64(n), n == math.Trunc(n) && math.Abs(n) < 1<<53
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	if i, ok := toInt(value); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// describe prints an input value for error messages
+func describe(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	if value == nil {
+		return "null"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
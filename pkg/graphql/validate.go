@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxSelections caps the fields and fragments an operation selects, spreading a fragment again
+// counts once since its cost is reused
+const maxSelections = 5000
+
+// maxCost caps the complexities added up so that nested lists can't overflow them
+const maxCost = 1 << 31
+
+// validator checks an operation against the schema before it is executed, and measures its depth
+// and complexity. It stops at the first limit exceeded.
+type validator struct {
+	schema   *Schema
+	doc      *document
+	vars     map[string]interface{}
+	config   Config
+	defined  map[string]bool
+	visiting map[string]bool
+	// fragments are the costs of the fragments already validated, they are reused by other spreads
+	fragments map[fragmentKey]cost
+	visited   int
+	stopped   bool
+	errors    []*Error
+}
+
+// cost is the complexity of selections and their depth, root fields are at depth 1
+type cost struct {
+	complexity int
+	depth      int
+}
+
+// fragmentKey is a fragment spread where fields are metered or not, below introspection fields
+type fragmentKey struct {
+	name    string
+	metered bool
+}
+
+func (s *Schema) validate(doc *document, op *operation, root *Object, vars map[string]interface{}, config Config) []*Error {
+	v := &validator{
+		schema:    s,
+		doc:       doc,
+		vars:      vars,
+		config:    config,
+		defined:   make(map[string]bool, len(op.variables)),
+		visiting:  make(map[string]bool),
+		fragments: make(map[fragmentKey]cost),
+	}
+	for _, definition := range op.variables {
+		v.defined[definition.name] = true
+	}
+
+	v.selections(root, op.selections, 1, true)
+	return v.errors
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// stop reports a limit exceeded, nothing is validated after it
+func (v *validator) stop(loc Location, format string, args ...interface{}) {
+	v.errorf(loc, format, args...)
+	v.stopped = true
+}
+
+// visit counts a selection against maxSelections
+func (v *validator) visit(loc Location) bool {
+	v.visited++
+	if v.visited > maxSelections {
+		v.stop(loc, "Query has more than %d selections.", maxSelections)
+		return false
+	}
+	return true
+}
+
+// checkDepth tells whether a field at depth is within the maximum depth
+func (v *validator) checkDepth(depth int, loc Location) bool {
+	if v.config.MaxDepth > 0 && depth > v.config.MaxDepth {
+		v.stop(loc, "Query depth %d exceeds the maximum depth of %d.", depth, v.config.MaxDepth)
+		return false
+	}
+	return true
+}
+
+// selections validates the selections of an object type at depth and returns their cost, the depth
+// returned counts the levels below depth too. Metered is false below introspection fields, which
+// count neither towards the depth nor the complexity.
+func (v *validator) selections(t *Object, selections []selection, depth int, metered bool) cost {
+	var c cost
+	for _, s := range selections {
+		if v.stopped || !v.visit(s.location()) {
+			break
+		}
+
+		switch s := s.(type) {
+		case *field:
+			v.directives(s.directives)
+			c = c.add(v.field(t, s, depth, metered))
+		case *fragmentSpread:
+			v.directives(s.directives)
+			c = c.add(v.fragmentSpread(t, s, depth, metered))
+		case *inlineFragment:
+			v.directives(s.directives)
+			if s.typeCondition != "" && !v.typeCondition(t, s.typeCondition, s.loc) {
+				continue
+			}
+			c = c.add(v.selections(t, s.selections, depth, metered))
+		}
+
+		// Complexities only add up, the total is already too high
+		if depth == 1 && v.config.MaxComplexity > 0 && c.complexity > v.config.MaxComplexity && !v.stopped {
+			v.stop(s.location(), "Query complexity %d exceeds the maximum complexity of %d.", c.complexity, v.config.MaxComplexity)
+		}
+	}
+	return c
+}
+
+// fragmentSpread validates a fragment the first time it is spread, later spreads reuse its cost
+func (v *validator) fragmentSpread(t *Object, s *fragmentSpread, depth int, metered bool) cost {
+	frag, ok := v.doc.fragments[s.name]
+	if !ok {
+		v.errorf(s.loc, "Unknown fragment %q.", s.name)
+		return cost{}
+	}
+	if v.visiting[s.name] {
+		v.errorf(s.loc, "Cannot spread fragment %q within itself.", s.name)
+		return cost{}
+	}
+	if !v.typeCondition(t, frag.typeCondition, s.loc) {
+		return cost{}
+	}
+
+	key := fragmentKey{name: s.name, metered: metered}
+	if c, ok := v.fragments[key]; ok {
+		if c.depth > 0 {
+			v.checkDepth(depth+c.depth-1, s.loc)
+		}
+		return c
+	}
+
+	v.visiting[s.name] = true
+	c := v.selections(t, frag.selections, depth, metered)
+	delete(v.visiting, s.name)
+	v.fragments[key] = c
+	return c
+}
+
+func (v *validator) field(t *Object, f *field, depth int, metered bool) cost {
+	// __typename costs like any field, the other introspection fields are free
+	metered = metered && (f.name == "__typename" || !strings.HasPrefix(f.name, "__"))
+	if metered && !v.checkDepth(depth, f.loc) {
+		return cost{}
+	}
+
+	if f.name == "__typename" {
+		if len(f.selections) > 0 {
+			v.errorf(f.loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+		}
+		if !metered {
+			return cost{}
+		}
+		return cost{complexity: 1, depth: 1}
+	}
+
+	definition := v.schema.fieldDefinition(t, f.name)
+	if definition == nil {
+		v.errorf(f.loc, "Cannot query field %q on type %q.", f.name, t.Name)
+		return cost{}
+	}
+
+	for _, arg := range f.arguments {
+		v.variables(arg.value, arg.loc)
+	}
+	args, err := argumentValues(definition.Args, f.arguments, v.vars)
+	if err != nil {
+		v.errorf(f.loc, "Field %q: %v.", f.name, err)
+		args = map[string]interface{}{}
+	}
+
+	var children cost
+	if object, ok := namedType(definition.Type).(*Object); ok {
+		if len(f.selections) == 0 {
+			v.errorf(f.loc, "Field %q of type %q must have a selection of subfields.", f.name, definition.Type)
+			return cost{}
+		}
+		children = v.selections(object, f.selections, depth+1, metered)
+	} else if len(f.selections) > 0 {
+		v.errorf(f.loc, "Field %q must not have a selection since type %q has no subfields.", f.name, definition.Type)
+	}
+
+	if !metered {
+		return cost{}
+	}
+	c := cost{complexity: 1 + children.complexity, depth: 1 + children.depth}
+	if definition.Cost != nil {
+		c.complexity = definition.Cost(args, children.complexity)
+	}
+	if c.complexity < 0 || c.complexity > maxCost {
+		c.complexity = maxCost
+	}
+	return c
+}
+
+// add sums the complexities and keeps the deepest depth
+func (c cost) add(other cost) cost {
+	c.complexity += other.complexity
+	if c.complexity > maxCost {
+		c.complexity = maxCost
+	}
+	if other.depth > c.depth {
+		c.depth = other.depth
+	}
+	return c
+}
+
+// typeCondition tells whether a fragment on condition applies to t, the schema has no abstract
+// types so it must be t itself
+func (v *validator) typeCondition(t *Object, condition string, loc Location) bool {
+	if condition == t.Name {
+		return true
+	}
+	if _, ok := v.schema.types[condition]; !ok {
+		v.errorf(loc, "Unknown type %q.", condition)
+	} else {
+		v.errorf(loc, "Fragment cannot be spread here as objects of type %q can never be of type %q.", t.Name, condition)
+	}
+	return false
+}
+
+func (v *validator) directives(directives []*directive) {
+	for _, d := range directives {
+		definition, ok := directiveDefinitions[d.name]
+		if !ok {
+			v.errorf(d.loc, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		for _, arg := range d.arguments {
+			v.variables(arg.value, arg.loc)
+		}
+		if _, err := argumentValues(definition.Args, d.arguments, v.vars); err != nil {
+			v.errorf(d.loc, "Directive \"@%s\": %v.", d.name, err)
+		}
+	}
+}
+
+// variables reports the variables of a literal the operation doesn't define
+func (v *validator) variables(literal value, loc Location) {
+	switch literal := literal.(type) {
+	case variable:
+		if !v.defined[string(literal)] {
+			v.errorf(loc, "Variable \"$%s\" is not defined.", literal)
+		}
+	case listValue:
+		for _, item := range literal {
+			v.variables(item, loc)
+		}
+	case objectValue:
+		for _, f := range literal {
+			v.variables(f.value, loc)
+		}
+	}
+}
+
+// fieldDefinition finds a field of t, the introspection fields are only available on the query type
+func (s *Schema) fieldDefinition(t *Object, name string) *Field {
+	if t == s.query {
+		if meta, ok := s.meta[name]; ok {
+			return meta
+		}
+	}
+	return t.field(name)
+}
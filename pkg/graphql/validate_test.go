@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validationErrors(t *testing.T, s *Schema, query string, config Config) []string {
+	t.Helper()
+	response := s.Execute(context.Background(), Request{Query: query}, config)
+	if response.Data != nil {
+		t.Fatalf("%s was executed", query)
+	}
+	var messages []string
+	for _, err := range response.Errors {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
+
+func TestValidate(t *testing.T) {
+	s := newTestSchema(t, new(int))
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{ user(id: 1) { age } }`, `Cannot query field "age" on type "User".`},
+		{`{ user(id: 1) }`, `Field "user" of type "User" must have a selection of subfields.`},
+		{`{ user(id: 1) { name { id } } }`, `Field "name" must not have a selection since type "String!" has no subfields.`},
+		{`{ __typename { id } }`, `Field "__typename" must not have a selection since type "String!" has no subfields.`},
+		{`{ user { id } }`, `Field "user": argument "id" of type ID! is required.`},
+		{`{ user(id: 1) { friends(first: $n) { id } } }`, `Variable "$n" is not defined.`},
+		{`{ user(id: 1) { ...Missing } }`, `Unknown fragment "Missing".`},
+		{`{ user(id: 1) { ...A } } fragment A on User { friends { ...A } }`, `Cannot spread fragment "A" within itself.`},
+		{`{ user(id: 1) { ...Q } } fragment Q on Query { __typename }`, `Fragment cannot be spread here as objects of type "User" can never be of type "Query".`},
+		{`{ user(id: 1) { ... on Nope { id } } }`, `Unknown type "Nope".`},
+		{`{ user(id: 1) @cache { id } }`, `Unknown directive "@cache".`},
+	}
+	for _, tt := range tests {
+		errs := validationErrors(t, s, tt.query, Config{})
+		if len(errs) != 1 || errs[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, errs, tt.want)
+		}
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	s := newTestSchema(t, new(int))
+
+	tests := []struct {
+		name   string
+		query  string
+		config Config
+		want   string
+	}{
+		{
+			name:   "depth",
+			query:  `{ users { friends { friends { id } } } }`,
+			config: Config{MaxDepth: 3},
+			want:   `Query depth 4 exceeds the maximum depth of 3.`,
+		},
+		{
+			name:   "depth through a fragment spread deeper the second time",
+			query:  `{ users { ...F friends { ...F } } } fragment F on User { friends { id } }`,
+			config: Config{MaxDepth: 3},
+			want:   `Query depth 4 exceeds the maximum depth of 3.`,
+		},
+		{
+			name: "complexity",
+			// users: 1 + 2 * (friends: 1 + 10 * (id: 1)) = 23
+			query:  `{ users { friends(first: 10) { id } } }`,
+			config: Config{MaxComplexity: 22},
+			want:   `Query complexity 23 exceeds the maximum complexity of 22.`,
+		},
+		{
+			name:   "typename counts",
+			query:  `{ a: __typename b: __typename c: __typename }`,
+			config: Config{MaxComplexity: 2},
+			want:   `Query complexity 3 exceeds the maximum complexity of 2.`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validationErrors(t, s, tt.query, tt.config)
+			if len(errs) != 1 || errs[0] != tt.want {
+				t.Errorf("got %q, want %q", errs, tt.want)
+			}
+		})
+	}
+
+	// introspection is free, the same query within limits is executed
+	for _, query := range []string{`{ __schema { types { fields { type { name } } } } }`, `{ users { friends(first: 10) { id } } }`} {
+		if response := s.Execute(context.Background(), Request{Query: query}, Config{MaxDepth: 3, MaxComplexity: 23}); len(response.Errors) > 0 {
+			t.Errorf("%s: %v", query, response.Errors[0])
+		}
+	}
+}
+
+// nestedFragments spreads every fragment twice in the next one, the expanded query doubles with each
+func nestedFragments(levels int) string {
+	var b strings.Builder
+	b.WriteString(`{ user(id: 1) { ...F0 } }`)
+	for i := 0; i < levels; i++ {
+		fmt.Fprintf(&b, " fragment F%d on User { ...F%d friends { ...F%d } }", i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, " fragment F%d on User { id }", levels)
+	return b.String()
+}
+
+func TestValidateNestedFragmentsOnce(t *testing.T) {
+	s := newTestSchema(t, new(int))
+	query := nestedFragments(40)
+
+	start := time.Now()
+	deep := validationErrors(t, s, query, Config{MaxDepth: 8})
+	shallow := validationErrors(t, s, query, Config{MaxComplexity: 10000})
+	// the response would double with every level, only its validation runs
+	doc, err := parse(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlimited := s.validate(doc, doc.operations[0], s.query, nil, Config{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("validated in %v, fragments should be walked once", elapsed)
+	}
+
+	if len(deep) != 1 || deep[0] != `Query depth 9 exceeds the maximum depth of 8.` {
+		t.Errorf("got %q", deep)
+	}
+	if len(shallow) != 1 || !strings.HasSuffix(shallow[0], `exceeds the maximum complexity of 10000.`) {
+		t.Errorf("got %q", shallow)
+	}
+	if len(unlimited) > 0 {
+		t.Errorf("query without limits should be valid, got %v", unlimited[0])
+	}
+}
+
+func TestValidateSelectionsCap(t *testing.T) {
+	s := newTestSchema(t, new(int))
+	query := "{" + strings.Repeat(" __typename", maxSelections+1) + " }"
+
+	errs := validationErrors(t, s, query, Config{})
+	if len(errs) != 1 || errs[0] != fmt.Sprintf("Query has more than %d selections.", maxSelections) {
+		t.Errorf("got %q", errs)
+	}
+}
@@ -0,0 +1,271 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// coerceInput converts a JSON value of the variables to the Go value of t
+func coerceInput(t Type, input interface{}) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if input == nil {
+			return nil, fmt.Errorf("expected a non-null %s", nonNull.Of)
+		}
+		return coerceInput(nonNull.Of, input)
+	}
+	if input == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		items, ok := input.([]interface{})
+		if !ok {
+			// A single value is a list of one item
+			item, err := coerceInput(t.Of, input)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			value, err := coerceInput(t.Of, item)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list[i] = value
+		}
+		return list, nil
+	case *Scalar:
+		return t.Parse(input)
+	case *Enum:
+		name, ok := input.(string)
+		if !ok || !t.has(name) {
+			return nil, fmt.Errorf("%s is not a value of %s", describe(input), t.Name)
+		}
+		return name, nil
+	case *InputObject:
+		fields, ok := input.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type %s, found %s", t.Name, describe(input))
+		}
+		return coerceInputObject(t, fields, func(f *Argument, value interface{}) (interface{}, error) {
+			return coerceInput(f.Type, value)
+		})
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+func coerceInputObject(t *InputObject, fields map[string]interface{}, coerce func(*Argument, interface{}) (interface{}, error)) (interface{}, error) {
+	for name := range fields {
+		if t.field(name) == nil {
+			return nil, fmt.Errorf("field %q is not defined by type %s", name, t.Name)
+		}
+	}
+
+	object := make(map[string]interface{}, len(t.Fields))
+	for _, f := range t.Fields {
+		raw, ok := fields[f.Name]
+		if !ok {
+			if f.Default != nil {
+				object[f.Name] = f.Default
+			} else if _, required := f.Type.(*NonNull); required {
+				return nil, fmt.Errorf("field %s.%s of required type %s was not provided", t.Name, f.Name, f.Type)
+			}
+			continue
+		}
+		value, err := coerce(f, raw)
+		if err != nil {
+			return nil, fmt.Errorf("in field %q: %w", f.Name, err)
+		}
+		object[f.Name] = value
+	}
+	return object, nil
+}
+
+// valueFromLiteral converts a literal of the query to the Go value of t, vars are the coerced
+// variables. ok is false when the literal is a variable that was not provided.
+func valueFromLiteral(t Type, literal value, vars map[string]interface{}) (result interface{}, ok bool, err error) {
+	if v, isVariable := literal.(variable); isVariable {
+		result, ok = vars[string(v)]
+		if ok && result == nil {
+			if _, nonNull := t.(*NonNull); nonNull {
+				return nil, true, fmt.Errorf("variable $%s must not be null", v)
+			}
+		}
+		return result, ok, nil
+	}
+
+	if nonNull, isNonNull := t.(*NonNull); isNonNull {
+		if literal == nil {
+			return nil, true, fmt.Errorf("expected a non-null %s", nonNull.Of)
+		}
+		return valueFromLiteral(nonNull.Of, literal, vars)
+	}
+	if literal == nil {
+		return nil, true, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		items, isList := literal.(listValue)
+		if !isList {
+			item, _, err := valueFromLiteral(t.Of, literal, vars)
+			if err != nil {
+				return nil, true, err
+			}
+			return []interface{}{item}, true, nil
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			value, _, err := valueFromLiteral(t.Of, item, vars)
+			if err != nil {
+				return nil, true, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list[i] = value
+		}
+		return list, true, nil
+	case *Scalar:
+		switch literal.(type) {
+		case int64, float64, string, bool:
+			value, err := t.Parse(literal)
+			return value, true, err
+		}
+		return nil, true, fmt.Errorf("%s cannot represent %s", t.Name, describeLiteral(literal))
+	case *Enum:
+		name, isEnum := literal.(enumValue)
+		if !isEnum || !t.has(string(name)) {
+			return nil, true, fmt.Errorf("%s is not a value of %s", describeLiteral(literal), t.Name)
+		}
+		return string(name), true, nil
+	case *InputObject:
+		object, isObject := literal.(objectValue)
+		if !isObject {
+			return nil, true, fmt.Errorf("expected an object of type %s, found %s", t.Name, describeLiteral(literal))
+		}
+		fields := make(map[string]interface{}, len(object))
+		for _, f := range object {
+			fields[f.name] = f.value
+		}
+		// Fields set to variables that weren't provided are omitted
+		for name, fieldLiteral := range fields {
+			if v, isVariable := fieldLiteral.(variable); isVariable {
+				if _, provided := vars[string(v)]; !provided {
+					delete(fields, name)
+				}
+			}
+		}
+		value, err := coerceInputObject(t, fields, func(f *Argument, fieldLiteral interface{}) (interface{}, error) {
+			value, _, err := valueFromLiteral(f.Type, fieldLiteral, vars)
+			return value, err
+		})
+		return value, true, err
+	}
+	return nil, true, fmt.Errorf("%s is not an input type", t)
+}
+
+// argumentValues coerces the arguments of a field or a directive
+func argumentValues(definitions []*Argument, arguments []*argument, vars map[string]interface{}) (map[string]interface{}, error) {
+	literals := make(map[string]value, len(arguments))
+	for _, arg := range arguments {
+		found := false
+		for _, definition := range definitions {
+			found = found || definition.Name == arg.name
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown argument %q", arg.name)
+		}
+		literals[arg.name] = arg.value
+	}
+
+	values := make(map[string]interface{}, len(definitions))
+	for _, definition := range definitions {
+		literal, provided := literals[definition.Name]
+		if provided {
+			value, ok, err := valueFromLiteral(definition.Type, literal, vars)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q: %w", definition.Name, err)
+			}
+			if ok {
+				values[definition.Name] = value
+				continue
+			}
+		}
+
+		if definition.Default != nil {
+			values[definition.Name] = definition.Default
+		} else if _, required := definition.Type.(*NonNull); required {
+			return nil, fmt.Errorf("argument %q of type %s is required", definition.Name, definition.Type)
+		}
+	}
+	return values, nil
+}
+
+// resolveTypeRef finds the input type of a variable definition
+func (s *Schema) resolveTypeRef(ref *typeRef) (Type, error) {
+	var t Type
+	if ref.elem != nil {
+		elem, err := s.resolveTypeRef(ref.elem)
+		if err != nil {
+			return nil, err
+		}
+		t = NewList(elem)
+	} else {
+		named, ok := s.types[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q", ref.name)
+		}
+		switch named.(type) {
+		case *Scalar, *Enum, *InputObject:
+		default:
+			return nil, fmt.Errorf("type %s is not an input type", ref.name)
+		}
+		t = named
+	}
+
+	if ref.nonNull {
+		t = NewNonNull(t)
+	}
+	return t, nil
+}
+
+func (t *Enum) has(name string) bool {
+	for _, v := range t.Values {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *InputObject) field(name string) *Argument {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func describeLiteral(literal value) string {
+	switch literal := literal.(type) {
+	case enumValue:
+		return string(literal)
+	case variable:
+		return "$" + string(literal)
+	case listValue:
+		items := make([]string, len(literal))
+		for i, item := range literal {
+			items[i] = describeLiteral(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case objectValue:
+		fields := make([]string, len(literal))
+		for i, f := range literal {
+			fields[i] = f.name + ": " + describeLiteral(f.value)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return describe(literal)
+}
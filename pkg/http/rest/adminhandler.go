@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditLogResponse struct {
+	ID        uint      `json:"id"`
+	Action    string    `json:"action"`
+	UserID    uint      `json:"user_id"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AdminHandler struct {
+	loginGuard domain.LoginGuard
+}
+
+func NewAdminHandler(loginGuard domain.LoginGuard) *AdminHandler {
+	return &AdminHandler{
+		loginGuard: loginGuard,
+	}
+}
+
+// GetLockouts is a handler for listing the latest login lockouts, at most ?limit= (default 100)
+func (h *AdminHandler) GetLockouts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	events, err := h.loginGuard.GetLockoutEvents(limit)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respondList(c, formatAuditLogs(events), domain.PageRequest{}, int64(len(*events)))
+}
@@ -0,0 +1,718 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+)
+
+type authKind int
+
+const (
+	authNone authKind = iota
+	// authBearer accepts a token, or an API key with the Scope of the operation
+	authBearer
+	// authSession only accepts a token
+	authSession
+)
+
+// operationDoc documents a route of NewRouter, the request and response types are read by reflection
+type operationDoc struct {
+	Summary     string
+	Description string
+	Tag         string
+	Auth        authKind
+	Scope       string
+	// Request is a value of the JSON request body type
+	Request interface{}
+	// Form lists the files of a multipart request
+	Form  []string
+	Query []queryDoc
+	// Paginated operations accept ?page= and ?per_page=
+	Paginated bool
+	// List responses carry pagination metadata in the version 2 envelope
+	List bool
+	// Streaming responses are written as they go, they aren't held back for validation
+	Streaming bool
+	Responses []responseDoc
+}
+
+type queryDoc struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+type responseDoc struct {
+	Status      int
+	Description string
+	// Body is a value of the response type
+	Body interface{}
+	// Legacy is the version 1 response when it has another shape than Body
+	Legacy interface{}
+	// ContentType is set for responses that aren't versioned JSON, Body is then the schema of the
+	// content if any
+	ContentType string
+}
+
+func ok(body interface{}) []responseDoc {
+	return []responseDoc{{Status: http.StatusOK, Body: body}}
+}
+
+func created(body interface{}) []responseDoc {
+	return []responseDoc{{Status: http.StatusCreated, Body: body}}
+}
+
+// operationDocs are keyed by the method and the gin path of the route
+var operationDocs = map[string]operationDoc{
+	// Users
+	"POST /users/register": {
+		Summary:     "Register a user",
+		Description: "The username can't contain @, it would be taken for an email when logging in.",
+		Tag:         "users",
+		Request:     RegisterRequest{},
+		Responses:   created(UserResponse{}),
+	},
+	"POST /users/login": {
+		Summary:     "Log in",
+		Description: "The username accepts the email too. With two-factor authentication enabled a challenge token is returned, it is sent with a code to /users/login/2fa.",
+		Tag:         "users",
+		Request:     LoginRequest{},
+		Responses:   ok(LoginResponse{}),
+	},
+	"POST /users/login/2fa": {
+		Summary:   "Complete a login with a two-factor code",
+		Tag:       "users",
+		Request:   TwoFactorLoginRequest{},
+		Responses: ok(LoginResponse{}),
+	},
+	"POST /users/deletion/cancel": {
+		Summary:   "Cancel the deletion of an account",
+		Tag:       "users",
+		Request:   LoginRequest{},
+		Responses: ok(MessageResponse{}),
+	},
+	"POST /users/password/forgot": {
+		Summary:   "Send a password reset link",
+		Tag:       "users",
+		Request:   ForgotPasswordRequest{},
+		Responses: ok(MessageResponse{}),
+	},
+	"POST /users/password/reset": {
+		Summary:     "Choose a new password with a reset token",
+		Description: "Every session is logged out and the API keys revoked.",
+		Tag:         "users",
+		Request:     ResetPasswordRequest{},
+		Responses:   ok(MessageResponse{}),
+	},
+	"GET /users/verify": {
+		Summary:   "Verify an email with the emailed token",
+		Tag:       "users",
+		Query:     []queryDoc{{Name: "token", Type: "string", Required: true}},
+		Responses: ok(VerifyEmailResponse{}),
+	},
+	"GET /users/unsubscribe": {
+		Summary:     "Open the unsubscribe link of a digest",
+		Description: "Unsubscribe links don't expire and need no login. The page asks to confirm, opening the link changes nothing.",
+		Tag:         "users",
+		Query:       []queryDoc{{Name: "token", Type: "string", Required: true}},
+		Responses:   []responseDoc{{Status: http.StatusOK, ContentType: "text/html", Description: "A page posting to POST /users/unsubscribe"}},
+	},
+	"POST /users/unsubscribe": {
+		Summary:     "Turn the digest off",
+		Description: "Posted by the page of the unsubscribe link, and by mail clients for one-click unsubscribe (RFC 8058) with the List-Unsubscribe header of the digests.",
+		Tag:         "users",
+		Query:       []queryDoc{{Name: "token", Type: "string", Required: true}},
+		Responses:   ok(UnsubscribeResponse{}),
+	},
+	"GET /users/": {
+		Summary:   "Get the current user",
+		Tag:       "users",
+		Auth:      authBearer,
+		Scope:     domain.ScopeProfileRead,
+		Responses: ok(UserResponse{}),
+	},
+	"PUT /users/": {
+		Summary:     "Update the current user",
+		Description: "A new email replaces the current one once it is verified. A new username can't contain @.",
+		Tag:         "users",
+		Auth:        authSession,
+		Request:     UpdateUserRequest{},
+		Responses:   ok(UserResponse{}),
+	},
+	"DELETE /users/": {
+		Summary:     "Schedule the deletion of the current user",
+		Description: "The account is purged once the grace period ends.",
+		Tag:         "users",
+		Auth:        authSession,
+		Responses:   ok(DeletionResponse{}),
+	},
+	"PUT /users/password": {
+		Summary:     "Change the password",
+		Description: "The other sessions are logged out and the API keys revoked, the returned token replaces the current one.",
+		Tag:         "users",
+		Auth:        authSession,
+		Request:     ChangePasswordRequest{},
+		Responses:   ok(ChangePasswordResponse{}),
+	},
+	"POST /users/verify/resend": {
+		Summary:   "Send a new email verification link",
+		Tag:       "users",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+
+	// Data export and import
+	"POST /users/export": {
+		Summary:     "Start a personal data export",
+		Description: "The archive has the profile, photos with their originals, comments and social medias. MyGram has no likes or follows, so there are none to export. While an export of the user is pending or ready, it is returned instead of starting another.",
+		Tag:         "export",
+		Auth:        authSession,
+		Responses:   []responseDoc{{Status: http.StatusAccepted, Body: ExportResponse{}}},
+	},
+	"GET /users/export/:id": {
+		Summary: "Download a personal data export",
+		Tag:     "export",
+		Auth:    authSession,
+		Responses: []responseDoc{
+			{Status: http.StatusOK, Description: "The export archive", ContentType: "application/zip"},
+			{Status: http.StatusAccepted, Description: "The export is not ready yet", Body: ExportResponse{}},
+		},
+	},
+	"POST /users/import": {
+		Summary:   "Import an Instagram export",
+		Tag:       "import",
+		Auth:      authSession,
+		Form:      []string{"archive"},
+		Responses: []responseDoc{{Status: http.StatusAccepted, Body: ImportJobResponse{}}},
+	},
+	"GET /users/import/:id": {
+		Summary:   "Get the progress of an import",
+		Tag:       "import",
+		Auth:      authSession,
+		Responses: ok(ImportJobResponse{}),
+	},
+
+	// Two-factor authentication
+	"POST /users/2fa/enroll": {
+		Summary:   "Start the two-factor enrollment",
+		Tag:       "two-factor",
+		Auth:      authSession,
+		Responses: ok(TwoFactorEnrollmentResponse{}),
+	},
+	"POST /users/2fa/confirm": {
+		Summary:   "Enable two-factor authentication with a first code",
+		Tag:       "two-factor",
+		Auth:      authSession,
+		Request:   TwoFactorCodeRequest{},
+		Responses: ok(RecoveryCodesResponse{}),
+	},
+	"POST /users/2fa/disable": {
+		Summary:   "Disable two-factor authentication",
+		Tag:       "two-factor",
+		Auth:      authSession,
+		Request:   DisableTwoFactorRequest{},
+		Responses: ok(MessageResponse{}),
+	},
+	"POST /users/2fa/recovery-codes": {
+		Summary:   "Replace the recovery codes",
+		Tag:       "two-factor",
+		Auth:      authSession,
+		Request:   TwoFactorCodeRequest{},
+		Responses: ok(RecoveryCodesResponse{}),
+	},
+
+	// API keys
+	"POST /users/tokens": {
+		Summary:     "Create a personal API key",
+		Description: "The key is only returned here.",
+		Tag:         "api-keys",
+		Auth:        authSession,
+		Request:     CreateAPIKeyRequest{},
+		Responses:   created(CreateAPIKeyResponse{}),
+	},
+	"GET /users/tokens": {
+		Summary:   "List the API keys",
+		Tag:       "api-keys",
+		Auth:      authSession,
+		List:      true,
+		Responses: ok([]APIKeyResponse{}),
+	},
+	"DELETE /users/tokens/:id": {
+		Summary:   "Revoke an API key",
+		Tag:       "api-keys",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+
+	// Identity providers
+	"POST /users/identities/:provider": {
+		Summary:     "Start linking an identity provider",
+		Description: "The client sends the user to the returned authorization URL in the same browser, the response sets a cookie the callback checks.",
+		Tag:         "identities",
+		Auth:        authSession,
+		Responses:   ok(AuthorizationURLResponse{}),
+	},
+	"GET /users/identities": {
+		Summary:   "List the linked identities",
+		Tag:       "identities",
+		Auth:      authSession,
+		List:      true,
+		Responses: ok([]LinkedIdentityResponse{}),
+	},
+	"DELETE /users/identities/:id": {
+		Summary:   "Unlink an identity",
+		Tag:       "identities",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+	"GET /auth/oidc/providers": {
+		Summary:   "List the identity providers",
+		Tag:       "identities",
+		Responses: ok(ProvidersResponse{}),
+	},
+	"GET /auth/oidc/:provider/login": {
+		Summary:   "Sign in with an identity provider",
+		Tag:       "identities",
+		Responses: []responseDoc{{Status: http.StatusFound, Description: "Redirect to the identity provider"}},
+	},
+	"GET /auth/oidc/:provider/callback": {
+		Summary:     "Complete a sign in or a link",
+		Description: "The identity provider redirects here. The browser has to be the one that started the flow, it sends the cookie set then. A sign in responds like /users/login, a link with the linked identity.",
+		Tag:         "identities",
+		Query: []queryDoc{
+			{Name: "code", Type: "string"},
+			{Name: "state", Type: "string"},
+			{Name: "error", Type: "string", Description: "Set by the provider when the sign in failed"},
+		},
+		Responses: ok(oidcCallbackResponse{}),
+	},
+
+	// Sessions
+	"GET /users/sessions": {
+		Summary:   "List the devices logged in",
+		Tag:       "sessions",
+		Auth:      authSession,
+		List:      true,
+		Responses: ok([]SessionResponse{}),
+	},
+	"DELETE /users/sessions/:id": {
+		Summary:   "Log out a device",
+		Tag:       "sessions",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+
+	// Webhooks
+	"POST /users/webhooks": {
+		Summary:     "Subscribe a URL to events",
+		Description: "Deliveries are signed with the secret, which is only returned here. all_users is for administrators. The URL must resolve to a public address, redirects aren't followed.",
+		Tag:         "webhooks",
+		Auth:        authSession,
+		Request:     CreateWebhookRequest{},
+		Responses:   created(CreateWebhookResponse{}),
+	},
+	"GET /users/webhooks": {
+		Summary:   "List the webhooks",
+		Tag:       "webhooks",
+		Auth:      authSession,
+		List:      true,
+		Responses: ok([]WebhookResponse{}),
+	},
+	"DELETE /users/webhooks/:id": {
+		Summary:   "Delete a webhook",
+		Tag:       "webhooks",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+	"GET /users/webhooks/:id/dead-letters": {
+		Summary:     "List the dead letters of a webhook",
+		Description: "Deliveries which failed every attempt, oldest first.",
+		Tag:         "webhooks",
+		Auth:        authSession,
+		Paginated:   true,
+		List:        true,
+		Responses:   ok([]WebhookDeliveryResponse{}),
+	},
+	"POST /users/webhooks/:id/deliveries/:delivery_id/redeliver": {
+		Summary:     "Send a delivery again",
+		Description: "Dead and delivered deliveries are queued with a fresh set of attempts.",
+		Tag:         "webhooks",
+		Auth:        authSession,
+		Responses:   []responseDoc{{Status: http.StatusAccepted, Body: WebhookDeliveryResponse{}}},
+	},
+
+	// Email
+	"GET /users/email-preferences": {
+		Summary:   "Get the email preferences",
+		Tag:       "email",
+		Auth:      authSession,
+		Responses: ok(EmailPreferencesResponse{}),
+	},
+	"PUT /users/email-preferences": {
+		Summary:     "Change the email preferences",
+		Description: "The digest of the comments on your photos is sent daily, weekly or never.",
+		Tag:         "email",
+		Auth:        authSession,
+		Request:     UpdateEmailPreferencesRequest{},
+		Responses:   ok(EmailPreferencesResponse{}),
+	},
+	"GET /users/blocks": {
+		Summary:     "List the users you blocked",
+		Description: "Newest first. Blocked users can't see your photos, comment on them or message you, and their content is hidden from you.",
+		Tag:         "blocks",
+		Auth:        authSession,
+		Paginated:   true,
+		List:        true,
+		Responses:   ok([]RelationResponse{}),
+	},
+	"POST /users/blocks": {
+		Summary:     "Block a user",
+		Description: "Blocking a user again keeps the block as is.",
+		Tag:         "blocks",
+		Auth:        authSession,
+		Request:     AddRelationRequest{},
+		Responses:   created(RelationResponse{}),
+	},
+	"DELETE /users/blocks/:user_id": {
+		Summary:   "Unblock a user",
+		Tag:       "blocks",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+	"GET /users/mutes": {
+		Summary:     "List the users you muted",
+		Description: "Newest first. The content of muted users is hidden from you, they aren't told.",
+		Tag:         "blocks",
+		Auth:        authSession,
+		Paginated:   true,
+		List:        true,
+		Responses:   ok([]RelationResponse{}),
+	},
+	"POST /users/mutes": {
+		Summary:   "Mute a user",
+		Tag:       "blocks",
+		Auth:      authSession,
+		Request:   AddRelationRequest{},
+		Responses: created(RelationResponse{}),
+	},
+	"DELETE /users/mutes/:user_id": {
+		Summary:   "Unmute a user",
+		Tag:       "blocks",
+		Auth:      authSession,
+		Responses: ok(MessageResponse{}),
+	},
+	"GET /users/message-settings": {
+		Summary:   "Get who can message you",
+		Tag:       "messages",
+		Auth:      authSession,
+		Responses: ok(MessageSettingsResponse{}),
+	},
+	"PUT /users/message-settings": {
+		Summary:     "Choose who can message you",
+		Description: "everyone, or contacts to refuse the conversations of users you have never messaged.",
+		Tag:         "messages",
+		Auth:        authSession,
+		Request:     UpdateMessageSettingsRequest{},
+		Responses:   ok(MessageSettingsResponse{}),
+	},
+
+	// Photos
+	"POST /photos/": {
+		Summary:   "Post a photo",
+		Tag:       "photos",
+		Auth:      authBearer,
+		Scope:     domain.ScopePhotosWrite,
+		Request:   AddPhotoRequest{},
+		Responses: created(PhotoResponse{}),
+	},
+	"GET /photos/": {
+		Summary:   "List the photos of the current user",
+		Tag:       "photos",
+		Auth:      authBearer,
+		Scope:     domain.ScopePhotosRead,
+		Paginated: true,
+		List:      true,
+		Responses: ok([]PhotoOfUserResponse{}),
+	},
+	"PUT /photos/:id": {
+		Summary:   "Update a photo",
+		Tag:       "photos",
+		Auth:      authBearer,
+		Scope:     domain.ScopePhotosWrite,
+		Request:   AddPhotoRequest{},
+		Responses: ok(PhotoResponse{}),
+	},
+	"DELETE /photos/:id": {
+		Summary:   "Delete a photo",
+		Tag:       "photos",
+		Auth:      authBearer,
+		Scope:     domain.ScopePhotosWrite,
+		Responses: ok(MessageResponse{}),
+	},
+
+	// Comments
+	"POST /comments/": {
+		Summary:   "Comment a photo",
+		Tag:       "comments",
+		Auth:      authBearer,
+		Scope:     domain.ScopeCommentsWrite,
+		Request:   AddCommentRequest{},
+		Responses: created(CommentResponse{}),
+	},
+	"GET /comments/": {
+		Summary:   "List the comments of the current user",
+		Tag:       "comments",
+		Auth:      authBearer,
+		Scope:     domain.ScopeCommentsRead,
+		Paginated: true,
+		List:      true,
+		Responses: ok([]CommentOfUserResponse{}),
+	},
+	"PUT /comments/:id": {
+		Summary:   "Update a comment",
+		Tag:       "comments",
+		Auth:      authBearer,
+		Scope:     domain.ScopeCommentsWrite,
+		Request:   UpdateCommentRequest{},
+		Responses: ok(CommentResponse{}),
+	},
+	"DELETE /comments/:id": {
+		Summary:   "Delete a comment",
+		Tag:       "comments",
+		Auth:      authBearer,
+		Scope:     domain.ScopeCommentsWrite,
+		Responses: ok(MessageResponse{}),
+	},
+
+	// Social medias
+	"POST /socialmedias/": {
+		Summary: "Add a social media",
+		Tag:     "socialmedias",
+		Auth:    authBearer,
+		Scope:   domain.ScopeSocialMediasWrite,
+		Request: AddSocialMediaRequest{},
+		Responses: []responseDoc{
+			{Status: http.StatusCreated, Body: SocialMediaResponse{}, Legacy: legacyAddSocialMediaResponse{}},
+		},
+	},
+	"GET /socialmedias/": {
+		Summary:   "List the social medias of the current user",
+		Tag:       "socialmedias",
+		Auth:      authBearer,
+		Scope:     domain.ScopeSocialMediasRead,
+		Paginated: true,
+		List:      true,
+		Responses: []responseDoc{
+			{Status: http.StatusOK, Body: []SocialMediaOfUserResponse{}, Legacy: legacySocialMediasResponse{}},
+		},
+	},
+	"PUT /socialmedias/:id": {
+		Summary:   "Update a social media",
+		Tag:       "socialmedias",
+		Auth:      authBearer,
+		Scope:     domain.ScopeSocialMediasWrite,
+		Request:   AddSocialMediaRequest{},
+		Responses: ok(SocialMediaResponse{}),
+	},
+	"DELETE /socialmedias/:id": {
+		Summary:   "Delete a social media",
+		Tag:       "socialmedias",
+		Auth:      authBearer,
+		Scope:     domain.ScopeSocialMediasWrite,
+		Responses: ok(MessageResponse{}),
+	},
+
+	// GraphQL
+	"POST /graphql": {
+		Summary:     "Run a GraphQL query or mutation",
+		Description: "The schema covers users, photos, comments and social medias, it can be introspected. API keys need the scopes of the fields they select. Errors are returned in the body with a 200 status, their extensions carry the code of the matching problem details.",
+		Tag:         "graphql",
+		Auth:        authBearer,
+		Request:     GraphQLRequest{},
+		Responses:   graphQLResponses,
+	},
+	"GET /graphql": {
+		Summary: "Run a GraphQL query sent in the URL",
+		Tag:     "graphql",
+		Auth:    authBearer,
+		Query: []queryDoc{
+			{Name: "query", Type: "string", Required: true},
+			{Name: "operationName", Type: "string"},
+			{Name: "variables", Type: "string", Description: "JSON object of the variables"},
+		},
+		Responses: graphQLResponses,
+	},
+
+	// Notifications
+	"GET /notifications": {
+		Summary:     "List the notifications of the current user",
+		Description: "Newest first. The comments of several users on a photo are gathered in one notification until it is read. X-Unread-Count carries the number of unread notifications.",
+		Tag:         "notifications",
+		Auth:        authBearer,
+		Scope:       domain.ScopeNotificationsRead,
+		Query: []queryDoc{
+			{Name: "unread", Type: "boolean", Description: "Only list the unread notifications"},
+		},
+		Paginated: true,
+		List:      true,
+		Responses: ok([]NotificationResponse{}),
+	},
+	"GET /notifications/unread-count": {
+		Summary:   "Count the unread notifications",
+		Tag:       "notifications",
+		Auth:      authBearer,
+		Scope:     domain.ScopeNotificationsRead,
+		Responses: ok(UnreadCountResponse{}),
+	},
+	"POST /notifications/read-all": {
+		Summary:   "Mark every notification read",
+		Tag:       "notifications",
+		Auth:      authBearer,
+		Scope:     domain.ScopeNotificationsWrite,
+		Responses: ok(MarkAllReadResponse{}),
+	},
+	"POST /notifications/:id/read": {
+		Summary:   "Mark a notification read",
+		Tag:       "notifications",
+		Auth:      authBearer,
+		Scope:     domain.ScopeNotificationsWrite,
+		Responses: ok(NotificationResponse{}),
+	},
+	"GET /notifications/preferences": {
+		Summary:     "Get the notification preferences",
+		Description: "One preference per notification type, types are enabled until turned off.",
+		Tag:         "notifications",
+		Auth:        authBearer,
+		Scope:       domain.ScopeNotificationsRead,
+		Responses:   ok([]NotificationPreferenceResponse{}),
+	},
+	"PUT /notifications/preferences": {
+		Summary:     "Turn notification types on or off",
+		Description: "Types left out of the request keep their setting.",
+		Tag:         "notifications",
+		Auth:        authBearer,
+		Scope:       domain.ScopeNotificationsWrite,
+		Request:     UpdateNotificationPreferencesRequest{},
+		Responses:   ok([]NotificationPreferenceResponse{}),
+	},
+
+	// Direct messages
+	"POST /conversations": {
+		Summary:     "Start a conversation",
+		Description: "One participant starts a 1:1 conversation, which is returned as is when it exists already. Users who only accept messages from their contacts can't be added by users they have never messaged.",
+		Tag:         "messages",
+		Auth:        authBearer,
+		Scope:       domain.ScopeMessagesWrite,
+		Request:     StartConversationRequest{},
+		Responses:   created(ConversationResponse{}),
+	},
+	"GET /conversations": {
+		Summary:     "List the conversations of the current user",
+		Description: "The most recent activity first, with the last message and the number of unread messages of each.",
+		Tag:         "messages",
+		Auth:        authBearer,
+		Scope:       domain.ScopeMessagesRead,
+		Paginated:   true,
+		List:        true,
+		Responses:   ok([]ConversationResponse{}),
+	},
+	"GET /conversations/:id": {
+		Summary:     "Get a conversation",
+		Description: "The participants carry their read receipts.",
+		Tag:         "messages",
+		Auth:        authBearer,
+		Scope:       domain.ScopeMessagesRead,
+		Responses:   ok(ConversationResponse{}),
+	},
+	"GET /conversations/:id/messages": {
+		Summary:     "List the messages of a conversation",
+		Description: "Newest first, without the messages you deleted for yourself.",
+		Tag:         "messages",
+		Auth:        authBearer,
+		Scope:       domain.ScopeMessagesRead,
+		Paginated:   true,
+		List:        true,
+		Responses:   ok([]DirectMessageResponse{}),
+	},
+	"POST /conversations/:id/messages": {
+		Summary:     "Send a message",
+		Description: "A message has a body, a photo or both.",
+		Tag:         "messages",
+		Auth:        authBearer,
+		Scope:       domain.ScopeMessagesWrite,
+		Request:     SendDirectMessageRequest{},
+		Responses:   created(DirectMessageResponse{}),
+	},
+	"POST /conversations/:id/read": {
+		Summary:   "Mark the messages of a conversation read",
+		Tag:       "messages",
+		Auth:      authBearer,
+		Scope:     domain.ScopeMessagesWrite,
+		Responses: ok(ConversationResponse{}),
+	},
+	"DELETE /conversations/:id/messages/:message_id": {
+		Summary:     "Delete a message",
+		Description: "The message is hidden from you, or erased for everyone with for=everyone, which only its sender can do.",
+		Tag:         "messages",
+		Auth:        authBearer,
+		Scope:       domain.ScopeMessagesWrite,
+		Query: []queryDoc{
+			{Name: "for", Type: "string", Description: "me (default) or everyone"},
+		},
+		Responses: ok(MessageResponse{}),
+	},
+
+	// Streams
+	"POST /stream/tickets": {
+		Summary:     "Request a stream ticket",
+		Description: "Browsers can't set the Authorization header of EventSource and WebSocket requests, they pass the ticket as ?ticket= instead. A ticket opens streams for a minute with the scopes of the API key it was requested with, and stops working once its token is revoked.",
+		Tag:         "stream",
+		Auth:        authBearer,
+		Responses:   created(StreamTicketResponse{}),
+	},
+	"GET /stream": {
+		Summary:     "Follow topics over Server-Sent Events",
+		Description: "Each event carries a StreamEvent as data. The stream starts with a subscribed event and ends with an error event when the client falls too far behind.",
+		Tag:         "stream",
+		Auth:        authBearer,
+		Query: []queryDoc{
+			{Name: "topic", Type: "string", Required: true, Description: "notifications or photos/<id>/comments, repeated for several topics"},
+			{Name: "ticket", Type: "string", Description: "A stream ticket, instead of the Authorization header"},
+		},
+		Streaming: true,
+		Responses: []responseDoc{{Status: http.StatusOK, ContentType: "text/event-stream", Body: StreamEvent{}}},
+	},
+	"GET /stream/ws": {
+		Summary:     "Follow topics over a WebSocket",
+		Description: "The server sends StreamEvent frames, the client sends StreamCommand frames to subscribe to more topics or unsubscribe.",
+		Tag:         "stream",
+		Auth:        authBearer,
+		Query: []queryDoc{
+			{Name: "topic", Type: "string", Description: "notifications or photos/<id>/comments, repeated for several topics"},
+			{Name: "ticket", Type: "string", Description: "A stream ticket, instead of the Authorization header"},
+		},
+		Streaming: true,
+		Responses: []responseDoc{{Status: http.StatusSwitchingProtocols, Description: "Switching to the WebSocket protocol"}},
+	},
+
+	// Administration
+	"GET /admin/lockouts": {
+		Summary:   "List the latest login lockouts",
+		Tag:       "admin",
+		Auth:      authSession,
+		Query:     []queryDoc{{Name: "limit", Type: "integer", Description: "At most 1000, 100 by default"}},
+		List:      true,
+		Responses: ok([]AuditLogResponse{}),
+	},
+}
+
+// GraphQL responses aren't versioned
+var graphQLResponses = []responseDoc{{Status: http.StatusOK, Body: GraphQLResponse{}, ContentType: "application/json"}}
+
+// oidcCallbackResponse documents the two responses of the OpenID Connect callback
+type oidcCallbackResponse struct {
+	LoginResponse
+	Message  string                  `json:"message,omitempty"`
+	Identity *LinkedIdentityResponse `json:"identity,omitempty"`
+}
@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// ExpiresInDays is the lifetime of the key, 0 for a key that doesn't expire
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	Message string `json:"message"`
+	// Key is only returned when the key is created
+	Key    string         `json:"key"`
+	APIKey APIKeyResponse `json:"api_key"`
+}
+
+type APIKeyHandler struct {
+	apiKeyService domain.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService domain.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey is a handler for creating a personal API key, the key itself is only returned here
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	// Bind request body to CreateAPIKeyRequest struct
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	key, plaintext, err := h.apiKeyService.CreateAPIKey(currentUserID, &domain.CreateAPIKeyRequest{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresIn: time.Duration(req.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, CreateAPIKeyResponse{
+		Message: "Store the key safely, it won't be shown again",
+		Key:     plaintext,
+		APIKey:  formatAPIKey(key),
+	})
+}
+
+// GetAPIKeys is a handler for listing the API keys of the current user
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	keys, err := h.apiKeyService.GetAPIKeys(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]APIKeyResponse, len(*keys))
+	for i, key := range *keys {
+		responses[i] = formatAPIKey(&key)
+	}
+
+	// The number of keys is capped, they are listed on one page
+	respondList(c, responses, domain.PageRequest{}, int64(len(responses)))
+}
+
+// RevokeAPIKey is a handler for deleting an API key of the current user
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	// Get id from path
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid API key id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.apiKeyService.RevokeAPIKey(currentUserID, uint(keyID)); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "API key has been revoked",
+	})
+}
+
+func formatAPIKey(key *domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddRelationRequest struct {
+	UserID uint `json:"user_id" binding:"required,gt=0"`
+}
+
+type RelationResponse struct {
+	UserID uint `json:"user_id"`
+	// Username is empty when the account is pending deletion
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BlockHandler struct {
+	blockService domain.BlockService
+	userService  domain.UserService
+}
+
+func NewBlockHandler(blockService domain.BlockService, userService domain.UserService) *BlockHandler {
+	return &BlockHandler{
+		blockService: blockService,
+		userService:  userService,
+	}
+}
+
+// GetRelations returns a handler listing the users the current user blocked or muted, newest first
+func (h *BlockHandler) GetRelations(relationType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get page from query
+		page, err := parsePageRequest(c)
+		if err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+
+		// Get currentUserID from context
+		currentUserID := c.MustGet("currentUserID").(uint)
+
+		relations, total, err := h.blockService.GetRelations(currentUserID, relationType, page)
+		if err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+
+		// Get the users of every relation at once
+		userIDs := make([]uint, len(*relations))
+		for i, relation := range *relations {
+			userIDs[i] = relation.TargetID
+		}
+		users, err := h.userService.GetUsersByIDs(userIDs)
+		if err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+		usernames := make(map[uint]string, len(*users))
+		for _, user := range *users {
+			usernames[user.ID] = user.Username
+		}
+
+		responses := make([]RelationResponse, len(*relations))
+		for i, relation := range *relations {
+			responses[i] = RelationResponse{
+				UserID:    relation.TargetID,
+				Username:  usernames[relation.TargetID],
+				CreatedAt: relation.CreatedAt,
+			}
+		}
+
+		respondList(c, responses, page, total)
+	}
+}
+
+// AddRelation returns a handler blocking or muting a user
+func (h *BlockHandler) AddRelation(relationType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Bind request body to AddRelationRequest struct
+		var req AddRelationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+
+		// Get currentUserID from context
+		currentUserID := c.MustGet("currentUserID").(uint)
+
+		relation, err := h.blockService.AddRelation(currentUserID, req.UserID, relationType)
+		if err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+
+		user, err := h.userService.GetUserByID(relation.TargetID)
+		if err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+
+		respond(c, http.StatusCreated, RelationResponse{
+			UserID:    relation.TargetID,
+			Username:  user.Username,
+			CreatedAt: relation.CreatedAt,
+		})
+	}
+}
+
+// RemoveRelation returns a handler unblocking or unmuting a user
+func (h *BlockHandler) RemoveRelation(relationType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get user_id from path
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid user id"))
+			return
+		}
+
+		// Get currentUserID from context
+		currentUserID := c.MustGet("currentUserID").(uint)
+
+		if err := h.blockService.RemoveRelation(currentUserID, uint(userID), relationType); err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+
+		message := "User has been unblocked"
+		if relationType == domain.RelationMute {
+			message = "User has been unmuted"
+		}
+		respond(c, http.StatusOK, MessageResponse{
+			Message: message,
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"errors"
 	"final-project/pkg/domain"
 	"net/http"
 	"strconv"
@@ -11,12 +10,21 @@ import (
 )
 
 type AddCommentRequest struct {
-	Message string `json:"message" binding:"required max=2048"`
+	Message string `json:"message" binding:"required,max=2048"`
 	PhotoID uint   `json:"photo_id" binding:"required,gt=0"`
 }
 
 type UpdateCommentRequest struct {
-	Message string `json:"message" binding:"required max=2048"`
+	Message string `json:"message" binding:"required,max=2048"`
+}
+
+type CommentResponse struct {
+	ID        uint      `json:"id"`
+	Message   string    `json:"message"`
+	PhotoID   uint      `json:"photo_id"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type CommentOfUserResponse struct {
@@ -48,13 +56,15 @@ type CommentHandler struct {
 	commentService domain.CommentService
 	userService    domain.UserService
 	photoService   domain.PhotoService
+	blockService   domain.BlockService
 }
 
-func NewCommentHandler(commentService domain.CommentService, userService domain.UserService, photoService domain.PhotoService) *CommentHandler {
+func NewCommentHandler(commentService domain.CommentService, userService domain.UserService, photoService domain.PhotoService, blockService domain.BlockService) *CommentHandler {
 	return &CommentHandler{
 		commentService: commentService,
 		userService:    userService,
 		photoService:   photoService,
+		blockService:   blockService,
 	}
 }
 
@@ -62,36 +72,35 @@ func (h *CommentHandler) AddComment(c *gin.Context) {
 	// Bind request body to AddCommentRequest struct
 	var req AddCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Get currentUserID from context
 	currentUserID := c.MustGet("currentUserID").(uint)
 
+	// Check if photo exist
+	if _, err := h.photoService.GetPhotoByID(req.PhotoID); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
 	// Save comment
 	comment, err := h.commentService.AddComment(currentUserID, req.PhotoID, req.Message)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send response
-	c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":         comment.ID,
-		"message":    comment.Message,
-		"photo_id":   comment.PhotoID,
-		"user_id":    comment.UserID,
-		"created_at": comment.CreatedAt,
-	})
+	respond(c, http.StatusCreated, formatComment(comment))
 }
 
 func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	// Bind request body to UpdateCommentRequest struct
-	// TODO: Add validation
 	var req UpdateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
@@ -101,60 +110,72 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	// Get commentID from path
 	commentID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		SendErrorResponse(c, errors.New("invalid comment id"), http.StatusBadRequest)
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid comment id"))
 		return
 	}
 
 	// Check if comment userID is equal to currentUserID
 	comment, err := h.commentService.GetCommentByID(uint(commentID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 	if comment.UserID != currentUserID {
-		SendErrorResponse(c, err, http.StatusUnauthorized)
+		SendErrorResponse(c, domain.ErrNotOwner)
 		return
 	}
 
 	// Update comment
 	comment, err = h.commentService.UpdateComment(uint(commentID), req.Message)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send response
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"id":         comment.ID,
-		"message":    comment.Message,
-		"photo_id":   comment.PhotoID,
-		"user_id":    comment.UserID,
-		"updated_at": comment.UpdatedAt,
-	})
+	respond(c, http.StatusOK, formatComment(comment))
 }
 
 func (h *CommentHandler) GetCommentsByUserID(c *gin.Context) {
 	// Get currentUserID from context
 	currentUserID := c.MustGet("currentUserID").(uint)
 
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
 	// Get comments
-	comments, err := h.commentService.GetCommentsByUserID(currentUserID)
+	comments, total, err := h.commentService.GetCommentsByUserID(currentUserID, page)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Get user
 	user, err := h.userService.GetUserByID(currentUserID)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get users hidden from current user, their photos are left empty
+	hidden, err := h.blockService.HiddenUserIDs(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send response
-	commentResponses := formatCommentsOfUser(user, comments, h.photoService)
+	commentResponses, err := formatCommentsOfUser(user, comments, h.photoService, hidden)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, commentResponses)
+	respondList(c, commentResponses, page, total)
 }
 
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
@@ -164,30 +185,30 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	// Get commentID from path
 	commentID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid comment id"))
 		return
 	}
 
 	// Check if comment userID is equal to currentUserID
 	comment, err := h.commentService.GetCommentByID(uint(commentID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 	if comment.UserID != currentUserID {
-		SendErrorResponse(c, errors.New("insufficient privileges"), http.StatusUnauthorized)
+		SendErrorResponse(c, domain.ErrNotOwner)
 		return
 	}
 
 	// Delete comment
 	err = h.commentService.DeleteComment(uint(commentID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send response
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Your comment ahs been deleted successfully",
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Your comment ahs been deleted successfully",
 	})
 }
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"bytes"
+	_ "embed"
+	"final-project/pkg/domain"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unsubscribePage asks to confirm the unsubscribe link of a digest, opening a link mustn't change
+// anything since mail scanners open them too
+//
+//go:embed unsubscribe.html
+var unsubscribeHTML string
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(unsubscribeHTML))
+
+type UpdateEmailPreferencesRequest struct {
+	// Digest is off, daily or weekly
+	Digest string `json:"digest" binding:"required"`
+}
+
+type EmailPreferencesResponse struct {
+	Digest       string     `json:"digest"`
+	LastDigestAt *time.Time `json:"last_digest_at"`
+}
+
+type UnsubscribeResponse struct {
+	Message string `json:"message"`
+	Digest  string `json:"digest"`
+}
+
+type EmailHandler struct {
+	digestService domain.DigestService
+}
+
+func NewEmailHandler(digestService domain.DigestService) *EmailHandler {
+	return &EmailHandler{
+		digestService: digestService,
+	}
+}
+
+func (h *EmailHandler) GetEmailPreferences(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	preferences, err := h.digestService.GetEmailPreferences(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatEmailPreferences(preferences))
+}
+
+func (h *EmailHandler) UpdateEmailPreferences(c *gin.Context) {
+	// Bind request body to UpdateEmailPreferencesRequest struct
+	var req UpdateEmailPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	preferences, err := h.digestService.UpdateEmailPreferences(currentUserID, req.Digest)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatEmailPreferences(preferences))
+}
+
+// ConfirmUnsubscribe is a handler for the unsubscribe links of the digests, it shows a page posting
+// to Unsubscribe once the token is checked
+func (h *EmailHandler) ConfirmUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		SendErrorResponse(c, domain.NewFieldValidationError("token", "is required"))
+		return
+	}
+
+	preferences, err := h.digestService.CheckUnsubscribeToken(token)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	var page bytes.Buffer
+	if err := unsubscribePage.Execute(&page, map[string]string{"Token": token, "Digest": preferences.Digest}); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// Unsubscribe is a handler for the confirmation of the unsubscribe page and the one-click
+// unsubscribe of mail clients
+func (h *EmailHandler) Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		SendErrorResponse(c, domain.NewFieldValidationError("token", "is required"))
+		return
+	}
+
+	preferences, err := h.digestService.Unsubscribe(token)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, UnsubscribeResponse{
+		Message: "You won't receive digests anymore",
+		Digest:  preferences.Digest,
+	})
+}
+
+func formatEmailPreferences(preferences *domain.EmailPreferences) EmailPreferencesResponse {
+	return EmailPreferencesResponse{
+		Digest:       preferences.Digest,
+		LastDigestAt: preferences.LastDigestAt,
+	}
+}
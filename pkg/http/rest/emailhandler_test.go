@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The token "valid" belongs to a user with weekly digests
+type fakeDigestService struct {
+	domain.DigestService
+	unsubscribed int
+}
+
+func (s *fakeDigestService) CheckUnsubscribeToken(token string) (*domain.EmailPreferences, error) {
+	if token != "valid" {
+		return nil, domain.ErrInvalidUnsubscribeToken
+	}
+	return &domain.EmailPreferences{UserID: 1, Digest: domain.DigestWeekly}, nil
+}
+
+func (s *fakeDigestService) Unsubscribe(token string) (*domain.EmailPreferences, error) {
+	if _, err := s.CheckUnsubscribeToken(token); err != nil {
+		return nil, err
+	}
+	s.unsubscribed++
+	return &domain.EmailPreferences{UserID: 1, Digest: domain.DigestOff}, nil
+}
+
+func TestUnsubscribeLinkOnlyConfirms(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	digestService := &fakeDigestService{}
+	h := NewEmailHandler(digestService)
+	r := gin.New()
+	r.GET("/users/unsubscribe", h.ConfirmUnsubscribe)
+	r.POST("/users/unsubscribe", h.Unsubscribe)
+
+	serve := func(method string, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	w := serve(http.MethodGet, "/users/unsubscribe?token=valid")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), `<form method="post" action="?token=valid">`) {
+		t.Errorf("page has no confirmation form:\n%s", w.Body.String())
+	}
+	if digestService.unsubscribed != 0 {
+		t.Error("opening the link unsubscribed")
+	}
+
+	if w := serve(http.MethodGet, "/users/unsubscribe?token=forged"); w.Code != http.StatusBadRequest {
+		t.Errorf("forged token: got %d", w.Code)
+	}
+
+	if w := serve(http.MethodPost, "/users/unsubscribe?token=valid"); w.Code != http.StatusOK || digestService.unsubscribed != 1 {
+		t.Errorf("got %d, unsubscribed %d times", w.Code, digestService.unsubscribed)
+	}
+}
@@ -0,0 +1,201 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemDetails is the RFC 7807 body of every error response. Code is stable and meant for
+// clients to branch on, Detail is for humans.
+type ProblemDetails struct {
+	Type     string               `json:"type"`
+	Title    string               `json:"title"`
+	Status   int                  `json:"status"`
+	Detail   string               `json:"detail"`
+	Instance string               `json:"instance,omitempty"`
+	Code     string               `json:"code"`
+	Errors   []FieldErrorResponse `json:"errors,omitempty"`
+}
+
+type FieldErrorResponse struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+const problemContentType = "application/problem+json"
+
+// errorKindStatus maps the domain error kinds to HTTP status codes
+var errorKindStatus = map[domain.ErrorKind]int{
+	domain.ErrorKindValidation:      http.StatusBadRequest,
+	domain.ErrorKindUnauthorized:    http.StatusUnauthorized,
+	domain.ErrorKindForbidden:       http.StatusForbidden,
+	domain.ErrorKindNotFound:        http.StatusNotFound,
+	domain.ErrorKindConflict:        http.StatusConflict,
+	domain.ErrorKindGone:            http.StatusGone,
+	domain.ErrorKindTooManyRequests: http.StatusTooManyRequests,
+	domain.ErrorKindNotAcceptable:   http.StatusNotAcceptable,
+	domain.ErrorKindTooLarge:        http.StatusRequestEntityTooLarge,
+}
+
+var errInternal = &domain.Error{Code: "internal_error", Message: "internal server error"}
+
+// Function to send error response. Domain errors are sent with the status of their kind, request
+// binding errors as validation errors and any other error as an internal error whose details are
+// only logged.
+func SendErrorResponse(c *gin.Context, err error) {
+	domainErr, status := toDomainError(err)
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	sendProblem(c, status, domainErr)
+}
+
+func sendProblem(c *gin.Context, status int, domainErr *domain.Error) {
+	problem := ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   domainErr.Message,
+		Instance: c.Request.URL.Path,
+		Code:     domainErr.Code,
+	}
+	for _, field := range domainErr.Fields {
+		problem.Errors = append(problem.Errors, FieldErrorResponse{
+			Field:   field.Field,
+			Message: field.Message,
+		})
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, problemContentType, body)
+}
+
+func toDomainError(err error) (*domain.Error, int) {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		if status, ok := errorKindStatus[domainErr.Kind]; ok {
+			return domainErr, status
+		}
+		return errInternal, http.StatusInternalServerError
+	}
+
+	var lockedErr *domain.LockedError
+	if errors.As(err, &lockedErr) {
+		return domain.NewTooManyRequestsError("login_locked", lockedErr.Error()), http.StatusTooManyRequests
+	}
+
+	if bindingErr := toBindingError(err); bindingErr != nil {
+		return bindingErr, http.StatusBadRequest
+	}
+
+	return errInternal, http.StatusInternalServerError
+}
+
+// toBindingError converts the errors of ShouldBindJSON and friends, nil for other errors
+func toBindingError(err error) *domain.Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]domain.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, domain.FieldError{
+				Field:   fieldErr.Field(),
+				Message: validationMessage(fieldErr),
+			})
+		}
+		return domain.NewValidationError(domain.ErrCodeValidationFailed, "request has invalid fields", fields...)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		return domain.NewFieldValidationError(typeErr.Field, typeMessage(typeErr.Type.Kind()))
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return domain.NewValidationError("malformed_body", "request body is not valid JSON")
+	case errors.Is(err, http.ErrMissingFile), errors.Is(err, http.ErrNotMultipart):
+		return domain.NewValidationError("malformed_body", "request body is not a valid multipart form")
+	}
+
+	return nil
+}
+
+// typeMessage describes the JSON type expected for a Go kind
+func typeMessage(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "must be a string"
+	case reflect.Bool:
+		return "must be a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "must be a number"
+	case reflect.Slice, reflect.Array:
+		return "must be an array"
+	}
+	return "must be an object"
+}
+
+// validationMessage describes the failed rule of a binding tag
+func validationMessage(fieldErr validator.FieldError) string {
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "url":
+		return "must be a valid url"
+	case "min", "gte":
+		return "must be at least " + fieldErr.Param() + unit
+	case "max", "lte":
+		return "must be at most " + fieldErr.Param() + unit
+	case "gt":
+		return "must be greater than " + fieldErr.Param() + unit
+	case "lt":
+		return "must be less than " + fieldErr.Param() + unit
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	}
+	return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+}
+
+// registerFieldNames makes validation errors name fields like the request body does
+func registerFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
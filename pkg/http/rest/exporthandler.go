@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportResponse struct {
+	ID        uint      `json:"id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportHandler struct {
+	exportService domain.ExportService
+}
+
+func NewExportHandler(exportService domain.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// RequestExport is a handler for starting a personal data export
+func (h *ExportHandler) RequestExport(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	export, err := h.exportService.RequestExport(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, formatExport(export))
+}
+
+// DownloadExport is a handler for downloading a finished personal data export
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	// Get exportID from URL
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid export id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	export, err := h.exportService.GetExport(currentUserID, uint(exportID))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	switch export.Status {
+	case domain.ExportStatusPending:
+		respond(c, http.StatusAccepted, formatExport(export))
+	case domain.ExportStatusFailed:
+		SendErrorResponse(c, fmt.Errorf("export failed: %s", export.Error))
+	default:
+		c.FileAttachment(export.FilePath, fmt.Sprintf("mygram-export-%d.zip", export.ID))
+	}
+}
+
+func formatExport(export *domain.DataExport) ExportResponse {
+	return ExportResponse{
+		ID:        export.ID,
+		Status:    export.Status,
+		CreatedAt: export.CreatedAt,
+	}
+}
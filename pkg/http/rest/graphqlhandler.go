@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"final-project/pkg/domain"
+	"final-project/pkg/graphql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLRequest struct {
+	Query         string                  `json:"query" binding:"required"`
+	OperationName *string                 `json:"operationName"`
+	Variables     *map[string]interface{} `json:"variables"`
+}
+
+// GraphQLResponse documents the body of graphql.Response, data is null when a non-null root field failed
+type GraphQLResponse struct {
+	Data   *map[string]interface{} `json:"data,omitempty"`
+	Errors []GraphQLError          `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	Path      []interface{}     `json:"path,omitempty"`
+	// Extensions hold the code of the error, as in problem details, and the invalid fields
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type GraphQLHandler struct {
+	schema                *graphql.Schema
+	config                graphql.Config
+	verifiedEmailRequired []string
+	rateLimits            []domain.RateLimitRule
+	rateLimiter           domain.RateLimiter
+	userService           domain.UserService
+	photoService          domain.PhotoService
+	commentService        domain.CommentService
+	socialMediaService    domain.SocialMediaService
+	blockService          domain.BlockService
+}
+
+func NewGraphQLHandler(
+	userService domain.UserService,
+	photoService domain.PhotoService,
+	commentService domain.CommentService,
+	socialMediaService domain.SocialMediaService,
+	blockService domain.BlockService,
+	rateLimiter domain.RateLimiter,
+	config Config,
+) *GraphQLHandler {
+	h := &GraphQLHandler{
+		config:                config.GraphQL,
+		verifiedEmailRequired: config.VerifiedEmailRequired,
+		rateLimits:            config.RateLimits,
+		rateLimiter:           rateLimiter,
+		userService:           userService,
+		photoService:          photoService,
+		commentService:        commentService,
+		socialMediaService:    socialMediaService,
+		blockService:          blockService,
+	}
+
+	schema, err := newGraphQLSchema(h)
+	if err != nil {
+		// The schema is static, an error is a bug
+		panic(err)
+	}
+	h.schema = schema
+	return h
+}
+
+// Query is a handler for GraphQL requests sent as a JSON body
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	gqlReq := graphql.Request{Query: req.Query}
+	if req.OperationName != nil {
+		gqlReq.OperationName = *req.OperationName
+	}
+	if req.Variables != nil {
+		gqlReq.Variables = *req.Variables
+	}
+
+	h.execute(c, gqlReq, h.config)
+}
+
+// QueryURL is a handler for GraphQL queries sent in the URL, mutations aren't allowed
+func (h *GraphQLHandler) QueryURL(c *gin.Context) {
+	gqlReq := graphql.Request{
+		Query:         c.Query("query"),
+		OperationName: c.Query("operationName"),
+	}
+	if variables := c.Query("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &gqlReq.Variables); err != nil {
+			SendErrorResponse(c, domain.NewFieldValidationError("variables", "must be a JSON object"))
+			return
+		}
+	}
+
+	config := h.config
+	config.QueryOnly = true
+	h.execute(c, gqlReq, config)
+}
+
+// execute runs a request as the current user, errors of the resolvers are described like problem details
+func (h *GraphQLHandler) execute(c *gin.Context, req graphql.Request, config graphql.Config) {
+	session := &graphQLSession{
+		userID: c.MustGet("currentUserID").(uint),
+		users:  make(map[uint]*domain.User),
+		photos: make(map[uint]*domain.Photo),
+	}
+	hidden, err := h.blockService.HiddenUserIDs(session.userID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+	session.hidden = hidden
+	if scopes, ok := c.Get("apiKeyScopes"); ok {
+		session.scopes = append([]string{}, scopes.([]string)...)
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphQLSessionKey{}, session)
+	resp := h.schema.Execute(ctx, req, config)
+	for _, gqlErr := range resp.Errors {
+		if gqlErr.Err == nil {
+			gqlErr.Extensions = map[string]interface{}{"code": "invalid_query"}
+			continue
+		}
+
+		domainErr, status := toDomainError(gqlErr.Err)
+		if status >= http.StatusInternalServerError {
+			log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, gqlErr.Err)
+		}
+		gqlErr.Message = domainErr.Message
+		gqlErr.Extensions = map[string]interface{}{"code": domainErr.Code}
+		if len(domainErr.Fields) > 0 {
+			fields := make([]FieldErrorResponse, 0, len(domainErr.Fields))
+			for _, field := range domainErr.Fields {
+				fields = append(fields, FieldErrorResponse{Field: field.Field, Message: field.Message})
+			}
+			gqlErr.Extensions["errors"] = fields
+		}
+	}
+
+	// GraphQL responses aren't versioned, errors are part of the body
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"encoding/json"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeGraphQLCommentService struct {
+	domain.CommentService
+}
+
+func (fakeGraphQLCommentService) AddComment(userID uint, photoID uint, message string) (*domain.Comment, error) {
+	return &domain.Comment{ID: 1, UserID: userID, PhotoID: photoID, Message: message, CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+}
+
+func TestGraphQLMutationsShareTheRESTRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := fake.NewRateLimiter()
+	h := NewGraphQLHandler(nil, fakeStreamPhotoService{}, fakeGraphQLCommentService{}, nil, fakeStreamBlockService{}, limiter, Config{
+		RateLimits: []domain.RateLimitRule{
+			{Group: "comments", Methods: []string{"POST", "PUT"}, Policy: domain.RateLimitPolicy{Limit: 1, Window: time.Minute}},
+			{Group: "comments", Policy: domain.RateLimitPolicy{Limit: 300, Window: time.Minute}},
+		},
+	})
+	r := gin.New()
+	r.POST("/graphql", func(c *gin.Context) { c.Set("currentUserID", uint(1)) }, h.Query)
+
+	mutate := func() GraphQLResponse {
+		w := httptest.NewRecorder()
+		body := `{"query": "mutation { createComment(photoId: 7, message: \"Nice\") { id } }"}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		var resp GraphQLResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := mutate(); len(resp.Errors) > 0 {
+		t.Fatalf("first comment: %+v", resp.Errors)
+	}
+	if resp := mutate(); len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "rate_limited" {
+		t.Errorf("second comment: got %+v, want rate_limited", resp.Errors)
+	}
+	// the counters are the ones of POST /comments
+	if writes, all := limiter.Count("comments:POST,PUT:user:1"), limiter.Count("comments:user:1"); writes != 2 || all != 1 {
+		t.Errorf("counted %d writes and %d comment requests, want 2 and 1", writes, all)
+	}
+}
@@ -0,0 +1,1018 @@
+package rest
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"final-project/pkg/graphql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// graphQLSession is the caller of a GraphQL request, resolvers find it in the context
+type graphQLSession struct {
+	userID uint
+	// scopes are the scopes of an API key, nil for logins which have every scope
+	scopes []string
+	// hidden are the users whose content is hidden from the caller, their accounts, photos and
+	// comments resolve as if they didn't exist
+	hidden map[uint]bool
+
+	// The loaders cache the users and photos loaded during the request, nil for unknown ids
+	users  map[uint]*domain.User
+	photos map[uint]*domain.Photo
+}
+
+type graphQLSessionKey struct{}
+
+func sessionFrom(ctx context.Context) *graphQLSession {
+	return ctx.Value(graphQLSessionKey{}).(*graphQLSession)
+}
+
+// requireScope is RequireScope for GraphQL fields
+func (s *graphQLSession) requireScope(scope string) error {
+	if s.scopes == nil {
+		return nil
+	}
+	for _, granted := range s.scopes {
+		if granted == scope {
+			return nil
+		}
+	}
+	return domain.NewForbiddenError("insufficient_scope", fmt.Sprintf("API key is missing the %s scope", scope))
+}
+
+// loadUsers returns the users of ids, the ones not cached yet are loaded in one query
+func (h *GraphQLHandler) loadUsers(session *graphQLSession, ids []uint) (map[uint]*domain.User, error) {
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := session.users[id]; !ok {
+			missing = append(missing, id)
+			// Ids without a user are cached as nil
+			session.users[id] = nil
+		}
+	}
+
+	if len(missing) > 0 {
+		users, err := h.userService.GetUsersByIDs(missing)
+		if err != nil {
+			return nil, err
+		}
+		for i := range *users {
+			user := &(*users)[i]
+			if !session.hidden[user.ID] {
+				session.users[user.ID] = user
+			}
+		}
+	}
+	return session.users, nil
+}
+
+// loadPhotos returns the photos of ids, the ones not cached yet are loaded in one query
+func (h *GraphQLHandler) loadPhotos(session *graphQLSession, ids []uint) (map[uint]*domain.Photo, error) {
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := session.photos[id]; !ok {
+			missing = append(missing, id)
+			session.photos[id] = nil
+		}
+	}
+
+	if len(missing) > 0 {
+		photos, err := h.photoService.GetPhotosByIDs(missing)
+		if err != nil {
+			return nil, err
+		}
+		for i := range *photos {
+			photo := &(*photos)[i]
+			if !session.hidden[photo.UserID] {
+				session.photos[photo.ID] = photo
+			}
+		}
+	}
+	return session.photos, nil
+}
+
+// requireVerifiedEmail is RequireVerifiedEmail for the mutations of a route group restricted by the config
+func (h *GraphQLHandler) requireVerifiedEmail(session *graphQLSession, group string) error {
+	for _, restricted := range h.verifiedEmailRequired {
+		if restricted != group {
+			continue
+		}
+
+		user, err := h.userService.GetUserByID(session.userID)
+		if err != nil {
+			return err
+		}
+		if !user.EmailVerified {
+			return domain.NewForbiddenError("email_not_verified", "please verify your email first")
+		}
+	}
+	return nil
+}
+
+// rateLimit counts a mutation against the rules of the route group covering method, the same
+// counters as the REST route making the change
+func (h *GraphQLHandler) rateLimit(session *graphQLSession, group string, method string) error {
+	for _, rule := range h.rateLimits {
+		if rule.Group != group || !rule.Covers(method) {
+			continue
+		}
+
+		result, err := h.rateLimiter.Allow(domain.RateLimitUserKey(rule.Name(), session.userID), rule.Policy)
+		if err != nil {
+			// Don't take the API down with the counter store
+			log.Printf("rate limit %s: %v", rule.Name(), err)
+			continue
+		}
+		if !result.Allowed {
+			return errRateLimited
+		}
+	}
+	return nil
+}
+
+// DateTime is a time serialized in RFC 3339
+var dateTimeScalar = &graphql.Scalar{
+	Name:        "DateTime",
+	Description: "A date and time in RFC 3339 format.",
+	Serialize: func(value interface{}) (interface{}, error) {
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("DateTime cannot represent %v", value)
+		}
+		return t.Format(time.RFC3339Nano), nil
+	},
+	Parse: func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("DateTime cannot represent %v", value)
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	},
+}
+
+// parseID converts an ID argument to a database id
+func parseID(value interface{}, resource string) (uint, error) {
+	id, err := strconv.ParseUint(value.(string), 10, 64)
+	if err != nil || id == 0 {
+		return 0, domain.NewValidationError("invalid_id", "invalid "+resource+" id")
+	}
+	return uint(id), nil
+}
+
+// Inputs of the mutations, validated with the binding rules of the REST requests
+type graphQLPhotoInput struct {
+	Title    string `json:"title" binding:"required,max=255"`
+	Caption  string `json:"caption" binding:"max=2048"`
+	PhotoUrl string `json:"photoUrl" binding:"required,max=512,url"`
+}
+
+type graphQLCommentInput struct {
+	Message string `json:"message" binding:"required,max=2048"`
+}
+
+type graphQLSocialMediaInput struct {
+	Name           string `json:"name" binding:"required,max=255"`
+	SocialMediaUrl string `json:"socialMediaUrl" binding:"required,max=512,url"`
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// pageArgs are the arguments of paginated root lists
+var pageArgs = []*graphql.Argument{
+	{Name: "page", Type: graphql.Int, Default: 1},
+	{Name: "perPage", Type: graphql.Int, Default: defaultPerPage},
+}
+
+func pageFromArgs(args map[string]interface{}) (domain.PageRequest, error) {
+	// Explicit nulls are zeros, which fail the checks
+	number, _ := args["page"].(int)
+	perPage, _ := args["perPage"].(int)
+	page := domain.PageRequest{Page: number, PerPage: perPage}
+	if page.Page < 1 {
+		return page, domain.NewFieldValidationError("page", "must be a number of at least 1")
+	}
+	if page.PerPage < 1 || page.PerPage > maxPerPage {
+		return page, domain.NewFieldValidationError("perPage", fmt.Sprintf("must be a number between 1 and %d", maxPerPage))
+	}
+	return page, nil
+}
+
+// pageCost counts the children of every item a page can hold
+func pageCost(args map[string]interface{}, children int) int {
+	perPage, _ := args["perPage"].(int)
+	return 1 + perPage*children
+}
+
+// firstArgs limit the nested lists, which aren't paginated
+var firstArgs = []*graphql.Argument{
+	{Name: "first", Type: graphql.Int, Default: defaultPerPage, Description: fmt.Sprintf("Number of items returned, at most %d.", maxPerPage)},
+}
+
+func firstFromArgs(args map[string]interface{}) (int, error) {
+	first, ok := args["first"].(int)
+	if !ok || first < 0 || first > maxPerPage {
+		return 0, domain.NewFieldValidationError("first", fmt.Sprintf("must be a number between 0 and %d", maxPerPage))
+	}
+	return first, nil
+}
+
+func firstCost(args map[string]interface{}, children int) int {
+	first, _ := args["first"].(int)
+	return 1 + first*children
+}
+
+// newGraphQLSchema builds the schema over the services of h
+func newGraphQLSchema(h *GraphQLHandler) (*graphql.Schema, error) {
+	user := &graphql.Object{Name: "User", Description: "A registered user."}
+	photo := &graphql.Object{Name: "Photo", Description: "A photo posted by a user."}
+	comment := &graphql.Object{Name: "Comment", Description: "A comment on a photo."}
+	socialMedia := &graphql.Object{Name: "SocialMedia", Description: "A social media profile of a user."}
+
+	user.Fields = []*graphql.Field{
+		userField("id", graphql.NewNonNull(graphql.ID), func(u *domain.User) interface{} { return u.ID }),
+		userField("username", graphql.NewNonNull(graphql.String), func(u *domain.User) interface{} { return u.Username }),
+		{
+			Name:        "email",
+			Description: "Only visible to the user themselves.",
+			Type:        graphql.String,
+			Resolve: func(ctx context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+				u := source.(*domain.User)
+				if u.ID != sessionFrom(ctx).userID {
+					return nil, nil
+				}
+				return u.Email, nil
+			},
+		},
+		userField("createdAt", graphql.NewNonNull(dateTimeScalar), func(u *domain.User) interface{} { return u.CreatedAt }),
+		{
+			Name:  "photos",
+			Type:  graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(photo))),
+			Args:  firstArgs,
+			Batch: h.userPhotos,
+			Cost:  firstCost,
+		},
+		{
+			Name:  "socialMedias",
+			Type:  graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(socialMedia))),
+			Args:  firstArgs,
+			Batch: h.userSocialMedias,
+			Cost:  firstCost,
+		},
+	}
+
+	photo.Fields = []*graphql.Field{
+		photoField("id", graphql.NewNonNull(graphql.ID), func(p *domain.Photo) interface{} { return p.ID }),
+		photoField("title", graphql.NewNonNull(graphql.String), func(p *domain.Photo) interface{} { return p.Title }),
+		photoField("caption", graphql.NewNonNull(graphql.String), func(p *domain.Photo) interface{} { return p.Caption }),
+		photoField("photoUrl", graphql.NewNonNull(graphql.String), func(p *domain.Photo) interface{} { return p.PhotoUrl }),
+		photoField("createdAt", graphql.NewNonNull(dateTimeScalar), func(p *domain.Photo) interface{} { return p.CreatedAt }),
+		photoField("updatedAt", graphql.NewNonNull(dateTimeScalar), func(p *domain.Photo) interface{} { return p.UpdatedAt }),
+		{
+			Name:        "owner",
+			Description: "Null when the account is pending deletion.",
+			Type:        user,
+			Batch: func(ctx context.Context, sources []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+				return h.usersOf(ctx, sources, func(source interface{}) uint { return source.(*domain.Photo).UserID })
+			},
+		},
+		{
+			Name:  "comments",
+			Type:  graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(comment))),
+			Args:  firstArgs,
+			Batch: h.photoComments,
+			Cost:  firstCost,
+		},
+	}
+
+	comment.Fields = []*graphql.Field{
+		commentField("id", graphql.NewNonNull(graphql.ID), func(c *domain.Comment) interface{} { return c.ID }),
+		commentField("message", graphql.NewNonNull(graphql.String), func(c *domain.Comment) interface{} { return c.Message }),
+		commentField("createdAt", graphql.NewNonNull(dateTimeScalar), func(c *domain.Comment) interface{} { return c.CreatedAt }),
+		commentField("updatedAt", graphql.NewNonNull(dateTimeScalar), func(c *domain.Comment) interface{} { return c.UpdatedAt }),
+		{
+			Name:        "author",
+			Description: "Null when the account is pending deletion.",
+			Type:        user,
+			Batch: func(ctx context.Context, sources []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+				return h.usersOf(ctx, sources, func(source interface{}) uint { return source.(*domain.Comment).UserID })
+			},
+		},
+		{
+			Name:  "photo",
+			Type:  photo,
+			Batch: h.commentPhotos,
+		},
+	}
+
+	socialMedia.Fields = []*graphql.Field{
+		socialMediaField("id", graphql.NewNonNull(graphql.ID), func(s *domain.SocialMedia) interface{} { return s.ID }),
+		socialMediaField("name", graphql.NewNonNull(graphql.String), func(s *domain.SocialMedia) interface{} { return s.Name }),
+		socialMediaField("url", graphql.NewNonNull(graphql.String), func(s *domain.SocialMedia) interface{} { return s.SocialMediaUrl }),
+		socialMediaField("createdAt", graphql.NewNonNull(dateTimeScalar), func(s *domain.SocialMedia) interface{} { return s.CreatedAt }),
+		socialMediaField("updatedAt", graphql.NewNonNull(dateTimeScalar), func(s *domain.SocialMedia) interface{} { return s.UpdatedAt }),
+		{
+			Name: "owner",
+			Type: user,
+			Batch: func(ctx context.Context, sources []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+				return h.usersOf(ctx, sources, func(source interface{}) uint { return source.(*domain.SocialMedia).UserID })
+			},
+		},
+	}
+
+	idArgs := []*graphql.Argument{{Name: "id", Type: graphql.NewNonNull(graphql.ID)}}
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.Field{
+			{
+				Name:        "me",
+				Description: "The current user.",
+				Type:        graphql.NewNonNull(user),
+				Resolve:     h.me,
+			},
+			{
+				Name:    "user",
+				Type:    user,
+				Args:    idArgs,
+				Resolve: h.user,
+			},
+			{
+				Name:    "photo",
+				Type:    photo,
+				Args:    idArgs,
+				Resolve: h.photo,
+			},
+			{
+				Name:        "photos",
+				Description: "The photos of the current user.",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(photo))),
+				Args:        pageArgs,
+				Resolve:     h.photos,
+				Cost:        pageCost,
+			},
+			{
+				Name:        "comments",
+				Description: "The comments of the current user.",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(comment))),
+				Args:        pageArgs,
+				Resolve:     h.comments,
+				Cost:        pageCost,
+			},
+			{
+				Name:        "socialMedias",
+				Description: "The social medias of the current user.",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(socialMedia))),
+				Args:        pageArgs,
+				Resolve:     h.socialMedias,
+				Cost:        pageCost,
+			},
+		},
+	}
+
+	photoInput := &graphql.InputObject{
+		Name: "PhotoInput",
+		Fields: []*graphql.Argument{
+			{Name: "title", Type: graphql.NewNonNull(graphql.String)},
+			{Name: "caption", Type: graphql.String, Default: ""},
+			{Name: "photoUrl", Type: graphql.NewNonNull(graphql.String)},
+		},
+	}
+	socialMediaInput := &graphql.InputObject{
+		Name: "SocialMediaInput",
+		Fields: []*graphql.Argument{
+			{Name: "name", Type: graphql.NewNonNull(graphql.String)},
+			{Name: "socialMediaUrl", Type: graphql.NewNonNull(graphql.String)},
+		},
+	}
+	deletedID := graphql.NewNonNull(graphql.ID)
+
+	mutation := &graphql.Object{
+		Name: "Mutation",
+		Fields: []*graphql.Field{
+			{
+				Name:    "createPhoto",
+				Type:    graphql.NewNonNull(photo),
+				Args:    []*graphql.Argument{{Name: "input", Type: graphql.NewNonNull(photoInput)}},
+				Resolve: h.createPhoto,
+			},
+			{
+				Name:    "updatePhoto",
+				Type:    graphql.NewNonNull(photo),
+				Args:    []*graphql.Argument{idArgs[0], {Name: "input", Type: graphql.NewNonNull(photoInput)}},
+				Resolve: h.updatePhoto,
+			},
+			{
+				Name:        "deletePhoto",
+				Description: "Returns the id of the deleted photo.",
+				Type:        deletedID,
+				Args:        idArgs,
+				Resolve:     h.deletePhoto,
+			},
+			{
+				Name: "createComment",
+				Type: graphql.NewNonNull(comment),
+				Args: []*graphql.Argument{
+					{Name: "photoId", Type: graphql.NewNonNull(graphql.ID)},
+					{Name: "message", Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: h.createComment,
+			},
+			{
+				Name:    "updateComment",
+				Type:    graphql.NewNonNull(comment),
+				Args:    []*graphql.Argument{idArgs[0], {Name: "message", Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.updateComment,
+			},
+			{
+				Name:        "deleteComment",
+				Description: "Returns the id of the deleted comment.",
+				Type:        deletedID,
+				Args:        idArgs,
+				Resolve:     h.deleteComment,
+			},
+			{
+				Name:    "createSocialMedia",
+				Type:    graphql.NewNonNull(socialMedia),
+				Args:    []*graphql.Argument{{Name: "input", Type: graphql.NewNonNull(socialMediaInput)}},
+				Resolve: h.createSocialMedia,
+			},
+			{
+				Name:    "updateSocialMedia",
+				Type:    graphql.NewNonNull(socialMedia),
+				Args:    []*graphql.Argument{idArgs[0], {Name: "input", Type: graphql.NewNonNull(socialMediaInput)}},
+				Resolve: h.updateSocialMedia,
+			},
+			{
+				Name:        "deleteSocialMedia",
+				Description: "Returns the id of the deleted social media.",
+				Type:        deletedID,
+				Args:        idArgs,
+				Resolve:     h.deleteSocialMedia,
+			},
+		},
+	}
+
+	return graphql.NewSchema(query, mutation)
+}
+
+func userField(name string, t graphql.Type, get func(*domain.User) interface{}) *graphql.Field {
+	return &graphql.Field{Name: name, Type: t, Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(source.(*domain.User)), nil
+	}}
+}
+
+func photoField(name string, t graphql.Type, get func(*domain.Photo) interface{}) *graphql.Field {
+	return &graphql.Field{Name: name, Type: t, Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(source.(*domain.Photo)), nil
+	}}
+}
+
+func commentField(name string, t graphql.Type, get func(*domain.Comment) interface{}) *graphql.Field {
+	return &graphql.Field{Name: name, Type: t, Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(source.(*domain.Comment)), nil
+	}}
+}
+
+func socialMediaField(name string, t graphql.Type, get func(*domain.SocialMedia) interface{}) *graphql.Field {
+	return &graphql.Field{Name: name, Type: t, Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(source.(*domain.SocialMedia)), nil
+	}}
+}
+
+// Queries
+
+func (h *GraphQLHandler) me(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopeProfileRead); err != nil {
+		return nil, err
+	}
+
+	users, err := h.loadUsers(session, []uint{session.userID})
+	if err != nil {
+		return nil, err
+	}
+	if users[session.userID] == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return users[session.userID], nil
+}
+
+func (h *GraphQLHandler) user(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	userID, err := parseID(args["id"], "user")
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := h.loadUsers(sessionFrom(ctx), []uint{userID})
+	if err != nil || users[userID] == nil {
+		return nil, err
+	}
+	return users[userID], nil
+}
+
+func (h *GraphQLHandler) photo(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopePhotosRead); err != nil {
+		return nil, err
+	}
+	photoID, err := parseID(args["id"], "photo")
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := h.loadPhotos(session, []uint{photoID})
+	if err != nil || photos[photoID] == nil {
+		return nil, err
+	}
+	return photos[photoID], nil
+}
+
+func (h *GraphQLHandler) photos(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopePhotosRead); err != nil {
+		return nil, err
+	}
+	page, err := pageFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, _, err := h.photoService.GetPhotosByUserID(session.userID, page)
+	if err != nil {
+		return nil, err
+	}
+	for i := range *photos {
+		session.photos[(*photos)[i].ID] = &(*photos)[i]
+	}
+	return photos, nil
+}
+
+func (h *GraphQLHandler) comments(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopeCommentsRead); err != nil {
+		return nil, err
+	}
+	page, err := pageFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, _, err := h.commentService.GetCommentsByUserID(session.userID, page)
+	return comments, err
+}
+
+func (h *GraphQLHandler) socialMedias(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopeSocialMediasRead); err != nil {
+		return nil, err
+	}
+	page, err := pageFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	socialMedias, _, err := h.socialMediaService.GetSocialMediasByUserID(session.userID, page)
+	return socialMedias, err
+}
+
+// Batched fields, each runs one query for all the parent objects of a level
+
+// usersOf resolves the user of every source, userID reads it from a source
+func (h *GraphQLHandler) usersOf(ctx context.Context, sources []interface{}, userID func(interface{}) uint) ([]interface{}, error) {
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = userID(source)
+	}
+
+	users, err := h.loadUsers(sessionFrom(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(sources))
+	for i, id := range ids {
+		if users[id] != nil {
+			results[i] = users[id]
+		}
+	}
+	return results, nil
+}
+
+func (h *GraphQLHandler) userPhotos(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopePhotosRead); err != nil {
+		return nil, err
+	}
+	first, err := firstFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = source.(*domain.User).ID
+	}
+	photos, err := h.photoService.GetPhotosByUserIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[uint][]*domain.Photo, len(ids))
+	for i := range *photos {
+		photo := &(*photos)[i]
+		if session.hidden[photo.UserID] {
+			continue
+		}
+		session.photos[photo.ID] = photo
+		if len(byUser[photo.UserID]) < first {
+			byUser[photo.UserID] = append(byUser[photo.UserID], photo)
+		}
+	}
+
+	results := make([]interface{}, len(sources))
+	for i, id := range ids {
+		list := byUser[id]
+		if list == nil {
+			list = []*domain.Photo{}
+		}
+		results[i] = list
+	}
+	return results, nil
+}
+
+func (h *GraphQLHandler) userSocialMedias(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	if err := sessionFrom(ctx).requireScope(domain.ScopeSocialMediasRead); err != nil {
+		return nil, err
+	}
+	first, err := firstFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = source.(*domain.User).ID
+	}
+	socialMedias, err := h.socialMediaService.GetSocialMediasByUserIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[uint][]*domain.SocialMedia, len(ids))
+	for i := range *socialMedias {
+		socialMedia := &(*socialMedias)[i]
+		if len(byUser[socialMedia.UserID]) < first {
+			byUser[socialMedia.UserID] = append(byUser[socialMedia.UserID], socialMedia)
+		}
+	}
+
+	results := make([]interface{}, len(sources))
+	for i, id := range ids {
+		list := byUser[id]
+		if list == nil {
+			list = []*domain.SocialMedia{}
+		}
+		results[i] = list
+	}
+	return results, nil
+}
+
+func (h *GraphQLHandler) photoComments(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopeCommentsRead); err != nil {
+		return nil, err
+	}
+	first, err := firstFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = source.(*domain.Photo).ID
+	}
+	comments, err := h.commentService.GetCommentsByPhotoIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byPhoto := make(map[uint][]*domain.Comment, len(ids))
+	for i := range *comments {
+		comment := &(*comments)[i]
+		if session.hidden[comment.UserID] {
+			continue
+		}
+		if len(byPhoto[comment.PhotoID]) < first {
+			byPhoto[comment.PhotoID] = append(byPhoto[comment.PhotoID], comment)
+		}
+	}
+
+	results := make([]interface{}, len(sources))
+	for i, id := range ids {
+		list := byPhoto[id]
+		if list == nil {
+			list = []*domain.Comment{}
+		}
+		results[i] = list
+	}
+	return results, nil
+}
+
+func (h *GraphQLHandler) commentPhotos(ctx context.Context, sources []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopePhotosRead); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = source.(*domain.Comment).PhotoID
+	}
+	photos, err := h.loadPhotos(session, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(sources))
+	for i, id := range ids {
+		if photos[id] != nil {
+			results[i] = photos[id]
+		}
+	}
+	return results, nil
+}
+
+// Mutations, with the checks of the REST handlers
+
+func (h *GraphQLHandler) createPhoto(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	input, err := h.photoInput(session, http.MethodPost, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.photoService.SavePhoto(session.userID, &domain.AddPhotoRequest{
+		Title:    input.Title,
+		Caption:  input.Caption,
+		PhotoUrl: input.PhotoUrl,
+	})
+}
+
+func (h *GraphQLHandler) updatePhoto(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	input, err := h.photoInput(session, http.MethodPut, args)
+	if err != nil {
+		return nil, err
+	}
+	photoID, err := h.ownedPhoto(session, args)
+	if err != nil {
+		return nil, err
+	}
+
+	photo, err := h.photoService.UpdatePhoto(photoID, &domain.AddPhotoRequest{
+		Title:    input.Title,
+		Caption:  input.Caption,
+		PhotoUrl: input.PhotoUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	session.photos[photo.ID] = photo
+	return photo, nil
+}
+
+func (h *GraphQLHandler) deletePhoto(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopePhotosWrite); err != nil {
+		return nil, err
+	}
+	if err := h.rateLimit(session, "photos", http.MethodDelete); err != nil {
+		return nil, err
+	}
+	photoID, err := h.ownedPhoto(session, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.photoService.DeletePhoto(photoID); err != nil {
+		return nil, err
+	}
+	session.photos[photoID] = nil
+	return photoID, nil
+}
+
+// photoInput checks the scope, rate limits and email of the caller, then validates the input
+// argument. method is the one of the REST route doing the same.
+func (h *GraphQLHandler) photoInput(session *graphQLSession, method string, args map[string]interface{}) (*graphQLPhotoInput, error) {
+	if err := session.requireScope(domain.ScopePhotosWrite); err != nil {
+		return nil, err
+	}
+	if err := h.rateLimit(session, "photos", method); err != nil {
+		return nil, err
+	}
+	if err := h.requireVerifiedEmail(session, "photos"); err != nil {
+		return nil, err
+	}
+
+	fields := args["input"].(map[string]interface{})
+	input := &graphQLPhotoInput{
+		Title:    stringArg(fields, "title"),
+		Caption:  stringArg(fields, "caption"),
+		PhotoUrl: stringArg(fields, "photoUrl"),
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// ownedPhoto returns the id argument when it is a photo of the caller
+func (h *GraphQLHandler) ownedPhoto(session *graphQLSession, args map[string]interface{}) (uint, error) {
+	photoID, err := parseID(args["id"], "photo")
+	if err != nil {
+		return 0, err
+	}
+
+	photo, err := h.photoService.GetPhotoByID(photoID)
+	if err != nil {
+		return 0, err
+	}
+	if photo.UserID != session.userID {
+		return 0, domain.ErrNotOwner
+	}
+	return photoID, nil
+}
+
+func (h *GraphQLHandler) createComment(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	input, err := h.commentInput(session, http.MethodPost, args)
+	if err != nil {
+		return nil, err
+	}
+	photoID, err := parseID(args["photoId"], "photo")
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if photo exist
+	if _, err := h.photoService.GetPhotoByID(photoID); err != nil {
+		return nil, err
+	}
+	return h.commentService.AddComment(session.userID, photoID, input.Message)
+}
+
+func (h *GraphQLHandler) updateComment(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	input, err := h.commentInput(session, http.MethodPut, args)
+	if err != nil {
+		return nil, err
+	}
+	commentID, err := h.ownedComment(session, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.commentService.UpdateComment(commentID, input.Message)
+}
+
+func (h *GraphQLHandler) deleteComment(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopeCommentsWrite); err != nil {
+		return nil, err
+	}
+	if err := h.rateLimit(session, "comments", http.MethodDelete); err != nil {
+		return nil, err
+	}
+	commentID, err := h.ownedComment(session, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.commentService.DeleteComment(commentID); err != nil {
+		return nil, err
+	}
+	return commentID, nil
+}
+
+func (h *GraphQLHandler) commentInput(session *graphQLSession, method string, args map[string]interface{}) (*graphQLCommentInput, error) {
+	if err := session.requireScope(domain.ScopeCommentsWrite); err != nil {
+		return nil, err
+	}
+	if err := h.rateLimit(session, "comments", method); err != nil {
+		return nil, err
+	}
+	if err := h.requireVerifiedEmail(session, "comments"); err != nil {
+		return nil, err
+	}
+
+	input := &graphQLCommentInput{Message: stringArg(args, "message")}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *GraphQLHandler) ownedComment(session *graphQLSession, args map[string]interface{}) (uint, error) {
+	commentID, err := parseID(args["id"], "comment")
+	if err != nil {
+		return 0, err
+	}
+
+	comment, err := h.commentService.GetCommentByID(commentID)
+	if err != nil {
+		return 0, err
+	}
+	if comment.UserID != session.userID {
+		return 0, domain.ErrNotOwner
+	}
+	return commentID, nil
+}
+
+func (h *GraphQLHandler) createSocialMedia(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	input, err := h.socialMediaInput(session, http.MethodPost, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.socialMediaService.AddSocialMedia(session.userID, input.Name, input.SocialMediaUrl)
+}
+
+func (h *GraphQLHandler) updateSocialMedia(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	input, err := h.socialMediaInput(session, http.MethodPut, args)
+	if err != nil {
+		return nil, err
+	}
+	socialMediaID, err := h.ownedSocialMedia(session, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.socialMediaService.UpdateSocialMedia(socialMediaID, input.Name, input.SocialMediaUrl)
+}
+
+func (h *GraphQLHandler) deleteSocialMedia(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	session := sessionFrom(ctx)
+	if err := session.requireScope(domain.ScopeSocialMediasWrite); err != nil {
+		return nil, err
+	}
+	if err := h.rateLimit(session, "socialmedias", http.MethodDelete); err != nil {
+		return nil, err
+	}
+	socialMediaID, err := h.ownedSocialMedia(session, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.socialMediaService.DeleteSocialMedia(socialMediaID); err != nil {
+		return nil, err
+	}
+	return socialMediaID, nil
+}
+
+func (h *GraphQLHandler) socialMediaInput(session *graphQLSession, method string, args map[string]interface{}) (*graphQLSocialMediaInput, error) {
+	if err := session.requireScope(domain.ScopeSocialMediasWrite); err != nil {
+		return nil, err
+	}
+	if err := h.rateLimit(session, "socialmedias", method); err != nil {
+		return nil, err
+	}
+	if err := h.requireVerifiedEmail(session, "socialmedias"); err != nil {
+		return nil, err
+	}
+
+	fields := args["input"].(map[string]interface{})
+	input := &graphQLSocialMediaInput{
+		Name:           stringArg(fields, "name"),
+		SocialMediaUrl: stringArg(fields, "socialMediaUrl"),
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *GraphQLHandler) ownedSocialMedia(session *graphQLSession, args map[string]interface{}) (uint, error) {
+	socialMediaID, err := parseID(args["id"], "social media")
+	if err != nil {
+		return 0, err
+	}
+
+	socialMedia, err := h.socialMediaService.GetSocialMediaByID(socialMediaID)
+	if err != nil {
+		return 0, err
+	}
+	if socialMedia.UserID != session.userID {
+		return 0, domain.ErrNotOwner
+	}
+	return socialMediaID, nil
+}
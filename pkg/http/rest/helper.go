@@ -2,8 +2,48 @@ package rest
 
 import "final-project/pkg/domain"
 
+type ImportJobResponse struct {
+	ID        uint                      `json:"id"`
+	Source    string                    `json:"source"`
+	Status    string                    `json:"status"`
+	Total     int                       `json:"total"`
+	Processed int                       `json:"processed"`
+	Imported  int                       `json:"imported"`
+	Failed    int                       `json:"failed"`
+	Errors    []ImportItemErrorResponse `json:"errors"`
+}
+
+type ImportItemErrorResponse struct {
+	Item    string `json:"item"`
+	Message string `json:"message"`
+}
+
+func formatUser(user *domain.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Age:           user.Age,
+		EmailVerified: user.EmailVerified,
+		PendingEmail:  user.PendingEmail,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+func formatPhoto(photo *domain.Photo) PhotoResponse {
+	return PhotoResponse{
+		ID:        photo.ID,
+		Title:     photo.Title,
+		Caption:   photo.Caption,
+		PhotoUrl:  photo.PhotoUrl,
+		UserID:    photo.UserID,
+		CreatedAt: photo.CreatedAt,
+		UpdatedAt: photo.UpdatedAt,
+	}
+}
+
 func formatPhotosOfUser(user domain.User, photos []domain.Photo) []PhotoOfUserResponse {
-	var photosOfUser []PhotoOfUserResponse
+	photosOfUser := make([]PhotoOfUserResponse, 0, len(photos))
 	for _, photo := range photos {
 		photosOfUser = append(photosOfUser, PhotoOfUserResponse{
 			ID:        photo.ID,
@@ -22,15 +62,38 @@ func formatPhotosOfUser(user domain.User, photos []domain.Photo) []PhotoOfUserRe
 	return photosOfUser
 }
 
-func formatCommentsOfUser(user *domain.User, comments *[]domain.Comment, photoService domain.PhotoService) []CommentOfUserResponse {
-	var commentsOfUser []CommentOfUserResponse
+func formatComment(comment *domain.Comment) CommentResponse {
+	return CommentResponse{
+		ID:        comment.ID,
+		Message:   comment.Message,
+		PhotoID:   comment.PhotoID,
+		UserID:    comment.UserID,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
+
+// formatCommentsOfUser leaves the photos of hidden owners empty, like the ones deleted since
+func formatCommentsOfUser(user *domain.User, comments *[]domain.Comment, photoService domain.PhotoService, hidden map[uint]bool) ([]CommentOfUserResponse, error) {
+	// Get the photos of every comment at once
+	photoIDs := make([]uint, 0, len(*comments))
 	for _, comment := range *comments {
-		// Get photo
-		photo, err := photoService.GetPhotoByID(comment.PhotoID)
-		if err != nil {
-			return nil
+		photoIDs = append(photoIDs, comment.PhotoID)
+	}
+	photos, err := photoService.GetPhotosByIDs(photoIDs)
+	if err != nil {
+		return nil, err
+	}
+	photosByID := make(map[uint]domain.Photo, len(*photos))
+	for _, photo := range *photos {
+		if !hidden[photo.UserID] {
+			photosByID[photo.ID] = photo
 		}
+	}
 
+	commentsOfUser := make([]CommentOfUserResponse, 0, len(*comments))
+	for _, comment := range *comments {
+		photo := photosByID[comment.PhotoID]
 		commentsOfUser = append(commentsOfUser, CommentOfUserResponse{
 			ID:        comment.ID,
 			Message:   comment.Message,
@@ -52,11 +115,22 @@ func formatCommentsOfUser(user *domain.User, comments *[]domain.Comment, photoSe
 			},
 		})
 	}
-	return commentsOfUser
+	return commentsOfUser, nil
+}
+
+func formatSocialMedia(socialMedia *domain.SocialMedia) SocialMediaResponse {
+	return SocialMediaResponse{
+		ID:             socialMedia.ID,
+		Name:           socialMedia.Name,
+		SocialMediaUrl: socialMedia.SocialMediaUrl,
+		UserID:         socialMedia.UserID,
+		CreatedAt:      socialMedia.CreatedAt,
+		UpdatedAt:      socialMedia.UpdatedAt,
+	}
 }
 
 func formatSocialMediaOfUser(user *domain.User, socialMedia *[]domain.SocialMedia) []SocialMediaOfUserResponse {
-	var socialMediaOfUser []SocialMediaOfUserResponse
+	socialMediaOfUser := make([]SocialMediaOfUserResponse, 0, len(*socialMedia))
 	for _, sm := range *socialMedia {
 		socialMediaOfUser = append(socialMediaOfUser, SocialMediaOfUserResponse{
 			ID:             sm.ID,
@@ -74,3 +148,38 @@ func formatSocialMediaOfUser(user *domain.User, socialMedia *[]domain.SocialMedi
 	}
 	return socialMediaOfUser
 }
+
+func formatImportJob(job *domain.ImportJob) ImportJobResponse {
+	itemErrors := make([]ImportItemErrorResponse, len(job.Errors))
+	for i, itemError := range job.Errors {
+		itemErrors[i] = ImportItemErrorResponse{
+			Item:    itemError.Item,
+			Message: itemError.Message,
+		}
+	}
+
+	return ImportJobResponse{
+		ID:        job.ID,
+		Source:    job.Source,
+		Status:    job.Status,
+		Total:     job.Total,
+		Processed: job.Processed,
+		Imported:  job.Imported,
+		Failed:    job.Failed,
+		Errors:    itemErrors,
+	}
+}
+
+func formatAuditLogs(auditLogs *[]domain.AuditLog) []AuditLogResponse {
+	responses := make([]AuditLogResponse, len(*auditLogs))
+	for i, auditLog := range *auditLogs {
+		responses[i] = AuditLogResponse{
+			ID:        auditLog.ID,
+			Action:    auditLog.Action,
+			UserID:    auditLog.UserID,
+			Detail:    auditLog.Detail,
+			CreatedAt: auditLog.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"errors"
+	"final-project/pkg/domain"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize is the largest archive accepted for import
+const maxImportSize = 2 << 30
+
+var errImportTooLarge = domain.NewTooLargeError("archive_too_large", "archive is larger than 2 GB")
+
+type ImportHandler struct {
+	importService domain.ImportService
+}
+
+func NewImportHandler(importService domain.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// StartImport is a handler for importing an Instagram export uploaded as the "archive" form file
+func (h *ImportHandler) StartImport(c *gin.Context) {
+	// Get uploaded archive
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	fileHeader, err := c.FormFile("archive")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		SendErrorResponse(c, errImportTooLarge)
+		return
+	}
+	if err != nil {
+		SendErrorResponse(c, domain.NewFieldValidationError("archive", "is required"))
+		return
+	}
+
+	// Copy the upload to a temporary file, the import service removes it when done
+	tmp, err := os.CreateTemp("", "mygram-import-*.zip")
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+	tmp.Close()
+
+	if err := c.SaveUploadedFile(fileHeader, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	job, err := h.importService.StartImport(currentUserID, tmp.Name())
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, formatImportJob(job))
+}
+
+// GetImport is a handler for reporting the progress of an import
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	// Get importID from URL
+	importID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid import id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	job, err := h.importService.GetImportJob(currentUserID, uint(importID))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatImportJob(job))
+}
@@ -0,0 +1,354 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StartConversationRequest struct {
+	// ParticipantIDs are the other users, one for a 1:1 conversation
+	ParticipantIDs []uint `json:"participant_ids" binding:"required,min=1"`
+	// Title names group conversations, it is ignored for 1:1 conversations
+	Title string `json:"title" binding:"max=100"`
+}
+
+type SendDirectMessageRequest struct {
+	// Body may be empty when a photo is attached
+	Body    string `json:"body"`
+	PhotoID *uint  `json:"photo_id"`
+}
+
+type UpdateMessageSettingsRequest struct {
+	// AllowFrom is everyone or contacts, the users you have messaged before
+	AllowFrom string `json:"allow_from" binding:"required"`
+}
+
+type ConversationParticipantResponse struct {
+	UserID uint `json:"user_id"`
+	// LastReadMessageID is the latest message the participant has read, 0 when none
+	LastReadMessageID uint       `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+}
+
+type ConversationResponse struct {
+	ID           uint                              `json:"id"`
+	IsGroup      bool                              `json:"is_group"`
+	Title        string                            `json:"title"`
+	CreatedBy    uint                              `json:"created_by"`
+	Participants []ConversationParticipantResponse `json:"participants"`
+	LastMessage  *DirectMessageResponse            `json:"last_message"`
+	Unread       int64                             `json:"unread"`
+	CreatedAt    time.Time                         `json:"created_at"`
+	UpdatedAt    time.Time                         `json:"updated_at"`
+}
+
+type DirectMessageResponse struct {
+	ID             uint   `json:"id"`
+	ConversationID uint   `json:"conversation_id"`
+	SenderID       uint   `json:"sender_id"`
+	Body           string `json:"body"`
+	PhotoID        *uint  `json:"photo_id"`
+	// Photo is null when no photo is attached or the photo has been deleted
+	Photo *PhotoResponse `json:"photo"`
+	// ReadBy lists the other participants who have read the message
+	ReadBy []uint `json:"read_by"`
+	// Deleted is set when the sender deleted the message for everyone
+	Deleted   bool      `json:"deleted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MessageSettingsResponse struct {
+	AllowFrom string `json:"allow_from"`
+}
+
+type MessageHandler struct {
+	messageService domain.MessageService
+}
+
+func NewMessageHandler(messageService domain.MessageService) *MessageHandler {
+	return &MessageHandler{
+		messageService: messageService,
+	}
+}
+
+func (h *MessageHandler) StartConversation(c *gin.Context) {
+	// Bind request body to StartConversationRequest struct
+	var req StartConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	conversation, err := h.messageService.StartConversation(currentUserID, &domain.StartConversationRequest{
+		ParticipantIDs: req.ParticipantIDs,
+		Title:          req.Title,
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, formatConversation(conversation))
+}
+
+// GetConversations is a handler for listing the conversations of the current user, the most
+// recent activity first
+func (h *MessageHandler) GetConversations(c *gin.Context) {
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	conversations, total, err := h.messageService.GetConversations(currentUserID, page)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]ConversationResponse, len(*conversations))
+	for i, conversation := range *conversations {
+		responses[i] = formatConversation(&conversation)
+	}
+
+	respondList(c, responses, page, total)
+}
+
+func (h *MessageHandler) GetConversation(c *gin.Context) {
+	// Get id from path
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid conversation id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	conversation, err := h.messageService.GetConversation(currentUserID, uint(conversationID))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatConversation(conversation))
+}
+
+// GetMessages is a handler for listing the messages of a conversation, newest first
+func (h *MessageHandler) GetMessages(c *gin.Context) {
+	// Get id from path
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid conversation id"))
+		return
+	}
+
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	messages, total, err := h.messageService.GetMessages(currentUserID, uint(conversationID), page)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]DirectMessageResponse, len(*messages))
+	for i, message := range *messages {
+		responses[i] = formatDirectMessage(&message)
+	}
+
+	respondList(c, responses, page, total)
+}
+
+func (h *MessageHandler) SendMessage(c *gin.Context) {
+	// Get id from path
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid conversation id"))
+		return
+	}
+
+	// Bind request body to SendDirectMessageRequest struct
+	var req SendDirectMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	message, err := h.messageService.SendMessage(currentUserID, uint(conversationID), &domain.SendMessageRequest{
+		Body:    req.Body,
+		PhotoID: req.PhotoID,
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, formatDirectMessage(message))
+}
+
+// MarkRead is a handler for marking every message of a conversation read
+func (h *MessageHandler) MarkRead(c *gin.Context) {
+	// Get id from path
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid conversation id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	conversation, err := h.messageService.MarkRead(currentUserID, uint(conversationID))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatConversation(conversation))
+}
+
+// DeleteMessage is a handler for deleting a message for the current user, or for everyone with
+// ?for=everyone
+func (h *MessageHandler) DeleteMessage(c *gin.Context) {
+	// Get ids from path
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid conversation id"))
+		return
+	}
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid message id"))
+		return
+	}
+
+	// Get deletion target from query
+	forEveryone := false
+	switch c.DefaultQuery("for", "me") {
+	case "me":
+	case "everyone":
+		forEveryone = true
+	default:
+		SendErrorResponse(c, domain.NewFieldValidationError("for", "must be me or everyone"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.messageService.DeleteMessage(currentUserID, uint(conversationID), uint(messageID), forEveryone); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Message has been deleted",
+	})
+}
+
+func (h *MessageHandler) GetSettings(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	settings, err := h.messageService.GetSettings(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageSettingsResponse{
+		AllowFrom: settings.AllowFrom,
+	})
+}
+
+func (h *MessageHandler) UpdateSettings(c *gin.Context) {
+	// Bind request body to UpdateMessageSettingsRequest struct
+	var req UpdateMessageSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	settings, err := h.messageService.UpdateSettings(currentUserID, req.AllowFrom)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageSettingsResponse{
+		AllowFrom: settings.AllowFrom,
+	})
+}
+
+func formatConversation(conversation *domain.Conversation) ConversationResponse {
+	participants := make([]ConversationParticipantResponse, len(conversation.Participants))
+	for i, participant := range conversation.Participants {
+		participants[i] = ConversationParticipantResponse{
+			UserID:            participant.UserID,
+			LastReadMessageID: participant.LastReadMessageID,
+			LastReadAt:        participant.LastReadAt,
+		}
+	}
+
+	response := ConversationResponse{
+		ID:           conversation.ID,
+		IsGroup:      conversation.IsGroup,
+		Title:        conversation.Title,
+		CreatedBy:    conversation.CreatedBy,
+		Participants: participants,
+		Unread:       conversation.Unread,
+		CreatedAt:    conversation.CreatedAt,
+		UpdatedAt:    conversation.UpdatedAt,
+	}
+	if conversation.LastMessage != nil {
+		lastMessage := formatDirectMessage(conversation.LastMessage)
+		response.LastMessage = &lastMessage
+	}
+	return response
+}
+
+func formatDirectMessage(message *domain.Message) DirectMessageResponse {
+	response := DirectMessageResponse{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+		PhotoID:        message.PhotoID,
+		ReadBy:         message.ReadBy,
+		Deleted:        message.DeletedAt != nil,
+		CreatedAt:      message.CreatedAt,
+	}
+	if response.ReadBy == nil {
+		response.ReadBy = []uint{}
+	}
+	if message.Photo != nil {
+		photo := formatPhoto(message.Photo)
+		response.Photo = &photo
+	}
+	return response
+}
@@ -1,22 +1,27 @@
 package rest
 
 import (
-	"errors"
 	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Gin middleware to validate JWT token
-func AuthMiddleware(authService domain.AuthService) gin.HandlerFunc {
+// Gin middleware to validate a JWT token or a personal API key. Requests made with an API key
+// also get the granted scopes in context under "apiKeyScopes".
+func AuthMiddleware(authService domain.AuthService, userService domain.UserService, apiKeyService domain.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from header
 		token := c.GetHeader("Authorization")
 		isBearer := strings.HasPrefix(token, "Bearer ")
 		if !isBearer {
-			SendErrorResponse(c, errors.New("invalid token format"), http.StatusUnauthorized)
+			SendErrorResponse(c, domain.NewUnauthorizedError("invalid_token", "invalid token format"))
 			c.Abort()
 			return
 		}
@@ -24,17 +29,250 @@ func AuthMiddleware(authService domain.AuthService) gin.HandlerFunc {
 		// Remove "Bearer " from token
 		token = strings.TrimPrefix(token, "Bearer ")
 
+		if apiKeyService.IsAPIKey(token) {
+			// Validate API key
+			key, err := apiKeyService.Authenticate(token)
+			if err != nil {
+				SendErrorResponse(c, err)
+				c.Abort()
+				return
+			}
+
+			// Reject keys of accounts pending deletion
+			if _, err := userService.GetUserByID(key.UserID); err != nil {
+				SendErrorResponse(c, domain.NewUnauthorizedError("invalid_api_key", "invalid or expired API key"))
+				c.Abort()
+				return
+			}
+
+			// Set userID and scopes to context
+			c.Set("currentUserID", key.UserID)
+			c.Set("apiKeyScopes", key.Scopes)
+
+			c.Next()
+			return
+		}
+
 		// Validate token
-		userID, err := authService.ValidateToken(token)
+		claims, err := authService.ValidateToken(token)
+		if err != nil {
+			SendErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+
+		// Reject tokens of deleted accounts, accounts pending deletion, revoked tokens and revoked sessions
+		if err := userService.VerifyTokenClaims(claims); err != nil {
+			SendErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+
+		// Set userID, sessionID and the claims, which stream tickets carry, to context
+		c.Set("currentUserID", claims.UserID)
+		if claims.SessionID != 0 {
+			c.Set("currentSessionID", claims.SessionID)
+		}
+		c.Set("currentTokenClaims", claims)
+
+		c.Next()
+	}
+}
+
+// Gin middleware for the streams, which also accept a stream ticket in the ?ticket= query since
+// browsers can't set the Authorization header of EventSource and WebSocket requests
+func StreamAuthMiddleware(authService domain.AuthService, userService domain.UserService, apiKeyService domain.APIKeyService) gin.HandlerFunc {
+	authMiddleware := AuthMiddleware(authService, userService, apiKeyService)
+
+	return func(c *gin.Context) {
+		ticketString := c.Query("ticket")
+		if ticketString == "" || c.GetHeader("Authorization") != "" {
+			authMiddleware(c)
+			return
+		}
+
+		// Validate ticket
+		ticket, err := authService.ValidateStreamTicket(ticketString)
+		if err != nil {
+			SendErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+
+		if ticket.Scopes != nil {
+			// Reject tickets of accounts pending deletion
+			if _, err := userService.GetUserByID(ticket.UserID); err != nil {
+				SendErrorResponse(c, domain.NewUnauthorizedError("invalid_ticket", "invalid stream ticket"))
+				c.Abort()
+				return
+			}
+			c.Set("apiKeyScopes", ticket.Scopes)
+		} else {
+			// Tickets die with the token they were requested with
+			claims := &domain.TokenClaims{UserID: ticket.UserID, TokenVersion: ticket.TokenVersion, SessionID: ticket.SessionID}
+			if err := userService.VerifyTokenClaims(claims); err != nil {
+				SendErrorResponse(c, err)
+				c.Abort()
+				return
+			}
+			if ticket.SessionID != 0 {
+				c.Set("currentSessionID", ticket.SessionID)
+			}
+		}
+
+		c.Set("currentUserID", ticket.UserID)
+
+		c.Next()
+	}
+}
+
+// Gin middleware to require scope from API keys, logged in users have every scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasScope(c, scope) {
+			SendErrorResponse(c, domain.NewForbiddenError("insufficient_scope", fmt.Sprintf("API key is missing the %s scope", scope)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Gin middleware to require readScope from API keys for GET requests and writeScope for the others
+func RequireReadWriteScope(readScope string, writeScope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := writeScope
+		if c.Request.Method == http.MethodGet {
+			scope = readScope
+		}
+
+		if !hasScope(c, scope) {
+			SendErrorResponse(c, domain.NewForbiddenError("insufficient_scope", fmt.Sprintf("API key is missing the %s scope", scope)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Gin middleware to close an endpoint to API keys, for account management
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyScopes"); ok {
+			SendErrorResponse(c, domain.NewForbiddenError("session_required", "this endpoint is not available with an API key"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func hasScope(c *gin.Context, scope string) bool {
+	scopes, ok := c.Get("apiKeyScopes")
+	if !ok {
+		return true
+	}
+
+	for _, granted := range scopes.([]string) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Gin middleware to restrict an endpoint to users with a verified email
+func RequireVerifiedEmail(userService domain.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get currentUserID from context
+		currentUserID := c.MustGet("currentUserID").(uint)
+
+		user, err := userService.GetUserByID(currentUserID)
 		if err != nil {
-			SendErrorResponse(c, err, http.StatusUnauthorized)
+			SendErrorResponse(c, err)
 			c.Abort()
 			return
 		}
 
-		// Set userID to context
-		c.Set("currentUserID", userID)
+		if !user.EmailVerified {
+			SendErrorResponse(c, domain.NewForbiddenError("email_not_verified", "please verify your email first"))
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// Gin middleware to restrict an endpoint to administrators
+func RequireAdmin(userService domain.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get currentUserID from context
+		currentUserID := c.MustGet("currentUserID").(uint)
+
+		user, err := userService.GetUserByID(currentUserID)
+		if err != nil {
+			SendErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+
+		if !user.IsAdmin {
+			SendErrorResponse(c, domain.NewForbiddenError("admin_required", "insufficient privileges"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Gin middleware to limit requests per user, or per IP address before authentication.
+// name separates the counters of different policies.
+func RateLimit(limiter domain.RateLimiter, name string, policy domain.RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkRateLimit(c, limiter, name, policy) {
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+var errRateLimited = domain.NewTooManyRequestsError("rate_limited", "too many requests")
+
+// checkRateLimit counts the request, sets the RateLimit headers and sends the error response
+// when the limit is exceeded. It returns whether the request may go on.
+func checkRateLimit(c *gin.Context, limiter domain.RateLimiter, name string, policy domain.RateLimitPolicy) bool {
+	key := name + ":ip:" + c.ClientIP()
+	if currentUserID, ok := c.Get("currentUserID"); ok {
+		key = domain.RateLimitUserKey(name, currentUserID.(uint))
+	}
+
+	result, err := limiter.Allow(key, policy)
+	if err != nil {
+		// Don't take the API down with the counter store
+		log.Printf("rate limit %s: %v", name, err)
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		SendErrorResponse(c, errRateLimited)
+		return false
+	}
+
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
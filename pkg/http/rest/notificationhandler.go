@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationResponse struct {
+	ID      uint   `json:"id"`
+	Type    string `json:"type"`
+	PhotoID uint   `json:"photo_id"`
+	// ActorIDs are the users who caused the notification, most recent first
+	ActorIDs  []uint     `json:"actor_ids"`
+	Message   string     `json:"message"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+type MarkAllReadResponse struct {
+	Message string `json:"message"`
+	// Marked is the number of notifications which were unread
+	Marked int64 `json:"marked"`
+}
+
+type NotificationPreferenceRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Enabled *bool  `json:"enabled" binding:"required"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences" binding:"required,dive"`
+}
+
+type NotificationPreferenceResponse struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+type NotificationHandler struct {
+	notificationService domain.NotificationService
+}
+
+func NewNotificationHandler(notificationService domain.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotifications is a handler for listing the notifications of the current user, newest first.
+// The X-Unread-Count header carries the number of unread notifications.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	// Get unread filter from query
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewFieldValidationError("unread", "must be true or false"))
+		return
+	}
+
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	notifications, total, err := h.notificationService.GetNotifications(currentUserID, unreadOnly, page)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	unread, err := h.notificationService.CountUnread(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]NotificationResponse, len(*notifications))
+	for i, notification := range *notifications {
+		responses[i] = formatNotification(&notification)
+	}
+
+	c.Header("X-Unread-Count", strconv.FormatInt(unread, 10))
+	respondList(c, responses, page, total)
+}
+
+// GetUnreadCount is a handler for the number of unread notifications, for badges
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	unread, err := h.notificationService.CountUnread(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, UnreadCountResponse{
+		Unread: unread,
+	})
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	// Get id from path
+	notificationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid notification id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	notification, err := h.notificationService.MarkRead(currentUserID, uint(notificationID))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatNotification(notification))
+}
+
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	marked, err := h.notificationService.MarkAllRead(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MarkAllReadResponse{
+		Message: "All notifications have been marked read",
+		Marked:  marked,
+	})
+}
+
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	preferences, err := h.notificationService.GetPreferences(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatNotificationPreferences(preferences))
+}
+
+// UpdatePreferences is a handler for turning notification types on and off, the types left out
+// keep their setting
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	// Bind request body to UpdateNotificationPreferencesRequest struct
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	preferences := make([]domain.NotificationPreference, len(req.Preferences))
+	for i, preference := range req.Preferences {
+		preferences[i] = domain.NotificationPreference{
+			Type:    preference.Type,
+			Enabled: *preference.Enabled,
+		}
+	}
+
+	updated, err := h.notificationService.UpdatePreferences(currentUserID, preferences)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatNotificationPreferences(updated))
+}
+
+func formatNotification(notification *domain.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		PhotoID:   notification.PhotoID,
+		ActorIDs:  notification.ActorIDs,
+		Message:   notification.Message,
+		Read:      notification.ReadAt != nil,
+		ReadAt:    notification.ReadAt,
+		CreatedAt: notification.CreatedAt,
+		UpdatedAt: notification.UpdatedAt,
+	}
+}
+
+func formatNotificationPreferences(preferences []domain.NotificationPreference) []NotificationPreferenceResponse {
+	responses := make([]NotificationPreferenceResponse, len(preferences))
+	for i, preference := range preferences {
+		responses[i] = NotificationPreferenceResponse{
+			Type:    preference.Type,
+			Enabled: preference.Enabled,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LinkedIdentityResponse struct {
+	ID        uint      `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+type AuthorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type LinkIdentityResponse struct {
+	Message  string                 `json:"message"`
+	Identity LinkedIdentityResponse `json:"identity"`
+}
+
+// oidcBindingCookie keeps the binding of a started sign in or link until the provider redirects
+// back, the callback fails in a browser without it
+const oidcBindingCookie = "mygram_oidc_binding"
+
+type OIDCHandler struct {
+	oidcService domain.OIDCService
+}
+
+func NewOIDCHandler(oidcService domain.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// GetProviders is a handler for listing the identity providers users can sign in with
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	respond(c, http.StatusOK, ProvidersResponse{
+		Providers: h.oidcService.Providers(),
+	})
+}
+
+// Login is a handler for starting a sign in, it redirects to the identity provider
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authorization, err := h.oidcService.AuthorizationURL(c.Param("provider"), 0)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	setOIDCBinding(c, authorization)
+	c.Redirect(http.StatusFound, authorization.URL)
+}
+
+// Callback is a handler for the redirect back from the identity provider, it responds like
+// /users/login for a sign in
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// The provider reports a denied consent and other failures as query parameters
+	if errorCode := c.Query("error"); errorCode != "" {
+		SendErrorResponse(c, domain.NewUnauthorizedError("identity_provider_failed", "identity provider error: "+errorCode+" "+c.Query("error_description")))
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		SendErrorResponse(c, domain.NewValidationError("invalid_oidc_callback", "code and state are required"))
+		return
+	}
+
+	// The binding is single use like the state, a missing cookie fails as an invalid state
+	binding, _ := c.Cookie(oidcBindingCookie)
+	clearOIDCBinding(c)
+
+	result, err := h.oidcService.Callback(&domain.OIDCCallbackRequest{
+		Provider:  c.Param("provider"),
+		Code:      code,
+		State:     state,
+		Binding:   binding,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	if result.LinkedIdentity != nil {
+		respond(c, http.StatusOK, LinkIdentityResponse{
+			Message:  "Identity has been linked to your account",
+			Identity: formatLinkedIdentity(result.LinkedIdentity),
+		})
+		return
+	}
+
+	sendLoginResult(c, result.Login)
+}
+
+// LinkIdentity is a handler for linking an identity provider to the current user, the client
+// sends the user to the returned authorization URL from the browser that got the binding cookie
+func (h *OIDCHandler) LinkIdentity(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	authorization, err := h.oidcService.AuthorizationURL(c.Param("provider"), currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	setOIDCBinding(c, authorization)
+	respond(c, http.StatusOK, AuthorizationURLResponse{
+		AuthorizationURL: authorization.URL,
+	})
+}
+
+// GetLinkedIdentities is a handler for listing the identities linked to the current user
+func (h *OIDCHandler) GetLinkedIdentities(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	identities, err := h.oidcService.GetLinkedIdentities(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]LinkedIdentityResponse, len(*identities))
+	for i, identity := range *identities {
+		responses[i] = formatLinkedIdentity(&identity)
+	}
+
+	respondList(c, responses, domain.PageRequest{}, int64(len(responses)))
+}
+
+// UnlinkIdentity is a handler for removing an identity linked to the current user
+func (h *OIDCHandler) UnlinkIdentity(c *gin.Context) {
+	// Get id from path
+	identityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid identity id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.oidcService.UnlinkIdentity(currentUserID, uint(identityID)); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Identity has been unlinked",
+	})
+}
+
+// setOIDCBinding stores the binding in a cookie only sent to the callback. Lax lets the browser
+// send it on the redirect from the provider, which is a top level navigation.
+func setOIDCBinding(c *gin.Context, authorization *domain.OIDCAuthorization) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, authorization.Binding, int(time.Until(authorization.ExpiresAt).Seconds()),
+		"/auth/oidc", "", c.Request.TLS != nil, true)
+}
+
+func clearOIDCBinding(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, "", -1, "/auth/oidc", "", c.Request.TLS != nil, true)
+}
+
+func formatLinkedIdentity(identity *domain.LinkedIdentity) LinkedIdentityResponse {
+	return LinkedIdentityResponse{
+		ID:        identity.ID,
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
+}
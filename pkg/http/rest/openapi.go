@@ -0,0 +1,447 @@
+package rest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsPage renders /openapi.json, it is bundled so the docs work offline
+//
+//go:embed docs.html
+var docsPage []byte
+
+// pathParam matches the gin path parameters, e.g. :id
+var pathParam = regexp.MustCompile(`:([a-zA-Z_]+)`)
+
+// BuildOpenAPI generates the OpenAPI document of routes from the operation docs and the Go types of
+// the requests and responses. It also returns the drift between both: routes without docs and docs
+// of routes that aren't registered.
+func BuildOpenAPI(routes gin.RoutesInfo) (map[string]interface{}, []string) {
+	g := &schemaGenerator{components: make(map[string]interface{})}
+	paths := make(map[string]interface{})
+	var drift []string
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		if !isDocumentedRoute(route) {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+		registered[key] = true
+
+		doc, ok := operationDocs[key]
+		if !ok {
+			drift = append(drift, "route "+key+" is not documented")
+			continue
+		}
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = g.operation(route, doc)
+	}
+
+	for key := range operationDocs {
+		if !registered[key] {
+			drift = append(drift, "documented route "+key+" is not registered")
+		}
+	}
+	sort.Strings(drift)
+
+	spec := map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   "MyGram API",
+			"version": strconv.Itoa(APIVersion2),
+			"description": "Responses are unwrapped by default, clients sending `Accept: application/vnd.mygram.v2+json` get " +
+				"them in an envelope with pagination metadata for lists. Errors are RFC 7807 problem details.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.components,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+					"description": "The token of /users/login, or a personal API key (mgp_...). The roles of the " +
+						"security requirements are the scopes API keys need.",
+				},
+			},
+		},
+	}
+	return spec, drift
+}
+
+// isDocumentedRoute leaves out HEAD routes, the static media and the docs themselves
+func isDocumentedRoute(route gin.RouteInfo) bool {
+	if route.Method == http.MethodHead || strings.HasPrefix(route.Path, "/media/") {
+		return false
+	}
+	return route.Path != "/openapi.json" && route.Path != "/docs"
+}
+
+// OpenAPIHandler serves the generated document
+func OpenAPIHandler(spec map[string]interface{}) gin.HandlerFunc {
+	body, err := json.Marshal(spec)
+	return func(c *gin.Context) {
+		if err != nil {
+			SendErrorResponse(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", body)
+	}
+}
+
+// DocsHandler serves the bundled docs page
+func DocsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+func (g *schemaGenerator) operation(route gin.RouteInfo, doc operationDoc) map[string]interface{} {
+	op := map[string]interface{}{
+		"summary":     doc.Summary,
+		"tags":        []string{doc.Tag},
+		"operationId": operationID(route),
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if doc.Streaming {
+		op["x-streaming"] = true
+	}
+
+	switch doc.Auth {
+	case authSession:
+		op["security"] = []interface{}{map[string]interface{}{"bearer": []string{}}}
+		op["description"] = strings.TrimSpace(doc.Description + " Not available with API keys.")
+	case authBearer:
+		scopes := []string{}
+		if doc.Scope != "" {
+			scopes = append(scopes, doc.Scope)
+		}
+		op["security"] = []interface{}{map[string]interface{}{"bearer": scopes}}
+	}
+
+	var params []interface{}
+	for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+		schema := map[string]interface{}{"type": "string"}
+		if match[1] == "id" {
+			schema = map[string]interface{}{"type": "integer", "minimum": 1}
+		}
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+	}
+	for _, query := range doc.Query {
+		params = append(params, map[string]interface{}{
+			"name":        query.Name,
+			"in":          "query",
+			"required":    query.Required,
+			"description": query.Description,
+			"schema":      map[string]interface{}{"type": query.Type},
+		})
+	}
+	if doc.Paginated {
+		params = append(params,
+			map[string]interface{}{
+				"name":        "page",
+				"in":          "query",
+				"description": "Page number, starting at 1",
+				"schema":      map[string]interface{}{"type": "integer", "minimum": 1, "default": 1},
+			},
+			map[string]interface{}{
+				"name":        "per_page",
+				"in":          "query",
+				"description": "Items per page",
+				"schema":      map[string]interface{}{"type": "integer", "minimum": 1, "maximum": maxPerPage, "default": defaultPerPage},
+			},
+		)
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if doc.Request != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schemaOf(reflect.TypeOf(doc.Request))},
+			},
+		}
+	}
+	if len(doc.Form) > 0 {
+		properties := make(map[string]interface{})
+		required := make([]string, 0, len(doc.Form))
+		for _, name := range doc.Form {
+			properties[name] = map[string]interface{}{"type": "string", "contentMediaType": "application/octet-stream"}
+			required = append(required, name)
+		}
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object", "properties": properties, "required": required},
+				},
+			},
+		}
+	}
+
+	responses := map[string]interface{}{
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				problemContentType: map[string]interface{}{"schema": g.schemaOf(reflect.TypeOf(ProblemDetails{}))},
+			},
+		},
+	}
+	for _, response := range doc.Responses {
+		responses[strconv.Itoa(response.Status)] = g.response(response, doc.List)
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+func (g *schemaGenerator) response(response responseDoc, list bool) map[string]interface{} {
+	result := map[string]interface{}{"description": response.Description}
+	if result["description"] == "" {
+		result["description"] = http.StatusText(response.Status)
+	}
+
+	if response.ContentType != "" {
+		media := map[string]interface{}{}
+		if response.Body != nil {
+			media["schema"] = g.schemaOf(reflect.TypeOf(response.Body))
+		}
+		result["content"] = map[string]interface{}{response.ContentType: media}
+		return result
+	}
+	if response.Body == nil {
+		return result
+	}
+
+	schema := g.schemaOf(reflect.TypeOf(response.Body))
+	legacy := schema
+	if response.Legacy != nil {
+		legacy = g.schemaOf(reflect.TypeOf(response.Legacy))
+	}
+
+	envelope := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"data": schema},
+		"required":   []string{"data"},
+	}
+	if list {
+		envelope["properties"].(map[string]interface{})["meta"] = g.schemaOf(reflect.TypeOf(Meta{}))
+		envelope["required"] = []string{"data", "meta"}
+	}
+
+	result["content"] = map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": legacy},
+		fmt.Sprintf("application/vnd.mygram.v%d+json", APIVersion2): map[string]interface{}{"schema": envelope},
+	}
+	return result
+}
+
+// operationID is derived from the route, e.g. GET /photos/:id is getPhotosById
+func operationID(route gin.RouteInfo) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '-' || r == '_'
+	}) {
+		if strings.HasPrefix(part, ":") {
+			b.WriteString("By")
+			part = part[1:]
+		}
+		b.WriteString(upperFirst(part))
+	}
+	return b.String()
+}
+
+// schemaGenerator turns Go types into JSON schemas, named structs become components
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		schema := g.schemaOf(t.Elem())
+		if ref, ok := schema["$ref"]; ok {
+			return map[string]interface{}{"oneOf": []interface{}{map[string]interface{}{"$ref": ref}, map[string]interface{}{"type": "null"}}}
+		}
+		schema["type"] = []interface{}{schema["type"], "null"}
+		return schema
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schemaOf(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := upperFirst(t.Name())
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first for recursive types
+			g.components[name] = map[string]interface{}{}
+			g.components[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// Like encoding/json, the fields of untagged embedded structs are promoted
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type)
+			for embeddedName, embeddedSchema := range embedded["properties"].(map[string]interface{}) {
+				properties[embeddedName] = embeddedSchema
+			}
+			if embeddedRequired, ok := embedded["required"].([]string); ok {
+				required = append(required, embeddedRequired...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.schemaOf(field.Type)
+		if applyBindingRules(schema, field.Type, field.Tag.Get("binding")) {
+			required = append(required, name)
+		}
+		properties[name] = schema
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyBindingRules adds the validations of a binding tag to schema and tells whether the field is required
+func applyBindingRules(schema map[string]interface{}, t reflect.Type, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		value, err := strconv.ParseFloat(param, 64)
+		hasValue := err == nil
+
+		switch {
+		case name == "required":
+			required = true
+		case name == "email":
+			schema["format"] = "email"
+		case name == "url":
+			schema["format"] = "uri"
+		case name == "oneof":
+			enum := []interface{}{}
+			for _, option := range strings.Fields(param) {
+				enum = append(enum, option)
+			}
+			schema["enum"] = enum
+		case hasValue:
+			applyBound(schema, t, name, value)
+		}
+	}
+	return required
+}
+
+// applyBound translates min, max, gt, gte, lt and lte to the keyword of the field type
+func applyBound(schema map[string]interface{}, t reflect.Type, rule string, value float64) {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		prefix := map[reflect.Kind]string{reflect.String: "Length", reflect.Map: "Properties"}[t.Kind()]
+		if prefix == "" {
+			prefix = "Items"
+		}
+		// Lengths are integers, gt and lt bound them exclusively
+		switch rule {
+		case "min", "gte":
+			schema["min"+prefix] = int(value)
+		case "gt":
+			schema["min"+prefix] = int(value) + 1
+		case "max", "lte":
+			schema["max"+prefix] = int(value)
+		case "lt":
+			schema["max"+prefix] = int(value) - 1
+		}
+	default:
+		switch rule {
+		case "min", "gte":
+			schema["minimum"] = value
+		case "gt":
+			schema["exclusiveMinimum"] = value
+		case "max", "lte":
+			schema["maximum"] = value
+		case "lt":
+			schema["exclusiveMaximum"] = value
+		}
+	}
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
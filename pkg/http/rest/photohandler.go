@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"errors"
 	"final-project/pkg/domain"
 	"net/http"
 	"strconv"
@@ -16,6 +15,16 @@ type AddPhotoRequest struct {
 	PhotoUrl string `json:"photo_url" binding:"required,max=512,url"`
 }
 
+type PhotoResponse struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Caption   string    `json:"caption"`
+	PhotoUrl  string    `json:"photo_url"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type PhotoOfUserResponse struct {
 	ID        uint      `json:"id"`
 	Title     string    `json:"title"`
@@ -49,7 +58,7 @@ func (h *PhotoHandler) AddPhoto(c *gin.Context) {
 	// TODO: Add validation
 	var req AddPhotoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
@@ -63,56 +72,56 @@ func (h *PhotoHandler) AddPhoto(c *gin.Context) {
 	})
 
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":         photo.ID,
-		"title":      photo.Title,
-		"caption":    photo.Caption,
-		"photo_url":  photo.PhotoUrl,
-		"user_id":    photo.UserID,
-		"created_at": photo.CreatedAt,
-	})
+	respond(c, http.StatusCreated, formatPhoto(photo))
 }
 
 func (h *PhotoHandler) GetPhotos(c *gin.Context) {
 	// Get currentUserID from context
 	currentUserID := c.MustGet("currentUserID").(uint)
 
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
 	// Get photos of current user
-	photos, err := h.photoService.GetPhotosByUserID(currentUserID)
+	photos, total, err := h.photoService.GetPhotosByUserID(currentUserID, page)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Get the user corressponding to the userID
 	user, err := h.userService.GetUserByID(currentUserID)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Format json response
 	photosOfUserResponse := formatPhotosOfUser(*user, *photos)
 
-	c.JSON(http.StatusOK, photosOfUserResponse)
+	respondList(c, photosOfUserResponse, page, total)
 }
 
 func (h *PhotoHandler) UpdatePhoto(c *gin.Context) {
 	// Bind request body to AddPhotoRequest struct
 	var req AddPhotoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Get photoID from URL
 	photoID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid photo id"))
 		return
 	}
 
@@ -122,12 +131,12 @@ func (h *PhotoHandler) UpdatePhoto(c *gin.Context) {
 	// Check if photo userID equal to current userID
 	photo, err := h.photoService.GetPhotoByID(uint(photoID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	if photo.UserID != currentUserID {
-		SendErrorResponse(c, errors.New("insufficient privileges"), http.StatusUnauthorized)
+		SendErrorResponse(c, domain.ErrNotOwner)
 		return
 	}
 
@@ -139,25 +148,18 @@ func (h *PhotoHandler) UpdatePhoto(c *gin.Context) {
 	})
 
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"id":         photo.ID,
-		"title":      photo.Title,
-		"caption":    photo.Caption,
-		"photo_url":  photo.PhotoUrl,
-		"user_id":    photo.UserID,
-		"updated_at": photo.UpdatedAt,
-	})
+	respond(c, http.StatusOK, formatPhoto(photo))
 }
 
 func (h *PhotoHandler) DeletePhoto(c *gin.Context) {
 	// Get photoID from URL
 	photoID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid photo id"))
 		return
 	}
 
@@ -167,23 +169,23 @@ func (h *PhotoHandler) DeletePhoto(c *gin.Context) {
 	// Check if photo userID equal to current userID
 	photo, err := h.photoService.GetPhotoByID(uint(photoID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	if photo.UserID != currentUserID {
-		SendErrorResponse(c, errors.New("insufficient privileges"), http.StatusUnauthorized)
+		SendErrorResponse(c, domain.ErrNotOwner)
 		return
 	}
 
 	// Delete photo
 	err = h.photoService.DeletePhoto(uint(photoID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusInternalServerError)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Your photo has been successfully deleted",
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Your photo has been successfully deleted",
 	})
 }
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIVersion1 responses are the bare resources, as before the envelope
+	APIVersion1 = 1
+	// APIVersion2 responses are wrapped in an Envelope and lists carry pagination metadata
+	APIVersion2 = 2
+
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// versionMediaType matches the media types clients pick a version with, e.g. application/vnd.mygram.v2+json
+var versionMediaType = regexp.MustCompile(`application/vnd\.mygram\.v(\d+)\+json`)
+
+// Envelope wraps every version 2 response
+type Envelope struct {
+	Data interface{} `json:"data"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+type Meta struct {
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	PerPage    int   `json:"per_page"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Gin middleware to pick the response version from the Accept header, defaultVersion is used when
+// the client doesn't ask for one
+func APIVersion(defaultVersion int) gin.HandlerFunc {
+	if defaultVersion == 0 {
+		defaultVersion = APIVersion1
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+
+		version := defaultVersion
+		if match := versionMediaType.FindStringSubmatch(c.GetHeader("Accept")); match != nil {
+			version, _ = strconv.Atoi(match[1])
+		}
+		if version != APIVersion1 && version != APIVersion2 {
+			SendErrorResponse(c, domain.NewNotAcceptableError("unsupported_api_version",
+				fmt.Sprintf("API version %d is not supported, use 1 or 2", version)))
+			c.Abort()
+			return
+		}
+
+		c.Set("apiVersion", version)
+		c.Next()
+	}
+}
+
+func apiVersion(c *gin.Context) int {
+	if version := c.GetInt("apiVersion"); version != 0 {
+		return version
+	}
+	return APIVersion1
+}
+
+// respond sends data as it is to version 1 clients and in the envelope to version 2 clients
+func respond(c *gin.Context, status int, data interface{}) {
+	respondVersioned(c, status, data, data)
+}
+
+// respondVersioned is respond for endpoints whose version 1 response has another shape, legacy is sent
+// to version 1 clients
+func respondVersioned(c *gin.Context, status int, data interface{}, legacy interface{}) {
+	if apiVersion(c) == APIVersion1 {
+		c.JSON(status, legacy)
+		return
+	}
+
+	c.Header("Content-Type", fmt.Sprintf("application/vnd.mygram.v%d+json; charset=utf-8", APIVersion2))
+	c.JSON(status, Envelope{Data: data})
+}
+
+// respondList sends a page of a list, version 2 clients get the pagination metadata in the envelope.
+// Both get the number of items in the X-Total-Count header.
+func respondList(c *gin.Context, items interface{}, page domain.PageRequest, total int64) {
+	respondListVersioned(c, items, page, total, items)
+}
+
+// respondListVersioned is respondList for lists whose version 1 response has another shape
+func respondListVersioned(c *gin.Context, items interface{}, page domain.PageRequest, total int64, legacy interface{}) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if apiVersion(c) == APIVersion1 {
+		c.JSON(http.StatusOK, legacy)
+		return
+	}
+
+	pagination := &Pagination{
+		Page:       1,
+		PerPage:    int(total),
+		Total:      total,
+		TotalPages: 1,
+	}
+	if page.PerPage > 0 {
+		pagination.Page = page.Page
+		pagination.PerPage = page.PerPage
+		pagination.TotalPages = int((total + int64(page.PerPage) - 1) / int64(page.PerPage))
+	}
+
+	c.Header("Content-Type", fmt.Sprintf("application/vnd.mygram.v%d+json; charset=utf-8", APIVersion2))
+	c.JSON(http.StatusOK, Envelope{
+		Data: items,
+		Meta: &Meta{Pagination: pagination},
+	})
+}
+
+// parsePageRequest reads the ?page= and ?per_page= query parameters. Version 1 clients that send
+// neither get the whole list like before pagination.
+func parsePageRequest(c *gin.Context) (domain.PageRequest, error) {
+	pageParam, hasPage := c.GetQuery("page")
+	perPageParam, hasPerPage := c.GetQuery("per_page")
+	if !hasPage && !hasPerPage && apiVersion(c) == APIVersion1 {
+		return domain.PageRequest{}, nil
+	}
+
+	page := domain.PageRequest{Page: 1, PerPage: defaultPerPage}
+	if hasPage {
+		value, err := strconv.Atoi(pageParam)
+		if err != nil || value < 1 {
+			return page, domain.NewFieldValidationError("page", "must be a number of at least 1")
+		}
+		page.Page = value
+	}
+	if hasPerPage {
+		value, err := strconv.Atoi(perPageParam)
+		if err != nil || value < 1 || value > maxPerPage {
+			return page, domain.NewFieldValidationError("per_page", fmt.Sprintf("must be a number between 1 and %d", maxPerPage))
+		}
+		page.PerPage = value
+	}
+
+	return page, nil
+}
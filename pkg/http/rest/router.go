@@ -2,14 +2,32 @@ package rest
 
 import (
 	"final-project/pkg/domain"
+	"final-project/pkg/graphql"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-type BaseResponse struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+type Config struct {
+	// MediaDir is served under /media when set
+	MediaDir string
+	// VerifiedEmailRequired lists the route groups ("photos", "comments", "socialmedias")
+	// whose create and update endpoints are closed to users with an unverified email
+	VerifiedEmailRequired []string
+	// RateLimits are the rate limit policies of the route groups ("users", "photos",
+	// "comments", "socialmedias", "graphql", "stream", "notifications", "messages", "admin"), groups without a rule are not limited
+	RateLimits []domain.RateLimitRule
+	// DefaultAPIVersion is the response version of clients that don't ask for one in the Accept
+	// header, APIVersion1 when zero
+	DefaultAPIVersion int
+	// ValidateResponses checks every response against the OpenAPI document and replaces the ones
+	// breaking it with an internal error, meant for tests as responses are buffered
+	ValidateResponses bool
+	// GraphQL limits the depth and complexity of GraphQL queries, zero values disable the limits
+	GraphQL graphql.Config
+	// TrustedProxies are the addresses or CIDRs of the reverse proxies whose X-Forwarded-For is
+	// believed, the client IP is the peer address when empty
+	TrustedProxies []string
 }
 
 func NewRouter(
@@ -18,43 +36,151 @@ func NewRouter(
 	photoService *domain.PhotoService,
 	commentService *domain.CommentService,
 	socialMediaService *domain.SocialMediaService,
+	exportService *domain.ExportService,
+	importService *domain.ImportService,
+	twoFactorService *domain.TwoFactorService,
+	loginGuard *domain.LoginGuard,
+	rateLimiter *domain.RateLimiter,
+	apiKeyService *domain.APIKeyService,
+	oidcService *domain.OIDCService,
+	sessionService *domain.SessionService,
+	webhookService *domain.WebhookService,
+	streamHub *domain.StreamHub,
+	notificationService *domain.NotificationService,
+	digestService *domain.DigestService,
+	messageService *domain.MessageService,
+	blockService *domain.BlockService,
+	config Config,
 ) *gin.Engine {
 	r := gin.Default()
+	// Clients could rotate X-Forwarded-For to escape the lockouts and rate limits per IP otherwise
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+	registerFieldNames()
+	r.Use(APIVersion(config.DefaultAPIVersion))
+
+	// Requests are validated against the OpenAPI document, loaded once the routes are registered.
+	// Each group validates after its authentication and rate limits, so neither is skipped by an
+	// invalid request nor spent on reading its body.
+	openAPIValidator := newOpenAPIValidator(config.ValidateResponses)
+	validate := openAPIValidator.Middleware()
+
+	// Locally stored media
+	if config.MediaDir != "" {
+		r.Static("/media", config.MediaDir)
+	}
+
+	authMiddleware := AuthMiddleware(*authService, *userService, *apiKeyService)
 
 	// User handler routes
 	userHandler := NewUserHandler(*userService)
+	exportHandler := NewExportHandler(*exportService)
+	importHandler := NewImportHandler(*importService)
+	twoFactorHandler := NewTwoFactorHandler(*twoFactorService)
+	apiKeyHandler := NewAPIKeyHandler(*apiKeyService)
+	oidcHandler := NewOIDCHandler(*oidcService)
+	sessionHandler := NewSessionHandler(*sessionService)
+	webhookHandler := NewWebhookHandler(*webhookService)
+	emailHandler := NewEmailHandler(*digestService)
+	messageHandler := NewMessageHandler(*messageService)
+	blockHandler := NewBlockHandler(*blockService, *userService)
 	userRouter := r.Group("/users")
 	{
-		userRouter.POST("/register", userHandler.Register)
-		userRouter.POST("/login", userHandler.Login)
+		// Counted per IP address, most of these endpoints are used before logging in
+		userRouter.Use(rateLimitGuard(config, "users", *rateLimiter))
+
+		publicUserRouter := userRouter.Group("/")
+		{
+			publicUserRouter.Use(validate)
+			publicUserRouter.POST("/register", userHandler.Register)
+			publicUserRouter.POST("/login", userHandler.Login)
+			publicUserRouter.POST("/login/2fa", userHandler.LoginTwoFactor)
+			publicUserRouter.POST("/deletion/cancel", userHandler.CancelDeletion)
+			publicUserRouter.POST("/password/forgot", userHandler.ForgotPassword)
+			publicUserRouter.POST("/password/reset", userHandler.ResetPassword)
+			publicUserRouter.GET("/verify", userHandler.VerifyEmail)
+			publicUserRouter.GET("/unsubscribe", emailHandler.ConfirmUnsubscribe)
+			publicUserRouter.POST("/unsubscribe", emailHandler.Unsubscribe)
+		}
 
 		protectedUserRouter := userRouter.Group("/")
 		{
-			protectedUserRouter.Use(AuthMiddleware(*authService))
-			protectedUserRouter.PUT("/", userHandler.UpdateUser)
-			protectedUserRouter.DELETE("/", userHandler.DeleteUser)
-			protectedUserRouter.GET("/", userHandler.GetUser)
+			protectedUserRouter.Use(authMiddleware, validate)
+			protectedUserRouter.GET("/", RequireScope(domain.ScopeProfileRead), userHandler.GetUser)
+		}
+
+		// Account management is closed to API keys
+		sessionUserRouter := userRouter.Group("/")
+		{
+			sessionUserRouter.Use(authMiddleware, RequireSession(), validate)
+			sessionUserRouter.PUT("/", userHandler.UpdateUser)
+			sessionUserRouter.DELETE("/", userHandler.DeleteUser)
+			sessionUserRouter.PUT("/password", userHandler.ChangePassword)
+			sessionUserRouter.POST("/verify/resend", userHandler.ResendEmailVerification)
+			sessionUserRouter.POST("/export", exportHandler.RequestExport)
+			sessionUserRouter.GET("/export/:id", exportHandler.DownloadExport)
+			sessionUserRouter.POST("/import", verifiedEmailGuard(config, "photos", *userService), importHandler.StartImport)
+			sessionUserRouter.GET("/import/:id", importHandler.GetImport)
+			sessionUserRouter.POST("/2fa/enroll", twoFactorHandler.Enroll)
+			sessionUserRouter.POST("/2fa/confirm", twoFactorHandler.Confirm)
+			sessionUserRouter.POST("/2fa/disable", twoFactorHandler.Disable)
+			sessionUserRouter.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+			sessionUserRouter.POST("/tokens", apiKeyHandler.CreateAPIKey)
+			sessionUserRouter.GET("/tokens", apiKeyHandler.GetAPIKeys)
+			sessionUserRouter.DELETE("/tokens/:id", apiKeyHandler.RevokeAPIKey)
+			sessionUserRouter.POST("/identities/:provider", oidcHandler.LinkIdentity)
+			sessionUserRouter.GET("/identities", oidcHandler.GetLinkedIdentities)
+			sessionUserRouter.DELETE("/identities/:id", oidcHandler.UnlinkIdentity)
+			sessionUserRouter.GET("/sessions", sessionHandler.GetSessions)
+			sessionUserRouter.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			sessionUserRouter.POST("/webhooks", webhookHandler.CreateWebhook)
+			sessionUserRouter.GET("/webhooks", webhookHandler.GetWebhooks)
+			sessionUserRouter.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+			sessionUserRouter.GET("/webhooks/:id/dead-letters", webhookHandler.GetDeadLetters)
+			sessionUserRouter.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+			sessionUserRouter.GET("/email-preferences", emailHandler.GetEmailPreferences)
+			sessionUserRouter.PUT("/email-preferences", emailHandler.UpdateEmailPreferences)
+			sessionUserRouter.GET("/message-settings", messageHandler.GetSettings)
+			sessionUserRouter.PUT("/message-settings", messageHandler.UpdateSettings)
+			sessionUserRouter.GET("/blocks", blockHandler.GetRelations(domain.RelationBlock))
+			sessionUserRouter.POST("/blocks", blockHandler.AddRelation(domain.RelationBlock))
+			sessionUserRouter.DELETE("/blocks/:user_id", blockHandler.RemoveRelation(domain.RelationBlock))
+			sessionUserRouter.GET("/mutes", blockHandler.GetRelations(domain.RelationMute))
+			sessionUserRouter.POST("/mutes", blockHandler.AddRelation(domain.RelationMute))
+			sessionUserRouter.DELETE("/mutes/:user_id", blockHandler.RemoveRelation(domain.RelationMute))
 		}
 	}
 
+	// Sign in with OpenID Connect providers
+	oidcRouter := r.Group("/auth/oidc")
+	{
+		oidcRouter.Use(rateLimitGuard(config, "users", *rateLimiter), validate)
+		oidcRouter.GET("/providers", oidcHandler.GetProviders)
+		oidcRouter.GET("/:provider/login", oidcHandler.Login)
+		oidcRouter.GET("/:provider/callback", oidcHandler.Callback)
+	}
+
 	// Photo handler routes
 	photoHandler := NewPhotoHandler(*photoService, *userService)
 	photoRouter := r.Group("/photos")
 	{
-		photoRouter.Use(AuthMiddleware(*authService))
-		photoRouter.POST("/", photoHandler.AddPhoto)
+		photoRouter.Use(authMiddleware, RequireReadWriteScope(domain.ScopePhotosRead, domain.ScopePhotosWrite), rateLimitGuard(config, "photos", *rateLimiter), validate)
+		photoGuard := verifiedEmailGuard(config, "photos", *userService)
+		photoRouter.POST("/", photoGuard, photoHandler.AddPhoto)
 		photoRouter.GET("/", photoHandler.GetPhotos)
-		photoRouter.PUT("/:id", photoHandler.UpdatePhoto)
+		photoRouter.PUT("/:id", photoGuard, photoHandler.UpdatePhoto)
 		photoRouter.DELETE("/:id", photoHandler.DeletePhoto)
 	}
 
 	// Comment handler routes
-	commentHandler := NewCommentHandler(*commentService, *userService, *photoService)
+	commentHandler := NewCommentHandler(*commentService, *userService, *photoService, *blockService)
 	commentRouter := r.Group("/comments")
 	{
-		commentRouter.Use(AuthMiddleware(*authService))
-		commentRouter.POST("/", commentHandler.AddComment)
-		commentRouter.PUT("/:id", commentHandler.UpdateComment)
+		commentRouter.Use(authMiddleware, RequireReadWriteScope(domain.ScopeCommentsRead, domain.ScopeCommentsWrite), rateLimitGuard(config, "comments", *rateLimiter), validate)
+		commentGuard := verifiedEmailGuard(config, "comments", *userService)
+		commentRouter.POST("/", commentGuard, commentHandler.AddComment)
+		commentRouter.PUT("/:id", commentGuard, commentHandler.UpdateComment)
 		commentRouter.DELETE("/:id", commentHandler.DeleteComment)
 		commentRouter.GET("/", commentHandler.GetCommentsByUserID)
 	}
@@ -63,21 +189,116 @@ func NewRouter(
 	socialmediaHandler := NewSocialMediaHandler(*socialMediaService, *userService)
 	socialmediaRouter := r.Group("/socialmedias")
 	{
-		socialmediaRouter.Use(AuthMiddleware(*authService))
-		socialmediaRouter.POST("/", socialmediaHandler.AddSocialMedia)
-		socialmediaRouter.PUT("/:id", socialmediaHandler.UpdateSocialMedia)
+		socialmediaRouter.Use(authMiddleware, RequireReadWriteScope(domain.ScopeSocialMediasRead, domain.ScopeSocialMediasWrite), rateLimitGuard(config, "socialmedias", *rateLimiter), validate)
+		socialmediaGuard := verifiedEmailGuard(config, "socialmedias", *userService)
+		socialmediaRouter.POST("/", socialmediaGuard, socialmediaHandler.AddSocialMedia)
+		socialmediaRouter.PUT("/:id", socialmediaGuard, socialmediaHandler.UpdateSocialMedia)
 		socialmediaRouter.GET("/", socialmediaHandler.GetSocialMedias)
 		socialmediaRouter.DELETE("/:id", socialmediaHandler.DeleteSocialMedia)
 	}
 
+	// GraphQL over the same services, the checks of the routes above are made per field
+	graphQLHandler := NewGraphQLHandler(*userService, *photoService, *commentService, *socialMediaService, *blockService, *rateLimiter, config)
+	graphQLRouter := r.Group("/graphql")
+	{
+		graphQLRouter.Use(authMiddleware, rateLimitGuard(config, "graphql", *rateLimiter), validate)
+		graphQLRouter.POST("", graphQLHandler.Query)
+		graphQLRouter.GET("", graphQLHandler.QueryURL)
+	}
+
+	// Notification handler routes
+	notificationHandler := NewNotificationHandler(*notificationService)
+	notificationRouter := r.Group("/notifications")
+	{
+		notificationRouter.Use(authMiddleware, RequireReadWriteScope(domain.ScopeNotificationsRead, domain.ScopeNotificationsWrite), rateLimitGuard(config, "notifications", *rateLimiter), validate)
+		notificationRouter.GET("", notificationHandler.GetNotifications)
+		notificationRouter.GET("/unread-count", notificationHandler.GetUnreadCount)
+		notificationRouter.POST("/read-all", notificationHandler.MarkAllRead)
+		notificationRouter.POST("/:id/read", notificationHandler.MarkRead)
+		notificationRouter.GET("/preferences", notificationHandler.GetPreferences)
+		notificationRouter.PUT("/preferences", notificationHandler.UpdatePreferences)
+	}
+
+	// Direct message routes
+	conversationRouter := r.Group("/conversations")
+	{
+		conversationRouter.Use(authMiddleware, RequireReadWriteScope(domain.ScopeMessagesRead, domain.ScopeMessagesWrite), rateLimitGuard(config, "messages", *rateLimiter), validate)
+		conversationRouter.POST("", messageHandler.StartConversation)
+		conversationRouter.GET("", messageHandler.GetConversations)
+		conversationRouter.GET("/:id", messageHandler.GetConversation)
+		conversationRouter.GET("/:id/messages", messageHandler.GetMessages)
+		conversationRouter.POST("/:id/messages", messageHandler.SendMessage)
+		conversationRouter.POST("/:id/read", messageHandler.MarkRead)
+		conversationRouter.DELETE("/:id/messages/:message_id", messageHandler.DeleteMessage)
+	}
+
+	// Live updates of the photo and comment services, the topics are checked by the handler
+	streamHandler := NewStreamHandler(*streamHub, *authService, *photoService, *blockService)
+	streamAuthMiddleware := StreamAuthMiddleware(*authService, *userService, *apiKeyService)
+	streamGuard := rateLimitGuard(config, "stream", *rateLimiter)
+	streamRouter := r.Group("/stream")
+	{
+		streamRouter.POST("/tickets", authMiddleware, streamGuard, validate, streamHandler.CreateTicket)
+		// Browsers open the streams with a ticket in the URL
+		streamRouter.GET("", streamAuthMiddleware, streamGuard, validate, streamHandler.Stream)
+		streamRouter.GET("/ws", streamAuthMiddleware, streamGuard, validate, streamHandler.StreamWebSocket)
+	}
+
+	// Admin handler routes
+	adminHandler := NewAdminHandler(*loginGuard)
+	adminRouter := r.Group("/admin")
+	{
+		adminRouter.Use(authMiddleware, RequireSession(), RequireAdmin(*userService), rateLimitGuard(config, "admin", *rateLimiter), validate)
+		adminRouter.GET("/lockouts", adminHandler.GetLockouts)
+	}
+
+	// API documentation, generated from the routes registered above
+	spec, drift := BuildOpenAPI(r.Routes())
+	for _, problem := range drift {
+		log.Printf("openapi: %s", problem)
+	}
+	openAPIValidator.load(spec)
+	r.GET("/openapi.json", OpenAPIHandler(spec))
+	r.GET("/docs", DocsHandler)
+
 	return r
 }
 
-// Function to send error response
-func SendErrorResponse(c *gin.Context, err error, code int) {
-	c.JSON(code, BaseResponse{
-		Status:  "error",
-		Message: err.Error(),
-		Data:    nil,
-	})
+// verifiedEmailGuard returns RequireVerifiedEmail when group is restricted by the config, a no-op otherwise
+func verifiedEmailGuard(config Config, group string, userService domain.UserService) gin.HandlerFunc {
+	for _, restricted := range config.VerifiedEmailRequired {
+		if restricted == group {
+			return RequireVerifiedEmail(userService)
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.Next()
+	}
+}
+
+// rateLimitGuard returns a middleware applying the config rules of group to the requests whose method they cover.
+// With several matching rules the headers describe the last one checked.
+func rateLimitGuard(config Config, group string, limiter domain.RateLimiter) gin.HandlerFunc {
+	var rules []domain.RateLimitRule
+	for _, rule := range config.RateLimits {
+		if rule.Group == group {
+			rules = append(rules, rule)
+		}
+	}
+
+	return func(c *gin.Context) {
+		for _, rule := range rules {
+			if !rule.Covers(c.Request.Method) {
+				continue
+			}
+
+			if !checkRateLimit(c, limiter, rule.Name(), rule.Policy) {
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
 }
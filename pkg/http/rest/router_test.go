@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"encoding/json"
+	"final-project/pkg/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter builds the router without services, enough to list the routes
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	var (
+		userService         domain.UserService
+		authService         domain.AuthService
+		photoService        domain.PhotoService
+		commentService      domain.CommentService
+		socialMediaService  domain.SocialMediaService
+		exportService       domain.ExportService
+		importService       domain.ImportService
+		twoFactorService    domain.TwoFactorService
+		loginGuard          domain.LoginGuard
+		rateLimiter         domain.RateLimiter
+		apiKeyService       domain.APIKeyService
+		oidcService         domain.OIDCService
+		sessionService      domain.SessionService
+		webhookService      domain.WebhookService
+		streamHub           domain.StreamHub
+		notificationService domain.NotificationService
+		digestService       domain.DigestService
+		messageService      domain.MessageService
+		blockService        domain.BlockService
+	)
+	return NewRouter(
+		&userService,
+		&authService,
+		&photoService,
+		&commentService,
+		&socialMediaService,
+		&exportService,
+		&importService,
+		&twoFactorService,
+		&loginGuard,
+		&rateLimiter,
+		&apiKeyService,
+		&oidcService,
+		&sessionService,
+		&webhookService,
+		&streamHub,
+		&notificationService,
+		&digestService,
+		&messageService,
+		&blockService,
+		Config{},
+	)
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	_, drift := BuildOpenAPI(newTestRouter().Routes())
+	for _, problem := range drift {
+		t.Error(problem)
+	}
+}
+
+func TestOpenAPIReportsDrift(t *testing.T) {
+	// one route goes missing, another one has no docs
+	routes := gin.RoutesInfo{{Method: http.MethodGet, Path: "/undocumented"}}
+	for _, route := range newTestRouter().Routes() {
+		if route.Method+" "+route.Path != "POST /users/login" {
+			routes = append(routes, route)
+		}
+	}
+	_, drift := BuildOpenAPI(routes)
+
+	var undocumented, unregistered bool
+	for _, problem := range drift {
+		undocumented = undocumented || strings.Contains(problem, "GET /undocumented is not documented")
+		unregistered = unregistered || strings.Contains(problem, "POST /users/login is not registered")
+	}
+	if !undocumented || !unregistered {
+		t.Errorf("drift of both kinds expected, got %v", drift)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	w := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var spec struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Paths["/users/login"]; !ok {
+		t.Error("/users/login missing from the served document")
+	}
+}
+
+func TestValidationRunsAfterAuthentication(t *testing.T) {
+	r := newTestRouter()
+
+	// the body is invalid, yet the missing token is reported first
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/photos/", strings.NewReader(`{"title": 1}`)))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want 401", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	body := `{"email": "` + strings.Repeat("a", maxJSONBodySize) + `@example.com", "password": "secret"}`
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/login", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status %d, want 413", w.Code)
+	}
+}
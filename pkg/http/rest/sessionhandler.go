@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Current marks the session of the request
+	Current bool `json:"current"`
+}
+
+type SessionHandler struct {
+	sessionService domain.SessionService
+}
+
+func NewSessionHandler(sessionService domain.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// GetSessions is a handler for listing the devices the current user is logged in on
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	// Get currentUserID and currentSessionID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+	currentSessionID := c.GetUint("currentSessionID")
+
+	sessions, err := h.sessionService.GetSessions(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]SessionResponse, len(*sessions))
+	for i, session := range *sessions {
+		responses[i] = SessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		}
+	}
+
+	respondList(c, responses, domain.PageRequest{}, int64(len(responses)))
+}
+
+// RevokeSession is a handler for logging out a device, its token stops working right away
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	// Get id from path
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid session id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.sessionService.RevokeSession(currentUserID, uint(sessionID)); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Session has been revoked",
+	})
+}
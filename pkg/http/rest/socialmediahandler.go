@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"errors"
 	"final-project/pkg/domain"
 	"net/http"
 	"strconv"
@@ -15,6 +14,29 @@ type AddSocialMediaRequest struct {
 	SocialMediaUrl string `json:"social_media_url" binding:"required,max=512,url"`
 }
 
+type SocialMediaResponse struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	SocialMediaUrl string    `json:"social_media_url"`
+	UserID         uint      `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// legacyAddSocialMediaResponse is the version 1 response of AddSocialMedia
+type legacyAddSocialMediaResponse struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	SocialMediaUrl string    `json:"social_media_url"`
+	UserID         uint      `json:"user_id"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// legacySocialMediasResponse is the version 1 response of GetSocialMedias
+type legacySocialMediasResponse struct {
+	SocialMedias []SocialMediaOfUserResponse `json:"social_medias"`
+}
+
 type SocialMediaOfUserResponse struct {
 	ID             uint            `json:"id"`
 	Name           string          `json:"name"`
@@ -47,7 +69,7 @@ func (h *SocialMediaHandler) AddSocialMedia(c *gin.Context) {
 	// Bind the request body to the AddSocialMediaRequest struct
 	var req AddSocialMediaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
@@ -57,17 +79,17 @@ func (h *SocialMediaHandler) AddSocialMedia(c *gin.Context) {
 	// Save the social media
 	socialMedia, err := h.SocialMediaService.AddSocialMedia(currentUserID, req.Name, req.SocialMediaUrl)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send the response
-	c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":               socialMedia.ID,
-		"name":             socialMedia.Name,
-		"social_media_url": socialMedia.SocialMediaUrl,
-		"user_id":          socialMedia.UserID,
-		"createdAt":        socialMedia.CreatedAt,
+	respondVersioned(c, http.StatusCreated, formatSocialMedia(socialMedia), legacyAddSocialMediaResponse{
+		ID:             socialMedia.ID,
+		Name:           socialMedia.Name,
+		SocialMediaUrl: socialMedia.SocialMediaUrl,
+		UserID:         socialMedia.UserID,
+		CreatedAt:      socialMedia.CreatedAt,
 	})
 }
 
@@ -78,22 +100,29 @@ func (h *SocialMediaHandler) GetSocialMedias(c *gin.Context) {
 	// Get user
 	user, err := h.UserService.GetUserByID(currentUserID)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Get social medias
-	socialMedias, err := h.SocialMediaService.GetSocialMediasByUserID(currentUserID)
+	socialMedias, total, err := h.SocialMediaService.GetSocialMediasByUserID(currentUserID, page)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	res := formatSocialMediaOfUser(user, socialMedias)
 
 	// Send the response
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"social_medias": res,
+	respondListVersioned(c, res, page, total, legacySocialMediasResponse{
+		SocialMedias: res,
 	})
 }
 
@@ -101,14 +130,14 @@ func (h *SocialMediaHandler) UpdateSocialMedia(c *gin.Context) {
 	// Bind the request body to the AddSocialMediaRequest struct
 	var req AddSocialMediaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Get socialMediaID from URL
 	socialMediaID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid social media id"))
 		return
 	}
 
@@ -118,34 +147,31 @@ func (h *SocialMediaHandler) UpdateSocialMedia(c *gin.Context) {
 	// Check if social media userID is equal to current userID
 	socialMedia, err := h.SocialMediaService.GetSocialMediaByID(uint(socialMediaID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	if socialMedia.UserID != currentUserID {
-		SendErrorResponse(c, errors.New("insufficient privileges"), http.StatusUnauthorized)
+		SendErrorResponse(c, domain.ErrNotOwner)
 		return
 	}
 
 	// Update the social media
 	socialMedia, err = h.SocialMediaService.UpdateSocialMedia(uint(socialMediaID), req.Name, req.SocialMediaUrl)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send the response
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"name":             socialMedia.Name,
-		"social_media_url": socialMedia.SocialMediaUrl,
-	})
+	respond(c, http.StatusOK, formatSocialMedia(socialMedia))
 }
 
 func (h *SocialMediaHandler) DeleteSocialMedia(c *gin.Context) {
 	// Get socialMediaID from URL
 	socialMediaID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid social media id"))
 		return
 	}
 
@@ -155,24 +181,24 @@ func (h *SocialMediaHandler) DeleteSocialMedia(c *gin.Context) {
 	// Check if social media userID is equal to current userID
 	socialMedia, err := h.SocialMediaService.GetSocialMediaByID(uint(socialMediaID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	if socialMedia.UserID != currentUserID {
-		SendErrorResponse(c, errors.New("insufficient privileges"), http.StatusUnauthorized)
+		SendErrorResponse(c, domain.ErrNotOwner)
 		return
 	}
 
 	// Delete the social media
 	err = h.SocialMediaService.DeleteSocialMedia(uint(socialMediaID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
 	// Send the response
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Your social media has successfully been deleted",
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Your social media has successfully been deleted",
 	})
 }
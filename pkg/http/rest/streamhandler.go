@@ -0,0 +1,397 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"final-project/pkg/domain"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// notificationsTopic is the topic of the notifications of the current user
+	notificationsTopic = "notifications"
+	// streamKeepAlive is the interval of the SSE comments and WebSocket pings which keep idle
+	// connections open through proxies
+	streamKeepAlive = 25 * time.Second
+	// wsWriteTimeout bounds the writes to a WebSocket, a client that stops reading is dropped
+	wsWriteTimeout = 10 * time.Second
+)
+
+// StreamEvent documents the data of the SSE events and the WebSocket frames
+type StreamEvent struct {
+	// ID increases with every event of the server
+	ID    uint64 `json:"id,omitempty"`
+	Topic string `json:"topic,omitempty"`
+	// Event is the type of the event, e.g. comment.created, or subscribed and error
+	Event string `json:"event"`
+	// Data is the event as sent to webhooks
+	Data json.RawMessage `json:"data,omitempty"`
+	// Topics are the topics of the subscription, in subscribed events
+	Topics []string `json:"topics,omitempty"`
+	// Code and Message describe error events
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// StreamTicketResponse is a ticket opening a stream from its URL
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StreamCommand is a frame sent by WebSocket clients
+type StreamCommand struct {
+	// Action is subscribe or unsubscribe
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+type StreamHandler struct {
+	hub          domain.StreamHub
+	authService  domain.AuthService
+	photoService domain.PhotoService
+	blockService domain.BlockService
+	upgrader     websocket.Upgrader
+}
+
+func NewStreamHandler(hub domain.StreamHub, authService domain.AuthService, photoService domain.PhotoService, blockService domain.BlockService) *StreamHandler {
+	return &StreamHandler{
+		hub:          hub,
+		authService:  authService,
+		photoService: photoService,
+		blockService: blockService,
+		upgrader: websocket.Upgrader{
+			// Clients authenticate with the Authorization header or a ticket rather than cookies,
+			// so a page of another origin can't connect on behalf of the user
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// streamCaller is who opened a stream, read from the context once since the WebSocket commands
+// are applied after the handler hands the connection over
+type streamCaller struct {
+	userID uint
+	// scopes are nil for tokens, which have every scope
+	scopes []string
+}
+
+func newStreamCaller(c *gin.Context) streamCaller {
+	caller := streamCaller{userID: c.MustGet("currentUserID").(uint)}
+	if scopes, ok := c.Get("apiKeyScopes"); ok {
+		caller.scopes = append([]string{}, scopes.([]string)...)
+	}
+	return caller
+}
+
+func (caller streamCaller) hasScope(scope string) bool {
+	if caller.scopes == nil {
+		return true
+	}
+	for _, granted := range caller.scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTicket is a handler for requesting a ticket which opens a stream from its URL, for
+// browsers since EventSource and WebSocket requests can't have an Authorization header
+func (h *StreamHandler) CreateTicket(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	ticket := &domain.StreamTicket{UserID: currentUserID}
+	if scopes, ok := c.Get("apiKeyScopes"); ok {
+		ticket.Scopes = append([]string{}, scopes.([]string)...)
+	} else if claims, ok := c.Get("currentTokenClaims"); ok {
+		ticket.TokenVersion = claims.(*domain.TokenClaims).TokenVersion
+		ticket.SessionID = claims.(*domain.TokenClaims).SessionID
+	}
+
+	token, expiresAt, err := h.authService.GenerateStreamTicket(ticket)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, StreamTicketResponse{Ticket: token, ExpiresAt: expiresAt})
+}
+
+// Stream is a handler for following topics over Server-Sent Events, ?topic= is repeated per topic
+func (h *StreamHandler) Stream(c *gin.Context) {
+	caller := newStreamCaller(c)
+
+	// The events of users hidden from the current user are skipped, blocks and mutes made later
+	// apply from the next connection
+	hidden, err := h.blockService.HiddenUserIDs(caller.userID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	topics, err := h.resolveTopics(caller, hidden, c.QueryArray("topic"))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+	if len(topics) == 0 {
+		SendErrorResponse(c, domain.NewFieldValidationError("topic", "is required"))
+		return
+	}
+
+	sub, err := h.hub.Subscribe(caller.userID, topics)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	// Nginx buffers responses unless told otherwise
+	c.Header("X-Accel-Buffering", "no")
+	c.Render(http.StatusOK, sse.Event{Event: "subscribed", Data: StreamEvent{Event: "subscribed", Topics: c.QueryArray("topic")}})
+	// The client waits for the headers until the first flush
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					c.Render(-1, sse.Event{Event: "error", Data: streamError(err)})
+				}
+				return false
+			}
+			if hidden[msg.ActorID] {
+				return true
+			}
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatUint(msg.ID, 10),
+				Event: msg.Type,
+				Data:  streamMessage(msg),
+			})
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// StreamWebSocket is a handler for following topics over a WebSocket. The ?topic= query gives
+// the first topics, the client then sends subscribe and unsubscribe commands.
+func (h *StreamHandler) StreamWebSocket(c *gin.Context) {
+	caller := newStreamCaller(c)
+
+	hidden, err := h.blockService.HiddenUserIDs(caller.userID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	topics, err := h.resolveTopics(caller, hidden, c.QueryArray("topic"))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	sub, err := h.hub.Subscribe(caller.userID, topics)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already responded
+		return
+	}
+	// Replies to the commands are written by the loop below, the only writer of the connection.
+	// The reader is stopped before returning, closing the connection ends its read.
+	replies := make(chan StreamEvent, 8)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go h.readCommands(caller, conn, sub, hidden, replies, stop, done)
+	defer func() {
+		close(stop)
+		conn.Close()
+		<-done
+	}()
+
+	replies <- StreamEvent{Event: "subscribed", Topics: c.QueryArray("topic")}
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					writeFrame(conn, streamError(err))
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "subscription closed"), time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if hidden[msg.ActorID] {
+				continue
+			}
+			err = writeFrame(conn, streamMessage(msg))
+		case reply := <-replies:
+			err = writeFrame(conn, reply)
+		case <-keepAlive.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readCommands applies the commands of the client until the connection breaks or stop is closed,
+// then closes done
+func (h *StreamHandler) readCommands(caller streamCaller, conn *websocket.Conn, sub domain.StreamSubscription, hidden map[uint]bool, replies chan<- StreamEvent, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	// A client that answers neither pings nor sends anything is gone
+	readTimeout := 2 * streamKeepAlive
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	for {
+		var cmd StreamCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				return
+			}
+			cmd = StreamCommand{}
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		reply := h.applyCommand(caller, sub, hidden, cmd)
+		select {
+		case replies <- reply:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (h *StreamHandler) applyCommand(caller streamCaller, sub domain.StreamSubscription, hidden map[uint]bool, cmd StreamCommand) StreamEvent {
+	topics, err := h.resolveTopics(caller, hidden, cmd.Topics)
+	if err == nil && len(topics) == 0 {
+		err = domain.NewFieldValidationError("topics", "is required")
+	}
+	if err != nil {
+		return streamError(err)
+	}
+
+	switch cmd.Action {
+	case "subscribe":
+		if err := sub.Subscribe(topics); err != nil {
+			return streamError(err)
+		}
+		return StreamEvent{Event: "subscribed", Topics: cmd.Topics}
+	case "unsubscribe":
+		sub.Unsubscribe(topics)
+		return StreamEvent{Event: "unsubscribed", Topics: cmd.Topics}
+	}
+	return streamError(domain.NewFieldValidationError("action", "must be subscribe or unsubscribe"))
+}
+
+// resolveTopics maps the topics of a client to the topics of the hub once it checked the current
+// user may read them: "notifications" and "photos/<id>/comments" of photos not hidden from them
+func (h *StreamHandler) resolveTopics(caller streamCaller, hidden map[uint]bool, topics []string) ([]string, error) {
+	resolved := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if topic == notificationsTopic {
+			if !caller.hasScope(domain.ScopeProfileRead) {
+				return nil, domain.NewForbiddenError("insufficient_scope", "API key is missing the "+domain.ScopeProfileRead+" scope")
+			}
+			resolved = append(resolved, domain.NotificationsTopic(caller.userID))
+			continue
+		}
+
+		photoID, ok := parsePhotoCommentsTopic(topic)
+		if !ok {
+			return nil, domain.NewFieldValidationError("topic", "must be notifications or photos/<id>/comments, got "+strconv.Quote(topic))
+		}
+		if !caller.hasScope(domain.ScopeCommentsRead) {
+			return nil, domain.NewForbiddenError("insufficient_scope", "API key is missing the "+domain.ScopeCommentsRead+" scope")
+		}
+		// Check if photo exist
+		photo, err := h.photoService.GetPhotoByID(photoID)
+		if err != nil {
+			return nil, err
+		}
+		if hidden[photo.UserID] {
+			return nil, domain.ErrPhotoNotFound
+		}
+		resolved = append(resolved, domain.PhotoCommentsTopic(photoID))
+	}
+
+	return resolved, nil
+}
+
+func parsePhotoCommentsTopic(topic string) (uint, bool) {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 3 || parts[0] != "photos" || parts[2] != "comments" {
+		return 0, false
+	}
+	photoID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || photoID == 0 {
+		return 0, false
+	}
+	return uint(photoID), true
+}
+
+func streamMessage(msg domain.StreamMessage) StreamEvent {
+	// Clients know their notifications topic by its short name
+	topic := msg.Topic
+	if strings.HasPrefix(topic, "users/") && strings.HasSuffix(topic, "/notifications") {
+		topic = notificationsTopic
+	}
+
+	return StreamEvent{
+		ID:    msg.ID,
+		Topic: topic,
+		Event: msg.Type,
+		Data:  msg.Data,
+	}
+}
+
+// streamError describes an error like problem details do
+func streamError(err error) StreamEvent {
+	domainErr, _ := toDomainError(err)
+	return StreamEvent{
+		Event:   "error",
+		Code:    domainErr.Code,
+		Message: domainErr.Message,
+	}
+}
+
+func writeFrame(conn *websocket.Conn, event StreamEvent) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(event)
+}
@@ -0,0 +1,233 @@
+package rest
+
+import (
+	"encoding/json"
+	"final-project/pkg/auth"
+	"final-project/pkg/domain"
+	"final-project/pkg/stream"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Users have the token version in tokenVersions, API keys are "mgp_" followed by their scopes
+type fakeStreamUserService struct {
+	domain.UserService
+	tokenVersions map[uint]uint
+}
+
+func (s fakeStreamUserService) VerifyTokenClaims(claims *domain.TokenClaims) error {
+	if s.tokenVersions[claims.UserID] != claims.TokenVersion {
+		return domain.NewUnauthorizedError("token_revoked", "token has been revoked")
+	}
+	return nil
+}
+
+func (s fakeStreamUserService) GetUserByID(userID uint) (*domain.User, error) {
+	return &domain.User{ID: userID}, nil
+}
+
+type fakeStreamAPIKeyService struct {
+	domain.APIKeyService
+}
+
+func (fakeStreamAPIKeyService) IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, "mgp_")
+}
+
+func (fakeStreamAPIKeyService) Authenticate(key string) (*domain.APIKey, error) {
+	return &domain.APIKey{UserID: 1, Scopes: strings.Split(strings.TrimPrefix(key, "mgp_"), ",")}, nil
+}
+
+type fakeStreamPhotoService struct {
+	domain.PhotoService
+}
+
+func (fakeStreamPhotoService) GetPhotoByID(photoID uint) (*domain.Photo, error) {
+	return &domain.Photo{ID: photoID, UserID: 2}, nil
+}
+
+type fakeStreamBlockService struct {
+	domain.BlockService
+}
+
+func (fakeStreamBlockService) HiddenUserIDs(userID uint) (map[uint]bool, error) {
+	return map[uint]bool{}, nil
+}
+
+type streamTestServer struct {
+	*httptest.Server
+	hub         domain.StreamHub
+	authService domain.AuthService
+	users       fakeStreamUserService
+	// returned receives a value when a WebSocket handler returns
+	returned chan struct{}
+}
+
+func newStreamTestServer(t *testing.T) *streamTestServer {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "secret")
+	gin.SetMode(gin.TestMode)
+
+	s := &streamTestServer{
+		hub:         stream.NewHub(stream.Config{}),
+		authService: auth.NewAuthService(),
+		users:       fakeStreamUserService{tokenVersions: map[uint]uint{1: 1}},
+		returned:    make(chan struct{}, 1),
+	}
+	h := NewStreamHandler(s.hub, s.authService, fakeStreamPhotoService{}, fakeStreamBlockService{})
+	streamAuthMiddleware := StreamAuthMiddleware(s.authService, s.users, fakeStreamAPIKeyService{})
+
+	r := gin.New()
+	r.POST("/stream/tickets", AuthMiddleware(s.authService, s.users, fakeStreamAPIKeyService{}), h.CreateTicket)
+	r.GET("/stream", streamAuthMiddleware, h.Stream)
+	r.GET("/stream/ws", streamAuthMiddleware, func(c *gin.Context) {
+		h.StreamWebSocket(c)
+		s.returned <- struct{}{}
+	})
+	s.Server = httptest.NewServer(r)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *streamTestServer) token(t *testing.T, userID uint) string {
+	t.Helper()
+	token, err := s.authService.GenerateToken(&domain.TokenClaims{UserID: userID, TokenVersion: s.users.tokenVersions[userID]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (s *streamTestServer) ticket(t *testing.T, token string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/stream/tickets", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("got status %d, want 201", res.StatusCode)
+	}
+	var body StreamTicketResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Ticket == "" || time.Until(body.ExpiresAt) > time.Minute {
+		t.Fatalf("unexpected ticket %+v", body)
+	}
+	return body.Ticket
+}
+
+func (s *streamTestServer) dial(t *testing.T, query url.Values) *websocket.Conn {
+	t.Helper()
+	conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/stream/ws?"+query.Encode(), nil)
+	if err != nil {
+		status := 0
+		if res != nil {
+			status = res.StatusCode
+		}
+		t.Fatalf("dial: %v, status %d", err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) StreamEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event StreamEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func (s *streamTestServer) status(t *testing.T, path string) int {
+	t.Helper()
+	res, err := http.Get(s.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestStreamTickets(t *testing.T) {
+	s := newStreamTestServer(t)
+
+	token := s.token(t, 1)
+	ticket := s.ticket(t, token)
+	conn := s.dial(t, url.Values{"ticket": {ticket}, "topic": {"notifications"}})
+	if event := readFrame(t, conn); event.Event != "subscribed" {
+		t.Errorf("got %+v, want subscribed", event)
+	}
+
+	if status := s.status(t, "/stream?topic=notifications&ticket=forged"); status != http.StatusUnauthorized {
+		t.Errorf("forged ticket: got status %d", status)
+	}
+	// a ticket isn't an access token
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/stream/tickets", nil)
+	req.Header.Set("Authorization", "Bearer "+ticket)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ticket used as a token: got status %d", res.StatusCode)
+	}
+
+	// tickets of API keys keep their scopes
+	keyTicket := s.ticket(t, "mgp_comments:read")
+	if status := s.status(t, "/stream?topic=notifications&ticket="+keyTicket); status != http.StatusForbidden {
+		t.Errorf("ticket of a key without profile:read: got status %d", status)
+	}
+
+	// revoking the token revokes its tickets
+	s.users.tokenVersions[1] = 2
+	if status := s.status(t, "/stream?topic=notifications&ticket="+ticket); status != http.StatusUnauthorized {
+		t.Errorf("ticket of a revoked token: got status %d", status)
+	}
+}
+
+func TestStreamWebSocketCommands(t *testing.T) {
+	s := newStreamTestServer(t)
+	conn := s.dial(t, url.Values{"ticket": {s.ticket(t, s.token(t, 1))}})
+	readFrame(t, conn)
+
+	if err := conn.WriteJSON(StreamCommand{Action: "subscribe", Topics: []string{"photos/7/comments"}}); err != nil {
+		t.Fatal(err)
+	}
+	if event := readFrame(t, conn); event.Event != "subscribed" || len(event.Topics) != 1 {
+		t.Fatalf("got %+v, want subscribed", event)
+	}
+
+	s.hub.Publish(&domain.Event{Type: domain.EventCommentCreated, ActorID: 2, Data: &domain.Comment{ID: 1, PhotoID: 7, UserID: 2}})
+	if event := readFrame(t, conn); event.Event != domain.EventCommentCreated || event.Topic != "photos/7/comments" {
+		t.Errorf("got %+v, want the comment", event)
+	}
+
+	if err := conn.WriteJSON(StreamCommand{Action: "leave", Topics: []string{"photos/7/comments"}}); err != nil {
+		t.Fatal(err)
+	}
+	if event := readFrame(t, conn); event.Event != "error" || event.Code != domain.ErrCodeValidationFailed {
+		t.Errorf("got %+v, want a validation error", event)
+	}
+
+	// the handler returns once the client is gone, its reader stopped
+	conn.Close()
+	select {
+	case <-s.returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still running after the client left")
+	}
+}
@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"encoding/base64"
+	"final-project/pkg/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRCode is a PNG data URI
+	QRCode string `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorHandler struct {
+	twoFactorService domain.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService domain.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// Enroll is a handler for starting the two-factor enrollment, the QR code is returned as a PNG data URI
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	enrollment, err := h.twoFactorService.Enroll(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, TwoFactorEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCodePNG),
+	})
+}
+
+// Confirm is a handler for enabling two-factor authentication with the first code of the authenticator app
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	// Bind request body to TwoFactorCodeRequest struct
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	recoveryCodes, err := h.twoFactorService.Confirm(currentUserID, req.Code)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, RecoveryCodesResponse{
+		Message:       "Two-factor authentication has been enabled, store your recovery codes safely",
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// Disable is a handler for turning two-factor authentication off
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	// Bind request body to DisableTwoFactorRequest struct
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.twoFactorService.Disable(currentUserID, req.Password, req.Code); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Two-factor authentication has been disabled",
+	})
+}
+
+// RegenerateRecoveryCodes is a handler for replacing the recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	// Bind request body to TwoFactorCodeRequest struct
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	recoveryCodes, err := h.twoFactorService.RegenerateRecoveryCodes(currentUserID, req.Code)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, RecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	})
+}
@@ -1,8 +1,11 @@
 package rest
 
 import (
+	"errors"
 	"final-project/pkg/domain"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,15 +18,68 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
+	// Username accepts the email too
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
 type UpdateUserRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6,max=255"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6,max=255"`
+}
+
+type UserResponse struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Age           int       `json:"age"`
+	EmailVerified bool      `json:"email_verified"`
+	PendingEmail  string    `json:"pending_email"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LoginResponse has the token, or the challenge token when two-factor authentication is enabled
+type LoginResponse struct {
+	Token             string `json:"token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+type DeletionResponse struct {
+	Message             string     `json:"message"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+	Token   string `json:"token"`
+}
+
+type VerifyEmailResponse struct {
+	Message       string `json:"message"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
 type UserHandler struct {
 	userService domain.UserService
 }
@@ -39,7 +95,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	// Bind request body to RegisterRequest struct
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
@@ -51,16 +107,11 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Password: req.Password,
 	})
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"age":      user.Age,
-	})
+	respond(c, http.StatusCreated, formatUser(user))
 }
 
 // Login is a handler for user login end point
@@ -69,21 +120,46 @@ func (h *UserHandler) Login(c *gin.Context) {
 	// TODO: Add validation
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	token, err := h.userService.Login(&domain.LoginRequest{
-		Username: req.Username,
-		Password: req.Password,
+	result, err := h.userService.Login(&domain.LoginRequest{
+		Username:  req.Username,
+		Password:  req.Password,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		sendLoginErrorResponse(c, err)
+		return
+	}
+
+	sendLoginResult(c, result)
+}
+
+// LoginTwoFactor is a handler for the second step of a login with two-factor authentication
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	// Bind request body to TwoFactorLoginRequest struct
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	token, err := h.userService.LoginTwoFactor(&domain.TwoFactorLoginRequest{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
 	})
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		sendLoginErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"token": token,
+	respond(c, http.StatusOK, LoginResponse{
+		Token: *token,
 	})
 }
 
@@ -92,7 +168,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	// Bind request body to UpdateUserRequest struct
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
@@ -105,29 +181,51 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		Email:    req.Email,
 	})
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"email":    user.Email,
-		"username": user.Username,
-	})
-
+	respond(c, http.StatusOK, formatUser(user))
 }
 
+// DeleteUser is a handler for requesting account deletion, the account is purged after the grace period
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// Get userID from context
 	currentUserID := c.MustGet("currentUserID").(uint)
 
-	err := h.userService.DeleteUser(uint(currentUserID))
+	user, err := h.userService.RequestDeletion(uint(currentUserID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]string{
-		"message": "Your account has been successfully deleted",
+	respond(c, http.StatusOK, DeletionResponse{
+		Message:             "Your account has been scheduled for deletion",
+		DeletionScheduledAt: user.DeletionScheduledAt,
+	})
+}
+
+// CancelDeletion is a handler for restoring an account pending deletion
+func (h *UserHandler) CancelDeletion(c *gin.Context) {
+	// Bind request body to LoginRequest struct
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	err := h.userService.CancelDeletion(&domain.LoginRequest{
+		Username: req.Username,
+		Password: req.Password,
+		IP:       c.ClientIP(),
+	})
+	if err != nil {
+		sendLoginErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Your account deletion has been cancelled",
 	})
 }
 
@@ -137,13 +235,142 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 	user, err := h.userService.GetUserByID(uint(currentUserID))
 	if err != nil {
-		SendErrorResponse(c, err, http.StatusBadRequest)
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, formatUser(user))
+}
+
+// ChangePassword is a handler for changing the password of the current user, other sessions are logged out
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	// Bind request body to ChangePasswordRequest struct
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"id":       user.ID,
-		"email":    user.Email,
-		"username": user.Username,
+	// Get userID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	token, err := h.userService.ChangePassword(currentUserID, &domain.ChangePasswordRequest{
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+		SessionID:       c.GetUint("currentSessionID"),
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, ChangePasswordResponse{
+		Message: "Your password has been changed",
+		Token:   *token,
 	})
 }
+
+// ForgotPassword is a handler for requesting a password reset email
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	// Bind request body to ForgotPasswordRequest struct
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(req.Email); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Same response whether the email is registered or not
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword is a handler for choosing a new password with a reset token
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	// Bind request body to ResetPasswordRequest struct
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	err := h.userService.ResetPassword(&domain.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Your password has been reset, please log in again",
+	})
+}
+
+// VerifyEmail is a handler for the link sent by email to confirm an email address
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		SendErrorResponse(c, domain.NewFieldValidationError("token", "is required"))
+		return
+	}
+
+	user, err := h.userService.VerifyEmail(token)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, VerifyEmailResponse{
+		Message:       "Your email has been verified",
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+	})
+}
+
+// ResendEmailVerification is a handler for sending a new email verification link
+func (h *UserHandler) ResendEmailVerification(c *gin.Context) {
+	// Get userID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.userService.ResendEmailVerification(currentUserID); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "A new verification link has been sent",
+	})
+}
+
+// sendLoginResult sends the token, or the challenge token when two-factor authentication is enabled
+func sendLoginResult(c *gin.Context, result *domain.LoginResult) {
+	// The challenge token has to be sent with a code to /users/login/2fa
+	if result.TwoFactorRequired {
+		respond(c, http.StatusOK, LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    result.ChallengeToken,
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, LoginResponse{
+		Token: result.Token,
+	})
+}
+
+// sendLoginErrorResponse adds Retry-After to the error response of lockouts
+func sendLoginErrorResponse(c *gin.Context, err error) {
+	var lockedErr *domain.LockedError
+	if errors.As(err, &lockedErr) {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(lockedErr.RetryAfter)))
+	}
+
+	SendErrorResponse(c, err)
+}
@@ -0,0 +1,493 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxJSONBodySize is the largest JSON request body read
+const maxJSONBodySize = 1 << 20
+
+var errBodyTooLarge = domain.NewTooLargeError("body_too_large", "request body is larger than 1 MB")
+
+// specPathParam matches the OpenAPI path parameters, e.g. {id}
+var specPathParam = regexp.MustCompile(`\{([a-zA-Z_]+)\}`)
+
+// openAPIValidator checks requests, and responses when enabled, against the operations of the
+// OpenAPI document. The document is generated once every route is registered, so the middleware
+// is added first and the document loaded afterwards.
+type openAPIValidator struct {
+	schemas    map[string]interface{}
+	operations map[string]map[string]interface{}
+	responses  bool
+}
+
+func newOpenAPIValidator(validateResponses bool) *openAPIValidator {
+	return &openAPIValidator{responses: validateResponses}
+}
+
+// load indexes the operations of spec by method and gin path, e.g. "PUT /photos/:id"
+func (v *openAPIValidator) load(spec map[string]interface{}) {
+	v.schemas = spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	v.operations = make(map[string]map[string]interface{})
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		ginPath := specPathParam.ReplaceAllString(path, ":$1")
+		for method, operation := range item.(map[string]interface{}) {
+			v.operations[strings.ToUpper(method)+" "+ginPath] = operation.(map[string]interface{})
+		}
+	}
+}
+
+// Gin middleware to validate the path and query parameters and the JSON body of requests against
+// the OpenAPI document. Invalid requests get a validation_failed error listing every invalid field.
+func (v *openAPIValidator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation, ok := v.operations[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if err := v.validateRequest(c, operation); err != nil {
+			SendErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+
+		// Streams are never complete enough to validate
+		if streaming, _ := operation["x-streaming"].(bool); streaming || !v.responses {
+			c.Next()
+			return
+		}
+		v.validateResponse(c, operation)
+	}
+}
+
+func (v *openAPIValidator) validateRequest(c *gin.Context, operation map[string]interface{}) error {
+	var fields []domain.FieldError
+
+	params, _ := operation["parameters"].([]interface{})
+	for _, param := range params {
+		param := param.(map[string]interface{})
+		name := param["name"].(string)
+		schema := param["schema"].(map[string]interface{})
+
+		var raw string
+		var present bool
+		switch param["in"] {
+		case "path":
+			raw, present = c.Param(name), true
+		case "query":
+			raw, present = c.GetQuery(name)
+		}
+		if !present {
+			if required, _ := param["required"].(bool); required {
+				fields = append(fields, domain.FieldError{Field: name, Message: "is required"})
+			}
+			continue
+		}
+
+		value, ok := parseParam(raw, schemaType(schema))
+		if !ok {
+			fields = append(fields, domain.FieldError{Field: name, Message: typeDescription(schemaType(schema))})
+			continue
+		}
+		fields = v.validate(schema, value, name, fields)
+	}
+
+	if body, ok := operation["requestBody"].(map[string]interface{}); ok {
+		content := body["content"].(map[string]interface{})
+		if media, ok := content["application/json"].(map[string]interface{}); ok {
+			value, err := readJSONBody(c)
+			if err != nil {
+				return err
+			}
+			fields = v.validate(media["schema"].(map[string]interface{}), value, "", fields)
+		}
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(domain.ErrCodeValidationFailed, "request has invalid fields", fields...)
+	}
+	return nil
+}
+
+// readJSONBody decodes the request body and puts it back for the handler
+func readJSONBody(c *gin.Context) (interface{}, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxJSONBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, errBodyTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, domain.NewValidationError("malformed_body", "request body is not valid JSON")
+	}
+	return value, nil
+}
+
+// parseParam converts a path or query parameter to the JSON value of its schema type
+func parseParam(raw string, schemaType string) (interface{}, bool) {
+	switch schemaType {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(raw), true
+	case "boolean":
+		value, err := strconv.ParseBool(raw)
+		return value, err == nil
+	}
+	return raw, true
+}
+
+// responseRecorder holds the response back until it is validated
+type responseRecorder struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *responseRecorder) WriteHeaderNow() {}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *responseRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *responseRecorder) Size() int {
+	return w.body.Len()
+}
+
+func (w *responseRecorder) Written() bool {
+	return w.status != 0 || w.body.Len() > 0
+}
+
+// validateResponse runs the handler and checks its response against the documented ones. A response
+// breaking the contract is logged and replaced with an internal error so tests notice it.
+func (v *openAPIValidator) validateResponse(c *gin.Context, operation map[string]interface{}) {
+	writer := c.Writer
+	recorder := &responseRecorder{ResponseWriter: writer}
+	c.Writer = recorder
+	c.Next()
+	c.Writer = writer
+
+	if err := v.checkResponse(recorder, operation); err != nil {
+		log.Printf("openapi: %s %s: %v", c.Request.Method, c.FullPath(), err)
+		writer.Header().Del("Content-Type")
+		writer.Header().Del("X-Total-Count")
+		sendProblem(c, http.StatusInternalServerError, &domain.Error{Code: "response_contract_violation", Message: err.Error()})
+		return
+	}
+
+	writer.WriteHeader(recorder.Status())
+	writer.Write(recorder.body.Bytes())
+}
+
+func (v *openAPIValidator) checkResponse(recorder *responseRecorder, operation map[string]interface{}) error {
+	responses := operation["responses"].(map[string]interface{})
+	response, ok := responses[strconv.Itoa(recorder.Status())].(map[string]interface{})
+	if !ok {
+		if response, ok = responses["default"].(map[string]interface{}); !ok || recorder.Status() < http.StatusBadRequest {
+			return fmt.Errorf("status %d is not documented", recorder.Status())
+		}
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", mediaType, recorder.Status())
+	}
+	schema, ok := media["schema"].(map[string]interface{})
+	if !ok || !strings.Contains(mediaType, "json") {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(recorder.body.Bytes()))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	if fields := v.validate(schema, value, "", nil); len(fields) > 0 {
+		messages := make([]string, 0, len(fields))
+		for _, field := range fields {
+			messages = append(messages, field.Field+" "+field.Message)
+		}
+		return errors.New("body " + strings.Join(messages, ", "))
+	}
+	return nil
+}
+
+// validate appends the violations of schema by value to fields, field is the path of value in the body
+func (v *openAPIValidator) validate(schema map[string]interface{}, value interface{}, field string, fields []domain.FieldError) []domain.FieldError {
+	schema = v.resolve(schema)
+	fail := func(message string) []domain.FieldError {
+		name := field
+		if name == "" {
+			name = "body"
+		}
+		return append(fields, domain.FieldError{Field: name, Message: message})
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		var first []domain.FieldError
+		for i, option := range oneOf {
+			optionFields := v.validate(option.(map[string]interface{}), value, field, nil)
+			if len(optionFields) == 0 {
+				return fields
+			}
+			if i == 0 {
+				first = optionFields
+			}
+		}
+		return append(fields, first...)
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesType(types, value) {
+		return fail(typeDescription(types[0]))
+	}
+
+	switch value := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(value)
+		if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+			return fail(fmt.Sprintf("must be at least %s characters", formatNumber(min)))
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+			return fail(fmt.Sprintf("must be at most %s characters", formatNumber(max)))
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+			options := make([]string, 0, len(enum))
+			for _, option := range enum {
+				options = append(options, fmt.Sprint(option))
+			}
+			return fail("must be one of " + strings.Join(options, ", "))
+		}
+		if format, ok := schema["format"].(string); ok && !matchesFormat(format, value) {
+			return fail(map[string]string{
+				"email":     "must be a valid email",
+				"uri":       "must be a valid url",
+				"date-time": "must be an RFC 3339 date-time",
+			}[format])
+		}
+	case json.Number:
+		number, _ := value.Float64()
+		if min, ok := schemaNumber(schema, "minimum"); ok && number < min {
+			return fail("must be at least " + formatNumber(min))
+		}
+		if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= min {
+			return fail("must be greater than " + formatNumber(min))
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && number > max {
+			return fail("must be at most " + formatNumber(max))
+		}
+		if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= max {
+			return fail("must be less than " + formatNumber(max))
+		}
+	case []interface{}:
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < min {
+			return fail(fmt.Sprintf("must have at least %s items", formatNumber(min)))
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > max {
+			return fail(fmt.Sprintf("must have at most %s items", formatNumber(max)))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				fields = v.validate(items, item, fmt.Sprintf("%s[%d]", field, i), fields)
+			}
+		}
+	case map[string]interface{}:
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, ok := value[name]; !ok {
+				fields = append(fields, domain.FieldError{Field: joinField(field, name), Message: "is required"})
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				fields = v.validate(property, value[name], joinField(field, name), fields)
+			}
+		}
+	}
+
+	return fields
+}
+
+// resolve follows the $ref of a schema to the components
+func (v *openAPIValidator) resolve(schema map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		schema = v.schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]interface{})
+	}
+}
+
+func schemaType(schema map[string]interface{}) string {
+	if types := schemaTypes(schema); len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// schemaTypes lists the types of a schema, nullable ones have a type array
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			types = append(types, fmt.Sprint(item))
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch value := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if _, err := value.Int64(); err == nil && t == "integer" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeDescription describes a JSON schema type like typeMessage does for Go kinds
+func typeDescription(schemaType string) string {
+	switch schemaType {
+	case "integer":
+		return "must be an integer"
+	case "number":
+		return "must be a number"
+	case "array":
+		return "must be an array"
+	case "object":
+		return "must be an object"
+	}
+	return "must be a " + schemaType
+}
+
+func matchesFormat(format string, value string) bool {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uri":
+		u, err := url.ParseRequestURI(value)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	}
+	return true
+}
+
+// schemaNumber reads a numeric keyword, the generated document holds Go ints and floats
+func schemaNumber(schema map[string]interface{}, keyword string) (float64, bool) {
+	switch value := schema[keyword].(type) {
+	case int:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, option := range values {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinField(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"final-project/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=512,url"`
+	Events []string `json:"events" binding:"required"`
+	// Secret signs the deliveries, one is generated when it is left out
+	Secret string `json:"secret"`
+	// AllUsers subscribes to the events of every account, for administrators
+	AllUsers bool `json:"all_users"`
+}
+
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	AllUsers  bool      `json:"all_users"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWebhookResponse struct {
+	Message string `json:"message"`
+	// Secret is only returned when the webhook is created
+	Secret  string          `json:"secret"`
+	Webhook WebhookResponse `json:"webhook"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID        uint   `json:"id"`
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	// Payload is the JSON body sent to the webhook
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type WebhookHandler struct {
+	webhookService domain.WebhookService
+}
+
+func NewWebhookHandler(webhookService domain.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook is a handler for subscribing a URL to events, the secret is only returned here
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	// Bind request body to CreateWebhookRequest struct
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	webhook, err := h.webhookService.CreateWebhook(currentUserID, &domain.CreateWebhookRequest{
+		URL:      req.URL,
+		Secret:   req.Secret,
+		Events:   req.Events,
+		AllUsers: req.AllUsers,
+	})
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, CreateWebhookResponse{
+		Message: "Store the secret safely to verify the signatures, it won't be shown again",
+		Secret:  webhook.Secret,
+		Webhook: formatWebhook(webhook),
+	})
+}
+
+// GetWebhooks is a handler for listing the webhooks of the current user
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	webhooks, err := h.webhookService.GetWebhooks(currentUserID)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]WebhookResponse, len(*webhooks))
+	for i, webhook := range *webhooks {
+		responses[i] = formatWebhook(&webhook)
+	}
+
+	// The number of webhooks is capped, they are listed on one page
+	respondList(c, responses, domain.PageRequest{}, int64(len(responses)))
+}
+
+// DeleteWebhook is a handler for deleting a webhook of the current user, its pending deliveries are dropped
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	// Get id from path
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid webhook id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	if err := h.webhookService.DeleteWebhook(currentUserID, uint(webhookID)); err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, MessageResponse{
+		Message: "Webhook has been deleted",
+	})
+}
+
+// GetDeadLetters is a handler for listing the deliveries of a webhook which failed every attempt
+func (h *WebhookHandler) GetDeadLetters(c *gin.Context) {
+	// Get id from path
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid webhook id"))
+		return
+	}
+
+	// Get page from query
+	page, err := parsePageRequest(c)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	deliveries, total, err := h.webhookService.GetDeadLetters(currentUserID, uint(webhookID), page)
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]WebhookDeliveryResponse, len(*deliveries))
+	for i, delivery := range *deliveries {
+		responses[i] = formatWebhookDelivery(&delivery)
+	}
+
+	respondList(c, responses, page, total)
+}
+
+// Redeliver is a handler for sending a dead or delivered delivery again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	// Get ids from path
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid webhook id"))
+		return
+	}
+	deliveryID, err := strconv.Atoi(c.Param("delivery_id"))
+	if err != nil {
+		SendErrorResponse(c, domain.NewValidationError("invalid_id", "invalid delivery id"))
+		return
+	}
+
+	// Get currentUserID from context
+	currentUserID := c.MustGet("currentUserID").(uint)
+
+	delivery, err := h.webhookService.Redeliver(currentUserID, uint(webhookID), uint(deliveryID))
+	if err != nil {
+		SendErrorResponse(c, err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, formatWebhookDelivery(delivery))
+}
+
+func formatWebhook(webhook *domain.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		AllUsers:  webhook.AllUsers,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+func formatWebhookDelivery(delivery *domain.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+}
@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the archive so a ZIP bomb fills neither the disk nor the memory
+const (
+	maxArchiveEntries = 50000
+	// maxArchiveSize is the uncompressed size of every file together
+	maxArchiveSize = 8 << 30
+	maxJSONSize    = 64 << 20
+	maxMediaSize   = 512 << 20
+)
+
+var (
+	postsFileName        = regexp.MustCompile(`^posts_\d+\.json$`)
+	commentsFileName     = regexp.MustCompile(`^post_comments(_\d+)?\.json$`)
+	personalInfoFileName = "personal_information.json"
+)
+
+// instagramArchive is the content of an Instagram "Download your information" export in JSON format
+type instagramArchive struct {
+	files    []*zip.File
+	owner    string
+	posts    []instagramPost
+	comments []instagramComment
+}
+
+type instagramPost struct {
+	Media             []instagramMedia `json:"media"`
+	Title             string           `json:"title"`
+	CreationTimestamp int64            `json:"creation_timestamp"`
+}
+
+type instagramMedia struct {
+	URI               string `json:"uri"`
+	Title             string `json:"title"`
+	CreationTimestamp int64  `json:"creation_timestamp"`
+}
+
+type instagramComment struct {
+	Message    string
+	MediaOwner string
+	// MediaURI is the media the comment is on, older exports don't have it
+	MediaURI  string
+	CreatedAt time.Time
+}
+
+type instagramStringMap struct {
+	StringMapData map[string]instagramValue `json:"string_map_data"`
+}
+
+type instagramCommentEntry struct {
+	MediaListData []struct {
+		URI string `json:"uri"`
+	} `json:"media_list_data"`
+	StringMapData map[string]instagramValue `json:"string_map_data"`
+}
+
+type instagramValue struct {
+	Value     string `json:"value"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// readInstagramArchive parses the posts, comments and owner username of the archive.
+// Files are matched by name anywhere in the ZIP since the folder layout changed over the years.
+func readInstagramArchive(zr *zip.Reader) (*instagramArchive, error) {
+	if len(zr.File) > maxArchiveEntries {
+		return nil, fmt.Errorf("archive has more than %d files", maxArchiveEntries)
+	}
+	var size uint64
+	for _, f := range zr.File {
+		size += f.UncompressedSize64
+		if size > maxArchiveSize {
+			return nil, fmt.Errorf("archive is larger than %d GB uncompressed", maxArchiveSize>>30)
+		}
+	}
+
+	archive := &instagramArchive{
+		files: zr.File,
+	}
+
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+
+		var err error
+		switch {
+		case postsFileName.MatchString(name):
+			var posts []instagramPost
+			if err = decodeZipJSON(f, &posts); err == nil {
+				archive.posts = append(archive.posts, posts...)
+			}
+		case commentsFileName.MatchString(name):
+			var comments []instagramComment
+			if comments, err = decodeInstagramComments(f); err == nil {
+				archive.comments = append(archive.comments, comments...)
+			}
+		case name == personalInfoFileName:
+			archive.owner, err = decodeInstagramOwner(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(archive.posts) == 0 {
+		return nil, errors.New("no posts found, expected a posts_1.json file in the archive")
+	}
+
+	// Import oldest first so the photos keep the order they were posted in
+	sort.SliceStable(archive.posts, func(i, j int) bool {
+		return archive.posts[i].createdAt() < archive.posts[j].createdAt()
+	})
+	sort.SliceStable(archive.comments, func(i, j int) bool {
+		return archive.comments[i].CreatedAt.Before(archive.comments[j].CreatedAt)
+	})
+
+	return archive, nil
+}
+
+// createdAt returns the post timestamp, single media posts often only carry it on the media
+func (p instagramPost) createdAt() int64 {
+	if p.CreationTimestamp == 0 && len(p.Media) > 0 {
+		return p.Media[0].CreationTimestamp
+	}
+	return p.CreationTimestamp
+}
+
+// openMedia opens a media file referenced by uri, exports wrapped in a top level folder are supported
+func (a *instagramArchive) openMedia(uri string) (io.ReadCloser, error) {
+	uri = mediaKey(uri)
+	for _, f := range a.files {
+		if f.Name == uri || strings.HasSuffix(f.Name, "/"+uri) {
+			return openZipFile(f, maxMediaSize)
+		}
+	}
+	return nil, errors.New("media file not found in archive: " + uri)
+}
+
+// mediaKey is how media uris are compared, comments and posts don't always agree on the leading slash
+func mediaKey(uri string) string {
+	return strings.TrimPrefix(uri, "/")
+}
+
+// openZipFile opens a file of the archive smaller than limit bytes. Its content is read through a
+// limit too rather than trusting the size in its header.
+func openZipFile(f *zip.File, limit int64) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is larger than %d MB", f.Name, limit>>20)
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(r, limit), r}, nil
+}
+
+func decodeZipJSON(f *zip.File, v interface{}) error {
+	r, err := openZipFile(f, maxJSONSize)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return json.NewDecoder(r).Decode(v)
+}
+
+func decodeInstagramComments(f *zip.File) ([]instagramComment, error) {
+	r, err := openZipFile(f, maxJSONSize)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older exports wrap the list in a "comments_media_comments" object
+	var entries []instagramCommentEntry
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped struct {
+			Comments []instagramCommentEntry `json:"comments_media_comments"`
+		}
+		err = json.Unmarshal(trimmed, &wrapped)
+		entries = wrapped.Comments
+	} else {
+		err = json.Unmarshal(trimmed, &entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]instagramComment, 0, len(entries))
+	for _, entry := range entries {
+		data := entry.StringMapData
+		comment := instagramComment{
+			Message:    fixInstagramText(data["Comment"].Value),
+			MediaOwner: fixInstagramText(data["Media Owner"].Value),
+			CreatedAt:  time.Unix(data["Time"].Timestamp, 0),
+		}
+		if len(entry.MediaListData) > 0 {
+			comment.MediaURI = entry.MediaListData[0].URI
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func decodeInstagramOwner(f *zip.File) (string, error) {
+	var info struct {
+		ProfileUser []instagramStringMap `json:"profile_user"`
+	}
+	if err := decodeZipJSON(f, &info); err != nil {
+		return "", err
+	}
+
+	for _, profile := range info.ProfileUser {
+		if username := profile.StringMapData["Username"].Value; username != "" {
+			return fixInstagramText(username), nil
+		}
+	}
+	return "", nil
+}
+
+// fixInstagramText repairs text of Instagram exports, which escape each UTF-8 byte as a separate code point
+func fixInstagramText(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return s
+		}
+		b = append(b, byte(r))
+	}
+
+	if !utf8.Valid(b) {
+		return s
+	}
+	return string(b)
+}
@@ -0,0 +1,280 @@
+package importer
+
+import (
+	"archive/zip"
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	SourceInstagram = "instagram"
+
+	defaultTitle = "Imported from Instagram"
+
+	// The job is saved every saveEvery items or saveInterval, whichever comes first, and once done
+	saveEvery    = 50
+	saveInterval = 2 * time.Second
+)
+
+// mediaExtensions are the media types imported. The media store serves files by their extension, so
+// anything else, like HTML or SVG, would run scripts on the site.
+var mediaExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+	".heic": true,
+	".mp4":  true,
+	".mov":  true,
+}
+
+type service struct {
+	repo           domain.ImportRepository
+	photoService   domain.PhotoService
+	commentService domain.CommentService
+	mediaStore     domain.MediaStore
+}
+
+func NewService(
+	repo domain.ImportRepository,
+	photoService domain.PhotoService,
+	commentService domain.CommentService,
+	mediaStore domain.MediaStore,
+) domain.ImportService {
+	return &service{
+		repo:           repo,
+		photoService:   photoService,
+		commentService: commentService,
+		mediaStore:     mediaStore,
+	}
+}
+
+func (s *service) StartImport(userID uint, archivePath string) (*domain.ImportJob, error) {
+	job, err := s.newJob(userID)
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
+
+	go func(job domain.ImportJob) {
+		defer os.Remove(archivePath)
+		s.run(&job, archivePath, nil)
+	}(*job)
+
+	return job, nil
+}
+
+func (s *service) RunImport(userID uint, archivePath string, progress func(job *domain.ImportJob)) (*domain.ImportJob, error) {
+	job, err := s.newJob(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.run(job, archivePath, progress)
+	if job.Status == domain.ImportStatusFailed {
+		return job, errors.New(job.Errors[len(job.Errors)-1].Message)
+	}
+
+	return job, nil
+}
+
+func (s *service) GetImportJob(userID uint, jobID uint) (*domain.ImportJob, error) {
+	job, err := s.repo.GetImportJobByID(jobID)
+	if err != nil || job.UserID != userID {
+		return nil, domain.ErrImportNotFound
+	}
+
+	return job, nil
+}
+
+func (s *service) newJob(userID uint) (*domain.ImportJob, error) {
+	return s.repo.SaveImportJob(&domain.ImportJob{
+		UserID: userID,
+		Source: SourceInstagram,
+		Status: domain.ImportStatusPending,
+	})
+}
+
+func (s *service) run(job *domain.ImportJob, archivePath string, progress func(job *domain.ImportJob)) {
+	lastSaved := time.Now()
+	save := func() {
+		if _, err := s.repo.UpdateImportJob(job); err != nil {
+			log.Printf("failed to update import %d: %v", job.ID, err)
+		}
+		lastSaved = time.Now()
+		if progress != nil {
+			progress(job)
+		}
+	}
+	// report saves the progress of large archives now and then rather than after every item
+	report := func() {
+		if job.Processed%saveEvery == 0 || time.Since(lastSaved) >= saveInterval {
+			save()
+		} else if progress != nil {
+			progress(job)
+		}
+	}
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		s.fail(job, fmt.Errorf("cannot open archive: %w", err))
+		save()
+		return
+	}
+	defer zr.Close()
+
+	archive, err := readInstagramArchive(&zr.Reader)
+	if err != nil {
+		s.fail(job, fmt.Errorf("cannot read archive: %w", err))
+		save()
+		return
+	}
+
+	for _, post := range archive.posts {
+		job.Total += len(post.Media)
+	}
+	job.Total += len(archive.comments)
+	job.Status = domain.ImportStatusRunning
+	save()
+
+	// Photos imported so far by the uri of their media, comments reference the media they are on
+	photoIDs := map[string]uint{}
+
+	for _, post := range archive.posts {
+		for i, media := range post.Media {
+			item := fmt.Sprintf("post %s", media.URI)
+			photo, err := s.importMedia(job.UserID, archive, post, i)
+			s.record(job, item, err)
+			if err == nil {
+				photoIDs[mediaKey(media.URI)] = photo.ID
+			}
+			report()
+		}
+	}
+
+	for i, comment := range archive.comments {
+		item := fmt.Sprintf("comment %d", i+1)
+		s.record(job, item, s.importComment(job.UserID, archive.owner, photoIDs, comment))
+		report()
+	}
+
+	job.Status = domain.ImportStatusCompleted
+	save()
+}
+
+// importMedia stores one media item of a post and saves it as a photo through the photo service
+func (s *service) importMedia(userID uint, archive *instagramArchive, post instagramPost, index int) (*domain.Photo, error) {
+	media := post.Media[index]
+
+	caption := fixInstagramText(post.Title)
+	if caption == "" {
+		caption = fixInstagramText(media.Title)
+	}
+
+	createdAt := media.CreationTimestamp
+	if createdAt == 0 {
+		createdAt = post.createdAt()
+	}
+
+	title := titleFromCaption(caption)
+	if len(post.Media) > 1 {
+		title = truncate(title, 245) + fmt.Sprintf(" (%d/%d)", index+1, len(post.Media))
+	}
+
+	fileName := path.Base(media.URI)
+	if !mediaExtensions[strings.ToLower(path.Ext(fileName))] {
+		return nil, fmt.Errorf("unsupported media type %q, only images and videos are imported", path.Ext(fileName))
+	}
+
+	file, err := archive.openMedia(media.URI)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	photoUrl, err := s.mediaStore.Save(fileName, file)
+	if err != nil {
+		return nil, err
+	}
+
+	photo, err := s.photoService.SavePhoto(userID, &domain.AddPhotoRequest{
+		Title:     title,
+		Caption:   truncate(caption, 2048),
+		PhotoUrl:  photoUrl,
+		CreatedAt: time.Unix(createdAt, 0),
+	})
+	if err != nil {
+		s.mediaStore.Delete(photoUrl)
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// importComment adds a comment the owner made on one of their own posts to the photo of the media
+// it references, comments without a reference are skipped rather than guessed
+func (s *service) importComment(userID uint, owner string, photoIDs map[string]uint, comment instagramComment) error {
+	if owner == "" || !strings.EqualFold(comment.MediaOwner, owner) {
+		return errors.New("only comments on your own posts can be imported")
+	}
+
+	if comment.MediaURI == "" {
+		return errors.New("comment doesn't reference its post")
+	}
+	photoID, ok := photoIDs[mediaKey(comment.MediaURI)]
+	if !ok {
+		return errors.New("the post of this comment was not imported")
+	}
+
+	if strings.TrimSpace(comment.Message) == "" {
+		return errors.New("comment is empty")
+	}
+
+	_, err := s.commentService.AddComment(userID, photoID, truncate(comment.Message, 2048))
+	return err
+}
+
+func (s *service) record(job *domain.ImportJob, item string, err error) {
+	job.Processed++
+	if err != nil {
+		job.Failed++
+		job.Errors = append(job.Errors, domain.ImportItemError{
+			Item:    item,
+			Message: err.Error(),
+		})
+		return
+	}
+	job.Imported++
+}
+
+func (s *service) fail(job *domain.ImportJob, err error) {
+	job.Status = domain.ImportStatusFailed
+	job.Errors = append(job.Errors, domain.ImportItemError{
+		Item:    "archive",
+		Message: err.Error(),
+	})
+}
+
+// titleFromCaption uses the first line of the caption as photo title
+func titleFromCaption(caption string) string {
+	title := strings.TrimSpace(strings.SplitN(caption, "\n", 2)[0])
+	if title == "" {
+		return defaultTitle
+	}
+	return truncate(title, 255)
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,213 @@
+package importer
+
+import (
+	"archive/zip"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeImportRepo struct {
+	updates int
+	saved   domain.ImportJob
+}
+
+func (r *fakeImportRepo) SaveImportJob(job *domain.ImportJob) (*domain.ImportJob, error) {
+	job.ID = 1
+	return job, nil
+}
+
+func (r *fakeImportRepo) GetImportJobByID(jobID uint) (*domain.ImportJob, error) {
+	return &r.saved, nil
+}
+
+func (r *fakeImportRepo) UpdateImportJob(job *domain.ImportJob) (*domain.ImportJob, error) {
+	r.updates++
+	r.saved = *job
+	return job, nil
+}
+
+type fakePhotoService struct {
+	domain.PhotoService
+	photos []domain.Photo
+}
+
+func (s *fakePhotoService) SavePhoto(userID uint, req *domain.AddPhotoRequest) (*domain.Photo, error) {
+	photo := domain.Photo{ID: uint(len(s.photos) + 1), Title: req.Title, PhotoUrl: req.PhotoUrl, UserID: userID}
+	s.photos = append(s.photos, photo)
+	return &photo, nil
+}
+
+type fakeCommentService struct {
+	domain.CommentService
+	comments []domain.Comment
+}
+
+func (s *fakeCommentService) AddComment(userID uint, photoID uint, message string) (*domain.Comment, error) {
+	comment := domain.Comment{UserID: userID, PhotoID: photoID, Message: message}
+	s.comments = append(s.comments, comment)
+	return &comment, nil
+}
+
+// writeArchive writes a ZIP with the given files in a temporary directory
+func writeArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "instagram.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return archivePath
+}
+
+func TestRunImportMatchesCommentsByMedia(t *testing.T) {
+	archivePath := writeArchive(t, map[string]string{
+		"content/posts_1.json": `[
+			{"title": "First", "creation_timestamp": 100, "media": [{"uri": "media/posts/a.jpg"}]},
+			{"title": "Second", "creation_timestamp": 200, "media": [{"uri": "media/posts/b.jpg"}]}
+		]`,
+		"media/posts/a.jpg": "a",
+		"media/posts/b.jpg": "b",
+		"comments/post_comments_1.json": `[
+			{"media_list_data": [{"uri": "/media/posts/a.jpg"}], "string_map_data": {
+				"Comment": {"value": "on the first, written late"}, "Media Owner": {"value": "alice"}, "Time": {"timestamp": 300}}},
+			{"string_map_data": {
+				"Comment": {"value": "no reference"}, "Media Owner": {"value": "alice"}, "Time": {"timestamp": 250}}}
+		]`,
+		"personal_information/personal_information.json": `{"profile_user": [{"string_map_data": {"Username": {"value": "alice"}}}]}`,
+	})
+
+	repo := &fakeImportRepo{}
+	photos := &fakePhotoService{}
+	comments := &fakeCommentService{}
+	s := NewService(repo, photos, comments, fake.NewMediaStore())
+
+	job, err := s.RunImport(7, archivePath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Imported != 3 || job.Failed != 1 {
+		t.Errorf("imported %d, failed %d, want 3 and 1: %+v", job.Imported, job.Failed, job.Errors)
+	}
+	if len(comments.comments) != 1 || comments.comments[0].PhotoID != photos.photos[0].ID {
+		t.Errorf("comment should be on the first photo, got %+v", comments.comments)
+	}
+	if len(job.Errors) != 1 || !strings.Contains(job.Errors[0].Message, "reference") {
+		t.Errorf("comment without reference should be skipped, errors: %+v", job.Errors)
+	}
+	if repo.saved.Status != domain.ImportStatusCompleted {
+		t.Errorf("final state not saved, status %q", repo.saved.Status)
+	}
+}
+
+func TestRunImportSavesProgressNowAndThen(t *testing.T) {
+	files := map[string]string{}
+	var posts []string
+	for i := 0; i < 3*saveEvery; i++ {
+		uri := fmt.Sprintf("media/posts/%d.jpg", i)
+		files[uri] = "x"
+		posts = append(posts, fmt.Sprintf(`{"creation_timestamp": %d, "media": [{"uri": %q}]}`, i+1, uri))
+	}
+	files["posts_1.json"] = "[" + strings.Join(posts, ",") + "]"
+
+	repo := &fakeImportRepo{}
+	reported := 0
+	s := NewService(repo, &fakePhotoService{}, &fakeCommentService{}, fake.NewMediaStore())
+
+	job, err := s.RunImport(7, writeArchive(t, files), func(job *domain.ImportJob) {
+		reported++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Imported != 3*saveEvery {
+		t.Fatalf("imported %d photos, want %d", job.Imported, 3*saveEvery)
+	}
+	if reported < 3*saveEvery {
+		t.Errorf("progress called %d times, want once per item at least", reported)
+	}
+	// running, every saveEvery items and completed
+	if repo.updates > 5 {
+		t.Errorf("job saved %d times for %d items", repo.updates, job.Total)
+	}
+}
+
+func TestRunImportOnlyStoresImagesAndVideos(t *testing.T) {
+	archivePath := writeArchive(t, map[string]string{
+		"posts_1.json": `[
+			{"creation_timestamp": 100, "media": [{"uri": "media/posts/a.html"}]},
+			{"creation_timestamp": 200, "media": [{"uri": "media/posts/b.SVG"}]},
+			{"creation_timestamp": 300, "media": [{"uri": "media/posts/c.JPG"}]}
+		]`,
+		"media/posts/a.html": "<script>alert(1)</script>",
+		"media/posts/b.SVG":  "<svg onload=alert(1)>",
+		"media/posts/c.JPG":  "c",
+	})
+
+	photos := &fakePhotoService{}
+	job, err := NewService(&fakeImportRepo{}, photos, &fakeCommentService{}, fake.NewMediaStore()).RunImport(7, archivePath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Imported != 1 || len(photos.photos) != 1 || photos.photos[0].PhotoUrl != "/media/c.JPG" {
+		t.Errorf("imported %v, want only the JPEG", photos.photos)
+	}
+	if job.Failed != 2 || !strings.Contains(job.Errors[0].Message, "unsupported media type") {
+		t.Errorf("got errors %+v, want the HTML and SVG refused", job.Errors)
+	}
+}
+
+func TestRunImportRefusesOversizedFiles(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "bomb.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	// the header claims more than the JSON limit, like a ZIP bomb would once inflated
+	content := []byte("[]")
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "posts_1.json",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: maxJSONSize + 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(content)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	job, err := NewService(&fakeImportRepo{}, &fakePhotoService{}, &fakeCommentService{}, fake.NewMediaStore()).RunImport(7, archivePath, nil)
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("got %v, want the oversized file refused", err)
+	}
+	if job.Status != domain.ImportStatusFailed {
+		t.Errorf("got status %q, want failed", job.Status)
+	}
+}
@@ -0,0 +1,30 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+)
+
+// BlockRepo keeps the blocks and mutes in memory
+type BlockRepo struct {
+	domain.BlockRepository
+	Relations []domain.UserRelation
+}
+
+func NewBlockRepo(relations ...domain.UserRelation) *BlockRepo {
+	return &BlockRepo{Relations: relations}
+}
+
+func (r *BlockRepo) GetHidingUserIDs(actorID uint, userIDs []uint) ([]uint, error) {
+	hiding := []uint{}
+	for _, userID := range userIDs {
+		for _, relation := range r.Relations {
+			// the user blocked or muted the actor, or the actor blocked the user
+			if (relation.UserID == userID && relation.TargetID == actorID) ||
+				(relation.UserID == actorID && relation.TargetID == userID && relation.Type == domain.RelationBlock) {
+				hiding = append(hiding, userID)
+				break
+			}
+		}
+	}
+	return hiding, nil
+}
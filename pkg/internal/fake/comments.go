@@ -0,0 +1,37 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+)
+
+// CommentRepo keeps the comments in memory
+type CommentRepo struct {
+	domain.CommentRepository
+	Comments []domain.Comment
+}
+
+func NewCommentRepo(comments ...domain.Comment) *CommentRepo {
+	return &CommentRepo{Comments: comments}
+}
+
+func (r *CommentRepo) GetCommentsByUserID(userID uint, page domain.PageRequest) (*[]domain.Comment, int64, error) {
+	comments := []domain.Comment{}
+	for _, comment := range r.Comments {
+		if comment.UserID == userID {
+			comments = append(comments, comment)
+		}
+	}
+	return &comments, int64(len(comments)), nil
+}
+
+func (r *CommentRepo) GetCommenterIDs(photoID uint) ([]uint, error) {
+	commenterIDs := []uint{}
+	seen := map[uint]bool{}
+	for _, comment := range r.Comments {
+		if comment.PhotoID == photoID && !seen[comment.UserID] {
+			seen[comment.UserID] = true
+			commenterIDs = append(commenterIDs, comment.UserID)
+		}
+	}
+	return commenterIDs, nil
+}
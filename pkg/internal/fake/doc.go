@@ -0,0 +1,3 @@
+// Package fake holds in-memory implementations of the domain repositories and services shared by
+// the tests of the other packages. Methods the tests don't need panic through the embedded interface.
+package fake
@@ -0,0 +1,38 @@
+package fake
+
+import (
+	"errors"
+	"final-project/pkg/domain"
+	"strings"
+	"sync"
+)
+
+// Mailer keeps the recipients of the emails sent. Emails to the addresses of bounce.example fail
+// like an unknown mailbox, and each send waits on Release first when it is set.
+type Mailer struct {
+	Release chan struct{}
+
+	mu         sync.Mutex
+	recipients []string
+}
+
+func (m *Mailer) Send(email *domain.Email) error {
+	if m.Release != nil {
+		<-m.Release
+	}
+	if strings.HasSuffix(email.To, "@bounce.example") {
+		return errors.New("550 5.1.1 No such user")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recipients = append(m.recipients, email.To)
+	return nil
+}
+
+// Recipients returns the addresses the emails were sent to, in order
+func (m *Mailer) Recipients() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.recipients...)
+}
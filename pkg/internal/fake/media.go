@@ -0,0 +1,36 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+	"io"
+	"strings"
+)
+
+// MediaStore keeps Files, the content of the local media by URL. Save stores them under
+// "/media/" and their name.
+type MediaStore struct {
+	domain.MediaStore
+	Files map[string]string
+}
+
+func NewMediaStore() *MediaStore {
+	return &MediaStore{Files: map[string]string{}}
+}
+
+func (s *MediaStore) Open(photoUrl string) (io.ReadCloser, error) {
+	content, ok := s.Files[photoUrl]
+	if !ok {
+		return nil, domain.ErrMediaNotLocal
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func (s *MediaStore) Save(fileName string, r io.Reader) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	photoUrl := "/media/" + fileName
+	s.Files[photoUrl] = string(content)
+	return photoUrl, nil
+}
@@ -0,0 +1,39 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+)
+
+// PhotoRepo keeps the photos in memory
+type PhotoRepo struct {
+	domain.PhotoRepository
+	Photos []domain.Photo
+	// Blocked holds the lookups of a photo by ID until its channel is closed
+	Blocked map[uint]chan struct{}
+}
+
+func NewPhotoRepo(photos ...domain.Photo) *PhotoRepo {
+	return &PhotoRepo{Photos: photos}
+}
+
+func (r *PhotoRepo) GetPhotoByID(photoID uint) (*domain.Photo, error) {
+	if gate, ok := r.Blocked[photoID]; ok {
+		<-gate
+	}
+	for _, photo := range r.Photos {
+		if photo.ID == photoID {
+			return &photo, nil
+		}
+	}
+	return nil, domain.ErrPhotoNotFound
+}
+
+func (r *PhotoRepo) GetPhotosByUserID(userID uint, page domain.PageRequest) (*[]domain.Photo, int64, error) {
+	photos := []domain.Photo{}
+	for _, photo := range r.Photos {
+		if photo.UserID == userID {
+			photos = append(photos, photo)
+		}
+	}
+	return &photos, int64(len(photos)), nil
+}
@@ -0,0 +1,32 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+	"sync"
+)
+
+// RateLimiter counts the requests per key and allows the policy limit of them, its windows never end
+type RateLimiter struct {
+	domain.RateLimiter
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{counts: map[string]int{}}
+}
+
+func (l *RateLimiter) Allow(key string, policy domain.RateLimitPolicy) (*domain.RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[key]++
+	return &domain.RateLimitResult{Allowed: l.counts[key] <= policy.Limit, Limit: policy.Limit}, nil
+}
+
+// Count returns the number of requests counted under key
+func (l *RateLimiter) Count(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.counts[key]
+}
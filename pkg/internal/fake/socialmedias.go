@@ -0,0 +1,25 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+)
+
+// SocialMediaRepo keeps the social medias in memory
+type SocialMediaRepo struct {
+	domain.SocialMediaRepository
+	SocialMedias []domain.SocialMedia
+}
+
+func NewSocialMediaRepo(socialMedias ...domain.SocialMedia) *SocialMediaRepo {
+	return &SocialMediaRepo{SocialMedias: socialMedias}
+}
+
+func (r *SocialMediaRepo) GetSocialMediasByUserID(userID uint, page domain.PageRequest) (*[]domain.SocialMedia, int64, error) {
+	socialMedias := []domain.SocialMedia{}
+	for _, socialMedia := range r.SocialMedias {
+		if socialMedia.UserID == userID {
+			socialMedias = append(socialMedias, socialMedia)
+		}
+	}
+	return &socialMedias, int64(len(socialMedias)), nil
+}
@@ -0,0 +1,39 @@
+package fake
+
+import (
+	"final-project/pkg/domain"
+)
+
+// UserRepo keeps the users in memory, SaveUser numbers them from 1
+type UserRepo struct {
+	domain.UserRepository
+	Users []domain.User
+}
+
+func NewUserRepo(users ...domain.User) *UserRepo {
+	return &UserRepo{Users: users}
+}
+
+func (r *UserRepo) GetUserByID(userID uint) (*domain.User, error) {
+	for _, user := range r.Users {
+		if user.ID == userID {
+			return &user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *UserRepo) GetUserByEmail(email string) (*domain.User, error) {
+	for _, user := range r.Users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *UserRepo) SaveUser(user *domain.User) (*domain.User, error) {
+	user.ID = uint(len(r.Users) + 1)
+	r.Users = append(r.Users, *user)
+	return user, nil
+}
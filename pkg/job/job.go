@@ -0,0 +1,37 @@
+package job
+
+import (
+	"log"
+	"time"
+)
+
+// Every runs fn in its own goroutine right away and then once per interval.
+// Errors are logged and do not stop the job. Call the returned function to stop it.
+func Every(name string, interval time.Duration, fn func() error) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	run := func() {
+		if err := fn(); err != nil {
+			log.Printf("job %s failed: %v", name, err)
+		}
+	}
+
+	go func() {
+		run()
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	log.Printf("job %s scheduled every %s", name, interval)
+	return func() {
+		close(done)
+	}
+}
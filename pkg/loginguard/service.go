@@ -0,0 +1,132 @@
+package loginguard
+
+import (
+	"final-project/pkg/canonical"
+	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"time"
+)
+
+type Config struct {
+	// UsernameFreeAttempts and IPFreeAttempts are the failures allowed before locking out,
+	// IP addresses get more since many users can share one
+	UsernameFreeAttempts int
+	IPFreeAttempts       int
+	// BaseLockout is the first lockout, it doubles with every further failure up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// ResetAfter is how long without failures before the count starts over
+	ResetAfter time.Duration
+}
+
+type service struct {
+	repo      domain.LoginAttemptRepository
+	userRepo  domain.UserRepository
+	auditRepo domain.AuditLogRepository
+	config    Config
+}
+
+func NewService(
+	repo domain.LoginAttemptRepository,
+	userRepo domain.UserRepository,
+	auditRepo domain.AuditLogRepository,
+	config Config,
+) domain.LoginGuard {
+	return &service{
+		repo:      repo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		config:    config,
+	}
+}
+
+func (s *service) Check(username string, ip string) error {
+	var retryAfter time.Duration
+	for _, key := range []string{usernameKey(username), ipKey(ip)} {
+		attempt, err := s.repo.GetLoginAttempt(key)
+		if err != nil || attempt.LockedUntil == nil {
+			continue
+		}
+
+		if wait := time.Until(*attempt.LockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &domain.LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (s *service) RecordFailure(username string, ip string) error {
+	if err := s.recordFailure(usernameKey(username), s.config.UsernameFreeAttempts, username, ip); err != nil {
+		return err
+	}
+	return s.recordFailure(ipKey(ip), s.config.IPFreeAttempts, username, ip)
+}
+
+// RecordSuccess clears the failures of the username, failures of the IP address are kept
+// so an attacker can't reset them by logging into their own account
+func (s *service) RecordSuccess(username string) error {
+	return s.repo.DeleteLoginAttempt(usernameKey(username))
+}
+
+func (s *service) GetLockoutEvents(limit int) (*[]domain.AuditLog, error) {
+	return s.auditRepo.GetAuditLogsByAction(domain.AuditActionLoginLockout, limit)
+}
+
+func (s *service) recordFailure(key string, freeAttempts int, username string, ip string) error {
+	attempt, err := s.repo.IncrementLoginFailures(key, time.Now().Add(-s.config.ResetAfter))
+	if err != nil {
+		return err
+	}
+
+	if attempt.Failures < freeAttempts {
+		return nil
+	}
+
+	// Exponential backoff, every failure past the free attempts doubles the lockout
+	lockout := s.config.BaseLockout
+	for i := freeAttempts; i < attempt.Failures && lockout < s.config.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > s.config.MaxLockout {
+		lockout = s.config.MaxLockout
+	}
+
+	lockedUntil := time.Now().Add(lockout)
+	if err := s.repo.LockLoginAttempt(key, lockedUntil); err != nil {
+		return err
+	}
+
+	s.logLockout(key, attempt.Failures, lockedUntil, username, ip)
+	return nil
+}
+
+func (s *service) logLockout(key string, failures int, lockedUntil time.Time, username string, ip string) {
+	// Attach the event to the account when the username exists
+	var userID uint
+	if user, err := s.userRepo.GetUserByUsername(username); err == nil {
+		userID = user.ID
+	}
+
+	_, err := s.auditRepo.SaveAuditLog(&domain.AuditLog{
+		Action: domain.AuditActionLoginLockout,
+		UserID: userID,
+		Detail: fmt.Sprintf("%s locked until %s after %d failures (username %q, ip %s)",
+			key, lockedUntil.Format(time.RFC3339), failures, username, ip),
+	})
+	if err != nil {
+		log.Printf("failed to save lockout event for %s: %v", key, err)
+	}
+}
+
+func usernameKey(username string) string {
+	return "username:" + canonical.Username(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"final-project/pkg/domain"
+	"os"
+	"time"
+)
+
+type fileMailer struct {
+	dir string
+}
+
+// NewFileMailer creates a mailer dropping every email as a .eml file in dir
+func NewFileMailer(dir string) domain.Mailer {
+	return &fileMailer{
+		dir: dir,
+	}
+}
+
+func (m *fileMailer) Send(email *domain.Email) error {
+	now := time.Now()
+	message, err := buildMessage("", email, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(m.dir, now.Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(message); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
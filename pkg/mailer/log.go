@@ -0,0 +1,19 @@
+package mailer
+
+import (
+	"final-project/pkg/domain"
+	"log"
+)
+
+type logMailer struct {
+}
+
+// NewLogMailer creates a mailer writing emails to the application log, for offline development
+func NewLogMailer() domain.Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(email *domain.Email) error {
+	log.Printf("email to %s\nSubject: %s\n\n%s", email.To, email.Subject, email.Body)
+	return nil
+}
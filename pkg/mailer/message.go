@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+var errHeaderInjection = errors.New("email header contains a line break")
+
+// buildMessage writes email as a MIME message, multipart/alternative when it has an HTML body.
+// from is left out when empty.
+func buildMessage(from string, email *domain.Email, date time.Time) ([]byte, error) {
+	headers := map[string]string{
+		"To":           email.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", email.Subject),
+		"Date":         date.Format(time.RFC1123Z),
+		"MIME-Version": "1.0",
+	}
+	if from != "" {
+		headers["From"] = from
+	}
+	for name, value := range email.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+	}
+
+	var buf bytes.Buffer
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := headers[name]
+		if strings.ContainsAny(name+value, "\r\n") {
+			return nil, errHeaderInjection
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	if email.HTMLBody == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, email.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+
+	// Clients show the last part they support, the HTML one goes last
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", email.Body},
+		{"text/html; charset=utf-8", email.HTMLBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	// Line breaks are sent as CRLF
+	if _, err := qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
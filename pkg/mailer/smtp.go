@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"final-project/pkg/domain"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host string
+	// Port is 587 when zero
+	Port int
+	// Username and Password authenticate with PLAIN when set, which needs TLS unless the server
+	// is on localhost
+	Username string
+	Password string
+	// From is the sender, e.g. "MyGram <no-reply@mygram.example>"
+	From string
+	// Timeout bounds the whole exchange with the server, 30 seconds when zero
+	Timeout time.Duration
+}
+
+type smtpMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer creates a mailer sending every email through an SMTP server, upgrading the
+// connection with STARTTLS when the server offers it
+func NewSMTPMailer(config SMTPConfig) domain.Mailer {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &smtpMailer{
+		config: config,
+	}
+}
+
+func (m *smtpMailer) Send(email *domain.Email) error {
+	sender, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return err
+	}
+
+	message, err := buildMessage(m.config.From, email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port)), m.config.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(m.config.Timeout))
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(email.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,259 @@
+package mailer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"final-project/pkg/domain"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what a client told fakeSMTPServer
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer accepts one session per connection on localhost, without STARTTLS, and answers
+// 550 to the recipients in reject
+type fakeSMTPServer struct {
+	listener net.Listener
+	reject   map[string]bool
+	sessions chan smtpSession
+}
+
+func newFakeSMTPServer(t *testing.T, reject ...string) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: listener, reject: map[string]bool{}, sessions: make(chan smtpSession, 8)}
+	for _, address := range reject {
+		s.reject[address] = true
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: portNumber, From: "MyGram <no-reply@mygram.example>", Timeout: 5 * time.Second}
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var session smtpSession
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			session.auth = string(credentials)
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			session.from = strings.TrimSuffix(strings.TrimPrefix(line, "MAIL FROM:<"), ">")
+			reply("250 OK")
+		case "RCPT":
+			to := strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">")
+			if s.reject[to] {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			session.to = append(session.to, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			session.data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			s.sessions <- session
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	config := server.config()
+	config.Username = "mygram"
+	config.Password = "secret"
+
+	err := NewSMTPMailer(config).Send(&domain.Email{
+		To:      "alice@example.com",
+		Subject: "Hello",
+		Body:    "Hi Alice",
+		Headers: map[string]string{"List-Unsubscribe": "<https://mygram.example/users/unsubscribe?token=1.abc>"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := <-server.sessions
+	if session.auth != "\x00mygram\x00secret" {
+		t.Errorf("got credentials %q", session.auth)
+	}
+	if session.from != "no-reply@mygram.example" || len(session.to) != 1 || session.to[0] != "alice@example.com" {
+		t.Errorf("got envelope from %q to %v", session.from, session.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("From") != config.From || msg.Header.Get("To") != "alice@example.com" {
+		t.Errorf("got headers %v", msg.Header)
+	}
+	if msg.Header.Get("List-Unsubscribe") != "<https://mygram.example/users/unsubscribe?token=1.abc>" {
+		t.Errorf("custom header missing, got %v", msg.Header)
+	}
+}
+
+func TestSMTPMailerRejectedRecipient(t *testing.T) {
+	server := newFakeSMTPServer(t, "bounce@example.com")
+
+	err := NewSMTPMailer(server.config()).Send(&domain.Email{To: "bounce@example.com", Subject: "Hello", Body: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("got %v, want the 550 of the server", err)
+	}
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	config := server.config()
+	server.listener.Close()
+
+	if err := NewSMTPMailer(config).Send(&domain.Email{To: "alice@example.com", Subject: "Hello", Body: "Hi"}); err == nil {
+		t.Error("sent without a server")
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	message, err := buildMessage("MyGram <no-reply@mygram.example>", &domain.Email{
+		To:       "alice@example.com",
+		Subject:  "Café ☕",
+		Body:     "Hi Alice,\nline two",
+		HTMLBody: "<p>Hi Alice</p>",
+	}, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Café ☕" {
+		t.Errorf("got subject %q, %v", subject, err)
+	}
+	if msg.Header.Get("Date") != date.Format(time.RFC1123Z) || msg.Header.Get("MIME-Version") != "1.0" {
+		t.Errorf("got headers %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got content type %q, %v", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, part.Header.Get("Content-Type")+": "+string(body))
+	}
+	want := []string{
+		"text/plain; charset=utf-8: Hi Alice,\r\nline two",
+		"text/html; charset=utf-8: <p>Hi Alice</p>",
+	}
+	if strings.Join(bodies, "|") != strings.Join(want, "|") {
+		t.Errorf("got parts %q, want %q", bodies, want)
+	}
+}
+
+func TestBuildMessagePlainText(t *testing.T) {
+	message, err := buildMessage("", &domain.Email{To: "alice@example.com", Subject: "Hello", Body: "Hi"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.Header["From"]; ok {
+		t.Error("empty sender written")
+	}
+	if msg.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("got content type %q", msg.Header.Get("Content-Type"))
+	}
+}
+
+func TestBuildMessageRefusesHeaderInjection(t *testing.T) {
+	for _, email := range []*domain.Email{
+		{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hello"},
+		{To: "alice@example.com", Subject: "Hello", Headers: map[string]string{"List-Unsubscribe": "<x>\nBcc: eve@example.com"}},
+	} {
+		if _, err := buildMessage("", email, time.Now()); err != errHeaderInjection {
+			t.Errorf("%q: got %v, want errHeaderInjection", email.To, err)
+		}
+	}
+}
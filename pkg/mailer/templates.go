@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"final-project/pkg/domain"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templateFS holds a name.txt template per email, defining its "subject", and optionally a
+// name.html one defining the "content" and "footer" of layout.html
+//
+//go:embed templates
+var templateFS embed.FS
+
+type templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewTemplates parses the embedded email templates
+func NewTemplates() domain.EmailTemplates {
+	t := &templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	files, _ := fs.Glob(templateFS, "templates/*.txt")
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+		t.text[name] = texttemplate.Must(texttemplate.ParseFS(templateFS, file))
+
+		htmlFile := "templates/" + name + ".html"
+		if _, err := fs.Stat(templateFS, htmlFile); err == nil {
+			t.html[name] = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/layout.html", htmlFile))
+		}
+	}
+
+	return t
+}
+
+func (t *templates) Render(name string, data interface{}) (*domain.Email, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	email := &domain.Email{
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    body.String(),
+	}
+
+	if html, ok := t.html[name]; ok {
+		var htmlBody bytes.Buffer
+		if err := html.ExecuteTemplate(&htmlBody, "layout.html", data); err != nil {
+			return nil, err
+		}
+		email.HTMLBody = htmlBody.String()
+	}
+
+	return email, nil
+}
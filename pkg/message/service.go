@@ -0,0 +1,426 @@
+// Package message implements the direct messages between users, in 1:1 conversations and small
+// groups, with read receipts and the choice of who can start a conversation with a user.
+package message
+
+import (
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Config struct {
+	// MaxParticipants is the size limit of the conversations, the current user included, 10 when zero
+	MaxParticipants int
+	// MaxBodyLength is the number of characters a message can hold, 4096 when zero
+	MaxBodyLength int
+	// DefaultAllowFrom applies to users who never changed their settings, everyone when empty
+	DefaultAllowFrom string
+}
+
+type service struct {
+	repo      domain.MessageRepository
+	userRepo  domain.UserRepository
+	photoRepo domain.PhotoRepository
+	blockRepo domain.BlockRepository
+	config    Config
+}
+
+func NewService(repo domain.MessageRepository, userRepo domain.UserRepository, photoRepo domain.PhotoRepository, blockRepo domain.BlockRepository, config Config) domain.MessageService {
+	if config.MaxParticipants <= 0 {
+		config.MaxParticipants = 10
+	}
+	if config.MaxBodyLength <= 0 {
+		config.MaxBodyLength = 4096
+	}
+	if config.DefaultAllowFrom == "" {
+		config.DefaultAllowFrom = domain.AllowMessagesFromEveryone
+	}
+
+	return &service{
+		repo:      repo,
+		userRepo:  userRepo,
+		photoRepo: photoRepo,
+		blockRepo: blockRepo,
+		config:    config,
+	}
+}
+
+func (s *service) StartConversation(userID uint, req *domain.StartConversationRequest) (*domain.Conversation, error) {
+	participantIDs := make([]uint, 0, len(req.ParticipantIDs))
+	seen := map[uint]bool{userID: true}
+	for _, id := range req.ParticipantIDs {
+		if !seen[id] {
+			seen[id] = true
+			participantIDs = append(participantIDs, id)
+		}
+	}
+	if len(participantIDs) == 0 {
+		return nil, domain.NewFieldValidationError("participant_ids", "must include another user")
+	}
+	if len(participantIDs)+1 > s.config.MaxParticipants {
+		return nil, domain.NewFieldValidationError("participant_ids", fmt.Sprintf("must include at most %d users", s.config.MaxParticipants-1))
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > 100 {
+		return nil, domain.NewFieldValidationError("title", "must be at most 100 characters")
+	}
+
+	users, err := s.userRepo.GetUsersByIDs(participantIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(*users) != len(participantIDs) {
+		return nil, domain.ErrUserNotFound
+	}
+
+	// Two users share one conversation, starting it again returns it
+	isGroup := len(participantIDs) > 1
+	if !isGroup {
+		conversation, err := s.repo.GetDirectConversation(userID, participantIDs[0])
+		if err == nil {
+			return s.withActivity(userID, conversation)
+		}
+		if !errors.Is(err, domain.ErrConversationNotFound) {
+			return nil, err
+		}
+		title = ""
+	}
+
+	for _, id := range participantIDs {
+		if err := s.checkAllowed(userID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	participants := []domain.ConversationParticipant{{UserID: userID}}
+	for _, id := range participantIDs {
+		participants = append(participants, domain.ConversationParticipant{UserID: id})
+	}
+
+	return s.repo.SaveConversation(&domain.Conversation{
+		IsGroup:      isGroup,
+		Title:        title,
+		CreatedBy:    userID,
+		Participants: participants,
+	})
+}
+
+func (s *service) GetConversations(userID uint, page domain.PageRequest) (*[]domain.Conversation, int64, error) {
+	conversations, total, err := s.repo.GetConversationsByUserID(userID, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.fillActivity(userID, *conversations); err != nil {
+		return nil, 0, err
+	}
+
+	return conversations, total, nil
+}
+
+func (s *service) GetConversation(userID uint, conversationID uint) (*domain.Conversation, error) {
+	conversation, err := s.getConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withActivity(userID, conversation)
+}
+
+func (s *service) GetMessages(userID uint, conversationID uint, page domain.PageRequest) (*[]domain.Message, int64, error) {
+	conversation, err := s.getConversation(userID, conversationID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messages, total, err := s.repo.GetMessages(userID, conversationID, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pointers := make([]*domain.Message, len(*messages))
+	for i := range *messages {
+		pointers[i] = &(*messages)[i]
+	}
+	if err := s.fillMessages(userID, conversation, pointers); err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+func (s *service) SendMessage(userID uint, conversationID uint, req *domain.SendMessageRequest) (*domain.Message, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" && req.PhotoID == nil {
+		return nil, domain.NewFieldValidationError("body", "is required without photo_id")
+	}
+	if utf8.RuneCountInString(body) > s.config.MaxBodyLength {
+		return nil, domain.NewFieldValidationError("body", fmt.Sprintf("must be at most %d characters", s.config.MaxBodyLength))
+	}
+
+	conversation, err := s.getConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var photo *domain.Photo
+	if req.PhotoID != nil {
+		photo, err = s.photoRepo.GetPhotoByID(*req.PhotoID)
+		if err != nil {
+			return nil, err
+		}
+		blocked, err := s.blockRepo.IsBlocked(userID, photo.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, domain.ErrPhotoNotFound
+		}
+	}
+
+	// The members of a group agreed to it when they were added, a user of a 1:1 conversation can
+	// change their mind
+	if !conversation.IsGroup {
+		for _, participant := range conversation.Participants {
+			if participant.UserID == userID {
+				continue
+			}
+			if err := s.checkAllowed(userID, participant.UserID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	message, err := s.repo.SaveMessage(&domain.Message{
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Body:           body,
+		PhotoID:        req.PhotoID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Senders have read their own messages
+	if err := s.repo.UpdateReadReceipt(conversationID, userID, message.ID, message.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	message.Photo = photo
+	message.ReadBy = []uint{}
+	return message, nil
+}
+
+func (s *service) MarkRead(userID uint, conversationID uint) (*domain.Conversation, error) {
+	conversation, err := s.getConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	lastMessages, err := s.repo.GetLastMessages(userID, []uint{conversationID})
+	if err != nil {
+		return nil, err
+	}
+	if last, ok := lastMessages[conversationID]; ok {
+		if err := s.repo.UpdateReadReceipt(conversationID, userID, last.ID, time.Now()); err != nil {
+			return nil, err
+		}
+		conversation, err = s.repo.GetConversationByID(conversationID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.withActivity(userID, conversation)
+}
+
+func (s *service) DeleteMessage(userID uint, conversationID uint, messageID uint, forEveryone bool) error {
+	if _, err := s.getConversation(userID, conversationID); err != nil {
+		return err
+	}
+
+	message, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		return err
+	}
+	if message.ConversationID != conversationID {
+		return domain.ErrMessageNotFound
+	}
+
+	if !forEveryone {
+		return s.repo.DeleteMessageForUser(messageID, userID)
+	}
+
+	if message.SenderID != userID {
+		return domain.ErrNotOwner
+	}
+	if message.DeletedAt != nil {
+		return nil
+	}
+	return s.repo.EraseMessage(messageID, time.Now())
+}
+
+func (s *service) GetSettings(userID uint) (*domain.MessageSettings, error) {
+	settings, err := s.repo.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.AllowFrom == "" {
+		settings.AllowFrom = s.config.DefaultAllowFrom
+	}
+	return settings, nil
+}
+
+func (s *service) UpdateSettings(userID uint, allowFrom string) (*domain.MessageSettings, error) {
+	valid := false
+	for _, value := range domain.AllowMessagesFromValues {
+		if value == allowFrom {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, domain.NewFieldValidationError("allow_from", "must be one of "+strings.Join(domain.AllowMessagesFromValues, ", "))
+	}
+
+	if err := s.repo.SaveSettings(&domain.MessageSettings{UserID: userID, AllowFrom: allowFrom}); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(userID)
+}
+
+// getConversation returns the conversation if the user takes part in it, others get
+// ErrConversationNotFound so they can't tell it exists
+func (s *service) getConversation(userID uint, conversationID uint) (*domain.Conversation, error) {
+	conversation, err := s.repo.GetConversationByID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, participant := range conversation.Participants {
+		if participant.UserID == userID {
+			return conversation, nil
+		}
+	}
+	return nil, domain.ErrConversationNotFound
+}
+
+// checkAllowed refuses messages of senderID to recipientID when either blocked the other, or when
+// the recipient only accepts messages from the people they have messaged
+func (s *service) checkAllowed(senderID uint, recipientID uint) error {
+	blocked, err := s.blockRepo.IsBlocked(senderID, recipientID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return domain.ErrMessagesRefused
+	}
+
+	settings, err := s.GetSettings(recipientID)
+	if err != nil {
+		return err
+	}
+	if settings.AllowFrom != domain.AllowMessagesFromContacts {
+		return nil
+	}
+
+	messaged, err := s.repo.HasMessaged(recipientID, senderID)
+	if err != nil {
+		return err
+	}
+	if !messaged {
+		return domain.ErrMessagesRefused
+	}
+	return nil
+}
+
+func (s *service) withActivity(userID uint, conversation *domain.Conversation) (*domain.Conversation, error) {
+	conversations := []domain.Conversation{*conversation}
+	if err := s.fillActivity(userID, conversations); err != nil {
+		return nil, err
+	}
+	return &conversations[0], nil
+}
+
+// fillActivity sets the unread counts and the last messages of the conversations
+func (s *service) fillActivity(userID uint, conversations []domain.Conversation) error {
+	ids := make([]uint, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+
+	unread, err := s.repo.GetUnreadCounts(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	lastMessages, err := s.repo.GetLastMessages(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range conversations {
+		conversations[i].Unread = unread[conversations[i].ID]
+		if last, ok := lastMessages[conversations[i].ID]; ok {
+			if err := s.fillMessages(userID, &conversations[i], []*domain.Message{&last}); err != nil {
+				return err
+			}
+			conversations[i].LastMessage = &last
+		}
+	}
+
+	return nil
+}
+
+// fillMessages sets the read receipts and the attached photos of messages of a conversation, the
+// photos of users hidden from the user are left out
+func (s *service) fillMessages(userID uint, conversation *domain.Conversation, messages []*domain.Message) error {
+	var photoIDs []uint
+	for _, message := range messages {
+		message.ReadBy = []uint{}
+		for _, participant := range conversation.Participants {
+			if participant.UserID != message.SenderID && participant.LastReadMessageID >= message.ID {
+				message.ReadBy = append(message.ReadBy, participant.UserID)
+			}
+		}
+		if message.PhotoID != nil {
+			photoIDs = append(photoIDs, *message.PhotoID)
+		}
+	}
+	if len(photoIDs) == 0 {
+		return nil
+	}
+
+	photos, err := s.photoRepo.GetPhotosByIDs(photoIDs)
+	if err != nil {
+		return err
+	}
+
+	hiddenIDs, err := s.blockRepo.GetHiddenUserIDs(userID)
+	if err != nil {
+		return err
+	}
+	hidden := make(map[uint]bool, len(hiddenIDs))
+	for _, id := range hiddenIDs {
+		hidden[id] = true
+	}
+
+	photosByID := make(map[uint]*domain.Photo, len(*photos))
+	for i := range *photos {
+		if !hidden[(*photos)[i].UserID] {
+			photosByID[(*photos)[i].ID] = &(*photos)[i]
+		}
+	}
+	for _, message := range messages {
+		if message.PhotoID != nil {
+			message.Photo = photosByID[*message.PhotoID]
+		}
+	}
+
+	return nil
+}
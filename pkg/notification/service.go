@@ -0,0 +1,334 @@
+package notification
+
+import (
+	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// QueueSize is the number of comments waiting for their notifications, 1024 when zero. The
+	// notifications of the comments published while it is full are dropped.
+	QueueSize int
+	// Workers generate the notifications of the queued comments, 2 when zero
+	Workers int
+}
+
+type service struct {
+	repo        domain.NotificationRepository
+	commentRepo domain.CommentRepository
+	photoRepo   domain.PhotoRepository
+	userRepo    domain.UserRepository
+	blockRepo   domain.BlockRepository
+
+	queue   chan domain.Comment
+	workers sync.WaitGroup
+	// closing guards closed and the sends to queue, which is closed once
+	closing sync.RWMutex
+	closed  bool
+}
+
+func NewService(repo domain.NotificationRepository, commentRepo domain.CommentRepository, photoRepo domain.PhotoRepository, userRepo domain.UserRepository, blockRepo domain.BlockRepository, config Config) domain.NotificationService {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1024
+	}
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+
+	s := &service{
+		repo:        repo,
+		commentRepo: commentRepo,
+		photoRepo:   photoRepo,
+		userRepo:    userRepo,
+		blockRepo:   blockRepo,
+		queue:       make(chan domain.Comment, config.QueueSize),
+	}
+	s.workers.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// Publish queues new comments for the workers, the service which posted the comment never waits
+// on the queries. Comments published while the queue is full or closed are dropped.
+func (s *service) Publish(event *domain.Event) {
+	comment, ok := event.Data.(*domain.Comment)
+	if !ok || event.Type != domain.EventCommentCreated {
+		return
+	}
+
+	s.closing.RLock()
+	defer s.closing.RUnlock()
+	if s.closed {
+		log.Printf("notifications of comment %d dropped, the service is closed", comment.ID)
+		return
+	}
+
+	select {
+	case s.queue <- *comment:
+	default:
+		log.Printf("notifications of comment %d dropped, the queue is full", comment.ID)
+	}
+}
+
+// Close stops taking comments and waits for the workers to empty the queue
+func (s *service) Close() {
+	s.closing.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.closing.Unlock()
+
+	s.workers.Wait()
+}
+
+func (s *service) work() {
+	defer s.workers.Done()
+	for comment := range s.queue {
+		s.generate(comment)
+	}
+}
+
+// generate notifies the owner and the commenters of the photo of the comment. Workers run it
+// concurrently, the comments of a photo posted at the same time still end up in one notification.
+func (s *service) generate(comment domain.Comment) {
+	photo, err := s.photoRepo.GetPhotoByID(comment.PhotoID)
+	if err != nil {
+		log.Printf("failed to generate notifications of comment %d: %v", comment.ID, err)
+		return
+	}
+
+	commenterIDs, err := s.commentRepo.GetCommenterIDs(photo.ID)
+	if err != nil {
+		log.Printf("failed to generate notifications of comment %d: %v", comment.ID, err)
+		return
+	}
+
+	// The owner of the photo gets one notification even if they commented on it too
+	var threadUserIDs []uint
+	for _, userID := range commenterIDs {
+		if userID != comment.UserID && userID != photo.UserID {
+			threadUserIDs = append(threadUserIDs, userID)
+		}
+	}
+
+	// Nobody hears about a photo hidden from them
+	hiding, err := s.blockRepo.GetHidingUserIDs(photo.UserID, threadUserIDs)
+	if err != nil {
+		log.Printf("failed to generate notifications of comment %d: %v", comment.ID, err)
+		return
+	}
+	threadUserIDs = without(threadUserIDs, hiding)
+
+	if photo.UserID != comment.UserID {
+		s.notifyAll(domain.NotificationPhotoComment, []uint{photo.UserID}, photo.ID, comment.UserID)
+	}
+	s.notifyAll(domain.NotificationThreadComment, threadUserIDs, photo.ID, comment.UserID)
+}
+
+// notifyAll notifies the users who didn't turn off notificationType, and don't hide actorID, that
+// actorID acted on the photo
+func (s *service) notifyAll(notificationType string, userIDs []uint, photoID uint, actorID uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	hiding, err := s.blockRepo.GetHidingUserIDs(actorID, userIDs)
+	if err != nil {
+		log.Printf("failed to load the blocks and mutes: %v", err)
+		return
+	}
+	userIDs = without(userIDs, hiding)
+
+	optedOut, err := s.repo.GetOptedOutUserIDs(notificationType, userIDs)
+	if err != nil {
+		log.Printf("failed to load the notification preferences: %v", err)
+		return
+	}
+	skip := make(map[uint]bool, len(optedOut))
+	for _, userID := range optedOut {
+		skip[userID] = true
+	}
+
+	for _, userID := range userIDs {
+		if skip[userID] {
+			continue
+		}
+		if err := s.repo.AddActor(userID, notificationType, photoID, actorID); err != nil {
+			log.Printf("failed to notify user %d: %v", userID, err)
+		}
+	}
+}
+
+func (s *service) GetNotifications(userID uint, unreadOnly bool, page domain.PageRequest) (*[]domain.Notification, int64, error) {
+	notifications, total, err := s.repo.GetNotificationsByUserID(userID, unreadOnly, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.describe(userID, *notifications); err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func (s *service) CountUnread(userID uint) (int64, error) {
+	return s.repo.CountUnread(userID)
+}
+
+func (s *service) MarkRead(userID uint, notificationID uint) (*domain.Notification, error) {
+	notification, err := s.repo.GetNotificationByID(notificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The notifications of others don't exist for the user
+	if notification.UserID != userID {
+		return nil, domain.ErrNotificationNotFound
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := s.repo.UpdateNotification(notification); err != nil {
+			return nil, err
+		}
+	}
+
+	notifications := []domain.Notification{*notification}
+	if err := s.describe(userID, notifications); err != nil {
+		return nil, err
+	}
+
+	return &notifications[0], nil
+}
+
+func (s *service) MarkAllRead(userID uint) (int64, error) {
+	return s.repo.MarkAllRead(userID, time.Now())
+}
+
+func (s *service) GetPreferences(userID uint) ([]domain.NotificationPreference, error) {
+	stored, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool, len(stored))
+	for _, preference := range stored {
+		enabled[preference.Type] = preference.Enabled
+	}
+
+	preferences := make([]domain.NotificationPreference, len(domain.NotificationTypes))
+	for i, notificationType := range domain.NotificationTypes {
+		preference := domain.NotificationPreference{Type: notificationType, Enabled: true}
+		if value, ok := enabled[notificationType]; ok {
+			preference.Enabled = value
+		}
+		preferences[i] = preference
+	}
+
+	return preferences, nil
+}
+
+func (s *service) UpdatePreferences(userID uint, preferences []domain.NotificationPreference) ([]domain.NotificationPreference, error) {
+	for _, preference := range preferences {
+		if !isNotificationType(preference.Type) {
+			return nil, domain.NewFieldValidationError("type", fmt.Sprintf("unknown notification type %q", preference.Type))
+		}
+	}
+
+	if err := s.repo.SavePreferences(userID, preferences); err != nil {
+		return nil, err
+	}
+
+	return s.GetPreferences(userID)
+}
+
+// describe writes the messages of the notifications of the user, naming their latest actor. The
+// actors hidden from the user since the notification was generated are left out.
+func (s *service) describe(userID uint, notifications []domain.Notification) error {
+	hidden, err := s.blockRepo.GetHiddenUserIDs(userID)
+	if err != nil {
+		return err
+	}
+	for i := range notifications {
+		notifications[i].ActorIDs = without(notifications[i].ActorIDs, hidden)
+	}
+
+	var actorIDs []uint
+	for _, notification := range notifications {
+		if len(notification.ActorIDs) > 0 {
+			actorIDs = append(actorIDs, notification.ActorIDs[0])
+		}
+	}
+
+	users, err := s.userRepo.GetUsersByIDs(actorIDs)
+	if err != nil {
+		return err
+	}
+	usernames := make(map[uint]string, len(*users))
+	for _, user := range *users {
+		usernames[user.ID] = user.Username
+	}
+
+	for i := range notifications {
+		notifications[i].Message = message(&notifications[i], usernames)
+	}
+
+	return nil
+}
+
+func message(notification *domain.Notification, usernames map[uint]string) string {
+	actors := "Someone"
+	if len(notification.ActorIDs) > 0 {
+		if username, ok := usernames[notification.ActorIDs[0]]; ok {
+			actors = username
+		}
+	}
+	switch others := len(notification.ActorIDs) - 1; {
+	case others == 1:
+		actors += " and 1 other"
+	case others > 1:
+		actors += fmt.Sprintf(" and %d others", others)
+	}
+
+	if notification.Type == domain.NotificationThreadComment {
+		return actors + " commented on a photo you commented on"
+	}
+	return actors + " commented on your photo"
+}
+
+// without returns ids without the ones of excluded, in the same order
+func without(ids []uint, excluded []uint) []uint {
+	if len(excluded) == 0 {
+		return ids
+	}
+
+	skip := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	kept := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !skip[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+func isNotificationType(notificationType string) bool {
+	for _, t := range domain.NotificationTypes {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// notificationKey is what the unique index of the notifications covers
+type notificationKey struct {
+	userID           uint
+	notificationType string
+	photoID          uint
+}
+
+// fakeNotificationRepo aggregates the actors like the upsert does
+type fakeNotificationRepo struct {
+	domain.NotificationRepository
+	mu      sync.Mutex
+	unread  map[notificationKey][]uint
+	created int
+}
+
+func (r *fakeNotificationRepo) AddActor(userID uint, notificationType string, photoID uint, actorID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := notificationKey{userID, notificationType, photoID}
+	actorIDs, ok := r.unread[key]
+	if !ok {
+		r.created++
+	}
+	r.unread[key] = append([]uint{actorID}, without(actorIDs, []uint{actorID})...)
+	return nil
+}
+
+func (r *fakeNotificationRepo) GetOptedOutUserIDs(notificationType string, userIDs []uint) ([]uint, error) {
+	return nil, nil
+}
+
+func (r *fakeNotificationRepo) actors(userID uint, notificationType string, photoID uint) []uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.unread[notificationKey{userID, notificationType, photoID}]
+}
+
+// newTestService notifies the comments on photos 1, 2, 3 and 7 of user 1, the lookups of the
+// photos in blocked wait on their channel
+func newTestService(blocked map[uint]chan struct{}, config Config) (*service, *fakeNotificationRepo) {
+	repo := &fakeNotificationRepo{unread: make(map[notificationKey][]uint)}
+	photos := fake.NewPhotoRepo(
+		domain.Photo{ID: 1, UserID: 1},
+		domain.Photo{ID: 2, UserID: 1},
+		domain.Photo{ID: 3, UserID: 1},
+		domain.Photo{ID: 7, UserID: 1},
+	)
+	photos.Blocked = blocked
+	return NewService(repo, fake.NewCommentRepo(), photos, nil, fake.NewBlockRepo(), config).(*service), repo
+}
+
+func commentEvent(photoID uint, userID uint) *domain.Event {
+	return &domain.Event{
+		Type:    domain.EventCommentCreated,
+		ActorID: userID,
+		Data:    &domain.Comment{PhotoID: photoID, UserID: userID},
+	}
+}
+
+func TestCommentsGatheredInOneNotification(t *testing.T) {
+	s, repo := newTestService(nil, Config{Workers: 4})
+
+	var wg sync.WaitGroup
+	for userID := uint(2); userID < 52; userID++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			s.Publish(commentEvent(7, userID))
+			s.Publish(commentEvent(7, userID))
+		}(userID)
+	}
+	// the owner's own comments don't notify them
+	s.Publish(commentEvent(7, 1))
+	wg.Wait()
+	s.Close()
+
+	if repo.created != 1 {
+		t.Errorf("created %d notifications, want 1", repo.created)
+	}
+	if actors := repo.actors(1, domain.NotificationPhotoComment, 7); len(actors) != 50 {
+		t.Errorf("got %d actors, want 50 once each", len(actors))
+	}
+}
+
+func TestPublishWhenQueueFull(t *testing.T) {
+	gate := make(chan struct{})
+	s, repo := newTestService(map[uint]chan struct{}{1: gate}, Config{QueueSize: 1, Workers: 1})
+
+	// the worker waits on the first comment, the second fills the queue
+	s.Publish(commentEvent(1, 2))
+	for len(s.queue) > 0 {
+		runtime.Gosched()
+	}
+	s.Publish(commentEvent(1, 3))
+
+	// the queue is full, the comment on another photo is dropped rather than blocking the caller
+	s.Publish(commentEvent(2, 4))
+
+	close(gate)
+	s.Close()
+	if actors := repo.actors(1, domain.NotificationPhotoComment, 1); len(actors) != 2 {
+		t.Errorf("queued comments lost, got actors %v", actors)
+	}
+	if actors := repo.actors(1, domain.NotificationPhotoComment, 2); len(actors) != 0 {
+		t.Errorf("comment published on a full queue was notified, got actors %v", actors)
+	}
+
+	// and so are the comments published after Close
+	s.Publish(commentEvent(3, 5))
+	if actors := repo.actors(1, domain.NotificationPhotoComment, 3); len(actors) != 0 {
+		t.Errorf("comment published after Close was notified, got actors %v", actors)
+	}
+}
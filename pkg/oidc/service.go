@@ -0,0 +1,347 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"final-project/pkg/domain"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errUnknownProvider = domain.NewNotFoundError("unknown_identity_provider", "unknown identity provider")
+	errInvalidState    = domain.NewValidationError("invalid_oidc_state", "invalid or expired sign in state, please start again")
+	errProviderFailed  = domain.NewUnauthorizedError("identity_provider_failed", "sign in with the identity provider failed")
+)
+
+// usernameInvalidChars are replaced when deriving a username from the provider claims
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.]+`)
+
+type Config struct {
+	// Providers are keyed by the name used in the URLs, e.g. "google"
+	Providers map[string]ProviderConfig
+	// StateTTL is how long the user has to complete the sign in at the provider
+	StateTTL time.Duration
+	// HTTPClient talks to the providers, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// flowState is kept with the state token until the provider redirects back
+type flowState struct {
+	Provider string `json:"p"`
+	Verifier string `json:"v"`
+	Nonce    string `json:"n"`
+	// Binding is the hash of the secret kept by the browser that started the flow
+	Binding    string `json:"b"`
+	LinkUserID uint   `json:"u,omitempty"`
+}
+
+type service struct {
+	repo          domain.LinkedIdentityRepository
+	userRepo      domain.UserRepository
+	tokenRepo     domain.UserTokenRepository
+	userService   domain.UserService
+	cryptoService domain.CryptoService
+	providers     map[string]*provider
+	config        Config
+}
+
+func NewService(
+	repo domain.LinkedIdentityRepository,
+	userRepo domain.UserRepository,
+	tokenRepo domain.UserTokenRepository,
+	userService domain.UserService,
+	cryptoService domain.CryptoService,
+	config Config,
+) domain.OIDCService {
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	providers := make(map[string]*provider)
+	for name, providerConfig := range config.Providers {
+		providers[name] = newProvider(providerConfig, client)
+	}
+
+	return &service{
+		repo:          repo,
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		userService:   userService,
+		cryptoService: cryptoService,
+		providers:     providers,
+		config:        config,
+	}
+}
+
+func (s *service) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *service) AuthorizationURL(providerName string, linkUserID uint) (*domain.OIDCAuthorization, error) {
+	p, ok := s.providers[providerName]
+	if !ok {
+		return nil, errUnknownProvider
+	}
+
+	state, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	// Without the binding anyone could send their own flow to a victim, e.g. to link the victim's
+	// provider account to the attacker's user
+	binding, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := p.authorizationURL(state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// The state is single use and only its hash is stored, like the other user tokens
+	data, err := json.Marshal(flowState{
+		Provider:   providerName,
+		Verifier:   verifier,
+		Nonce:      nonce,
+		Binding:    s.cryptoService.HashToken(binding),
+		LinkUserID: linkUserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(s.config.StateTTL)
+	_, err = s.tokenRepo.SaveUserToken(&domain.UserToken{
+		UserID:    linkUserID,
+		Purpose:   domain.UserTokenPurposeOIDCState,
+		TokenHash: s.cryptoService.HashToken(state),
+		Data:      string(data),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.OIDCAuthorization{
+		URL:       authorizationURL,
+		Binding:   binding,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *service) Callback(req *domain.OIDCCallbackRequest) (*domain.OIDCCallbackResult, error) {
+	p, ok := s.providers[req.Provider]
+	if !ok {
+		return nil, errUnknownProvider
+	}
+
+	flow, err := s.useState(req.Provider, req.State, req.Binding)
+	if err != nil {
+		return nil, err
+	}
+
+	// The details of provider failures are only logged
+	rawIDToken, err := p.exchange(req.Code, flow.Verifier)
+	if err != nil {
+		log.Printf("oidc %s: %v", req.Provider, err)
+		return nil, errProviderFailed
+	}
+	claims, err := p.verifyIDToken(rawIDToken, flow.Nonce)
+	if err != nil {
+		log.Printf("oidc %s: %v", req.Provider, err)
+		return nil, errProviderFailed
+	}
+
+	if flow.LinkUserID != 0 {
+		identity, err := s.link(flow.LinkUserID, req.Provider, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.OIDCCallbackResult{LinkedIdentity: identity}, nil
+	}
+
+	userID, err := s.resolveUser(req.Provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	login, err := s.userService.LoginWithIdentity(&domain.IdentityLoginRequest{
+		UserID:    userID,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OIDCCallbackResult{Login: login}, nil
+}
+
+func (s *service) GetLinkedIdentities(userID uint) (*[]domain.LinkedIdentity, error) {
+	return s.repo.GetLinkedIdentitiesByUserID(userID)
+}
+
+func (s *service) UnlinkIdentity(userID uint, identityID uint) error {
+	deleted, err := s.repo.DeleteLinkedIdentity(userID, identityID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return domain.NewNotFoundError("linked_identity_not_found", "linked identity not found")
+	}
+
+	return nil
+}
+
+// useState consumes the state token the provider sent back, the binding has to come from the
+// browser that started the flow
+func (s *service) useState(providerName string, state string, binding string) (*flowState, error) {
+	token, err := s.tokenRepo.GetUserTokenByHash(domain.UserTokenPurposeOIDCState, s.cryptoService.HashToken(state))
+	if err != nil || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errInvalidState
+	}
+
+	consumed, err := s.tokenRepo.ConsumeUserToken(token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errInvalidState
+	}
+
+	var flow flowState
+	if err := json.Unmarshal([]byte(token.Data), &flow); err != nil || flow.Provider != providerName {
+		return nil, errInvalidState
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(s.cryptoService.HashToken(binding)), []byte(flow.Binding)) != 1 {
+		return nil, errInvalidState
+	}
+
+	return &flow, nil
+}
+
+// link adds the provider account to a logged in user
+func (s *service) link(userID uint, providerName string, claims *idTokenClaims) (*domain.LinkedIdentity, error) {
+	existing, err := s.repo.GetLinkedIdentity(providerName, claims.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, domain.NewConflictError("identity_linked_to_other_user", "this account is already linked to another user")
+		}
+		return existing, nil
+	}
+
+	if _, err := s.userRepo.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveLinkedIdentity(&domain.LinkedIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+}
+
+// resolveUser finds the user of a sign in: the linked one, else the user with the same verified
+// email, else a new user
+func (s *service) resolveUser(providerName string, claims *idTokenClaims) (uint, error) {
+	identity, err := s.repo.GetLinkedIdentity(providerName, claims.Subject)
+	if err == nil {
+		return identity.UserID, nil
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return 0, domain.NewForbiddenError("identity_email_unverified", "the identity provider did not share a verified email")
+	}
+
+	user, err := s.userRepo.GetUserByEmail(claims.Email)
+	if err == nil {
+		// Linking to an unverified account would hand it to whoever registered it with this email
+		if !user.EmailVerified {
+			return 0, domain.NewConflictError("account_exists", "an account with this email exists, log in and link the provider from your account")
+		}
+	} else {
+		user, err = s.createUser(claims)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = s.repo.SaveLinkedIdentity(&domain.LinkedIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+// createUser registers a user signing in with a provider for the first time. The password is
+// random, the user can set one with a password reset.
+func (s *service) createUser(claims *idTokenClaims) (*domain.User, error) {
+	password, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.cryptoService.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.SaveUser(&domain.User{
+		Username:      s.availableUsername(claims),
+		Email:         claims.Email,
+		Password:      hashedPassword,
+		EmailVerified: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("user %d registered with an identity provider", user.ID)
+	return user, nil
+}
+
+// availableUsername derives a free username from the preferred username or the email
+func (s *service) availableUsername(claims *idTokenClaims) string {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameInvalidChars.ReplaceAllString(base, "_"), "_.")
+	if len(base) > 30 {
+		base = base[:30]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 2; !s.userService.IsUsernameAvailable(username); i++ {
+		username = base + strconv.Itoa(i)
+	}
+	return username
+}
@@ -0,0 +1,400 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"final-project/pkg/crypto"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// testIssuer is a local stand-in OpenID Connect provider serving discovery, JWKS and the token
+// endpoint. Codes are handed out by authorize, the way the provider would after the user consents.
+type testIssuer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	// audience overrides the aud claim of the ID tokens when set
+	audience string
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+type issuedCode struct {
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{
+		key:      key,
+		clientID: "mygram",
+		codes:    map[string]issuedCode{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// authorize plays the user signing in at the provider and returns the code and state of the redirect back
+func (i *testIssuer) authorize(t *testing.T, authorizationURL string, subject string, email string) (string, string) {
+	t.Helper()
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != i.clientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request: %s", authorizationURL)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	code := fmt.Sprintf("code-%d", len(i.codes)+1)
+	i.codes[code] = issuedCode{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		subject:   subject,
+		email:     email,
+	}
+	return code, query.Get("state")
+}
+
+func (i *testIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	i.mu.Lock()
+	issued, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	if !ok || codeChallenge(r.PostForm.Get("code_verifier")) != issued.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	audience := i.clientID
+	if i.audience != "" {
+		audience = i.audience
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            i.server.URL,
+		"sub":            issued.subject,
+		"aud":            audience,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          issued.nonce,
+		"email":          issued.email,
+		"email_verified": true,
+	})
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+type fakeTokenRepo struct {
+	mu     sync.Mutex
+	tokens []domain.UserToken
+}
+
+func (r *fakeTokenRepo) SaveUserToken(token *domain.UserToken) (*domain.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, *token)
+	return token, nil
+}
+
+func (r *fakeTokenRepo) GetUserTokenByHash(purpose string, tokenHash string) (*domain.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeTokenRepo) ConsumeUserToken(tokenID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token := &r.tokens[tokenID-1]
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeTokenRepo) ConsumeUserTokens(userID uint, purpose string) error {
+	return nil
+}
+
+type fakeIdentityRepo struct {
+	identities []domain.LinkedIdentity
+}
+
+func (r *fakeIdentityRepo) SaveLinkedIdentity(identity *domain.LinkedIdentity) (*domain.LinkedIdentity, error) {
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, *identity)
+	return identity, nil
+}
+
+func (r *fakeIdentityRepo) GetLinkedIdentity(provider string, subject string) (*domain.LinkedIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeIdentityRepo) GetLinkedIdentitiesByUserID(userID uint) (*[]domain.LinkedIdentity, error) {
+	return &r.identities, nil
+}
+
+func (r *fakeIdentityRepo) DeleteLinkedIdentity(userID uint, identityID uint) (bool, error) {
+	return false, nil
+}
+
+type fakeUserService struct {
+	domain.UserService
+}
+
+func (fakeUserService) IsUsernameAvailable(username string) bool {
+	return true
+}
+
+func (fakeUserService) LoginWithIdentity(req *domain.IdentityLoginRequest) (*domain.LoginResult, error) {
+	return &domain.LoginResult{Token: fmt.Sprintf("token-of-%d", req.UserID)}, nil
+}
+
+type testSetup struct {
+	issuer     *testIssuer
+	service    domain.OIDCService
+	users      *fake.UserRepo
+	identities *fakeIdentityRepo
+}
+
+func newTestSetup(t *testing.T) *testSetup {
+	issuer := newTestIssuer(t)
+	users := fake.NewUserRepo()
+	identities := &fakeIdentityRepo{}
+	cryptoService := crypto.NewCryptoService(crypto.Config{Algorithm: crypto.AlgorithmBcrypt, BcryptCost: 4})
+
+	service := NewService(identities, users, &fakeTokenRepo{}, fakeUserService{}, cryptoService, Config{
+		Providers: map[string]ProviderConfig{
+			"test": {
+				Issuer:      issuer.server.URL,
+				ClientID:    issuer.clientID,
+				RedirectURL: "http://localhost/auth/oidc/test/callback",
+			},
+		},
+		StateTTL:   time.Minute,
+		HTTPClient: issuer.server.Client(),
+	})
+
+	return &testSetup{
+		issuer:     issuer,
+		service:    service,
+		users:      users,
+		identities: identities,
+	}
+}
+
+func TestSignInRegistersAndLinksNewUser(t *testing.T) {
+	setup := newTestSetup(t)
+
+	authorization, err := setup.service.AuthorizationURL("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := setup.issuer.authorize(t, authorization.URL, "subject-1", "alice@example.com")
+
+	result, err := setup.service.Callback(&domain.OIDCCallbackRequest{
+		Provider: "test",
+		Code:     code,
+		State:    state,
+		Binding:  authorization.Binding,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Login == nil || result.Login.Token != "token-of-1" {
+		t.Errorf("expected a login of the new user, got %+v", result)
+	}
+	if len(setup.users.Users) != 1 || !setup.users.Users[0].EmailVerified || setup.users.Users[0].Username != "alice" {
+		t.Errorf("unexpected users: %+v", setup.users.Users)
+	}
+	if len(setup.identities.identities) != 1 || setup.identities.identities[0].Subject != "subject-1" {
+		t.Errorf("unexpected identities: %+v", setup.identities.identities)
+	}
+}
+
+func TestCallbackRejectsFlowStartedInAnotherBrowser(t *testing.T) {
+	setup := newTestSetup(t)
+	setup.users.SaveUser(&domain.User{Username: "attacker", Email: "attacker@example.com"})
+
+	// The attacker starts linking on their account and sends the provider URL to the victim
+	attackerFlow, err := setup.service.AuthorizationURL("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := setup.issuer.authorize(t, attackerFlow.URL, "victim-subject", "victim@example.com")
+
+	// The victim's browser has no binding, or the one of a flow of its own
+	victimFlow, err := setup.service.AuthorizationURL("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, binding := range []string{"", victimFlow.Binding} {
+		_, err := setup.service.Callback(&domain.OIDCCallbackRequest{
+			Provider: "test",
+			Code:     code,
+			State:    state,
+			Binding:  binding,
+		})
+		if !errors.Is(err, errInvalidState) {
+			t.Errorf("binding %q: got %v, want errInvalidState", binding, err)
+		}
+	}
+
+	if len(setup.identities.identities) != 0 {
+		t.Errorf("no identity should be linked, got %+v", setup.identities.identities)
+	}
+}
+
+func TestCallbackLinksIdentityInTheBrowserThatStartedIt(t *testing.T) {
+	setup := newTestSetup(t)
+	setup.users.SaveUser(&domain.User{Username: "bob", Email: "bob@example.com"})
+
+	authorization, err := setup.service.AuthorizationURL("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := setup.issuer.authorize(t, authorization.URL, "bob-subject", "bob@gmail.example")
+
+	result, err := setup.service.Callback(&domain.OIDCCallbackRequest{
+		Provider: "test",
+		Code:     code,
+		State:    state,
+		Binding:  authorization.Binding,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LinkedIdentity == nil || result.LinkedIdentity.UserID != 1 {
+		t.Errorf("expected an identity linked to user 1, got %+v", result)
+	}
+
+	// The state is single use
+	_, err = setup.service.Callback(&domain.OIDCCallbackRequest{
+		Provider: "test",
+		Code:     code,
+		State:    state,
+		Binding:  authorization.Binding,
+	})
+	if !errors.Is(err, errInvalidState) {
+		t.Errorf("replayed state: got %v, want errInvalidState", err)
+	}
+}
+
+func TestCallbackRejectsTokenForAnotherClient(t *testing.T) {
+	setup := newTestSetup(t)
+	setup.issuer.audience = "another-client"
+
+	authorization, err := setup.service.AuthorizationURL("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := setup.issuer.authorize(t, authorization.URL, "subject-1", "alice@example.com")
+
+	_, err = setup.service.Callback(&domain.OIDCCallbackRequest{
+		Provider: "test",
+		Code:     code,
+		State:    state,
+		Binding:  authorization.Binding,
+	})
+	if !errors.Is(err, errProviderFailed) {
+		t.Errorf("got %v, want errProviderFailed", err)
+	}
+	if len(setup.users.Users) != 0 {
+		t.Errorf("no user should be registered, got %+v", setup.users.Users)
+	}
+}
+
+func TestVerifyIDTokenChecksNonce(t *testing.T) {
+	setup := newTestSetup(t)
+	p := newProvider(ProviderConfig{Issuer: setup.issuer.server.URL, ClientID: setup.issuer.clientID}, setup.issuer.server.Client())
+
+	authorizationURL, err := p.authorizationURL("state", "expected-nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := setup.issuer.authorize(t, authorizationURL, "subject-1", "alice@example.com")
+	idToken, err := p.exchange(code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.verifyIDToken(idToken, "other-nonce"); err == nil {
+		t.Error("an ID token with another nonce should be rejected")
+	}
+	if _, err := p.verifyIDToken(idToken, "expected-nonce"); err != nil {
+		t.Errorf("valid ID token rejected: %v", err)
+	}
+}
@@ -2,37 +2,72 @@ package photo
 
 import (
 	"final-project/pkg/domain"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type service struct {
-	repo domain.PhotoRepository
+	repo   domain.PhotoRepository
+	events domain.EventPublisher
 }
 
-func NewService(repo domain.PhotoRepository) domain.PhotoService {
+func NewService(repo domain.PhotoRepository, events domain.EventPublisher) domain.PhotoService {
 	return &service{
-		repo: repo,
+		repo:   repo,
+		events: events,
 	}
 }
 
 func (s *service) SavePhoto(userID uint, photo *domain.AddPhotoRequest) (*domain.Photo, error) {
+	if err := validate(photo); err != nil {
+		return nil, err
+	}
+
 	photoToSave := &domain.Photo{
-		Title:    photo.Title,
-		Caption:  photo.Caption,
-		PhotoUrl: photo.PhotoUrl,
-		UserID:   userID,
+		Title:     photo.Title,
+		Caption:   photo.Caption,
+		PhotoUrl:  photo.PhotoUrl,
+		UserID:    userID,
+		CreatedAt: photo.CreatedAt,
+	}
+	saved, err := s.repo.SavePhoto(photoToSave)
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.SavePhoto(photoToSave)
+
+	s.events.Publish(&domain.Event{
+		Type:       domain.EventPhotoCreated,
+		ActorID:    userID,
+		UserIDs:    []uint{userID},
+		Data:       saved,
+		OccurredAt: time.Now(),
+	})
+	return saved, nil
 }
 
 func (s *service) GetPhotoByID(photoID uint) (*domain.Photo, error) {
 	return s.repo.GetPhotoByID(photoID)
 }
 
-func (s *service) GetPhotosByUserID(userID uint) (*[]domain.Photo, error) {
-	return s.repo.GetPhotosByUserID(userID)
+func (s *service) GetPhotosByUserID(userID uint, page domain.PageRequest) (*[]domain.Photo, int64, error) {
+	return s.repo.GetPhotosByUserID(userID, page)
+}
+
+func (s *service) GetPhotosByIDs(photoIDs []uint) (*[]domain.Photo, error) {
+	return s.repo.GetPhotosByIDs(photoIDs)
+}
+
+func (s *service) GetPhotosByUserIDs(userIDs []uint) (*[]domain.Photo, error) {
+	return s.repo.GetPhotosByUserIDs(userIDs)
 }
 
 func (s *service) UpdatePhoto(photoID uint, newPhoto *domain.AddPhotoRequest) (*domain.Photo, error) {
+	if err := validate(newPhoto); err != nil {
+		return nil, err
+	}
+
 	photo, err := s.GetPhotoByID(photoID)
 	if err != nil {
 		return nil, err
@@ -46,3 +81,27 @@ func (s *service) UpdatePhoto(photoID uint, newPhoto *domain.AddPhotoRequest) (*
 func (s *service) DeletePhoto(photoID uint) error {
 	return s.repo.DeletePhotoByID(photoID)
 }
+
+// validate applies the same rules as the REST request bindings so other callers like the importer can't bypass them
+func validate(photo *domain.AddPhotoRequest) error {
+	if strings.TrimSpace(photo.Title) == "" {
+		return domain.NewFieldValidationError("title", "is required")
+	}
+	if utf8.RuneCountInString(photo.Title) > 255 {
+		return domain.NewFieldValidationError("title", "must be at most 255 characters")
+	}
+	if utf8.RuneCountInString(photo.Caption) > 2048 {
+		return domain.NewFieldValidationError("caption", "must be at most 2048 characters")
+	}
+	if photo.PhotoUrl == "" {
+		return domain.NewFieldValidationError("photo_url", "is required")
+	}
+	if len(photo.PhotoUrl) > 512 {
+		return domain.NewFieldValidationError("photo_url", "must be at most 512 characters")
+	}
+	if u, err := url.ParseRequestURI(photo.PhotoUrl); err != nil || u.Scheme == "" || u.Host == "" {
+		return domain.NewFieldValidationError("photo_url", "must be a valid url")
+	}
+
+	return nil
+}
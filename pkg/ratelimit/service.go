@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"final-project/pkg/domain"
+	"math"
+	"time"
+)
+
+type service struct {
+	store domain.RateLimitStore
+}
+
+// NewService returns a sliding window limiter: the count of the previous fixed window
+// is weighted by how much of it still overlaps the sliding window
+func NewService(store domain.RateLimitStore) domain.RateLimiter {
+	return &service{
+		store: store,
+	}
+}
+
+func (s *service) Allow(key string, policy domain.RateLimitPolicy) (*domain.RateLimitResult, error) {
+	now := time.Now()
+	windowStart := now.Truncate(policy.Window)
+	elapsed := now.Sub(windowStart)
+
+	previous, err := s.store.GetCount(key, windowStart.Add(-policy.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	// The request is counted before it is checked so concurrent requests can't all slip under the
+	// limit. Counters are kept for two windows since the next window still reads this one.
+	current, err := s.store.Increment(key, windowStart, windowStart.Add(2*policy.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	previousWeight := 1 - float64(elapsed)/float64(policy.Window)
+	estimate := float64(previous)*previousWeight + float64(current)
+
+	result := &domain.RateLimitResult{
+		Limit: policy.Limit,
+		Reset: policy.Window - elapsed,
+	}
+
+	// Rejected requests are taken back so the client recovers as soon as the window slides
+	if estimate > float64(policy.Limit) {
+		if err := s.store.Decrement(key, windowStart); err != nil {
+			return nil, err
+		}
+		result.RetryAfter = retryAfter(previous, current-1, elapsed, policy)
+		return result, nil
+	}
+
+	result.Allowed = true
+	result.Remaining = int(math.Max(0, math.Floor(float64(policy.Limit)-estimate)))
+	return result, nil
+}
+
+func (s *service) PurgeExpired() (int64, error) {
+	return s.store.DeleteExpired(time.Now())
+}
+
+// retryAfter computes when the weighted count will have dropped enough to let one more request through
+func retryAfter(previous int, current int, elapsed time.Duration, policy domain.RateLimitPolicy) time.Duration {
+	window := float64(policy.Window)
+	free := float64(policy.Limit - 1)
+
+	var wait float64
+	if float64(current) <= free && previous > 0 {
+		// Wait for the previous window to slide out far enough in the current window
+		wait = window*(1-(free-float64(current))/float64(previous)) - float64(elapsed)
+	} else {
+		// The current window alone is full, wait for it to become the previous one
+		wait = window - float64(elapsed)
+		if current > 0 {
+			wait += window * math.Max(0, 1-free/float64(current))
+		}
+	}
+
+	if wait < float64(time.Second) {
+		wait = float64(time.Second)
+	}
+	return time.Duration(wait)
+}
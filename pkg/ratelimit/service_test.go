@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"final-project/pkg/domain"
+	"final-project/pkg/storage/memory"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAllowConcurrentRequests(t *testing.T) {
+	limiter := NewService(memory.NewRateLimitStore())
+	policy := domain.RateLimitPolicy{Limit: 10, Window: time.Hour}
+
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := limiter.Allow("ip:1", policy)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if result.Allowed {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != int32(policy.Limit) {
+		t.Errorf("%d concurrent requests allowed, limit is %d", allowed, policy.Limit)
+	}
+}
+
+func TestAllowDoesNotCountRejected(t *testing.T) {
+	store := memory.NewRateLimitStore()
+	limiter := NewService(store)
+	policy := domain.RateLimitPolicy{Limit: 3, Window: time.Hour}
+
+	for i := 0; i < 5; i++ {
+		result, err := limiter.Allow("ip:1", policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed && result.RetryAfter != 0 {
+			t.Errorf("allowed request %d has a retry after", i)
+		}
+		if !result.Allowed && result.RetryAfter <= 0 {
+			t.Errorf("rejected request %d has no retry after", i)
+		}
+	}
+
+	count, err := store.GetCount("ip:1", time.Now().Truncate(policy.Window))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count > policy.Limit {
+		t.Errorf("counter is %d, rejected requests should be taken back", count)
+	}
+}
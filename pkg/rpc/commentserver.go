@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"final-project/pkg/rpc/pb"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// commentInput holds the rules of rest.AddCommentRequest
+type commentInput struct {
+	Message string `json:"message" validate:"required,max=2048"`
+}
+
+type commentServer struct {
+	pb.UnimplementedCommentServiceServer
+	commentService domain.CommentService
+	photoService   domain.PhotoService
+	blockService   domain.BlockService
+}
+
+func (s *commentServer) CreateComment(ctx context.Context, req *pb.CreateCommentRequest) (*pb.Comment, error) {
+	input := commentInput{Message: req.Message}
+	if err := validate.Struct(input); err != nil {
+		return nil, err
+	}
+	photoID, err := requireID(req.PhotoId, "photo_id")
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if photo exist
+	if _, err := s.photoService.GetPhotoByID(photoID); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentService.AddComment(callerFrom(ctx).userID, photoID, input.Message)
+	if err != nil {
+		return nil, err
+	}
+	return toComment(comment), nil
+}
+
+func (s *commentServer) GetComment(ctx context.Context, req *pb.GetCommentRequest) (*pb.Comment, error) {
+	commentID, err := requireID(req.Id, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentService.GetCommentByID(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The comments of hidden users, and the ones on their photos, don't exist for the caller
+	hidden, err := s.blockService.HiddenUserIDs(callerFrom(ctx).userID)
+	if err != nil {
+		return nil, err
+	}
+	if hidden[comment.UserID] {
+		return nil, domain.ErrCommentNotFound
+	}
+	photo, err := s.photoService.GetPhotoByID(comment.PhotoID)
+	if err != nil {
+		return nil, err
+	}
+	if hidden[photo.UserID] {
+		return nil, domain.ErrCommentNotFound
+	}
+	return toComment(comment), nil
+}
+
+func (s *commentServer) ListComments(req *pb.ListCommentsRequest, stream pb.CommentService_ListCommentsServer) error {
+	if len(req.PhotoIds) > 0 {
+		comments, err := s.commentService.GetCommentsByPhotoIDs(toIDs(req.PhotoIds))
+		if err != nil {
+			return err
+		}
+		hidden, err := s.hiddenAuthorsAndPhotos(stream.Context(), toIDs(req.PhotoIds))
+		if err != nil {
+			return err
+		}
+		for i := range *comments {
+			if hidden(&(*comments)[i]) {
+				continue
+			}
+			if err := stream.Send(toComment(&(*comments)[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The comments of the current user are sent page by page
+	currentUserID := callerFrom(stream.Context()).userID
+	for page := 1; ; page++ {
+		comments, _, err := s.commentService.GetCommentsByUserID(currentUserID, domain.PageRequest{Page: page, PerPage: streamPageSize})
+		if err != nil {
+			return err
+		}
+		for i := range *comments {
+			if err := stream.Send(toComment(&(*comments)[i])); err != nil {
+				return err
+			}
+		}
+		if len(*comments) < streamPageSize {
+			return nil
+		}
+	}
+}
+
+// hiddenAuthorsAndPhotos returns whether a comment on one of the photos is hidden from the caller,
+// being written by a hidden user or on a photo of one
+func (s *commentServer) hiddenAuthorsAndPhotos(ctx context.Context, photoIDs []uint) (func(*domain.Comment) bool, error) {
+	hiddenUsers, err := s.blockService.HiddenUserIDs(callerFrom(ctx).userID)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := s.photoService.GetPhotosByIDs(photoIDs)
+	if err != nil {
+		return nil, err
+	}
+	hiddenPhotos := make(map[uint]bool)
+	for _, photo := range *photos {
+		if hiddenUsers[photo.UserID] {
+			hiddenPhotos[photo.ID] = true
+		}
+	}
+
+	return func(comment *domain.Comment) bool {
+		return hiddenUsers[comment.UserID] || hiddenPhotos[comment.PhotoID]
+	}, nil
+}
+
+func (s *commentServer) UpdateComment(ctx context.Context, req *pb.UpdateCommentRequest) (*pb.Comment, error) {
+	input := commentInput{Message: req.Message}
+	if err := validate.Struct(input); err != nil {
+		return nil, err
+	}
+	commentID, err := s.ownedComment(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentService.UpdateComment(commentID, input.Message)
+	if err != nil {
+		return nil, err
+	}
+	return toComment(comment), nil
+}
+
+func (s *commentServer) DeleteComment(ctx context.Context, req *pb.DeleteCommentRequest) (*emptypb.Empty, error) {
+	commentID, err := s.ownedComment(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.commentService.DeleteComment(commentID); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ownedComment returns the id of a comment of the caller
+func (s *commentServer) ownedComment(ctx context.Context, id uint64) (uint, error) {
+	commentID, err := requireID(id, "id")
+	if err != nil {
+		return 0, err
+	}
+
+	comment, err := s.commentService.GetCommentByID(commentID)
+	if err != nil {
+		return 0, err
+	}
+	if comment.UserID != callerFrom(ctx).userID {
+		return 0, domain.ErrNotOwner
+	}
+	return commentID, nil
+}
+
+func toComment(comment *domain.Comment) *pb.Comment {
+	return &pb.Comment{
+		Id:        uint64(comment.ID),
+		UserId:    uint64(comment.UserID),
+		PhotoId:   uint64(comment.PhotoID),
+		Message:   comment.Message,
+		CreatedAt: timestamppb.New(comment.CreatedAt),
+		UpdatedAt: timestamppb.New(comment.UpdatedAt),
+	}
+}
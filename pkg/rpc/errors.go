@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"errors"
+	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo details, their reason is the code of the domain error
+const errorDomain = "mygram"
+
+// errorKindCodes maps the domain error kinds to gRPC status codes
+var errorKindCodes = map[domain.ErrorKind]codes.Code{
+	domain.ErrorKindValidation:      codes.InvalidArgument,
+	domain.ErrorKindUnauthorized:    codes.Unauthenticated,
+	domain.ErrorKindForbidden:       codes.PermissionDenied,
+	domain.ErrorKindNotFound:        codes.NotFound,
+	domain.ErrorKindConflict:        codes.AlreadyExists,
+	domain.ErrorKindGone:            codes.FailedPrecondition,
+	domain.ErrorKindTooManyRequests: codes.ResourceExhausted,
+	domain.ErrorKindNotAcceptable:   codes.InvalidArgument,
+	domain.ErrorKindTooLarge:        codes.ResourceExhausted,
+}
+
+// toStatus converts an error of a handler to the status sent to the client. Domain errors keep their
+// message and carry their code in an ErrorInfo, invalid fields in a BadRequest. Any other error is
+// an internal error whose details are only logged.
+func toStatus(method string, err error) *status.Status {
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+
+	var domainErr *domain.Error
+	if verr := toValidationError(err); verr != nil {
+		domainErr = verr
+	} else if !errors.As(err, &domainErr) || errorKindCodes[domainErr.Kind] == codes.OK {
+		log.Printf("%s: %v", method, err)
+		return status.New(codes.Internal, "internal server error")
+	}
+
+	s := status.New(errorKindCodes[domainErr.Kind], domainErr.Message)
+	info := &errdetails.ErrorInfo{Reason: domainErr.Code, Domain: errorDomain}
+	if len(domainErr.Fields) == 0 {
+		if withDetails, err := s.WithDetails(info); err == nil {
+			return withDetails
+		}
+		return s
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range domainErr.Fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+		})
+	}
+	if withDetails, err := s.WithDetails(info, badRequest); err == nil {
+		return withDetails
+	}
+	return s
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Fields are named like in the protobuf messages
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+	return v
+}
+
+// toValidationError converts the errors of validate, nil for other errors
+func toValidationError(err error) *domain.Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	fields := make([]domain.FieldError, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields = append(fields, domain.FieldError{
+			Field:   fieldErr.Field(),
+			Message: validationMessage(fieldErr),
+		})
+	}
+	return domain.NewValidationError(domain.ErrCodeValidationFailed, "request has invalid fields", fields...)
+}
+
+// validationMessage describes the failed rule of a validate tag
+func validationMessage(fieldErr validator.FieldError) string {
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "url":
+		return "must be a valid url"
+	case "max":
+		return "must be at most " + fieldErr.Param() + unit
+	case "gt":
+		return "must be greater than " + fieldErr.Param() + unit
+	}
+	return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+}
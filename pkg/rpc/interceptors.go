@@ -0,0 +1,228 @@
+package rpc
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// caller is the authenticated user of a call
+type caller struct {
+	userID uint
+	// scopes are the scopes of an API key, nil for tokens which have every scope
+	scopes []string
+}
+
+type callerKey struct{}
+
+func callerFrom(ctx context.Context) *caller {
+	return ctx.Value(callerKey{}).(*caller)
+}
+
+// methodRule is the scope a method needs from API keys, the route group whose verified email
+// requirement applies to it, and the REST route whose rate limits it counts against
+type methodRule struct {
+	scope string
+	group string
+	route route
+}
+
+// route is the route group and HTTP method of a REST route
+type route struct {
+	group  string
+	method string
+}
+
+// methodRules mirror the scopes, the verified email guards and the rate limits of the REST routes.
+// API keys can't call the methods missing here, an empty scope lets any key call the method.
+var methodRules = map[string]methodRule{
+	// counted per user, the users routes count per IP as they are used before logging in
+	"/mygram.v1.UserService/GetCurrentUser": {scope: domain.ScopeProfileRead, route: route{"users", http.MethodGet}},
+	// the public profiles, like the users embedded in the responses of any scope
+	"/mygram.v1.UserService/GetUsers": {route: route{"users", http.MethodGet}},
+
+	"/mygram.v1.PhotoService/CreatePhoto": {scope: domain.ScopePhotosWrite, group: "photos", route: route{"photos", http.MethodPost}},
+	"/mygram.v1.PhotoService/GetPhoto":    {scope: domain.ScopePhotosRead, route: route{"photos", http.MethodGet}},
+	"/mygram.v1.PhotoService/ListPhotos":  {scope: domain.ScopePhotosRead, route: route{"photos", http.MethodGet}},
+	"/mygram.v1.PhotoService/UpdatePhoto": {scope: domain.ScopePhotosWrite, group: "photos", route: route{"photos", http.MethodPut}},
+	"/mygram.v1.PhotoService/DeletePhoto": {scope: domain.ScopePhotosWrite, route: route{"photos", http.MethodDelete}},
+
+	"/mygram.v1.CommentService/CreateComment": {scope: domain.ScopeCommentsWrite, group: "comments", route: route{"comments", http.MethodPost}},
+	"/mygram.v1.CommentService/GetComment":    {scope: domain.ScopeCommentsRead, route: route{"comments", http.MethodGet}},
+	"/mygram.v1.CommentService/ListComments":  {scope: domain.ScopeCommentsRead, route: route{"comments", http.MethodGet}},
+	"/mygram.v1.CommentService/UpdateComment": {scope: domain.ScopeCommentsWrite, group: "comments", route: route{"comments", http.MethodPut}},
+	"/mygram.v1.CommentService/DeleteComment": {scope: domain.ScopeCommentsWrite, route: route{"comments", http.MethodDelete}},
+
+	"/mygram.v1.SocialMediaService/CreateSocialMedia": {scope: domain.ScopeSocialMediasWrite, group: "socialmedias", route: route{"socialmedias", http.MethodPost}},
+	"/mygram.v1.SocialMediaService/GetSocialMedia":    {scope: domain.ScopeSocialMediasRead, route: route{"socialmedias", http.MethodGet}},
+	"/mygram.v1.SocialMediaService/ListSocialMedias":  {scope: domain.ScopeSocialMediasRead, route: route{"socialmedias", http.MethodGet}},
+	"/mygram.v1.SocialMediaService/UpdateSocialMedia": {scope: domain.ScopeSocialMediasWrite, group: "socialmedias", route: route{"socialmedias", http.MethodPut}},
+	"/mygram.v1.SocialMediaService/DeleteSocialMedia": {scope: domain.ScopeSocialMediasWrite, route: route{"socialmedias", http.MethodDelete}},
+}
+
+var errRateLimited = domain.NewTooManyRequestsError("rate_limited", "too many requests")
+
+// authenticator checks the bearer token or API key of the "authorization" metadata like
+// rest.AuthMiddleware, then the scope, the rate limits and the verified email required by the method
+type authenticator struct {
+	authService           domain.AuthService
+	userService           domain.UserService
+	apiKeyService         domain.APIKeyService
+	rateLimiter           domain.RateLimiter
+	rateLimits            []domain.RateLimitRule
+	verifiedEmailRequired []string
+}
+
+// authenticate returns ctx with the caller of method
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	// Reflection describes the services to anyone who can reach the port
+	if strings.HasPrefix(method, "/grpc.reflection.") {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, domain.NewUnauthorizedError("invalid_token", "invalid token format")
+	}
+	token := strings.TrimPrefix(values[0], "Bearer ")
+
+	c, err := a.caller(token)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, ok := methodRules[method]
+	if !ok && c.scopes != nil {
+		return nil, domain.NewForbiddenError("insufficient_scope", "API keys can't call this method")
+	}
+	if err := c.requireScope(rule.scope); err != nil {
+		return nil, err
+	}
+	if err := a.rateLimit(c, rule.route); err != nil {
+		return nil, err
+	}
+	if err := a.requireVerifiedEmail(c, rule.group); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, callerKey{}, c), nil
+}
+
+func (a *authenticator) caller(token string) (*caller, error) {
+	if a.apiKeyService.IsAPIKey(token) {
+		key, err := a.apiKeyService.Authenticate(token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Reject keys of accounts pending deletion
+		if _, err := a.userService.GetUserByID(key.UserID); err != nil {
+			return nil, domain.NewUnauthorizedError("invalid_api_key", "invalid or expired API key")
+		}
+		return &caller{userID: key.UserID, scopes: append([]string{}, key.Scopes...)}, nil
+	}
+
+	claims, err := a.authService.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject tokens of deleted accounts, accounts pending deletion, revoked tokens and revoked sessions
+	if err := a.userService.VerifyTokenClaims(claims); err != nil {
+		return nil, err
+	}
+	return &caller{userID: claims.UserID}, nil
+}
+
+func (c *caller) requireScope(scope string) error {
+	if scope == "" || c.scopes == nil {
+		return nil
+	}
+	for _, granted := range c.scopes {
+		if granted == scope {
+			return nil
+		}
+	}
+	return domain.NewForbiddenError("insufficient_scope", fmt.Sprintf("API key is missing the %s scope", scope))
+}
+
+// rateLimit counts the call against the rules of the REST route doing the same, with the counters of
+// the REST API
+func (a *authenticator) rateLimit(c *caller, r route) error {
+	for _, rule := range a.rateLimits {
+		if rule.Group != r.group || !rule.Covers(r.method) {
+			continue
+		}
+
+		result, err := a.rateLimiter.Allow(domain.RateLimitUserKey(rule.Name(), c.userID), rule.Policy)
+		if err != nil {
+			// Don't take the API down with the counter store
+			log.Printf("rate limit %s: %v", rule.Name(), err)
+			continue
+		}
+		if !result.Allowed {
+			return errRateLimited
+		}
+	}
+	return nil
+}
+
+func (a *authenticator) requireVerifiedEmail(c *caller, group string) error {
+	for _, restricted := range a.verifiedEmailRequired {
+		if group == "" || restricted != group {
+			continue
+		}
+
+		user, err := a.userService.GetUserByID(c.userID)
+		if err != nil {
+			return err
+		}
+		if !user.EmailVerified {
+			return domain.NewForbiddenError("email_not_verified", "please verify your email first")
+		}
+	}
+	return nil
+}
+
+// unaryInterceptor authenticates unary calls and converts the errors of the handlers to statuses
+func (a *authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, toStatus(info.FullMethod, err).Err()
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(info.FullMethod, err).Err()
+	}
+	return resp, nil
+}
+
+// streamInterceptor is unaryInterceptor for streaming calls
+func (a *authenticator) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return toStatus(info.FullMethod, err).Err()
+	}
+
+	if err := handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx}); err != nil {
+		return toStatus(info.FullMethod, err).Err()
+	}
+	return nil
+}
+
+// authenticatedStream carries the context with the caller to stream handlers
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,296 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"final-project/pkg/rpc/pb"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// The token "user-1" authenticates user 1 whose email is verified, "user-2" user 2 whose email isn't.
+// API keys are "mgp_" followed by their scopes.
+type fakeAuthService struct {
+	domain.AuthService
+}
+
+func (fakeAuthService) ValidateToken(token string) (*domain.TokenClaims, error) {
+	switch token {
+	case "user-1":
+		return &domain.TokenClaims{UserID: 1}, nil
+	case "user-2":
+		return &domain.TokenClaims{UserID: 2}, nil
+	}
+	return nil, domain.NewUnauthorizedError("invalid_token", "invalid token")
+}
+
+type fakeUserService struct {
+	domain.UserService
+}
+
+func (fakeUserService) VerifyTokenClaims(claims *domain.TokenClaims) error {
+	return nil
+}
+
+func (fakeUserService) GetUserByID(userID uint) (*domain.User, error) {
+	return &domain.User{ID: userID, Username: "user", EmailVerified: userID == 1}, nil
+}
+
+func (fakeUserService) GetUsersByIDs(userIDs []uint) (*[]domain.User, error) {
+	users := []domain.User{}
+	for _, userID := range userIDs {
+		users = append(users, domain.User{ID: userID, Username: "user"})
+	}
+	return &users, nil
+}
+
+type fakeAPIKeyService struct {
+	domain.APIKeyService
+}
+
+func (fakeAPIKeyService) IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, "mgp_")
+}
+
+func (fakeAPIKeyService) Authenticate(key string) (*domain.APIKey, error) {
+	scopes := strings.Split(strings.TrimPrefix(key, "mgp_"), ",")
+	return &domain.APIKey{UserID: 1, Scopes: scopes}, nil
+}
+
+// Photo 1 exists, photo 2 fails with an internal error
+type fakePhotoService struct {
+	domain.PhotoService
+}
+
+func (fakePhotoService) GetPhotoByID(photoID uint) (*domain.Photo, error) {
+	switch photoID {
+	case 1:
+		return &domain.Photo{ID: 1, Title: "Sunset", UserID: 1}, nil
+	case 2:
+		return nil, errors.New("connection refused")
+	}
+	return nil, domain.ErrPhotoNotFound
+}
+
+func (fakePhotoService) SavePhoto(userID uint, req *domain.AddPhotoRequest) (*domain.Photo, error) {
+	return &domain.Photo{ID: 3, Title: req.Title, PhotoUrl: req.PhotoUrl, UserID: userID}, nil
+}
+
+// Social media 2 belongs to user 2, social media 3 to user 3
+type fakeSocialMediaService struct {
+	domain.SocialMediaService
+}
+
+func (fakeSocialMediaService) GetSocialMediaByID(socialMediaID uint) (*domain.SocialMedia, error) {
+	if socialMediaID != 2 && socialMediaID != 3 {
+		return nil, domain.ErrSocialMediaNotFound
+	}
+	return &domain.SocialMedia{ID: socialMediaID, Name: "site", UserID: socialMediaID}, nil
+}
+
+func (fakeSocialMediaService) GetSocialMediasByUserIDs(userIDs []uint) (*[]domain.SocialMedia, error) {
+	socialMedias := []domain.SocialMedia{}
+	for _, userID := range userIDs {
+		socialMedias = append(socialMedias, domain.SocialMedia{ID: userID, Name: "site", UserID: userID})
+	}
+	return &socialMedias, nil
+}
+
+// User 3 is hidden from user 1
+type fakeBlockService struct {
+	domain.BlockService
+}
+
+func (fakeBlockService) HiddenUserIDs(userID uint) (map[uint]bool, error) {
+	if userID == 1 {
+		return map[uint]bool{3: true}, nil
+	}
+	return map[uint]bool{}, nil
+}
+
+// dialTestServer serves NewServer and the health service, which has no rule, over an in-memory
+// connection
+func dialTestServer(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	return dialRateLimitedTestServer(t, fake.NewRateLimiter())
+}
+
+// dialRateLimitedTestServer is dialTestServer counting the calls with limiter, users may create or
+// update 3 photos
+func dialRateLimitedTestServer(t *testing.T, limiter *fake.RateLimiter) *grpc.ClientConn {
+	t.Helper()
+	server := NewServer(fakeUserService{}, fakeAuthService{}, fakePhotoService{}, nil, fakeSocialMediaService{}, fakeAPIKeyService{}, fakeBlockService{}, limiter, Config{
+		VerifiedEmailRequired: []string{"photos"},
+		RateLimits: []domain.RateLimitRule{
+			{Group: "photos", Methods: []string{"POST", "PUT"}, Policy: domain.RateLimitPolicy{Limit: 3, Window: time.Hour}},
+			{Group: "photos", Policy: domain.RateLimitPolicy{Limit: 300, Window: time.Minute}},
+		},
+	})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// requireStatus checks the code of err and the reason of its ErrorInfo
+func requireStatus(t *testing.T, err error, code codes.Code, reason string) *status.Status {
+	t.Helper()
+	s := status.Convert(err)
+	if s.Code() != code {
+		t.Fatalf("got %v %q, want %v", s.Code(), s.Message(), code)
+	}
+	if reason == "" {
+		return s
+	}
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if info.Reason != reason || info.Domain != errorDomain {
+				t.Errorf("error info %s/%s, want %s/%s", info.Domain, info.Reason, errorDomain, reason)
+			}
+			return s
+		}
+	}
+	t.Errorf("no error info in %v", s.Details())
+	return s
+}
+
+func TestAuthentication(t *testing.T) {
+	users := pb.NewUserServiceClient(dialTestServer(t))
+
+	_, err := users.GetCurrentUser(context.Background(), &emptypb.Empty{})
+	requireStatus(t, err, codes.Unauthenticated, "invalid_token")
+
+	_, err = users.GetCurrentUser(withToken("forged"), &emptypb.Empty{})
+	requireStatus(t, err, codes.Unauthenticated, "invalid_token")
+
+	user, err := users.GetCurrentUser(withToken("user-1"), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != 1 {
+		t.Errorf("got user %d, want 1", user.Id)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	conn := dialTestServer(t)
+	photos := pb.NewPhotoServiceClient(conn)
+	users := pb.NewUserServiceClient(conn)
+	healthClient := healthpb.NewHealthClient(conn)
+
+	if _, err := photos.GetPhoto(withToken("mgp_photos:read"), &pb.GetPhotoRequest{Id: 1}); err != nil {
+		t.Errorf("key with the scope: %v", err)
+	}
+	_, err := photos.CreatePhoto(withToken("mgp_photos:read"), &pb.CreatePhotoRequest{Title: "t", PhotoUrl: "https://example.com/a.jpg"})
+	requireStatus(t, err, codes.PermissionDenied, "insufficient_scope")
+
+	// any key may look users up, none calls a method without a rule
+	if _, err := users.GetUsers(withToken("mgp_photos:read"), &pb.GetUsersRequest{Ids: []uint64{2}}); err != nil {
+		t.Errorf("GetUsers is open to every key: %v", err)
+	}
+	_, err = healthClient.Check(withToken("mgp_"+strings.Join(domain.APIKeyScopes, ",")), &healthpb.HealthCheckRequest{})
+	requireStatus(t, err, codes.PermissionDenied, "insufficient_scope")
+
+	// tokens have every scope
+	if _, err := healthClient.Check(withToken("user-1"), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("token on a method without a rule: %v", err)
+	}
+}
+
+func TestVerifiedEmailRequired(t *testing.T) {
+	photos := pb.NewPhotoServiceClient(dialTestServer(t))
+	req := &pb.CreatePhotoRequest{Title: "t", PhotoUrl: "https://example.com/a.jpg"}
+
+	_, err := photos.CreatePhoto(withToken("user-2"), req)
+	requireStatus(t, err, codes.PermissionDenied, "email_not_verified")
+
+	if _, err := photos.CreatePhoto(withToken("user-1"), req); err != nil {
+		t.Errorf("verified user: %v", err)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	limiter := fake.NewRateLimiter()
+	photos := pb.NewPhotoServiceClient(dialRateLimitedTestServer(t, limiter))
+	ctx := withToken("user-1")
+	req := &pb.CreatePhotoRequest{Title: "t", PhotoUrl: "https://example.com/a.jpg"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := photos.CreatePhoto(ctx, req); err != nil {
+			t.Fatalf("photo %d: %v", i+1, err)
+		}
+	}
+	_, err := photos.CreatePhoto(ctx, req)
+	requireStatus(t, err, codes.ResourceExhausted, "rate_limited")
+
+	if _, err := photos.GetPhoto(ctx, &pb.GetPhotoRequest{Id: 1}); err != nil {
+		t.Errorf("reads have their own limit: %v", err)
+	}
+
+	// the counters of the REST routes
+	if got := limiter.Count("photos:POST,PUT:user:1"); got != 4 {
+		t.Errorf("photos:POST,PUT:user:1 counted %d calls, want 4", got)
+	}
+	if got := limiter.Count("photos:user:1"); got != 4 {
+		t.Errorf("photos:user:1 counted %d calls, want 4", got)
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	photos := pb.NewPhotoServiceClient(dialTestServer(t))
+	ctx := withToken("user-1")
+
+	_, err := photos.GetPhoto(ctx, &pb.GetPhotoRequest{Id: 9})
+	requireStatus(t, err, codes.NotFound, "photo_not_found")
+
+	_, err = photos.GetPhoto(ctx, &pb.GetPhotoRequest{Id: 2})
+	if s := requireStatus(t, err, codes.Internal, ""); s.Message() != "internal server error" {
+		t.Errorf("internal details leaked: %q", s.Message())
+	}
+
+	_, err = photos.CreatePhoto(ctx, &pb.CreatePhotoRequest{PhotoUrl: "not a url"})
+	s := requireStatus(t, err, codes.InvalidArgument, domain.ErrCodeValidationFailed)
+	violations := map[string]string{}
+	for _, detail := range s.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				violations[violation.Field] = violation.Description
+			}
+		}
+	}
+	if violations["title"] != "is required" || violations["photo_url"] != "must be a valid url" {
+		t.Errorf("unexpected field violations: %v", violations)
+	}
+}
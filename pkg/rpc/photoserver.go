@@ -0,0 +1,162 @@
+package rpc
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"final-project/pkg/rpc/pb"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// photoInput holds the rules of rest.AddPhotoRequest
+type photoInput struct {
+	Title    string `json:"title" validate:"required,max=255"`
+	Caption  string `json:"caption" validate:"max=2048"`
+	PhotoUrl string `json:"photo_url" validate:"required,max=512,url"`
+}
+
+type photoServer struct {
+	pb.UnimplementedPhotoServiceServer
+	photoService domain.PhotoService
+	blockService domain.BlockService
+}
+
+func (s *photoServer) CreatePhoto(ctx context.Context, req *pb.CreatePhotoRequest) (*pb.Photo, error) {
+	input := photoInput{Title: req.Title, Caption: req.Caption, PhotoUrl: req.PhotoUrl}
+	if err := validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	photo, err := s.photoService.SavePhoto(callerFrom(ctx).userID, &domain.AddPhotoRequest{
+		Title:    input.Title,
+		Caption:  input.Caption,
+		PhotoUrl: input.PhotoUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toPhoto(photo), nil
+}
+
+func (s *photoServer) GetPhoto(ctx context.Context, req *pb.GetPhotoRequest) (*pb.Photo, error) {
+	photoID, err := requireID(req.Id, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	photo, err := s.photoService.GetPhotoByID(photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.blockService.HiddenUserIDs(callerFrom(ctx).userID)
+	if err != nil {
+		return nil, err
+	}
+	if hidden[photo.UserID] {
+		return nil, domain.ErrPhotoNotFound
+	}
+	return toPhoto(photo), nil
+}
+
+func (s *photoServer) ListPhotos(req *pb.ListPhotosRequest, stream pb.PhotoService_ListPhotosServer) error {
+	if len(req.UserIds) > 0 {
+		photos, err := s.photoService.GetPhotosByUserIDs(toIDs(req.UserIds))
+		if err != nil {
+			return err
+		}
+		hidden, err := s.blockService.HiddenUserIDs(callerFrom(stream.Context()).userID)
+		if err != nil {
+			return err
+		}
+		for i := range *photos {
+			if hidden[(*photos)[i].UserID] {
+				continue
+			}
+			if err := stream.Send(toPhoto(&(*photos)[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The photos of the current user are sent page by page
+	currentUserID := callerFrom(stream.Context()).userID
+	for page := 1; ; page++ {
+		photos, _, err := s.photoService.GetPhotosByUserID(currentUserID, domain.PageRequest{Page: page, PerPage: streamPageSize})
+		if err != nil {
+			return err
+		}
+		for i := range *photos {
+			if err := stream.Send(toPhoto(&(*photos)[i])); err != nil {
+				return err
+			}
+		}
+		if len(*photos) < streamPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *photoServer) UpdatePhoto(ctx context.Context, req *pb.UpdatePhotoRequest) (*pb.Photo, error) {
+	input := photoInput{Title: req.Title, Caption: req.Caption, PhotoUrl: req.PhotoUrl}
+	if err := validate.Struct(input); err != nil {
+		return nil, err
+	}
+	photoID, err := s.ownedPhoto(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	photo, err := s.photoService.UpdatePhoto(photoID, &domain.AddPhotoRequest{
+		Title:    input.Title,
+		Caption:  input.Caption,
+		PhotoUrl: input.PhotoUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toPhoto(photo), nil
+}
+
+func (s *photoServer) DeletePhoto(ctx context.Context, req *pb.DeletePhotoRequest) (*emptypb.Empty, error) {
+	photoID, err := s.ownedPhoto(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.photoService.DeletePhoto(photoID); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ownedPhoto returns the id of a photo of the caller
+func (s *photoServer) ownedPhoto(ctx context.Context, id uint64) (uint, error) {
+	photoID, err := requireID(id, "id")
+	if err != nil {
+		return 0, err
+	}
+
+	photo, err := s.photoService.GetPhotoByID(photoID)
+	if err != nil {
+		return 0, err
+	}
+	if photo.UserID != callerFrom(ctx).userID {
+		return 0, domain.ErrNotOwner
+	}
+	return photoID, nil
+}
+
+func toPhoto(photo *domain.Photo) *pb.Photo {
+	return &pb.Photo{
+		Id:        uint64(photo.ID),
+		Title:     photo.Title,
+		Caption:   photo.Caption,
+		PhotoUrl:  photo.PhotoUrl,
+		UserId:    uint64(photo.UserID),
+		CreatedAt: timestamppb.New(photo.CreatedAt),
+		UpdatedAt: timestamppb.New(photo.UpdatedAt),
+	}
+}
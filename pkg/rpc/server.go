@@ -0,0 +1,77 @@
+// Package rpc serves the domain services over gRPC for internal consumers, next to the REST API.
+// The protobuf definitions are in proto/, the code generated from them in pkg/rpc/pb.
+package rpc
+
+import (
+	"final-project/pkg/domain"
+	"final-project/pkg/rpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=final-project --go-grpc_out=../.. --go-grpc_opt=module=final-project mygram/v1/comment.proto mygram/v1/photo.proto mygram/v1/socialmedia.proto mygram/v1/user.proto
+
+type Config struct {
+	// VerifiedEmailRequired lists the route groups ("photos", "comments", "socialmedias"), as in
+	// rest.Config, whose create and update methods are closed to users with an unverified email
+	VerifiedEmailRequired []string
+	// RateLimits are the rules of rest.Config, each method counts against the ones of the REST route
+	// doing the same
+	RateLimits []domain.RateLimitRule
+}
+
+// streamPageSize is the page size used to stream the lists of the current user
+const streamPageSize = 100
+
+// NewServer returns a gRPC server with the user, photo, comment and social media services and
+// server reflection registered. Calls are authenticated with the same tokens and API keys as the
+// REST API, sent in the "authorization" metadata. The users hidden from the caller, see
+// domain.BlockService, and their content are left out as in the REST and GraphQL APIs.
+func NewServer(
+	userService domain.UserService,
+	authService domain.AuthService,
+	photoService domain.PhotoService,
+	commentService domain.CommentService,
+	socialMediaService domain.SocialMediaService,
+	apiKeyService domain.APIKeyService,
+	blockService domain.BlockService,
+	rateLimiter domain.RateLimiter,
+	config Config,
+) *grpc.Server {
+	auth := &authenticator{
+		authService:           authService,
+		userService:           userService,
+		apiKeyService:         apiKeyService,
+		rateLimiter:           rateLimiter,
+		rateLimits:            config.RateLimits,
+		verifiedEmailRequired: config.VerifiedEmailRequired,
+	}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.unaryInterceptor),
+		grpc.StreamInterceptor(auth.streamInterceptor),
+	)
+
+	pb.RegisterUserServiceServer(server, &userServer{userService: userService, blockService: blockService})
+	pb.RegisterPhotoServiceServer(server, &photoServer{photoService: photoService, blockService: blockService})
+	pb.RegisterCommentServiceServer(server, &commentServer{commentService: commentService, photoService: photoService, blockService: blockService})
+	pb.RegisterSocialMediaServiceServer(server, &socialMediaServer{socialMediaService: socialMediaService, blockService: blockService})
+	reflection.Register(server)
+	return server
+}
+
+func toIDs(ids []uint64) []uint {
+	result := make([]uint, len(ids))
+	for i, id := range ids {
+		result[i] = uint(id)
+	}
+	return result
+}
+
+// requireID rejects the zero id of a missing field
+func requireID(id uint64, field string) (uint, error) {
+	if id == 0 {
+		return 0, domain.NewFieldValidationError(field, "is required")
+	}
+	return uint(id), nil
+}
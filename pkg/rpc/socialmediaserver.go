@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"final-project/pkg/rpc/pb"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// socialMediaInput holds the rules of rest.AddSocialMediaRequest
+type socialMediaInput struct {
+	Name           string `json:"name" validate:"required,max=255"`
+	SocialMediaUrl string `json:"social_media_url" validate:"required,max=512,url"`
+}
+
+type socialMediaServer struct {
+	pb.UnimplementedSocialMediaServiceServer
+	socialMediaService domain.SocialMediaService
+	blockService       domain.BlockService
+}
+
+func (s *socialMediaServer) CreateSocialMedia(ctx context.Context, req *pb.CreateSocialMediaRequest) (*pb.SocialMedia, error) {
+	input := socialMediaInput{Name: req.Name, SocialMediaUrl: req.SocialMediaUrl}
+	if err := validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	socialMedia, err := s.socialMediaService.AddSocialMedia(callerFrom(ctx).userID, input.Name, input.SocialMediaUrl)
+	if err != nil {
+		return nil, err
+	}
+	return toSocialMedia(socialMedia), nil
+}
+
+func (s *socialMediaServer) GetSocialMedia(ctx context.Context, req *pb.GetSocialMediaRequest) (*pb.SocialMedia, error) {
+	socialMediaID, err := requireID(req.Id, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	socialMedia, err := s.socialMediaService.GetSocialMediaByID(socialMediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.blockService.HiddenUserIDs(callerFrom(ctx).userID)
+	if err != nil {
+		return nil, err
+	}
+	if hidden[socialMedia.UserID] {
+		return nil, domain.ErrSocialMediaNotFound
+	}
+	return toSocialMedia(socialMedia), nil
+}
+
+func (s *socialMediaServer) ListSocialMedias(req *pb.ListSocialMediasRequest, stream pb.SocialMediaService_ListSocialMediasServer) error {
+	if len(req.UserIds) > 0 {
+		socialMedias, err := s.socialMediaService.GetSocialMediasByUserIDs(toIDs(req.UserIds))
+		if err != nil {
+			return err
+		}
+		hidden, err := s.blockService.HiddenUserIDs(callerFrom(stream.Context()).userID)
+		if err != nil {
+			return err
+		}
+		for i := range *socialMedias {
+			if hidden[(*socialMedias)[i].UserID] {
+				continue
+			}
+			if err := stream.Send(toSocialMedia(&(*socialMedias)[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The social medias of the current user are sent page by page
+	currentUserID := callerFrom(stream.Context()).userID
+	for page := 1; ; page++ {
+		socialMedias, _, err := s.socialMediaService.GetSocialMediasByUserID(currentUserID, domain.PageRequest{Page: page, PerPage: streamPageSize})
+		if err != nil {
+			return err
+		}
+		for i := range *socialMedias {
+			if err := stream.Send(toSocialMedia(&(*socialMedias)[i])); err != nil {
+				return err
+			}
+		}
+		if len(*socialMedias) < streamPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *socialMediaServer) UpdateSocialMedia(ctx context.Context, req *pb.UpdateSocialMediaRequest) (*pb.SocialMedia, error) {
+	input := socialMediaInput{Name: req.Name, SocialMediaUrl: req.SocialMediaUrl}
+	if err := validate.Struct(input); err != nil {
+		return nil, err
+	}
+	socialMediaID, err := s.ownedSocialMedia(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	socialMedia, err := s.socialMediaService.UpdateSocialMedia(socialMediaID, input.Name, input.SocialMediaUrl)
+	if err != nil {
+		return nil, err
+	}
+	return toSocialMedia(socialMedia), nil
+}
+
+func (s *socialMediaServer) DeleteSocialMedia(ctx context.Context, req *pb.DeleteSocialMediaRequest) (*emptypb.Empty, error) {
+	socialMediaID, err := s.ownedSocialMedia(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.socialMediaService.DeleteSocialMedia(socialMediaID); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ownedSocialMedia returns the id of a social media of the caller
+func (s *socialMediaServer) ownedSocialMedia(ctx context.Context, id uint64) (uint, error) {
+	socialMediaID, err := requireID(id, "id")
+	if err != nil {
+		return 0, err
+	}
+
+	socialMedia, err := s.socialMediaService.GetSocialMediaByID(socialMediaID)
+	if err != nil {
+		return 0, err
+	}
+	if socialMedia.UserID != callerFrom(ctx).userID {
+		return 0, domain.ErrNotOwner
+	}
+	return socialMediaID, nil
+}
+
+func toSocialMedia(socialMedia *domain.SocialMedia) *pb.SocialMedia {
+	return &pb.SocialMedia{
+		Id:             uint64(socialMedia.ID),
+		Name:           socialMedia.Name,
+		SocialMediaUrl: socialMedia.SocialMediaUrl,
+		UserId:         uint64(socialMedia.UserID),
+		CreatedAt:      timestamppb.New(socialMedia.CreatedAt),
+		UpdatedAt:      timestamppb.New(socialMedia.UpdatedAt),
+	}
+}
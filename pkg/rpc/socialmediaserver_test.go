@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"final-project/pkg/rpc/pb"
+	"io"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestSocialMediasOfHiddenUsers(t *testing.T) {
+	socialMedias := pb.NewSocialMediaServiceClient(dialTestServer(t))
+	ctx := withToken("user-1")
+
+	if _, err := socialMedias.GetSocialMedia(ctx, &pb.GetSocialMediaRequest{Id: 2}); err != nil {
+		t.Errorf("visible social media: %v", err)
+	}
+	_, err := socialMedias.GetSocialMedia(ctx, &pb.GetSocialMediaRequest{Id: 3})
+	requireStatus(t, err, codes.NotFound, "social_media_not_found")
+
+	// user 2 sees the social medias of user 3
+	if _, err := socialMedias.GetSocialMedia(withToken("user-2"), &pb.GetSocialMediaRequest{Id: 3}); err != nil {
+		t.Errorf("social media hidden from another user: %v", err)
+	}
+
+	stream, err := socialMedias.ListSocialMedias(ctx, &pb.ListSocialMediasRequest{UserIds: []uint64{2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userIDs []uint64
+	for {
+		socialMedia, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, socialMedia.UserId)
+	}
+	if len(userIDs) != 1 || userIDs[0] != 2 {
+		t.Errorf("got the social medias of users %v, want only 2", userIDs)
+	}
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"final-project/pkg/rpc/pb"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	userService  domain.UserService
+	blockService domain.BlockService
+}
+
+func (s *userServer) GetCurrentUser(ctx context.Context, _ *emptypb.Empty) (*pb.User, error) {
+	currentUserID := callerFrom(ctx).userID
+
+	user, err := s.userService.GetUserByID(currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user, currentUserID), nil
+}
+
+func (s *userServer) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
+	currentUserID := callerFrom(ctx).userID
+
+	users, err := s.userService.GetUsersByIDs(toIDs(req.Ids))
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.blockService.HiddenUserIDs(currentUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetUsersResponse{Users: make([]*pb.User, 0, len(*users))}
+	for i := range *users {
+		// Hidden users are left out like unknown ids
+		if hidden[(*users)[i].ID] {
+			continue
+		}
+		resp.Users = append(resp.Users, toUser(&(*users)[i], currentUserID))
+	}
+	return resp, nil
+}
+
+// toUser converts a user, the email is only given to the user themselves
+func toUser(user *domain.User, currentUserID uint) *pb.User {
+	result := &pb.User{
+		Id:        uint64(user.ID),
+		Username:  user.Username,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+	if user.ID == currentUserID {
+		result.Email = user.Email
+	}
+	return result
+}
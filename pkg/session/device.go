@@ -0,0 +1,60 @@
+package session
+
+import "strings"
+
+// browsers and operatingSystems are matched in order, more specific tokens first
+// since e.g. Edge user agents also contain "Chrome" and "Safari"
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+var operatingSystems = []struct{ token, name string }{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// DeviceName describes a user agent for the session list, e.g. "Firefox on Windows"
+func DeviceName(userAgent string) string {
+	var browser, os string
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range operatingSystems {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	case userAgent != "":
+		if len(userAgent) > 100 {
+			return userAgent[:100]
+		}
+		return userAgent
+	}
+	return "Unknown device"
+}
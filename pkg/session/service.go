@@ -0,0 +1,125 @@
+package session
+
+import (
+	"final-project/pkg/domain"
+	"sync"
+	"time"
+)
+
+var errSessionRevoked = domain.NewUnauthorizedError("session_revoked", "session has been revoked")
+
+type Config struct {
+	// TTL is the lifetime of a session, it matches the lifetime of the access token
+	TTL time.Duration
+}
+
+type service struct {
+	repo   domain.SessionRepository
+	config Config
+
+	// lastSeen collects the activity between two flushes so requests don't write to the database
+	mu       sync.Mutex
+	lastSeen map[uint]time.Time
+}
+
+func NewService(repo domain.SessionRepository, config Config) domain.SessionService {
+	return &service{
+		repo:     repo,
+		config:   config,
+		lastSeen: make(map[uint]time.Time),
+	}
+}
+
+func (s *service) CreateSession(userID uint, userAgent string, ip string) (*domain.Session, error) {
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	return s.repo.SaveSession(&domain.Session{
+		UserID:     userID,
+		DeviceName: DeviceName(userAgent),
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: time.Now(),
+	})
+}
+
+func (s *service) GetSessions(userID uint) (*[]domain.Session, error) {
+	sessions, err := s.repo.GetActiveSessionsByUserID(userID, time.Now().Add(-s.config.TTL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Show the activity that wasn't flushed yet
+	s.mu.Lock()
+	for i, session := range *sessions {
+		if seenAt, ok := s.lastSeen[session.ID]; ok && seenAt.After(session.LastSeenAt) {
+			(*sessions)[i].LastSeenAt = seenAt
+		}
+	}
+	s.mu.Unlock()
+
+	return sessions, nil
+}
+
+func (s *service) RevokeSession(userID uint, sessionID uint) error {
+	revoked, err := s.repo.RevokeSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return domain.NewNotFoundError("session_not_found", "session not found")
+	}
+
+	return nil
+}
+
+func (s *service) RevokeOtherSessions(userID uint, keepSessionID uint) error {
+	return s.repo.RevokeSessionsExcept(userID, keepSessionID)
+}
+
+func (s *service) ValidateSession(userID uint, sessionID uint) error {
+	session, err := s.repo.GetSessionByID(sessionID)
+	if err != nil || session.UserID != userID {
+		return errSessionRevoked
+	}
+	if session.RevokedAt != nil || time.Since(session.CreatedAt) > s.config.TTL {
+		return errSessionRevoked
+	}
+
+	s.mu.Lock()
+	s.lastSeen[sessionID] = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *service) FlushLastSeen() error {
+	s.mu.Lock()
+	batch := s.lastSeen
+	s.lastSeen = make(map[uint]time.Time)
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := s.repo.UpdateLastSeen(batch); err != nil {
+		// Keep the batch for the next flush unless newer activity was recorded
+		s.mu.Lock()
+		for sessionID, seenAt := range batch {
+			if current, ok := s.lastSeen[sessionID]; !ok || current.Before(seenAt) {
+				s.lastSeen[sessionID] = seenAt
+			}
+		}
+		s.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// PurgeExpiredSessions deletes sessions whose tokens have expired, revoked or not
+func (s *service) PurgeExpiredSessions() (int64, error) {
+	return s.repo.DeleteSessionsCreatedBefore(time.Now().Add(-s.config.TTL))
+}
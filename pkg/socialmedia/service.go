@@ -28,8 +28,12 @@ func (s *service) GetSocialMediaByID(socialMediaID uint) (*domain.SocialMedia, e
 	return s.repo.GetSocialMediaByID(socialMediaID)
 }
 
-func (s *service) GetSocialMediasByUserID(userID uint) (*[]domain.SocialMedia, error) {
-	return s.repo.GetSocialMediasByUserID(userID)
+func (s *service) GetSocialMediasByUserID(userID uint, page domain.PageRequest) (*[]domain.SocialMedia, int64, error) {
+	return s.repo.GetSocialMediasByUserID(userID, page)
+}
+
+func (s *service) GetSocialMediasByUserIDs(userIDs []uint) (*[]domain.SocialMedia, error) {
+	return s.repo.GetSocialMediasByUserIDs(userIDs)
 }
 
 func (s *service) UpdateSocialMedia(socialMediaID uint, name string, socialMediaUrl string) (*domain.SocialMedia, error) {
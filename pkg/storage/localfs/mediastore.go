@@ -0,0 +1,110 @@
+package localfs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"final-project/pkg/domain"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type MediaStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewMediaStore creates a media store keeping files in dir, served publicly under baseURL
+func NewMediaStore(dir string, baseURL string) domain.MediaStore {
+	return &MediaStore{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s *MediaStore) Open(photoUrl string) (io.ReadCloser, error) {
+	name, ok := s.nameOf(photoUrl)
+	if !ok {
+		return nil, domain.ErrMediaNotLocal
+	}
+
+	return os.Open(filepath.Join(s.dir, filepath.FromSlash(name)))
+}
+
+func (s *MediaStore) Save(fileName string, r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Prefix the name with random bytes so uploads never overwrite each other
+	prefix := make([]byte, 8)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(prefix) + "-" + sanitizeFileName(fileName)
+
+	file, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return s.baseURL + "/" + name, nil
+}
+
+func (s *MediaStore) Delete(photoUrl string) error {
+	name, ok := s.nameOf(photoUrl)
+	if !ok {
+		return nil
+	}
+
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// sanitizeFileName keeps the base name of fileName with only url safe characters
+func sanitizeFileName(fileName string) string {
+	base := path.Base(filepath.ToSlash(fileName))
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, base)
+
+	if sanitized == "" || sanitized == "." || sanitized == ".." || sanitized == "/" {
+		return "file"
+	}
+	return sanitized
+}
+
+// nameOf returns the file name of photoUrl relative to the media directory
+func (s *MediaStore) nameOf(photoUrl string) (string, bool) {
+	if !strings.HasPrefix(photoUrl, s.baseURL+"/") {
+		return "", false
+	}
+
+	name := path.Clean(strings.TrimPrefix(photoUrl, s.baseURL+"/"))
+	if name == "." || strings.HasPrefix(name, "../") || name == ".." {
+		return "", false
+	}
+
+	return name, true
+}
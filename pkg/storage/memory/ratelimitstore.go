@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"final-project/pkg/domain"
+	"sync"
+	"time"
+)
+
+type rateLimitCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+type rateLimitCounterKey struct {
+	key         string
+	windowStart int64
+}
+
+// RateLimitStore keeps rate limit counters in process memory, counters are not shared between instances
+type RateLimitStore struct {
+	mu       sync.Mutex
+	counters map[rateLimitCounterKey]*rateLimitCounter
+}
+
+func NewRateLimitStore() domain.RateLimitStore {
+	return &RateLimitStore{
+		counters: make(map[rateLimitCounterKey]*rateLimitCounter),
+	}
+}
+
+func (s *RateLimitStore) GetCount(key string, windowStart time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[rateLimitCounterKey{key, windowStart.UnixNano()}]
+	if !ok {
+		return 0, nil
+	}
+	return counter.count, nil
+}
+
+func (s *RateLimitStore) Increment(key string, windowStart time.Time, expiresAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counterKey := rateLimitCounterKey{key, windowStart.UnixNano()}
+	counter, ok := s.counters[counterKey]
+	if !ok {
+		counter = &rateLimitCounter{expiresAt: expiresAt}
+		s.counters[counterKey] = counter
+	}
+	counter.count++
+
+	return counter.count, nil
+}
+
+func (s *RateLimitStore) Decrement(key string, windowStart time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if counter, ok := s.counters[rateLimitCounterKey{key, windowStart.UnixNano()}]; ok && counter.count > 0 {
+		counter.count--
+	}
+	return nil
+}
+
+func (s *RateLimitStore) DeleteExpired(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for counterKey, counter := range s.counters {
+		if counter.expiresAt.Before(before) {
+			delete(s.counters, counterKey)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,112 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKey struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"not null;index"`
+	Name   string `gorm:"not null;type:varchar(100)"`
+	Prefix string `gorm:"not null;type:varchar(16)"`
+	// KeyHash is the sha256 of the key
+	KeyHash string `gorm:"not null;uniqueIndex;type:varchar(64)"`
+	// Scopes are stored space separated
+	Scopes     string `gorm:"not null;type:varchar(255)"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) domain.APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+func (r *APIKeyRepository) SaveAPIKey(key *domain.APIKey) (*domain.APIKey, error) {
+	dbKey := APIKey{
+		UserID:    key.UserID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   key.KeyHash,
+		Scopes:    strings.Join(key.Scopes, " "),
+		ExpiresAt: key.ExpiresAt,
+	}
+
+	err := r.db.Create(&dbKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = dbKey.ID
+	key.CreatedAt = dbKey.CreatedAt
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeysByUserID(userID uint) (*[]domain.APIKey, error) {
+	var dbKeys []APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&dbKeys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]domain.APIKey, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		keys[i] = toDomainAPIKey(&dbKey)
+	}
+
+	return &keys, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(keyHash string) (*domain.APIKey, error) {
+	var dbKey APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&dbKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	key := toDomainAPIKey(&dbKey)
+	return &key, nil
+}
+
+func (r *APIKeyRepository) DeleteAPIKey(userID uint, keyID uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", keyID, userID).Delete(&APIKey{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *APIKeyRepository) DeleteAPIKeysByUserID(userID uint) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&APIKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *APIKeyRepository) UpdateAPIKeyLastUsed(keyID uint, usedAt time.Time) error {
+	return r.db.Model(&APIKey{}).Where("id = ?", keyID).Update("last_used_at", usedAt).Error
+}
+
+func toDomainAPIKey(dbKey *APIKey) domain.APIKey {
+	return domain.APIKey{
+		ID:         dbKey.ID,
+		UserID:     dbKey.UserID,
+		Name:       dbKey.Name,
+		Prefix:     dbKey.Prefix,
+		KeyHash:    dbKey.KeyHash,
+		Scopes:     strings.Fields(dbKey.Scopes),
+		ExpiresAt:  dbKey.ExpiresAt,
+		LastUsedAt: dbKey.LastUsedAt,
+		CreatedAt:  dbKey.CreatedAt,
+	}
+}
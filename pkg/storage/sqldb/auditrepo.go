@@ -0,0 +1,65 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuditLog struct {
+	ID        uint   `gorm:"primaryKey"`
+	Action    string `gorm:"not null;index;type:varchar(64)"`
+	UserID    uint   `gorm:"not null;index"`
+	Detail    string `gorm:"type:varchar(1024)"`
+	CreatedAt time.Time
+}
+
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) domain.AuditLogRepository {
+	return &AuditLogRepository{
+		db: db,
+	}
+}
+
+func (r *AuditLogRepository) SaveAuditLog(auditLog *domain.AuditLog) (*domain.AuditLog, error) {
+	dbAuditLog := AuditLog{
+		Action: auditLog.Action,
+		UserID: auditLog.UserID,
+		Detail: auditLog.Detail,
+	}
+
+	err := r.db.Create(&dbAuditLog).Error
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog.ID = dbAuditLog.ID
+	auditLog.CreatedAt = dbAuditLog.CreatedAt
+
+	return auditLog, nil
+}
+
+func (r *AuditLogRepository) GetAuditLogsByAction(action string, limit int) (*[]domain.AuditLog, error) {
+	var dbAuditLogs []AuditLog
+	err := r.db.Where("action = ?", action).Order("id desc").Limit(limit).Find(&dbAuditLogs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogs := make([]domain.AuditLog, len(dbAuditLogs))
+	for i, dbAuditLog := range dbAuditLogs {
+		auditLogs[i] = domain.AuditLog{
+			ID:        dbAuditLog.ID,
+			Action:    dbAuditLog.Action,
+			UserID:    dbAuditLog.UserID,
+			Detail:    dbAuditLog.Detail,
+			CreatedAt: dbAuditLog.CreatedAt,
+		}
+	}
+
+	return &auditLogs, nil
+}
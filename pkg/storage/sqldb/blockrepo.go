@@ -0,0 +1,121 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserRelation struct {
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	TargetID  uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	Type      string `gorm:"primaryKey;type:varchar(16)"`
+	CreatedAt time.Time
+}
+
+type BlockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) domain.BlockRepository {
+	return &BlockRepository{
+		db: db,
+	}
+}
+
+func (r *BlockRepository) SaveRelation(relation *domain.UserRelation) (*domain.UserRelation, error) {
+	dbRelation := UserRelation{
+		UserID:   relation.UserID,
+		TargetID: relation.TargetID,
+		Type:     relation.Type,
+	}
+
+	err := r.db.Where(&dbRelation).FirstOrCreate(&dbRelation).Error
+	if err != nil {
+		return nil, err
+	}
+
+	relation.CreatedAt = dbRelation.CreatedAt
+	return relation, nil
+}
+
+func (r *BlockRepository) DeleteRelation(userID uint, targetID uint, relationType string) error {
+	result := r.db.Where("user_id = ? AND target_id = ? AND type = ?", userID, targetID, relationType).Delete(&UserRelation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRelationNotFound
+	}
+	return nil
+}
+
+func (r *BlockRepository) GetRelationsByUserID(userID uint, relationType string, page domain.PageRequest) (*[]domain.UserRelation, int64, error) {
+	// A new session so the count and the find of findPage don't share one statement
+	var dbRelations []UserRelation
+	total, err := findPage(r.db.Order("created_at desc").Session(&gorm.Session{}), &dbRelations, page,
+		"user_id = ? AND type = ?", userID, relationType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	relations := make([]domain.UserRelation, len(dbRelations))
+	for i, dbRelation := range dbRelations {
+		relations[i] = domain.UserRelation{
+			UserID:    dbRelation.UserID,
+			TargetID:  dbRelation.TargetID,
+			Type:      dbRelation.Type,
+			CreatedAt: dbRelation.CreatedAt,
+		}
+	}
+
+	return &relations, total, nil
+}
+
+func (r *BlockRepository) IsBlocked(userID uint, otherUserID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&UserRelation{}).
+		Where("type = ? AND ((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?))", domain.RelationBlock, userID, otherUserID, otherUserID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *BlockRepository) GetHiddenUserIDs(userID uint) ([]uint, error) {
+	hidden := []uint{}
+	err := hiddenUserIDs(r.db, userID).Scan(&hidden).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hidden, nil
+}
+
+func (r *BlockRepository) GetHidingUserIDs(actorID uint, userIDs []uint) ([]uint, error) {
+	hiding := []uint{}
+	if len(userIDs) == 0 {
+		return hiding, nil
+	}
+
+	err := r.db.Raw("SELECT user_id FROM user_relations WHERE target_id = ? AND user_id IN ? "+
+		"UNION SELECT target_id FROM user_relations WHERE user_id = ? AND type = ? AND target_id IN ?",
+		actorID, userIDs, actorID, domain.RelationBlock, userIDs).
+		Scan(&hiding).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hiding, nil
+}
+
+// hiddenUserIDs is the subquery of the users whose content is hidden from the user, the
+// repositories of lists filter on it so every read path hides the same users
+func hiddenUserIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Raw("SELECT target_id FROM user_relations WHERE user_id = ? "+
+		"UNION SELECT user_id FROM user_relations WHERE target_id = ? AND type = ?",
+		userID, userID, domain.RelationBlock)
+}
@@ -0,0 +1,106 @@
+package sqldb
+
+import (
+	"final-project/pkg/canonical"
+	"final-project/pkg/domain"
+)
+
+// canonicalClaim is an account holding, or asking for, a canonical value
+type canonicalClaim struct {
+	userID uint
+	value  string
+}
+
+func (r *UserRepository) MigrateCanonicalIdentities() (*[]domain.IdentityCollision, error) {
+	var dbUsers []User
+	err := r.db.Select("id", "username", "email", "username_canonical", "email_canonical").
+		Order("id").
+		Find(&dbUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := make(map[string][]canonicalClaim)
+	emails := make(map[string][]canonicalClaim)
+	var usernameOrder, emailOrder []string
+
+	// Values already stored keep their owner, the oldest account wins among the others
+	for _, migrated := range []bool{true, false} {
+		for _, dbUser := range dbUsers {
+			if (dbUser.UsernameCanonical != nil) == migrated {
+				key := canonical.Username(dbUser.Username)
+				if len(usernames[key]) == 0 {
+					usernameOrder = append(usernameOrder, key)
+				}
+				usernames[key] = append(usernames[key], canonicalClaim{dbUser.ID, dbUser.Username})
+			}
+			if (dbUser.EmailCanonical != nil) == migrated {
+				key := canonical.Email(dbUser.Email)
+				if len(emails[key]) == 0 {
+					emailOrder = append(emailOrder, key)
+				}
+				emails[key] = append(emails[key], canonicalClaim{dbUser.ID, dbUser.Email})
+			}
+		}
+	}
+
+	migratedUsernames := make(map[uint]bool)
+	migratedEmails := make(map[uint]bool)
+	for _, dbUser := range dbUsers {
+		migratedUsernames[dbUser.ID] = dbUser.UsernameCanonical != nil
+		migratedEmails[dbUser.ID] = dbUser.EmailCanonical != nil
+	}
+
+	var collisions []domain.IdentityCollision
+
+	for _, key := range usernameOrder {
+		claims := usernames[key]
+		if owner := claims[0]; !migratedUsernames[owner.userID] {
+			err := r.db.Model(&User{}).Where("id = ?", owner.userID).Update("username_canonical", key).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(claims) > 1 {
+			collisions = append(collisions, newIdentityCollision("username", key, claims))
+		}
+	}
+
+	for _, key := range emailOrder {
+		claims := emails[key]
+		if owner := claims[0]; !migratedEmails[owner.userID] {
+			err := r.db.Model(&User{}).Where("id = ?", owner.userID).Update("email_canonical", key).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(claims) > 1 {
+			collisions = append(collisions, newIdentityCollision("email", key, claims))
+		}
+	}
+
+	return &collisions, nil
+}
+
+func newIdentityCollision(field string, key string, claims []canonicalClaim) domain.IdentityCollision {
+	collision := domain.IdentityCollision{
+		Field:     field,
+		Canonical: key,
+	}
+	for _, claim := range claims {
+		collision.UserIDs = append(collision.UserIDs, claim.userID)
+		collision.Values = append(collision.Values, claim.value)
+	}
+	return collision
+}
+
+// canonicalUsername and canonicalEmail return the value stored in the canonical columns
+func canonicalUsername(username string) *string {
+	value := canonical.Username(username)
+	return &value
+}
+
+func canonicalEmail(email string) *string {
+	value := canonical.Email(email)
+	return &value
+}
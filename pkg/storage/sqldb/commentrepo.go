@@ -49,9 +49,11 @@ func (r *CommentRepository) SaveComment(comment *domain.Comment) (*domain.Commen
 
 func (r *CommentRepository) GetCommentByID(commentID uint) (*domain.Comment, error) {
 	var dbComment Comment
-	err := r.db.First(&dbComment, commentID).Error
+	// Comments of accounts pending deletion and on their photos are hidden
+	err := r.db.Where("user_id NOT IN (?) AND photo_id NOT IN (?)", pendingDeletionUserIDs(r.db), r.photosOfPendingDeletion()).
+		First(&dbComment, commentID).Error
 	if err != nil {
-		return nil, err
+		return nil, translateNotFound(err, domain.ErrCommentNotFound)
 	}
 
 	comment := domain.Comment{
@@ -66,11 +68,12 @@ func (r *CommentRepository) GetCommentByID(commentID uint) (*domain.Comment, err
 	return &comment, nil
 }
 
-func (r *CommentRepository) GetCommentsByUserID(userID uint) (*[]domain.Comment, error) {
+func (r *CommentRepository) GetCommentsByUserID(userID uint, page domain.PageRequest) (*[]domain.Comment, int64, error) {
 	var dbComments []Comment
-	err := r.db.Where("user_id = ?", userID).Find(&dbComments).Error
+	total, err := findPage(r.db, &dbComments, page,
+		"user_id = ? AND user_id NOT IN (?)", userID, pendingDeletionUserIDs(r.db))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	comments := make([]domain.Comment, len(dbComments))
@@ -85,9 +88,50 @@ func (r *CommentRepository) GetCommentsByUserID(userID uint) (*[]domain.Comment,
 		}
 	}
 
+	return &comments, total, nil
+}
+
+func (r *CommentRepository) GetCommentsByPhotoIDs(photoIDs []uint) (*[]domain.Comment, error) {
+	comments := []domain.Comment{}
+	if len(photoIDs) == 0 {
+		return &comments, nil
+	}
+
+	var dbComments []Comment
+	err := r.db.Where("photo_id IN ? AND user_id NOT IN (?)", photoIDs, pendingDeletionUserIDs(r.db)).Order("id").Find(&dbComments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dbComment := range dbComments {
+		comments = append(comments, domain.Comment{
+			ID:        dbComment.ID,
+			UserID:    dbComment.UserID,
+			PhotoID:   dbComment.PhotoID,
+			Message:   dbComment.Message,
+			CreatedAt: dbComment.CreatedAt,
+			UpdatedAt: dbComment.UpdatedAt,
+		})
+	}
+
 	return &comments, nil
 }
 
+func (r *CommentRepository) GetCommenterIDs(photoID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.Model(&Comment{}).Where("photo_id = ? AND user_id NOT IN (?)", photoID, pendingDeletionUserIDs(r.db)).Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// photosOfPendingDeletion is the subquery of the photos of accounts pending deletion
+func (r *CommentRepository) photosOfPendingDeletion() *gorm.DB {
+	return r.db.Model(&Photo{}).Select("id").Where("user_id IN (?)", pendingDeletionUserIDs(r.db))
+}
+
 func (r *CommentRepository) UpdateComment(comment *domain.Comment) (*domain.Comment, error) {
 	err := r.db.Model(&Comment{}).Where("id = ?", comment.ID).Updates(Comment{
 		Message: comment.Message,
@@ -1,7 +1,10 @@
 package sqldb
 
 import (
+	"errors"
+	"final-project/pkg/domain"
 	"log"
+	"reflect"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -18,11 +21,57 @@ func NewStorage(dsn string) (*Storage, error) {
 		return nil, err
 	}
 
+	// Accounts created before emails were verified are grandfathered in when the column is added,
+	// otherwise they would lose the features that need a verified email
+	backfillEmailVerified := db.Migrator().HasTable(&User{}) && !db.Migrator().HasColumn(&User{}, "EmailVerified")
+
 	// Migrate the schema
 	db.AutoMigrate(&User{})
+	if backfillEmailVerified {
+		result := db.Model(&User{}).Where("email_verified = ?", false).Update("email_verified", true)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		log.Printf("marked the emails of %d existing accounts verified", result.RowsAffected)
+	}
 	db.AutoMigrate(&Photo{})
 	db.AutoMigrate(&Comment{})
 	db.AutoMigrate(&SocialMedia{})
+	db.AutoMigrate(&AuditLog{})
+	db.AutoMigrate(&DataExport{})
+	db.AutoMigrate(&ImportJob{})
+	db.AutoMigrate(&UserToken{})
+	db.AutoMigrate(&TwoFactor{})
+	db.AutoMigrate(&RecoveryCode{})
+	db.AutoMigrate(&LoginAttempt{})
+	db.AutoMigrate(&RateLimitCounter{})
+	db.AutoMigrate(&APIKey{})
+	db.AutoMigrate(&LinkedIdentity{})
+	db.AutoMigrate(&Session{})
+	db.AutoMigrate(&Webhook{})
+	db.AutoMigrate(&WebhookDelivery{})
+	// The unread notifications before the Unread column get it, the latest of a user, type and
+	// photo when there are several
+	backfillUnread := db.Migrator().HasTable(&Notification{}) && !db.Migrator().HasColumn(&Notification{}, "Unread")
+	db.AutoMigrate(&Notification{})
+	if backfillUnread {
+		err := db.Exec("UPDATE notifications SET unread = TRUE WHERE id IN (SELECT id FROM (SELECT MAX(id) AS id FROM notifications WHERE read_at IS NULL GROUP BY user_id, type, photo_id) AS latest)").Error
+		if err != nil {
+			return nil, err
+		}
+		// Covered by the unique index
+		if db.Migrator().HasIndex(&Notification{}, "idx_notifications_unread") {
+			db.Migrator().DropIndex(&Notification{}, "idx_notifications_unread")
+		}
+	}
+	db.AutoMigrate(&NotificationPreference{})
+	db.AutoMigrate(&EmailPreference{})
+	db.AutoMigrate(&Conversation{})
+	db.AutoMigrate(&ConversationParticipant{})
+	db.AutoMigrate(&Message{})
+	db.AutoMigrate(&MessageDeletion{})
+	db.AutoMigrate(&MessageSettings{})
+	db.AutoMigrate(&UserRelation{})
 
 	log.Println("Connected to database")
 	return &Storage{
@@ -37,3 +86,34 @@ func (s *Storage) Close() error {
 	}
 	return sqlDB.Close()
 }
+
+// translateNotFound replaces gorm.ErrRecordNotFound with the domain error of the missing record,
+// other errors are returned as they are
+func translateNotFound(err error, notFoundErr error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFoundErr
+	}
+	return err
+}
+
+// findPage loads the page of the rows matching the conditions into dest, a pointer to a slice of
+// models, and returns the number of matching rows
+func findPage(db *gorm.DB, dest interface{}, page domain.PageRequest, query interface{}, args ...interface{}) (int64, error) {
+	if page.PerPage <= 0 {
+		if err := db.Where(query, args...).Order("id").Find(dest).Error; err != nil {
+			return 0, err
+		}
+		return int64(reflect.ValueOf(dest).Elem().Len()), nil
+	}
+
+	var total int64
+	if err := db.Model(dest).Where(query, args...).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	err := db.Where(query, args...).Order("id").Limit(page.PerPage).Offset(page.Offset()).Find(dest).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
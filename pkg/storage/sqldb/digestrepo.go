@@ -0,0 +1,128 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailPreference is only stored once a user changes it or gets a digest
+type EmailPreference struct {
+	UserID       uint   `gorm:"primaryKey;autoIncrement:false"`
+	Digest       string `gorm:"not null;type:varchar(16)"`
+	LastDigestAt *time.Time
+	// DigestFailures counts the failed attempts at the due digest, retried at DigestRetryAt
+	DigestFailures int `gorm:"not null;default:0"`
+	DigestRetryAt  *time.Time
+	UpdatedAt      time.Time
+}
+
+type DigestRepository struct {
+	db *gorm.DB
+}
+
+func NewDigestRepository(db *gorm.DB) domain.DigestRepository {
+	return &DigestRepository{
+		db: db,
+	}
+}
+
+func (r *DigestRepository) GetEmailPreferences(userID uint) (*domain.EmailPreferences, error) {
+	var dbPreference EmailPreference
+	err := r.db.Where("user_id = ?", userID).First(&dbPreference).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrEmailPreferencesNotFound)
+	}
+
+	return &domain.EmailPreferences{
+		UserID:       dbPreference.UserID,
+		Digest:       dbPreference.Digest,
+		LastDigestAt: dbPreference.LastDigestAt,
+	}, nil
+}
+
+func (r *DigestRepository) SaveEmailPreferences(preferences *domain.EmailPreferences) error {
+	result := r.db.Model(&EmailPreference{}).Where("user_id = ?", preferences.UserID).Updates(map[string]interface{}{
+		"digest":     preferences.Digest,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	return r.db.Create(&EmailPreference{
+		UserID:       preferences.UserID,
+		Digest:       preferences.Digest,
+		LastDigestAt: preferences.LastDigestAt,
+	}).Error
+}
+
+func (r *DigestRepository) GetDigestRecipients(digest string, defaultDigest string, sentBefore time.Time, now time.Time, limit int) ([]domain.DigestRecipient, error) {
+	recipients := []domain.DigestRecipient{}
+	err := r.db.Model(&User{}).
+		Select("users.id AS user_id, users.username, users.email, email_preferences.last_digest_at, COALESCE(email_preferences.digest_failures, 0) AS digest_failures").
+		Joins("LEFT JOIN email_preferences ON email_preferences.user_id = users.id").
+		Where("users.email_verified = ? AND users.deletion_scheduled_at IS NULL", true).
+		Where("COALESCE(email_preferences.digest, ?) = ?", defaultDigest, digest).
+		Where("email_preferences.last_digest_at IS NULL OR email_preferences.last_digest_at < ?", sentBefore).
+		Where("email_preferences.digest_retry_at IS NULL OR email_preferences.digest_retry_at <= ?", now).
+		Order("users.id").Limit(limit).
+		Scan(&recipients).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+func (r *DigestRepository) MarkDigestSent(userID uint, digest string, sentAt time.Time) error {
+	result := r.db.Model(&EmailPreference{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"last_digest_at":  sentAt,
+		"digest_failures": 0,
+		"digest_retry_at": nil,
+	})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	return r.db.Create(&EmailPreference{
+		UserID:       userID,
+		Digest:       digest,
+		LastDigestAt: &sentAt,
+	}).Error
+}
+
+func (r *DigestRepository) MarkDigestFailed(userID uint, digest string, retryAt time.Time) error {
+	result := r.db.Model(&EmailPreference{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"digest_failures": gorm.Expr("digest_failures + 1"),
+		"digest_retry_at": retryAt,
+	})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	return r.db.Create(&EmailPreference{
+		UserID:         userID,
+		Digest:         digest,
+		DigestFailures: 1,
+		DigestRetryAt:  &retryAt,
+	}).Error
+}
+
+func (r *DigestRepository) GetPhotoActivity(userID uint, since time.Time) ([]domain.PhotoActivity, error) {
+	activity := []domain.PhotoActivity{}
+	err := r.db.Model(&Comment{}).
+		Select("photos.id AS photo_id, photos.title, COUNT(comments.id) AS comments, COUNT(DISTINCT comments.user_id) AS commenters").
+		Joins("JOIN photos ON photos.id = comments.photo_id").
+		Where("photos.user_id = ? AND comments.user_id <> ? AND comments.created_at >= ?", userID, userID, since).
+		Where("comments.user_id NOT IN (?) AND comments.user_id NOT IN (?)", hiddenUserIDs(r.db, userID), pendingDeletionUserIDs(r.db)).
+		Group("photos.id, photos.title").
+		Order("comments DESC, photos.id").
+		Scan(&activity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
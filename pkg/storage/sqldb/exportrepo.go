@@ -0,0 +1,131 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DataExport struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	Status    string     `gorm:"not null;type:varchar(16)"`
+	FilePath  string     `gorm:"type:varchar(1024)"`
+	Error     string     `gorm:"type:varchar(1024)"`
+	ExpiresAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) domain.ExportRepository {
+	return &ExportRepository{
+		db: db,
+	}
+}
+
+func (r *ExportRepository) SaveExport(export *domain.DataExport) (*domain.DataExport, error) {
+	dbExport := DataExport{
+		UserID: export.UserID,
+		Status: export.Status,
+	}
+
+	err := r.db.Create(&dbExport).Error
+	if err != nil {
+		return nil, err
+	}
+
+	export.ID = dbExport.ID
+	export.CreatedAt = dbExport.CreatedAt
+	export.UpdatedAt = dbExport.UpdatedAt
+
+	return export, nil
+}
+
+func (r *ExportRepository) GetExportByID(exportID uint) (*domain.DataExport, error) {
+	var dbExport DataExport
+	err := r.db.First(&dbExport, exportID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	export := toDomainExport(dbExport)
+	return &export, nil
+}
+
+func (r *ExportRepository) GetLatestExportByUserID(userID uint) (*domain.DataExport, error) {
+	var dbExport DataExport
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").First(&dbExport).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrExportNotFound)
+	}
+
+	export := toDomainExport(dbExport)
+	return &export, nil
+}
+
+func (r *ExportRepository) UpdateExport(export *domain.DataExport) (*domain.DataExport, error) {
+	err := r.db.Model(&DataExport{}).Where("id = ?", export.ID).Updates(map[string]interface{}{
+		"status":     export.Status,
+		"file_path":  export.FilePath,
+		"error":      export.Error,
+		"expires_at": export.ExpiresAt,
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (r *ExportRepository) GetExportsExpiredBefore(before time.Time) (*[]domain.DataExport, error) {
+	var dbExports []DataExport
+	err := r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", before).Find(&dbExports).Error
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]domain.DataExport, len(dbExports))
+	for i, dbExport := range dbExports {
+		exports[i] = toDomainExport(dbExport)
+	}
+
+	return &exports, nil
+}
+
+func (r *ExportRepository) GetExportsByStatus(status string) (*[]domain.DataExport, error) {
+	var dbExports []DataExport
+	err := r.db.Where("status = ?", status).Order("id").Find(&dbExports).Error
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]domain.DataExport, len(dbExports))
+	for i, dbExport := range dbExports {
+		exports[i] = toDomainExport(dbExport)
+	}
+
+	return &exports, nil
+}
+
+func (r *ExportRepository) DeleteExportByID(exportID uint) error {
+	return r.db.Delete(&DataExport{}, exportID).Error
+}
+
+func toDomainExport(dbExport DataExport) domain.DataExport {
+	return domain.DataExport{
+		ID:        dbExport.ID,
+		UserID:    dbExport.UserID,
+		Status:    dbExport.Status,
+		FilePath:  dbExport.FilePath,
+		Error:     dbExport.Error,
+		ExpiresAt: dbExport.ExpiresAt,
+		CreatedAt: dbExport.CreatedAt,
+		UpdatedAt: dbExport.UpdatedAt,
+	}
+}
@@ -0,0 +1,92 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LinkedIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Provider  string `gorm:"not null;uniqueIndex:idx_provider_subject;type:varchar(64)"`
+	Subject   string `gorm:"not null;uniqueIndex:idx_provider_subject;type:varchar(255)"`
+	Email     string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+}
+
+type LinkedIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewLinkedIdentityRepository(db *gorm.DB) domain.LinkedIdentityRepository {
+	return &LinkedIdentityRepository{
+		db: db,
+	}
+}
+
+func (r *LinkedIdentityRepository) SaveLinkedIdentity(identity *domain.LinkedIdentity) (*domain.LinkedIdentity, error) {
+	dbIdentity := LinkedIdentity{
+		UserID:   identity.UserID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	err := r.db.Create(&dbIdentity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	identity.ID = dbIdentity.ID
+	identity.CreatedAt = dbIdentity.CreatedAt
+
+	return identity, nil
+}
+
+func (r *LinkedIdentityRepository) GetLinkedIdentity(provider string, subject string) (*domain.LinkedIdentity, error) {
+	var dbIdentity LinkedIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&dbIdentity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	identity := toDomainLinkedIdentity(&dbIdentity)
+	return &identity, nil
+}
+
+func (r *LinkedIdentityRepository) GetLinkedIdentitiesByUserID(userID uint) (*[]domain.LinkedIdentity, error) {
+	var dbIdentities []LinkedIdentity
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&dbIdentities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	identities := make([]domain.LinkedIdentity, len(dbIdentities))
+	for i, dbIdentity := range dbIdentities {
+		identities[i] = toDomainLinkedIdentity(&dbIdentity)
+	}
+
+	return &identities, nil
+}
+
+func (r *LinkedIdentityRepository) DeleteLinkedIdentity(userID uint, identityID uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", identityID, userID).Delete(&LinkedIdentity{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func toDomainLinkedIdentity(dbIdentity *LinkedIdentity) domain.LinkedIdentity {
+	return domain.LinkedIdentity{
+		ID:        dbIdentity.ID,
+		UserID:    dbIdentity.UserID,
+		Provider:  dbIdentity.Provider,
+		Subject:   dbIdentity.Subject,
+		Email:     dbIdentity.Email,
+		CreatedAt: dbIdentity.CreatedAt,
+	}
+}
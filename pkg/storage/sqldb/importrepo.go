@@ -0,0 +1,142 @@
+package sqldb
+
+import (
+	"encoding/json"
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImportJob struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Source    string `gorm:"not null;type:varchar(32)"`
+	Status    string `gorm:"not null;type:varchar(16)"`
+	Total     int    `gorm:"not null"`
+	Processed int    `gorm:"not null"`
+	Imported  int    `gorm:"not null"`
+	Failed    int    `gorm:"not null"`
+	// Errors is the JSON encoded list of per item errors
+	Errors    string `gorm:"type:mediumtext"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type importItemError struct {
+	Item    string `json:"item"`
+	Message string `json:"message"`
+}
+
+type ImportRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) domain.ImportRepository {
+	return &ImportRepository{
+		db: db,
+	}
+}
+
+func (r *ImportRepository) SaveImportJob(job *domain.ImportJob) (*domain.ImportJob, error) {
+	dbJob, err := toDBImportJob(job)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Create(&dbJob).Error
+	if err != nil {
+		return nil, err
+	}
+
+	job.ID = dbJob.ID
+	job.CreatedAt = dbJob.CreatedAt
+	job.UpdatedAt = dbJob.UpdatedAt
+
+	return job, nil
+}
+
+func (r *ImportRepository) GetImportJobByID(jobID uint) (*domain.ImportJob, error) {
+	var dbJob ImportJob
+	err := r.db.First(&dbJob, jobID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var itemErrors []importItemError
+	if dbJob.Errors != "" {
+		if err := json.Unmarshal([]byte(dbJob.Errors), &itemErrors); err != nil {
+			return nil, err
+		}
+	}
+
+	job := domain.ImportJob{
+		ID:        dbJob.ID,
+		UserID:    dbJob.UserID,
+		Source:    dbJob.Source,
+		Status:    dbJob.Status,
+		Total:     dbJob.Total,
+		Processed: dbJob.Processed,
+		Imported:  dbJob.Imported,
+		Failed:    dbJob.Failed,
+		Errors:    make([]domain.ImportItemError, len(itemErrors)),
+		CreatedAt: dbJob.CreatedAt,
+		UpdatedAt: dbJob.UpdatedAt,
+	}
+	for i, itemError := range itemErrors {
+		job.Errors[i] = domain.ImportItemError{
+			Item:    itemError.Item,
+			Message: itemError.Message,
+		}
+	}
+
+	return &job, nil
+}
+
+func (r *ImportRepository) UpdateImportJob(job *domain.ImportJob) (*domain.ImportJob, error) {
+	dbJob, err := toDBImportJob(job)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&ImportJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":     dbJob.Status,
+		"total":      dbJob.Total,
+		"processed":  dbJob.Processed,
+		"imported":   dbJob.Imported,
+		"failed":     dbJob.Failed,
+		"errors":     dbJob.Errors,
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func toDBImportJob(job *domain.ImportJob) (ImportJob, error) {
+	itemErrors := make([]importItemError, len(job.Errors))
+	for i, itemError := range job.Errors {
+		itemErrors[i] = importItemError{
+			Item:    itemError.Item,
+			Message: itemError.Message,
+		}
+	}
+
+	encoded, err := json.Marshal(itemErrors)
+	if err != nil {
+		return ImportJob{}, err
+	}
+
+	return ImportJob{
+		UserID:    job.UserID,
+		Source:    job.Source,
+		Status:    job.Status,
+		Total:     job.Total,
+		Processed: job.Processed,
+		Imported:  job.Imported,
+		Failed:    job.Failed,
+		Errors:    string(encoded),
+	}, nil
+}
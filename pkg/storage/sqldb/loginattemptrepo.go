@@ -0,0 +1,70 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttempt struct {
+	Key           string `gorm:"primaryKey;type:varchar(320)"`
+	Failures      int    `gorm:"not null"`
+	LockedUntil   *time.Time
+	LastFailureAt time.Time `gorm:"not null"`
+}
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) domain.LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db: db,
+	}
+}
+
+func (r *LoginAttemptRepository) GetLoginAttempt(key string) (*domain.LoginAttempt, error) {
+	var dbAttempt LoginAttempt
+	err := r.db.First(&dbAttempt, "`key` = ?", key).Error
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := domain.LoginAttempt{
+		Key:           dbAttempt.Key,
+		Failures:      dbAttempt.Failures,
+		LockedUntil:   dbAttempt.LockedUntil,
+		LastFailureAt: dbAttempt.LastFailureAt,
+	}
+
+	return &attempt, nil
+}
+
+func (r *LoginAttemptRepository) IncrementLoginFailures(key string, resetBefore time.Time) (*domain.LoginAttempt, error) {
+	// Increment in a single statement so concurrent failures are all counted
+	err := r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("IF(last_failure_at < ?, 1, failures + 1)", resetBefore),
+			"last_failure_at": time.Now(),
+		}),
+	}).Create(&LoginAttempt{
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: time.Now(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetLoginAttempt(key)
+}
+
+func (r *LoginAttemptRepository) LockLoginAttempt(key string, lockedUntil time.Time) error {
+	return r.db.Model(&LoginAttempt{}).Where("`key` = ?", key).Update("locked_until", lockedUntil).Error
+}
+
+func (r *LoginAttemptRepository) DeleteLoginAttempt(key string) error {
+	return r.db.Delete(&LoginAttempt{}, "`key` = ?", key).Error
+}
@@ -0,0 +1,403 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Conversation struct {
+	ID      uint `gorm:"primaryKey"`
+	IsGroup bool `gorm:"not null;default:false"`
+	// DirectKey is "<lower user id>:<higher user id>" for 1:1 conversations, NULL for groups
+	DirectKey *string `gorm:"uniqueIndex;type:varchar(64)"`
+	Title     string  `gorm:"not null;default:'';type:varchar(100)"`
+	CreatedBy uint    `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+type ConversationParticipant struct {
+	ConversationID    uint `gorm:"primaryKey;autoIncrement:false"`
+	UserID            uint `gorm:"primaryKey;autoIncrement:false;index"`
+	LastReadMessageID uint `gorm:"not null;default:0"`
+	LastReadAt        *time.Time
+}
+
+type Message struct {
+	ID             uint   `gorm:"primaryKey"`
+	ConversationID uint   `gorm:"not null;index"`
+	SenderID       uint   `gorm:"not null;index"`
+	Body           string `gorm:"not null;type:text"`
+	PhotoID        *uint
+	// DeletedAt marks the messages erased for everyone, it isn't a gorm soft delete
+	DeletedAt *time.Time
+	CreatedAt time.Time
+}
+
+// MessageDeletion hides a message from one participant
+type MessageDeletion struct {
+	MessageID uint `gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+type MessageSettings struct {
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	AllowFrom string `gorm:"not null;type:varchar(16)"`
+}
+
+type MessageRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageRepository(db *gorm.DB) domain.MessageRepository {
+	return &MessageRepository{
+		db: db,
+	}
+}
+
+func (r *MessageRepository) SaveConversation(conversation *domain.Conversation) (*domain.Conversation, error) {
+	dbConversation := Conversation{
+		IsGroup:   conversation.IsGroup,
+		Title:     conversation.Title,
+		CreatedBy: conversation.CreatedBy,
+	}
+	if !conversation.IsGroup && len(conversation.Participants) == 2 {
+		key := directKey(conversation.Participants[0].UserID, conversation.Participants[1].UserID)
+		dbConversation.DirectKey = &key
+	}
+
+	// Transaction to create conversation and its participants
+	tx := r.db.Begin()
+
+	err := tx.Create(&dbConversation).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	dbParticipants := make([]ConversationParticipant, len(conversation.Participants))
+	for i, participant := range conversation.Participants {
+		dbParticipants[i] = ConversationParticipant{
+			ConversationID: dbConversation.ID,
+			UserID:         participant.UserID,
+		}
+	}
+	err = tx.Create(&dbParticipants).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	conversation.ID = dbConversation.ID
+	conversation.CreatedAt = dbConversation.CreatedAt
+	conversation.UpdatedAt = dbConversation.UpdatedAt
+
+	return conversation, nil
+}
+
+func (r *MessageRepository) GetConversationByID(conversationID uint) (*domain.Conversation, error) {
+	var dbConversation Conversation
+	err := r.db.First(&dbConversation, conversationID).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrConversationNotFound)
+	}
+
+	conversations, err := r.withParticipants([]Conversation{dbConversation})
+	if err != nil {
+		return nil, err
+	}
+
+	return &(*conversations)[0], nil
+}
+
+func (r *MessageRepository) GetDirectConversation(userID uint, otherUserID uint) (*domain.Conversation, error) {
+	var dbConversation Conversation
+	err := r.db.Where("direct_key = ?", directKey(userID, otherUserID)).First(&dbConversation).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrConversationNotFound)
+	}
+
+	conversations, err := r.withParticipants([]Conversation{dbConversation})
+	if err != nil {
+		return nil, err
+	}
+
+	return &(*conversations)[0], nil
+}
+
+func (r *MessageRepository) GetConversationsByUserID(userID uint, page domain.PageRequest) (*[]domain.Conversation, int64, error) {
+	// A new session so the count and the find of findPage don't share one statement
+	var dbConversations []Conversation
+	total, err := findPage(r.db.Order("updated_at desc").Session(&gorm.Session{}), &dbConversations, page,
+		"id IN (?) AND id NOT IN (?)",
+		r.db.Model(&ConversationParticipant{}).Select("conversation_id").Where("user_id = ?", userID),
+		r.hiddenDirectConversations(userID))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conversations, err := r.withParticipants(dbConversations)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return conversations, total, nil
+}
+
+func (r *MessageRepository) GetUnreadCounts(userID uint, conversationIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64)
+	if len(conversationIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ConversationID uint
+		Unread         int64
+	}
+	err := r.db.Model(&Message{}).
+		Select("messages.conversation_id, COUNT(*) AS unread").
+		Joins("JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id AND conversation_participants.user_id = ?", userID).
+		Where("messages.conversation_id IN ? AND messages.sender_id <> ? AND messages.deleted_at IS NULL", conversationIDs, userID).
+		Where("messages.id > conversation_participants.last_read_message_id").
+		Where("messages.id NOT IN (?) AND messages.sender_id NOT IN (?)", r.deletedBy(userID), hiddenUserIDs(r.db, userID)).
+		Group("messages.conversation_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Unread
+	}
+	return counts, nil
+}
+
+func (r *MessageRepository) GetLastMessages(userID uint, conversationIDs []uint) (map[uint]domain.Message, error) {
+	messages := make(map[uint]domain.Message)
+	if len(conversationIDs) == 0 {
+		return messages, nil
+	}
+
+	var dbMessages []Message
+	err := r.db.Where("id IN (?)", r.db.Model(&Message{}).
+		Select("MAX(id)").
+		Where("conversation_id IN ? AND id NOT IN (?) AND sender_id NOT IN (?)", conversationIDs, r.deletedBy(userID), hiddenUserIDs(r.db, userID)).
+		Group("conversation_id"),
+	).Find(&dbMessages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dbMessage := range dbMessages {
+		messages[dbMessage.ConversationID] = toDomainMessage(&dbMessage)
+	}
+	return messages, nil
+}
+
+func (r *MessageRepository) UpdateReadReceipt(conversationID uint, userID uint, messageID uint, readAt time.Time) error {
+	// Receipts only move forward
+	return r.db.Model(&ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ? AND last_read_message_id < ?", conversationID, userID, messageID).
+		Updates(map[string]interface{}{
+			"last_read_message_id": messageID,
+			"last_read_at":         readAt,
+		}).Error
+}
+
+func (r *MessageRepository) SaveMessage(message *domain.Message) (*domain.Message, error) {
+	dbMessage := Message{
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+		PhotoID:        message.PhotoID,
+	}
+
+	// Transaction to create message and move its conversation up the lists
+	tx := r.db.Begin()
+
+	err := tx.Create(&dbMessage).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Model(&Conversation{}).Where("id = ?", message.ConversationID).Update("updated_at", dbMessage.CreatedAt).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	message.ID = dbMessage.ID
+	message.CreatedAt = dbMessage.CreatedAt
+
+	return message, nil
+}
+
+func (r *MessageRepository) GetMessageByID(messageID uint) (*domain.Message, error) {
+	var dbMessage Message
+	err := r.db.First(&dbMessage, messageID).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrMessageNotFound)
+	}
+
+	message := toDomainMessage(&dbMessage)
+	return &message, nil
+}
+
+func (r *MessageRepository) GetMessages(userID uint, conversationID uint, page domain.PageRequest) (*[]domain.Message, int64, error) {
+	// A new session so the count and the find of findPage don't share one statement
+	var dbMessages []Message
+	total, err := findPage(r.db.Order("id desc").Session(&gorm.Session{}), &dbMessages, page,
+		"conversation_id = ? AND id NOT IN (?) AND sender_id NOT IN (?)", conversationID, r.deletedBy(userID), hiddenUserIDs(r.db, userID))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messages := make([]domain.Message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		messages[i] = toDomainMessage(&dbMessage)
+	}
+
+	return &messages, total, nil
+}
+
+func (r *MessageRepository) DeleteMessageForUser(messageID uint, userID uint) error {
+	deletion := MessageDeletion{MessageID: messageID, UserID: userID}
+	return r.db.Where(&deletion).FirstOrCreate(&deletion).Error
+}
+
+func (r *MessageRepository) EraseMessage(messageID uint, deletedAt time.Time) error {
+	return r.db.Model(&Message{}).Where("id = ?", messageID).Updates(map[string]interface{}{
+		"body":       "",
+		"photo_id":   nil,
+		"deleted_at": deletedAt,
+	}).Error
+}
+
+func (r *MessageRepository) HasMessaged(userID uint, otherUserID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&Message{}).
+		Joins("JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id AND conversation_participants.user_id = ?", otherUserID).
+		Where("messages.sender_id = ?", userID).
+		Limit(1).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *MessageRepository) GetSettings(userID uint) (*domain.MessageSettings, error) {
+	var dbSettings MessageSettings
+	err := r.db.Where("user_id = ?", userID).Limit(1).Find(&dbSettings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MessageSettings{
+		UserID:    userID,
+		AllowFrom: dbSettings.AllowFrom,
+	}, nil
+}
+
+func (r *MessageRepository) SaveSettings(settings *domain.MessageSettings) error {
+	result := r.db.Model(&MessageSettings{}).Where("user_id = ?", settings.UserID).Update("allow_from", settings.AllowFrom)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	// MySQL reports no affected row when the value doesn't change
+	var count int64
+	if err := r.db.Model(&MessageSettings{}).Where("user_id = ?", settings.UserID).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	return r.db.Create(&MessageSettings{
+		UserID:    settings.UserID,
+		AllowFrom: settings.AllowFrom,
+	}).Error
+}
+
+// deletedBy is the subquery of the messages the user deleted for themselves
+func (r *MessageRepository) deletedBy(userID uint) *gorm.DB {
+	return r.db.Model(&MessageDeletion{}).Select("message_id").Where("user_id = ?", userID)
+}
+
+// hiddenDirectConversations is the subquery of the 1:1 conversations of the user with users whose
+// content is hidden from them
+func (r *MessageRepository) hiddenDirectConversations(userID uint) *gorm.DB {
+	return r.db.Model(&ConversationParticipant{}).
+		Select("conversation_participants.conversation_id").
+		Joins("JOIN conversations ON conversations.id = conversation_participants.conversation_id AND conversations.is_group = ?", false).
+		Where("conversation_participants.user_id IN (?)", hiddenUserIDs(r.db, userID))
+}
+
+// withParticipants loads the participants of the conversations in one query
+func (r *MessageRepository) withParticipants(dbConversations []Conversation) (*[]domain.Conversation, error) {
+	ids := make([]uint, len(dbConversations))
+	for i, dbConversation := range dbConversations {
+		ids[i] = dbConversation.ID
+	}
+
+	var dbParticipants []ConversationParticipant
+	if len(ids) > 0 {
+		err := r.db.Where("conversation_id IN ?", ids).Order("user_id").Find(&dbParticipants).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	participants := make(map[uint][]domain.ConversationParticipant)
+	for _, dbParticipant := range dbParticipants {
+		participants[dbParticipant.ConversationID] = append(participants[dbParticipant.ConversationID], domain.ConversationParticipant{
+			UserID:            dbParticipant.UserID,
+			LastReadMessageID: dbParticipant.LastReadMessageID,
+			LastReadAt:        dbParticipant.LastReadAt,
+		})
+	}
+
+	conversations := make([]domain.Conversation, len(dbConversations))
+	for i, dbConversation := range dbConversations {
+		conversations[i] = domain.Conversation{
+			ID:           dbConversation.ID,
+			IsGroup:      dbConversation.IsGroup,
+			Title:        dbConversation.Title,
+			CreatedBy:    dbConversation.CreatedBy,
+			Participants: participants[dbConversation.ID],
+			CreatedAt:    dbConversation.CreatedAt,
+			UpdatedAt:    dbConversation.UpdatedAt,
+		}
+	}
+
+	return &conversations, nil
+}
+
+func toDomainMessage(dbMessage *Message) domain.Message {
+	return domain.Message{
+		ID:             dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		SenderID:       dbMessage.SenderID,
+		Body:           dbMessage.Body,
+		PhotoID:        dbMessage.PhotoID,
+		DeletedAt:      dbMessage.DeletedAt,
+		CreatedAt:      dbMessage.CreatedAt,
+	}
+}
+
+func directKey(userID uint, otherUserID uint) string {
+	if otherUserID < userID {
+		userID, otherUserID = otherUserID, userID
+	}
+	return fmt.Sprintf("%d:%d", userID, otherUserID)
+}
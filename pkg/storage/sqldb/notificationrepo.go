@@ -0,0 +1,254 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Notification struct {
+	ID      uint   `gorm:"primaryKey"`
+	UserID  uint   `gorm:"not null;uniqueIndex:idx_notifications_unread_once,priority:1"`
+	Type    string `gorm:"not null;type:varchar(32);uniqueIndex:idx_notifications_unread_once,priority:2"`
+	PhotoID uint   `gorm:"not null;uniqueIndex:idx_notifications_unread_once,priority:3"`
+	// ActorIDs are stored space separated
+	ActorIDs string `gorm:"not null;type:text"`
+	// Unread is true until the notification is read and NULL after, so a user has one unread
+	// notification per type and photo while the read ones don't collide
+	Unread    *bool `gorm:"uniqueIndex:idx_notifications_unread_once,priority:4"`
+	ReadAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+// NotificationPreference is only stored once a user changes it
+type NotificationPreference struct {
+	UserID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	Type    string `gorm:"primaryKey;type:varchar(32)"`
+	Enabled bool   `gorm:"not null"`
+}
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) domain.NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+func (r *NotificationRepository) SaveNotification(notification *domain.Notification) (*domain.Notification, error) {
+	dbNotification := Notification{
+		UserID:   notification.UserID,
+		Type:     notification.Type,
+		PhotoID:  notification.PhotoID,
+		ActorIDs: joinIDs(notification.ActorIDs),
+		Unread:   unreadFlag(notification.ReadAt),
+		ReadAt:   notification.ReadAt,
+	}
+
+	err := r.db.Create(&dbNotification).Error
+	if err != nil {
+		return nil, err
+	}
+
+	notification.ID = dbNotification.ID
+	notification.CreatedAt = dbNotification.CreatedAt
+	notification.UpdatedAt = dbNotification.UpdatedAt
+
+	return notification, nil
+}
+
+func (r *NotificationRepository) UpdateNotification(notification *domain.Notification) error {
+	notification.UpdatedAt = time.Now()
+	return r.db.Model(&Notification{}).Where("id = ?", notification.ID).Updates(map[string]interface{}{
+		"actor_ids":  joinIDs(notification.ActorIDs),
+		"unread":     unreadFlag(notification.ReadAt),
+		"read_at":    notification.ReadAt,
+		"updated_at": notification.UpdatedAt,
+	}).Error
+}
+
+func (r *NotificationRepository) GetNotificationByID(notificationID uint) (*domain.Notification, error) {
+	var dbNotification Notification
+	err := r.db.First(&dbNotification, notificationID).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrNotificationNotFound)
+	}
+
+	notification := toDomainNotification(&dbNotification)
+	return &notification, nil
+}
+
+func (r *NotificationRepository) AddActor(userID uint, notificationType string, photoID uint, actorID uint) error {
+	now := time.Now()
+	actor := strconv.FormatUint(uint64(actorID), 10)
+	dbNotification := Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		PhotoID:   photoID,
+		ActorIDs:  actor,
+		Unread:    unreadFlag(nil),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// One statement so comments posted at the same time land in the same notification, the unique
+	// index turns the insert into an update of the unread notification. The actor is taken out of
+	// the padded list and put first.
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"actor_ids":  gorm.Expr("TRIM(CONCAT(?, ' ', TRIM(REPLACE(CONCAT(' ', actor_ids, ' '), ?, ' '))))", actor, " "+actor+" "),
+			"updated_at": now,
+		}),
+	}).Create(&dbNotification).Error
+}
+
+func (r *NotificationRepository) GetNotificationsByUserID(userID uint, unreadOnly bool, page domain.PageRequest) (*[]domain.Notification, int64, error) {
+	query := "user_id = ?"
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+
+	// A new session so the count and the find of findPage don't share one statement
+	var dbNotifications []Notification
+	total, err := findPage(r.db.Order("updated_at desc").Session(&gorm.Session{}), &dbNotifications, page, query, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notifications := make([]domain.Notification, len(dbNotifications))
+	for i, dbNotification := range dbNotifications {
+		notifications[i] = toDomainNotification(&dbNotification)
+	}
+
+	return &notifications, total, nil
+}
+
+func (r *NotificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *NotificationRepository) MarkAllRead(userID uint, readAt time.Time) (int64, error) {
+	result := r.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Updates(map[string]interface{}{
+		"unread":  nil,
+		"read_at": readAt,
+	})
+	return result.RowsAffected, result.Error
+}
+
+func (r *NotificationRepository) GetPreferences(userID uint) ([]domain.NotificationPreference, error) {
+	var dbPreferences []NotificationPreference
+	err := r.db.Where("user_id = ?", userID).Find(&dbPreferences).Error
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make([]domain.NotificationPreference, len(dbPreferences))
+	for i, dbPreference := range dbPreferences {
+		preferences[i] = domain.NotificationPreference{
+			Type:    dbPreference.Type,
+			Enabled: dbPreference.Enabled,
+		}
+	}
+
+	return preferences, nil
+}
+
+func (r *NotificationRepository) SavePreferences(userID uint, preferences []domain.NotificationPreference) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+
+	dbPreferences := make([]NotificationPreference, len(preferences))
+	types := make([]string, len(preferences))
+	for i, preference := range preferences {
+		dbPreferences[i] = NotificationPreference{
+			UserID:  userID,
+			Type:    preference.Type,
+			Enabled: preference.Enabled,
+		}
+		types[i] = preference.Type
+	}
+
+	// Transaction to replace the preferences of the given types
+	tx := r.db.Begin()
+
+	err := tx.Where("user_id = ? AND type IN ?", userID, types).Delete(&NotificationPreference{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Create(&dbPreferences).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *NotificationRepository) GetOptedOutUserIDs(notificationType string, userIDs []uint) ([]uint, error) {
+	optedOut := []uint{}
+	if len(userIDs) == 0 {
+		return optedOut, nil
+	}
+
+	err := r.db.Model(&NotificationPreference{}).
+		Where("type = ? AND enabled = ? AND user_id IN ?", notificationType, false, userIDs).
+		Pluck("user_id", &optedOut).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return optedOut, nil
+}
+
+func toDomainNotification(dbNotification *Notification) domain.Notification {
+	return domain.Notification{
+		ID:        dbNotification.ID,
+		UserID:    dbNotification.UserID,
+		Type:      dbNotification.Type,
+		PhotoID:   dbNotification.PhotoID,
+		ActorIDs:  splitIDs(dbNotification.ActorIDs),
+		ReadAt:    dbNotification.ReadAt,
+		CreatedAt: dbNotification.CreatedAt,
+		UpdatedAt: dbNotification.UpdatedAt,
+	}
+}
+
+// unreadFlag is the Unread column of a notification read at readAt
+func unreadFlag(readAt *time.Time) *bool {
+	if readAt != nil {
+		return nil
+	}
+	unread := true
+	return &unread
+}
+
+func joinIDs(ids []uint) string {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(fields, " ")
+}
+
+func splitIDs(s string) []uint {
+	fields := strings.Fields(s)
+	ids := make([]uint, 0, len(fields))
+	for _, field := range fields {
+		if id, err := strconv.ParseUint(field, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
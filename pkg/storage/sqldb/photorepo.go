@@ -32,10 +32,11 @@ func NewPhotoRepository(db *gorm.DB) domain.PhotoRepository {
 
 func (r *PhotoRepository) SavePhoto(photo *domain.Photo) (*domain.Photo, error) {
 	dbPhoto := Photo{
-		Title:    photo.Title,
-		Caption:  photo.Caption,
-		PhotoUrl: photo.PhotoUrl,
-		UserID:   photo.UserID,
+		Title:     photo.Title,
+		Caption:   photo.Caption,
+		PhotoUrl:  photo.PhotoUrl,
+		UserID:    photo.UserID,
+		CreatedAt: photo.CreatedAt,
 	}
 
 	err := r.db.Create(&dbPhoto).Error
@@ -52,9 +53,9 @@ func (r *PhotoRepository) SavePhoto(photo *domain.Photo) (*domain.Photo, error)
 
 func (r *PhotoRepository) GetPhotoByID(photoID uint) (*domain.Photo, error) {
 	var dbPhoto Photo
-	err := r.db.First(&dbPhoto, photoID).Error
+	err := r.db.Where("user_id NOT IN (?)", pendingDeletionUserIDs(r.db)).First(&dbPhoto, photoID).Error
 	if err != nil {
-		return nil, err
+		return nil, translateNotFound(err, domain.ErrPhotoNotFound)
 	}
 
 	photo := domain.Photo{
@@ -70,11 +71,12 @@ func (r *PhotoRepository) GetPhotoByID(photoID uint) (*domain.Photo, error) {
 	return &photo, nil
 }
 
-func (r *PhotoRepository) GetPhotosByUserID(userId uint) (*[]domain.Photo, error) {
+func (r *PhotoRepository) GetPhotosByUserID(userId uint, page domain.PageRequest) (*[]domain.Photo, int64, error) {
 	var dbPhotos []Photo
-	err := r.db.Where("user_id = ?", userId).Find(&dbPhotos).Error
+	total, err := findPage(r.db, &dbPhotos, page,
+		"user_id = ? AND user_id NOT IN (?)", userId, pendingDeletionUserIDs(r.db))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	photos := make([]domain.Photo, len(dbPhotos))
@@ -90,6 +92,41 @@ func (r *PhotoRepository) GetPhotosByUserID(userId uint) (*[]domain.Photo, error
 		}
 	}
 
+	return &photos, total, nil
+}
+
+func (r *PhotoRepository) GetPhotosByIDs(photoIDs []uint) (*[]domain.Photo, error) {
+	return r.findPhotos("id IN ?", photoIDs)
+}
+
+func (r *PhotoRepository) GetPhotosByUserIDs(userIDs []uint) (*[]domain.Photo, error) {
+	return r.findPhotos("user_id IN ?", userIDs)
+}
+
+func (r *PhotoRepository) findPhotos(query string, ids []uint) (*[]domain.Photo, error) {
+	photos := []domain.Photo{}
+	if len(ids) == 0 {
+		return &photos, nil
+	}
+
+	var dbPhotos []Photo
+	err := r.db.Where(query, ids).Where("user_id NOT IN (?)", pendingDeletionUserIDs(r.db)).Order("id").Find(&dbPhotos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dbPhoto := range dbPhotos {
+		photos = append(photos, domain.Photo{
+			ID:        dbPhoto.ID,
+			Title:     dbPhoto.Title,
+			Caption:   dbPhoto.Caption,
+			PhotoUrl:  dbPhoto.PhotoUrl,
+			UserID:    dbPhoto.UserID,
+			CreatedAt: dbPhoto.CreatedAt,
+			UpdatedAt: dbPhoto.UpdatedAt,
+		})
+	}
+
 	return &photos, nil
 }
 
@@ -109,12 +146,16 @@ func (r *PhotoRepository) UpdatePhoto(photo *domain.Photo) (*domain.Photo, error
 }
 
 func (r *PhotoRepository) DeletePhotoByID(photoID uint) error {
-	// Transaction to delete photo and its comments
+	// Transaction to delete photo, its comments and the notifications about it
 	tx := r.db.Begin()
 	if err := tx.Delete(&Comment{}, "photo_id = ?", photoID).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
+	if err := tx.Delete(&Notification{}, "photo_id = ?", photoID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 	if err := tx.Delete(&Photo{}, photoID).Error; err != nil {
 		tx.Rollback()
 		return err
@@ -0,0 +1,77 @@
+package sqldb
+
+import (
+	"errors"
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RateLimitCounter struct {
+	Key         string    `gorm:"primaryKey;type:varchar(255)"`
+	WindowStart time.Time `gorm:"primaryKey"`
+	Count       int       `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+// RateLimitStore keeps rate limit counters in the database so all instances share them
+type RateLimitStore struct {
+	db *gorm.DB
+}
+
+func NewRateLimitStore(db *gorm.DB) domain.RateLimitStore {
+	return &RateLimitStore{
+		db: db,
+	}
+}
+
+func (s *RateLimitStore) GetCount(key string, windowStart time.Time) (int, error) {
+	var counter RateLimitCounter
+	err := s.db.First(&counter, "`key` = ? AND window_start = ?", key, windowStart).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Count, nil
+}
+
+func (s *RateLimitStore) Increment(key string, windowStart time.Time, expiresAt time.Time) (int, error) {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return 0, err
+	}
+
+	// One statement counts the request and returns the new count through LAST_INSERT_ID, so
+	// concurrent requests can't all read the same count before incrementing it
+	result, err := sqlDB.Exec("INSERT INTO rate_limit_counters (`key`, window_start, count, expires_at) VALUES (?, ?, 1, ?) "+
+		"ON DUPLICATE KEY UPDATE count = LAST_INSERT_ID(count + 1)", key, windowStart, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	// The id is 0 when the counter was inserted, the table has no auto increment column
+	if count == 0 {
+		return 1, nil
+	}
+
+	return int(count), nil
+}
+
+func (s *RateLimitStore) Decrement(key string, windowStart time.Time) error {
+	return s.db.Model(&RateLimitCounter{}).
+		Where("`key` = ? AND window_start = ? AND count > 0", key, windowStart).
+		Update("count", gorm.Expr("count - 1")).Error
+}
+
+func (s *RateLimitStore) DeleteExpired(before time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ?", before).Delete(&RateLimitCounter{})
+	return result.RowsAffected, result.Error
+}
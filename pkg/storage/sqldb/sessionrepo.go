@@ -0,0 +1,127 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Session struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"not null;index"`
+	DeviceName string    `gorm:"not null;type:varchar(100)"`
+	UserAgent  string    `gorm:"not null;type:varchar(512)"`
+	IP         string    `gorm:"not null;type:varchar(45)"`
+	CreatedAt  time.Time `gorm:"index"`
+	LastSeenAt time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+}
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) domain.SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+func (r *SessionRepository) SaveSession(session *domain.Session) (*domain.Session, error) {
+	dbSession := Session{
+		UserID:     session.UserID,
+		DeviceName: session.DeviceName,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		LastSeenAt: session.LastSeenAt,
+	}
+
+	err := r.db.Create(&dbSession).Error
+	if err != nil {
+		return nil, err
+	}
+
+	session.ID = dbSession.ID
+	session.CreatedAt = dbSession.CreatedAt
+
+	return session, nil
+}
+
+func (r *SessionRepository) GetSessionByID(sessionID uint) (*domain.Session, error) {
+	var dbSession Session
+	err := r.db.First(&dbSession, sessionID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	session := toDomainSession(&dbSession)
+	return &session, nil
+}
+
+func (r *SessionRepository) GetActiveSessionsByUserID(userID uint, createdAfter time.Time) (*[]domain.Session, error) {
+	var dbSessions []Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND created_at > ?", userID, createdAfter).
+		Order("last_seen_at desc").
+		Find(&dbSessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]domain.Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = toDomainSession(&dbSession)
+	}
+
+	return &sessions, nil
+}
+
+func (r *SessionRepository) RevokeSession(userID uint, sessionID uint) (bool, error) {
+	result := r.db.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *SessionRepository) RevokeSessionsExcept(userID uint, keepSessionID uint) error {
+	return r.db.Model(&Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// UpdateLastSeen writes the batch in one transaction, last seen times never move backwards
+func (r *SessionRepository) UpdateLastSeen(lastSeen map[uint]time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for sessionID, seenAt := range lastSeen {
+			err := tx.Model(&Session{}).
+				Where("id = ? AND last_seen_at < ?", sessionID, seenAt).
+				Update("last_seen_at", seenAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SessionRepository) DeleteSessionsCreatedBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
+func toDomainSession(dbSession *Session) domain.Session {
+	return domain.Session{
+		ID:         dbSession.ID,
+		UserID:     dbSession.UserID,
+		DeviceName: dbSession.DeviceName,
+		UserAgent:  dbSession.UserAgent,
+		IP:         dbSession.IP,
+		CreatedAt:  dbSession.CreatedAt,
+		LastSeenAt: dbSession.LastSeenAt,
+		RevokedAt:  dbSession.RevokedAt,
+	}
+}
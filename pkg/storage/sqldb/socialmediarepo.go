@@ -62,7 +62,7 @@ func (r *SocialMediaRepository) GetSocialMediaByID(socialMediaID uint) (*domain.
 	var dbSocialMedia SocialMedia
 	err := r.db.First(&dbSocialMedia, socialMediaID).Error
 	if err != nil {
-		return nil, err
+		return nil, translateNotFound(err, domain.ErrSocialMediaNotFound)
 	}
 
 	socialMedia := domain.SocialMedia{
@@ -77,11 +77,11 @@ func (r *SocialMediaRepository) GetSocialMediaByID(socialMediaID uint) (*domain.
 	return &socialMedia, nil
 }
 
-func (r *SocialMediaRepository) GetSocialMediasByUserID(userID uint) (*[]domain.SocialMedia, error) {
+func (r *SocialMediaRepository) GetSocialMediasByUserID(userID uint, page domain.PageRequest) (*[]domain.SocialMedia, int64, error) {
 	var dbSocialMedias []SocialMedia
-	err := r.db.Where("user_id = ?", userID).Find(&dbSocialMedias).Error
+	total, err := findPage(r.db, &dbSocialMedias, page, "user_id = ?", userID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	socialMedias := make([]domain.SocialMedia, len(dbSocialMedias))
@@ -96,6 +96,32 @@ func (r *SocialMediaRepository) GetSocialMediasByUserID(userID uint) (*[]domain.
 		}
 	}
 
+	return &socialMedias, total, nil
+}
+
+func (r *SocialMediaRepository) GetSocialMediasByUserIDs(userIDs []uint) (*[]domain.SocialMedia, error) {
+	socialMedias := []domain.SocialMedia{}
+	if len(userIDs) == 0 {
+		return &socialMedias, nil
+	}
+
+	var dbSocialMedias []SocialMedia
+	err := r.db.Where("user_id IN ?", userIDs).Order("id").Find(&dbSocialMedias).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dbSocialMedia := range dbSocialMedias {
+		socialMedias = append(socialMedias, domain.SocialMedia{
+			ID:             dbSocialMedia.ID,
+			Name:           dbSocialMedia.Name,
+			SocialMediaUrl: dbSocialMedia.SocialMediaUrl,
+			UserID:         dbSocialMedia.UserID,
+			CreatedAt:      dbSocialMedia.CreatedAt,
+			UpdatedAt:      dbSocialMedia.UpdatedAt,
+		})
+	}
+
 	return &socialMedias, nil
 }
 
@@ -0,0 +1,136 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactor struct {
+	UserID       uint   `gorm:"primaryKey;autoIncrement:false"`
+	Secret       string `gorm:"not null;type:varchar(64)"`
+	Enabled      bool   `gorm:"not null"`
+	LastUsedStep int64  `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type RecoveryCode struct {
+	ID       uint   `gorm:"primaryKey"`
+	UserID   uint   `gorm:"not null;index"`
+	CodeHash string `gorm:"not null;type:varchar(64)"`
+	UsedAt   *time.Time
+}
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) domain.TwoFactorRepository {
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+func (r *TwoFactorRepository) GetTwoFactor(userID uint) (*domain.TwoFactor, error) {
+	var dbTwoFactor TwoFactor
+	err := r.db.First(&dbTwoFactor, "user_id = ?", userID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor := domain.TwoFactor{
+		UserID:       dbTwoFactor.UserID,
+		Secret:       dbTwoFactor.Secret,
+		Enabled:      dbTwoFactor.Enabled,
+		LastUsedStep: dbTwoFactor.LastUsedStep,
+		CreatedAt:    dbTwoFactor.CreatedAt,
+		UpdatedAt:    dbTwoFactor.UpdatedAt,
+	}
+
+	return &twoFactor, nil
+}
+
+func (r *TwoFactorRepository) SaveTwoFactor(twoFactor *domain.TwoFactor) (*domain.TwoFactor, error) {
+	dbTwoFactor := TwoFactor{
+		UserID:       twoFactor.UserID,
+		Secret:       twoFactor.Secret,
+		Enabled:      twoFactor.Enabled,
+		LastUsedStep: twoFactor.LastUsedStep,
+	}
+
+	// One row per user, enrolling again replaces the unconfirmed secret
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled", "last_used_step", "updated_at"}),
+	}).Create(&dbTwoFactor).Error
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor.CreatedAt = dbTwoFactor.CreatedAt
+	twoFactor.UpdatedAt = dbTwoFactor.UpdatedAt
+
+	return twoFactor, nil
+}
+
+func (r *TwoFactorRepository) UpdateLastUsedStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&TwoFactor{}).Where("user_id = ? AND last_used_step < ?", userID, step).Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TwoFactorRepository) DeleteTwoFactor(userID uint) error {
+	tx := r.db.Begin()
+	if err := tx.Delete(&RecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&TwoFactor{}, "user_id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	tx := r.db.Begin()
+	if err := tx.Delete(&RecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	codes := make([]RecoveryCode, len(codeHashes))
+	for i, codeHash := range codeHashes {
+		codes[i] = RecoveryCode{
+			UserID:   userID,
+			CodeHash: codeHash,
+		}
+	}
+	if len(codes) > 0 {
+		if err := tx.Create(&codes).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *TwoFactorRepository) ConsumeRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Limit(1).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
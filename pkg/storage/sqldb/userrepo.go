@@ -1,6 +1,7 @@
 package sqldb
 
 import (
+	"final-project/pkg/canonical"
 	"final-project/pkg/domain"
 	"log"
 	"time"
@@ -9,16 +10,28 @@ import (
 )
 
 type User struct {
-	ID           uint   `gorm:"primaryKey"`
-	Username     string `gorm:"not null;unique;type:varchar(255)"`
-	Email        string `gorm:"not null;unique;type:varchar(255)"`
-	Password     string `gorm:"not null"`
-	Age          int    `gorm:"not null"`
+	ID       uint   `gorm:"primaryKey"`
+	Username string `gorm:"not null;unique;type:varchar(255)"`
+	Email    string `gorm:"not null;unique;type:varchar(255)"`
+	// UsernameCanonical and EmailCanonical are the case-folded NFKC forms used for lookups,
+	// NULL for accounts not migrated yet
+	UsernameCanonical *string `gorm:"uniqueIndex;type:varchar(255)"`
+	EmailCanonical    *string `gorm:"uniqueIndex;type:varchar(255)"`
+	Password          string  `gorm:"not null"`
+	Age               int     `gorm:"not null"`
+	// PendingEmail is the new email awaiting confirmation
+	PendingEmail  string `gorm:"not null;default:'';type:varchar(255)"`
+	EmailVerified bool   `gorm:"not null;default:false"`
+	IsAdmin       bool   `gorm:"not null;default:false"`
+	// TokenVersion is bumped on password changes to revoke issued tokens
+	TokenVersion uint `gorm:"not null;default:0"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
-	Photos       []Photo       `gorm:"foreignKey:UserID"`
-	Comments     []Comment     `gorm:"foreignKey:UserID"`
-	SocialMedias []SocialMedia `gorm:"foreignKey:UserID"`
+	// Accounts pending deletion are hidden from GetUserByID until purged or restored
+	DeletionScheduledAt *time.Time    `gorm:"index"`
+	Photos              []Photo       `gorm:"foreignKey:UserID"`
+	Comments            []Comment     `gorm:"foreignKey:UserID"`
+	SocialMedias        []SocialMedia `gorm:"foreignKey:UserID"`
 }
 
 type UserRepository struct {
@@ -34,10 +47,13 @@ func NewUserRepository(db *gorm.DB) domain.UserRepository {
 
 func (r *UserRepository) SaveUser(user *domain.User) (*domain.User, error) {
 	dbUser := User{
-		Username: user.Username,
-		Email:    user.Email,
-		Password: user.Password,
-		Age:      user.Age,
+		Username:          user.Username,
+		Email:             user.Email,
+		UsernameCanonical: canonicalUsername(user.Username),
+		EmailCanonical:    canonicalEmail(user.Email),
+		Password:          user.Password,
+		Age:               user.Age,
+		EmailVerified:     user.EmailVerified,
 	}
 
 	err := r.db.Create(&dbUser).Error
@@ -54,42 +70,127 @@ func (r *UserRepository) SaveUser(user *domain.User) (*domain.User, error) {
 
 func (r *UserRepository) GetUserByID(userID uint) (*domain.User, error) {
 	var dbUser User
-	err := r.db.First(&dbUser, userID).Error
+	err := r.db.Where("deletion_scheduled_at IS NULL").First(&dbUser, userID).Error
 	if err != nil {
-		return nil, err
+		return nil, translateNotFound(err, domain.ErrUserNotFound)
 	}
 
 	user := domain.User{
-		ID:        dbUser.ID,
-		Username:  dbUser.Username,
-		Email:     dbUser.Email,
-		Age:       dbUser.Age,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
+		ID:            dbUser.ID,
+		Username:      dbUser.Username,
+		Email:         dbUser.Email,
+		Password:      dbUser.Password,
+		Age:           dbUser.Age,
+		EmailVerified: dbUser.EmailVerified,
+		PendingEmail:  dbUser.PendingEmail,
+		IsAdmin:       dbUser.IsAdmin,
+		TokenVersion:  dbUser.TokenVersion,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
 	}
 
 	return &user, nil
 }
 
+func (r *UserRepository) GetUsersByIDs(userIDs []uint) (*[]domain.User, error) {
+	users := []domain.User{}
+	if len(userIDs) == 0 {
+		return &users, nil
+	}
+
+	var dbUsers []User
+	err := r.db.Where("deletion_scheduled_at IS NULL AND id IN ?", userIDs).Order("id").Find(&dbUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dbUser := range dbUsers {
+		users = append(users, domain.User{
+			ID:            dbUser.ID,
+			Username:      dbUser.Username,
+			Email:         dbUser.Email,
+			Password:      dbUser.Password,
+			Age:           dbUser.Age,
+			EmailVerified: dbUser.EmailVerified,
+			PendingEmail:  dbUser.PendingEmail,
+			IsAdmin:       dbUser.IsAdmin,
+			TokenVersion:  dbUser.TokenVersion,
+			CreatedAt:     dbUser.CreatedAt,
+			UpdatedAt:     dbUser.UpdatedAt,
+		})
+	}
+
+	return &users, nil
+}
+
 func (r *UserRepository) GetUserByUsername(username string) (*domain.User, error) {
 	var dbUser User
-	err := r.db.Where("username = ?", username).First(&dbUser).Error
+	err := r.db.Where("username_canonical = ? OR (username_canonical IS NULL AND username = ?)", canonical.Username(username), username).
+		First(&dbUser).Error
 	if err != nil {
-		return nil, err
+		return nil, translateNotFound(err, domain.ErrUserNotFound)
 	}
 
 	user := domain.User{
-		ID:       dbUser.ID,
-		Username: dbUser.Username,
-		Password: dbUser.Password,
-		Email:    dbUser.Email,
-		Age:      dbUser.Age,
+		ID:                  dbUser.ID,
+		Username:            dbUser.Username,
+		Password:            dbUser.Password,
+		Email:               dbUser.Email,
+		Age:                 dbUser.Age,
+		EmailVerified:       dbUser.EmailVerified,
+		PendingEmail:        dbUser.PendingEmail,
+		IsAdmin:             dbUser.IsAdmin,
+		TokenVersion:        dbUser.TokenVersion,
+		DeletionScheduledAt: dbUser.DeletionScheduledAt,
 	}
 
 	return &user, nil
 }
 
+func (r *UserRepository) GetUserByEmail(email string) (*domain.User, error) {
+	var dbUser User
+	err := r.db.Where("email_canonical = ? OR (email_canonical IS NULL AND email = ?)", canonical.Email(email), email).
+		First(&dbUser).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrUserNotFound)
+	}
+
+	user := domain.User{
+		ID:                  dbUser.ID,
+		Username:            dbUser.Username,
+		Password:            dbUser.Password,
+		Email:               dbUser.Email,
+		Age:                 dbUser.Age,
+		EmailVerified:       dbUser.EmailVerified,
+		PendingEmail:        dbUser.PendingEmail,
+		IsAdmin:             dbUser.IsAdmin,
+		TokenVersion:        dbUser.TokenVersion,
+		DeletionScheduledAt: dbUser.DeletionScheduledAt,
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) UpdatePassword(userID uint, hashedPassword string) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password":      hashedPassword,
+		"token_version": gorm.Expr("token_version + 1"),
+		"updated_at":    time.Now(),
+	}).Error
+}
+
 func (r *UserRepository) DeleteUserByID(userID uint) error {
+	_, err := r.deleteUser(userID, nil)
+	return err
+}
+
+func (r *UserRepository) PurgeUser(userID uint, scheduledBefore time.Time) (bool, error) {
+	return r.deleteUser(userID, &scheduledBefore)
+}
+
+// deleteUser deletes the user and everything they own in one transaction. With scheduledBefore, the
+// user is only deleted while their deletion is still scheduled before it, otherwise nothing is.
+func (r *UserRepository) deleteUser(userID uint, scheduledBefore *time.Time) (bool, error) {
 	// Transaction to delete user and all of his photos, comments, and social medias
 	tx := r.db.Begin()
 
@@ -97,14 +198,14 @@ func (r *UserRepository) DeleteUserByID(userID uint) error {
 	err := tx.Where("user_id = ?", userID).Delete(&SocialMedia{}).Error
 	if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
 	}
 
 	// Delete comments of user
 	err = tx.Where("user_id = ?", userID).Delete(&Comment{}).Error
 	if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
 	}
 
 	// Delete photos of user and all of its comments
@@ -113,15 +214,20 @@ func (r *UserRepository) DeleteUserByID(userID uint) error {
 	err = tx.Where("user_id = ?", userID).Find(&photos).Error
 	if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
 	}
 
-	// Delete all comments of photos
+	// Delete all comments of photos and the notifications about them
 	for _, photo := range photos {
 		err = tx.Where("photo_id = ?", photo.ID).Delete(&Comment{}).Error
 		if err != nil {
 			tx.Rollback()
-			return err
+			return false, err
+		}
+		err = tx.Where("photo_id = ?", photo.ID).Delete(&Notification{}).Error
+		if err != nil {
+			tx.Rollback()
+			return false, err
 		}
 	}
 
@@ -129,25 +235,211 @@ func (r *UserRepository) DeleteUserByID(userID uint) error {
 	err = tx.Where("user_id = ?", userID).Delete(&Photo{}).Error
 	if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
+	}
+
+	// Expire data exports of user so the purge job removes their archives
+	err = tx.Model(&DataExport{}).Where("user_id = ?", userID).Update("expires_at", time.Now()).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete import jobs of user
+	err = tx.Where("user_id = ?", userID).Delete(&ImportJob{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete password reset and verification tokens of user
+	err = tx.Where("user_id = ?", userID).Delete(&UserToken{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete two-factor setup and recovery codes of user
+	err = tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Where("user_id = ?", userID).Delete(&TwoFactor{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete API keys of user
+	err = tx.Where("user_id = ?", userID).Delete(&APIKey{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete linked identities of user
+	err = tx.Where("user_id = ?", userID).Delete(&LinkedIdentity{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete sessions of user
+	err = tx.Where("user_id = ?", userID).Delete(&Session{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete webhooks of user and their deliveries
+	err = tx.Where("webhook_id IN (?)", tx.Model(&Webhook{}).Select("id").Where("user_id = ?", userID)).Delete(&WebhookDelivery{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Where("user_id = ?", userID).Delete(&Webhook{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete notifications, notification and email preferences of user
+	err = tx.Where("user_id = ?", userID).Delete(&Notification{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Where("user_id = ?", userID).Delete(&NotificationPreference{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Where("user_id = ?", userID).Delete(&EmailPreference{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
 	}
 
-	// Delete user
-	err = tx.Where("id = ?", userID).Delete(&User{}).Error
+	// Delete messages of user, and the conversations nobody is left in
+	var conversationIDs []uint
+	err = tx.Model(&ConversationParticipant{}).Where("user_id = ?", userID).Pluck("conversation_id", &conversationIDs).Error
 	if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
+	}
+	err = tx.Where("user_id = ? OR message_id IN (?)", userID, tx.Model(&Message{}).Select("id").Where("sender_id = ?", userID)).
+		Delete(&MessageDeletion{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Where("sender_id = ?", userID).Delete(&Message{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = tx.Where("user_id = ?", userID).Delete(&ConversationParticipant{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if len(conversationIDs) > 0 {
+		empty := tx.Model(&Conversation{}).Select("id").
+			Where("id IN ? AND id NOT IN (?)", conversationIDs, tx.Model(&ConversationParticipant{}).Select("conversation_id"))
+		err = tx.Where("message_id IN (?)", tx.Model(&Message{}).Select("id").Where("conversation_id IN (?)", empty)).
+			Delete(&MessageDeletion{}).Error
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		err = tx.Where("conversation_id IN (?)", empty).Delete(&Message{}).Error
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		err = tx.Where("id IN ? AND id NOT IN (?)", conversationIDs, tx.Model(&ConversationParticipant{}).Select("conversation_id")).
+			Delete(&Conversation{}).Error
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+	err = tx.Where("user_id = ?", userID).Delete(&MessageSettings{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
 	}
 
-	tx.Commit()
-	return nil
+	// Delete blocks and mutes made by or of user
+	err = tx.Where("user_id = ? OR target_id = ?", userID, userID).Delete(&UserRelation{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// Delete user, unless the deletion was cancelled in the meantime
+	query := tx.Where("id = ?", userID)
+	if scheduledBefore != nil {
+		query = query.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", *scheduledBefore)
+	}
+	result := query.Delete(&User{})
+	if result.Error != nil {
+		tx.Rollback()
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 && scheduledBefore != nil {
+		tx.Rollback()
+		return false, nil
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *UserRepository) SetDeletionSchedule(userID uint, scheduledAt *time.Time) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Update("deletion_scheduled_at", scheduledAt).Error
+}
+
+// pendingDeletionUserIDs is the subquery of the accounts pending deletion, the photo and comment
+// repositories leave their content out so the account is hidden everywhere until it is purged
+func pendingDeletionUserIDs(db *gorm.DB) *gorm.DB {
+	return db.Model(&User{}).Select("id").Where("deletion_scheduled_at IS NOT NULL")
+}
+
+func (r *UserRepository) GetUsersScheduledForDeletion(before time.Time) (*[]domain.User, error) {
+	var dbUsers []User
+	err := r.db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", before).Find(&dbUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]domain.User, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		users[i] = domain.User{
+			ID:                  dbUser.ID,
+			Username:            dbUser.Username,
+			Email:               dbUser.Email,
+			Age:                 dbUser.Age,
+			CreatedAt:           dbUser.CreatedAt,
+			UpdatedAt:           dbUser.UpdatedAt,
+			DeletionScheduledAt: dbUser.DeletionScheduledAt,
+		}
+	}
+
+	return &users, nil
 }
 
 func (r *UserRepository) UpdateUser(user *domain.User) (*domain.User, error) {
-	err := r.db.Model(&User{}).Where("id = ?", user.ID).Updates(User{
-		Username:  user.Username,
-		Email:     user.Email,
-		UpdatedAt: time.Now(),
+	err := r.db.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"username":           user.Username,
+		"username_canonical": canonicalUsername(user.Username),
+		"email":              user.Email,
+		"email_canonical":    canonicalEmail(user.Email),
+		"pending_email":      user.PendingEmail,
+		"updated_at":         time.Now(),
 	}).Error
 	if err != nil {
 		return nil, err
@@ -156,15 +448,56 @@ func (r *UserRepository) UpdateUser(user *domain.User) (*domain.User, error) {
 	return user, nil
 }
 
+func (r *UserRepository) UpdatePasswordHash(userID uint, hashedPassword string) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+}
+
+func (r *UserRepository) HasPasswordHashWithPrefix(prefixes []string) (bool, error) {
+	query := r.db.Model(&User{})
+	conditions := r.db
+	for _, prefix := range prefixes {
+		conditions = conditions.Or("password LIKE ?", prefix+"%")
+	}
+
+	var count int64
+	err := query.Where(conditions).Limit(1).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *UserRepository) MarkEmailVerified(userID uint) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"email_verified": true,
+		"updated_at":     time.Now(),
+	}).Error
+}
+
+func (r *UserRepository) ReplaceEmail(userID uint, email string) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"email":           email,
+		"email_canonical": canonicalEmail(email),
+		"pending_email":   "",
+		"email_verified":  true,
+		"updated_at":      time.Now(),
+	}).Error
+}
+
+func (r *UserRepository) SetAdmin(userID uint, isAdmin bool) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Update("is_admin", isAdmin).Error
+}
+
 func (r *UserRepository) IsUsernameExist(username string) bool {
 	var dbUser User
-	err := r.db.Where("username = ?", username).First(&dbUser).Error
+	err := r.db.Where("username_canonical = ? OR username = ?", canonical.Username(username), username).First(&dbUser).Error
 	return err == nil
 }
 
 func (r *UserRepository) IsEmailExist(email string) bool {
 	var dbUser User
-	err := r.db.Where("email = ?", email).First(&dbUser).Error
+	err := r.db.Where("email_canonical = ? OR email = ?", canonical.Email(email), email).First(&dbUser).Error
 	return err == nil
 }
 
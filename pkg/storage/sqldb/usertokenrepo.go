@@ -0,0 +1,84 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"not null;type:varchar(32)"`
+	TokenHash string    `gorm:"not null;uniqueIndex;type:varchar(64)"`
+	Data      string    `gorm:"type:varchar(255)"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type UserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) domain.UserTokenRepository {
+	return &UserTokenRepository{
+		db: db,
+	}
+}
+
+func (r *UserTokenRepository) SaveUserToken(token *domain.UserToken) (*domain.UserToken, error) {
+	dbToken := UserToken{
+		UserID:    token.UserID,
+		Purpose:   token.Purpose,
+		TokenHash: token.TokenHash,
+		Data:      token.Data,
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	err := r.db.Create(&dbToken).Error
+	if err != nil {
+		return nil, err
+	}
+
+	token.ID = dbToken.ID
+	token.CreatedAt = dbToken.CreatedAt
+
+	return token, nil
+}
+
+func (r *UserTokenRepository) GetUserTokenByHash(purpose string, tokenHash string) (*domain.UserToken, error) {
+	var dbToken UserToken
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&dbToken).Error
+	if err != nil {
+		return nil, err
+	}
+
+	token := domain.UserToken{
+		ID:        dbToken.ID,
+		UserID:    dbToken.UserID,
+		Purpose:   dbToken.Purpose,
+		TokenHash: dbToken.TokenHash,
+		Data:      dbToken.Data,
+		ExpiresAt: dbToken.ExpiresAt,
+		UsedAt:    dbToken.UsedAt,
+		CreatedAt: dbToken.CreatedAt,
+	}
+
+	return &token, nil
+}
+
+func (r *UserTokenRepository) ConsumeUserToken(tokenID uint) (bool, error) {
+	// Only the update that flips used_at wins when the same token is submitted concurrently
+	result := r.db.Model(&UserToken{}).Where("id = ? AND used_at IS NULL", tokenID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserTokenRepository) ConsumeUserTokens(userID uint, purpose string) error {
+	return r.db.Model(&UserToken{}).Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,268 @@
+package sqldb
+
+import (
+	"final-project/pkg/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Webhook struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"not null;index"`
+	URL    string `gorm:"not null;type:varchar(512)"`
+	Secret string `gorm:"not null;type:varchar(255)"`
+	// Events are stored space separated
+	Events    string `gorm:"not null;type:varchar(255)"`
+	AllUsers  bool   `gorm:"not null;default:false;index"`
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID             uint   `gorm:"primaryKey"`
+	WebhookID      uint   `gorm:"not null;index"`
+	EventID        string `gorm:"not null;type:varchar(64)"`
+	EventType      string `gorm:"not null;type:varchar(32)"`
+	Payload        string `gorm:"not null;type:text"`
+	Status         string `gorm:"not null;type:varchar(16);index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int    `gorm:"not null;default:0"`
+	LastStatusCode int
+	LastError      string    `gorm:"type:varchar(1024)"`
+	NextAttemptAt  time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) domain.WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (r *WebhookRepository) SaveWebhook(webhook *domain.Webhook) (*domain.Webhook, error) {
+	dbWebhook := Webhook{
+		UserID:   webhook.UserID,
+		URL:      webhook.URL,
+		Secret:   webhook.Secret,
+		Events:   strings.Join(webhook.Events, " "),
+		AllUsers: webhook.AllUsers,
+	}
+
+	err := r.db.Create(&dbWebhook).Error
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.ID = dbWebhook.ID
+	webhook.CreatedAt = dbWebhook.CreatedAt
+
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetWebhookByID(webhookID uint) (*domain.Webhook, error) {
+	var dbWebhook Webhook
+	err := r.db.First(&dbWebhook, webhookID).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrWebhookNotFound)
+	}
+
+	webhook := toDomainWebhook(&dbWebhook)
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) GetWebhooksByUserID(userID uint) (*[]domain.Webhook, error) {
+	var dbWebhooks []Webhook
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&dbWebhooks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainWebhooks(dbWebhooks), nil
+}
+
+func (r *WebhookRepository) GetWebhooksForEvent(eventType string, userIDs []uint) (*[]domain.Webhook, error) {
+	var dbWebhooks []Webhook
+	query := r.db.Where("all_users = ?", true)
+	if len(userIDs) > 0 {
+		query = query.Or("user_id IN ?", userIDs)
+	}
+	if err := query.Find(&dbWebhooks).Error; err != nil {
+		return nil, err
+	}
+
+	// The events are matched here rather than with LIKE on the joined list
+	webhooks := make([]domain.Webhook, 0, len(dbWebhooks))
+	for _, dbWebhook := range dbWebhooks {
+		webhook := toDomainWebhook(&dbWebhook)
+		if webhook.Subscribes(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	return &webhooks, nil
+}
+
+func (r *WebhookRepository) DeleteWebhook(userID uint, webhookID uint) (bool, error) {
+	// Transaction to delete webhook and all of its deliveries
+	tx := r.db.Begin()
+
+	result := tx.Where("id = ? AND user_id = ?", webhookID, userID).Delete(&Webhook{})
+	if result.Error != nil {
+		tx.Rollback()
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	err := tx.Where("webhook_id = ?", webhookID).Delete(&WebhookDelivery{}).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit().Error
+}
+
+func (r *WebhookRepository) SaveDeliveries(deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	dbDeliveries := make([]WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		dbDeliveries[i] = WebhookDelivery{
+			WebhookID:     delivery.WebhookID,
+			EventID:       delivery.EventID,
+			EventType:     delivery.EventType,
+			Payload:       delivery.Payload,
+			Status:        delivery.Status,
+			NextAttemptAt: delivery.NextAttemptAt,
+		}
+	}
+
+	err := r.db.Create(&dbDeliveries).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		deliveries[i].ID = dbDeliveries[i].ID
+		deliveries[i].CreatedAt = dbDeliveries[i].CreatedAt
+		deliveries[i].UpdatedAt = dbDeliveries[i].UpdatedAt
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) GetDeliveryByID(deliveryID uint) (*domain.WebhookDelivery, error) {
+	var dbDelivery WebhookDelivery
+	err := r.db.First(&dbDelivery, deliveryID).Error
+	if err != nil {
+		return nil, translateNotFound(err, domain.ErrDeliveryNotFound)
+	}
+
+	delivery := toDomainDelivery(&dbDelivery)
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) GetDeadDeliveries(webhookID uint, page domain.PageRequest) (*[]domain.WebhookDelivery, int64, error) {
+	var dbDeliveries []WebhookDelivery
+	total, err := findPage(r.db, &dbDeliveries, page, "webhook_id = ? AND status = ?", webhookID, domain.DeliveryStatusDead)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return toDomainDeliveries(dbDeliveries), total, nil
+}
+
+func (r *WebhookRepository) GetDueDeliveries(now time.Time, limit int) (*[]domain.WebhookDelivery, error) {
+	var dbDeliveries []WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", domain.DeliveryStatusPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&dbDeliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainDeliveries(dbDeliveries), nil
+}
+
+func (r *WebhookRepository) ClaimDelivery(delivery *domain.WebhookDelivery, until time.Time) (bool, error) {
+	result := r.db.Model(&WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, domain.DeliveryStatusPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	delivery.NextAttemptAt = until
+	return true, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	return r.db.Model(&WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":           delivery.Status,
+		"attempts":         delivery.Attempts,
+		"last_status_code": delivery.LastStatusCode,
+		"last_error":       delivery.LastError,
+		"next_attempt_at":  delivery.NextAttemptAt,
+		"delivered_at":     delivery.DeliveredAt,
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+func toDomainWebhook(dbWebhook *Webhook) domain.Webhook {
+	return domain.Webhook{
+		ID:        dbWebhook.ID,
+		UserID:    dbWebhook.UserID,
+		URL:       dbWebhook.URL,
+		Secret:    dbWebhook.Secret,
+		Events:    strings.Fields(dbWebhook.Events),
+		AllUsers:  dbWebhook.AllUsers,
+		CreatedAt: dbWebhook.CreatedAt,
+	}
+}
+
+func toDomainWebhooks(dbWebhooks []Webhook) *[]domain.Webhook {
+	webhooks := make([]domain.Webhook, len(dbWebhooks))
+	for i, dbWebhook := range dbWebhooks {
+		webhooks[i] = toDomainWebhook(&dbWebhook)
+	}
+	return &webhooks
+}
+
+func toDomainDelivery(dbDelivery *WebhookDelivery) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:             dbDelivery.ID,
+		WebhookID:      dbDelivery.WebhookID,
+		EventID:        dbDelivery.EventID,
+		EventType:      dbDelivery.EventType,
+		Payload:        dbDelivery.Payload,
+		Status:         dbDelivery.Status,
+		Attempts:       dbDelivery.Attempts,
+		LastStatusCode: dbDelivery.LastStatusCode,
+		LastError:      dbDelivery.LastError,
+		NextAttemptAt:  dbDelivery.NextAttemptAt,
+		DeliveredAt:    dbDelivery.DeliveredAt,
+		CreatedAt:      dbDelivery.CreatedAt,
+		UpdatedAt:      dbDelivery.UpdatedAt,
+	}
+}
+
+func toDomainDeliveries(dbDeliveries []WebhookDelivery) *[]domain.WebhookDelivery {
+	deliveries := make([]domain.WebhookDelivery, len(dbDeliveries))
+	for i, dbDelivery := range dbDeliveries {
+		deliveries[i] = toDomainDelivery(&dbDelivery)
+	}
+	return &deliveries
+}
@@ -0,0 +1,237 @@
+// Package stream fans the events of the services out to the clients connected over Server-Sent
+// Events and WebSocket.
+package stream
+
+import (
+	"encoding/json"
+	"final-project/pkg/domain"
+	"final-project/pkg/event"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+type Config struct {
+	// BufferSize is the number of messages a subscription holds for its client, the subscription
+	// is closed with domain.ErrSlowConsumer when a message doesn't fit. 64 when zero.
+	BufferSize int
+	// MaxTopics caps the topics of a subscription, 20 when zero
+	MaxTopics int
+	// MaxSubscriptionsPerUser caps the open subscriptions of a user, 5 when zero
+	MaxSubscriptionsPerUser int
+}
+
+type hub struct {
+	config Config
+
+	// mu guards topics, perUser and the topics and state of the subscriptions. Publish only
+	// takes the read lock and never waits on a subscriber.
+	mu      sync.RWMutex
+	topics  map[string]map[*subscription]bool
+	perUser map[uint]int
+
+	lastID uint64
+}
+
+func NewHub(config Config) domain.StreamHub {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 64
+	}
+	if config.MaxTopics <= 0 {
+		config.MaxTopics = 20
+	}
+	if config.MaxSubscriptionsPerUser <= 0 {
+		config.MaxSubscriptionsPerUser = 5
+	}
+
+	return &hub{
+		config:  config,
+		topics:  make(map[string]map[*subscription]bool),
+		perUser: make(map[uint]int),
+	}
+}
+
+// Publish sends the event to the subscribers of its topics. A subscriber whose buffer is full is
+// closed instead of slowing down the service which raised the event.
+func (h *hub) Publish(e *domain.Event) {
+	topics := topicsOf(e)
+	if len(topics) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(event.NewPayload(e))
+	if err != nil {
+		log.Printf("failed to encode event %s: %v", e.Type, err)
+		return
+	}
+
+	id := atomic.AddUint64(&h.lastID, 1)
+
+	var overflowed []*subscription
+	h.mu.RLock()
+	// A subscriber of several of the topics gets the event once
+	sent := make(map[*subscription]bool)
+	for _, topic := range topics {
+		for sub := range h.topics[topic] {
+			if sent[sub] {
+				continue
+			}
+			sent[sub] = true
+
+			select {
+			case sub.messages <- domain.StreamMessage{ID: id, Topic: topic, Type: e.Type, ActorID: e.ActorID, Data: data}:
+			default:
+				overflowed = append(overflowed, sub)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range overflowed {
+		h.close(sub, domain.ErrSlowConsumer)
+	}
+}
+
+// topicsOf lists the topics an event is published to
+func topicsOf(e *domain.Event) []string {
+	var topics []string
+	if comment, ok := e.Data.(*domain.Comment); ok && e.Type == domain.EventCommentCreated {
+		topics = append(topics, domain.PhotoCommentsTopic(comment.PhotoID))
+	}
+	for _, userID := range e.UserIDs {
+		if userID != e.ActorID {
+			topics = append(topics, domain.NotificationsTopic(userID))
+		}
+	}
+	return topics
+}
+
+func (h *hub) Subscribe(userID uint, topics []string) (domain.StreamSubscription, error) {
+	if len(topics) > h.config.MaxTopics {
+		return nil, domain.NewFieldValidationError("topic", fmt.Sprintf("must have at most %d topics", h.config.MaxTopics))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.perUser[userID] >= h.config.MaxSubscriptionsPerUser {
+		return nil, domain.NewTooManyRequestsError("too_many_subscriptions", fmt.Sprintf("a user can have at most %d open subscriptions", h.config.MaxSubscriptionsPerUser))
+	}
+	h.perUser[userID]++
+
+	sub := &subscription{
+		hub:      h,
+		userID:   userID,
+		messages: make(chan domain.StreamMessage, h.config.BufferSize),
+		topics:   make(map[string]bool),
+	}
+	for _, topic := range topics {
+		h.add(sub, topic)
+	}
+	return sub, nil
+}
+
+// add subscribes sub to topic, the caller holds the lock
+func (h *hub) add(sub *subscription, topic string) {
+	if sub.topics[topic] {
+		return
+	}
+	sub.topics[topic] = true
+
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*subscription]bool)
+	}
+	h.topics[topic][sub] = true
+}
+
+// remove unsubscribes sub from topic, the caller holds the lock
+func (h *hub) remove(sub *subscription, topic string) {
+	if !sub.topics[topic] {
+		return
+	}
+	delete(sub.topics, topic)
+
+	delete(h.topics[topic], sub)
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+}
+
+// close ends a subscription once, err is nil when the client closed it
+func (h *hub) close(sub *subscription, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	sub.err = err
+
+	for topic := range sub.topics {
+		h.remove(sub, topic)
+	}
+	h.perUser[sub.userID]--
+	if h.perUser[sub.userID] == 0 {
+		delete(h.perUser, sub.userID)
+	}
+	close(sub.messages)
+}
+
+type subscription struct {
+	hub      *hub
+	userID   uint
+	messages chan domain.StreamMessage
+	// topics, closed and err are guarded by the lock of the hub
+	topics map[string]bool
+	closed bool
+	err    error
+}
+
+func (s *subscription) Messages() <-chan domain.StreamMessage {
+	return s.messages
+}
+
+func (s *subscription) Err() error {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return s.err
+}
+
+func (s *subscription) Subscribe(topics []string) error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	if s.closed {
+		return s.err
+	}
+
+	count := len(s.topics)
+	for _, topic := range topics {
+		if !s.topics[topic] {
+			count++
+		}
+	}
+	if count > s.hub.config.MaxTopics {
+		return domain.NewFieldValidationError("topics", fmt.Sprintf("must have at most %d topics", s.hub.config.MaxTopics))
+	}
+
+	for _, topic := range topics {
+		s.hub.add(s, topic)
+	}
+	return nil
+}
+
+func (s *subscription) Unsubscribe(topics []string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	for _, topic := range topics {
+		s.hub.remove(s, topic)
+	}
+}
+
+func (s *subscription) Close() {
+	s.hub.close(s, nil)
+}
@@ -0,0 +1,206 @@
+package twofactor
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"final-project/pkg/domain"
+	"final-project/pkg/totp"
+	"strings"
+	"time"
+
+	"rsc.io/qr"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	errNotEnabled     = domain.NewConflictError("two_factor_not_enabled", "two-factor authentication is not enabled")
+	errAlreadyEnabled = domain.NewConflictError("two_factor_already_enabled", "two-factor authentication is already enabled")
+	errInvalidCode    = domain.NewValidationError("invalid_two_factor_code", "invalid two-factor code",
+		domain.FieldError{Field: "code", Message: "is invalid"})
+)
+
+type Config struct {
+	// Issuer is the account issuer shown by authenticator apps
+	Issuer string
+}
+
+type service struct {
+	repo          domain.TwoFactorRepository
+	userRepo      domain.UserRepository
+	cryptoService domain.CryptoService
+	config        Config
+}
+
+func NewService(
+	repo domain.TwoFactorRepository,
+	userRepo domain.UserRepository,
+	cryptoService domain.CryptoService,
+	config Config,
+) domain.TwoFactorService {
+	return &service{
+		repo:          repo,
+		userRepo:      userRepo,
+		cryptoService: cryptoService,
+		config:        config,
+	}
+}
+
+// Enroll generates a new secret, two-factor authentication is enabled once a code is confirmed
+func (s *service) Enroll(userID uint) (*domain.TwoFactorEnrollment, error) {
+	if s.IsEnabled(userID) {
+		return nil, errAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.repo.SaveTwoFactor(&domain.TwoFactor{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(s.config.Issuer, user.Username, secret)
+	code, err := qr.Encode(uri, qr.M)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TwoFactorEnrollment{
+		Secret:    secret,
+		URI:       uri,
+		QRCodePNG: code.PNG(),
+	}, nil
+}
+
+func (s *service) Confirm(userID uint, code string) ([]string, error) {
+	twoFactor, err := s.repo.GetTwoFactor(userID)
+	if err != nil {
+		return nil, domain.NewConflictError("two_factor_not_enrolled", "start the two-factor enrollment first")
+	}
+	if twoFactor.Enabled {
+		return nil, errAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, normalizeCode(code), time.Now(), 1)
+	if !ok {
+		return nil, errInvalidCode
+	}
+
+	twoFactor.Enabled = true
+	twoFactor.LastUsedStep = step
+	if _, err := s.repo.SaveTwoFactor(twoFactor); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(userID)
+}
+
+// Disable turns two-factor authentication off, both the password and a code are required
+func (s *service) Disable(userID uint, password string, code string) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.cryptoService.VerifyPassword(password, user.Password); err != nil {
+		return domain.NewValidationError("password_incorrect", "password is incorrect",
+			domain.FieldError{Field: "password", Message: "is incorrect"})
+	}
+
+	if err := s.VerifyCode(userID, code); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteTwoFactor(userID)
+}
+
+func (s *service) IsEnabled(userID uint) bool {
+	twoFactor, err := s.repo.GetTwoFactor(userID)
+	return err == nil && twoFactor.Enabled
+}
+
+func (s *service) VerifyCode(userID uint, code string) error {
+	twoFactor, err := s.repo.GetTwoFactor(userID)
+	if err != nil || !twoFactor.Enabled {
+		return errNotEnabled
+	}
+
+	code = normalizeCode(code)
+
+	// Authenticator codes are digits only, anything else can only be a recovery code
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now(), 1)
+		if !ok {
+			return errInvalidCode
+		}
+
+		// Each code can only be used once
+		fresh, err := s.repo.UpdateLastUsedStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errInvalidCode
+		}
+		return nil
+	}
+
+	consumed, err := s.repo.ConsumeRecoveryCode(userID, s.cryptoService.HashToken(code))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return errInvalidCode
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, a valid code is required
+func (s *service) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.VerifyCode(userID, code); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(userID)
+}
+
+// newRecoveryCodes replaces the recovery codes of the user and returns them in plaintext, only hashes are stored
+func (s *service) newRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		// 8 base32 characters shown as xxxx-xxxx
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = s.cryptoService.HashToken(code)
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeCode drops the separators users tend to type and lowercases recovery codes
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
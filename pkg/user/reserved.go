@@ -0,0 +1,56 @@
+package user
+
+import (
+	"final-project/pkg/canonical"
+	"strings"
+)
+
+// defaultReservedUsernames can't be registered, they could be mistaken for the service itself
+var defaultReservedUsernames = []string{
+	"admin",
+	"administrator",
+	"root",
+	"system",
+	"support",
+	"help",
+	"security",
+	"mygram",
+	"staff",
+	"moderator",
+	"official",
+	"api",
+	"www",
+	"mail",
+	"postmaster",
+	"abuse",
+	"noreply",
+	"no-reply",
+	"null",
+	"undefined",
+	"me",
+	"users",
+	"photos",
+	"comments",
+	"socialmedias",
+}
+
+// isReservedUsername compares canonical forms so "ADMIN" or "ａｄｍｉｎ" are reserved too
+func (s *service) isReservedUsername(username string) bool {
+	name := canonical.Username(username)
+	for _, reserved := range defaultReservedUsernames {
+		if name == canonical.Username(reserved) {
+			return true
+		}
+	}
+	for _, reserved := range s.config.ReservedUsernames {
+		if name == canonical.Username(reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAt tells whether a username contains "@", including the full-width form, it would be taken for an email at login
+func hasAt(username string) bool {
+	return strings.Contains(canonical.Username(username), "@")
+}
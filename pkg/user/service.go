@@ -2,8 +2,43 @@ package user
 
 import (
 	"errors"
+	"final-project/pkg/canonical"
 	"final-project/pkg/domain"
+	"fmt"
 	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// DeletionGracePeriod is how long an account stays pending deletion before it is purged
+	DeletionGracePeriod time.Duration
+	// PasswordResetTTL is how long a password reset token can be used
+	PasswordResetTTL time.Duration
+	// PasswordResetURL is the page the reset token is sent to as the token query parameter
+	PasswordResetURL string
+	// EmailVerificationTTL is how long an email verification link can be used
+	EmailVerificationTTL time.Duration
+	// EmailVerificationURL is the verification endpoint, the token is added as query parameter
+	EmailVerificationURL string
+	// ReservedUsernames can't be registered, on top of the built-in list
+	ReservedUsernames []string
+}
+
+// dummyHashRecheckInterval is how often the algorithm of the dummy hash is chosen again
+const dummyHashRecheckInterval = time.Hour
+
+// passwordResetQueueSize is the number of password reset emails waiting to be sent
+const passwordResetQueueSize = 256
+
+var (
+	errUsernameReserved = domain.NewConflictError("username_reserved", "username is reserved")
+	errUsernameTaken    = domain.NewConflictError("username_taken", "username already exist")
+	errUsernameAt       = domain.NewFieldValidationError("username", "can't contain @")
+	errEmailTaken       = domain.NewConflictError("email_taken", "email already exist")
+	errInvalidToken     = domain.NewValidationError("invalid_token", "invalid or expired token")
 )
 
 // type ValidatorService interface {
@@ -16,6 +51,23 @@ type service struct {
 	repo          domain.UserRepository
 	cryptoService domain.CryptoService
 	authService   domain.AuthService
+	auditRepo     domain.AuditLogRepository
+	tokenRepo     domain.UserTokenRepository
+	mailer        domain.Mailer
+	templates     domain.EmailTemplates
+	twoFactor     domain.TwoFactorService
+	loginGuard    domain.LoginGuard
+	sessions      domain.SessionService
+	apiKeyRepo    domain.APIKeyRepository
+	events        domain.EventPublisher
+	config        Config
+	// dummyHashes are verified against for unknown usernames, by algorithm
+	dummyMu        sync.Mutex
+	dummyHashes    map[string]string
+	dummyAlgorithm string
+	dummyCheckedAt time.Time
+	// passwordResets are the emails password resets were requested for, sent one after the other
+	passwordResets chan string
 	// validator     ValidatorService
 }
 
@@ -23,24 +75,53 @@ func NewService(
 	repo domain.UserRepository,
 	cryptoService domain.CryptoService,
 	authService domain.AuthService,
+	auditRepo domain.AuditLogRepository,
+	tokenRepo domain.UserTokenRepository,
+	mailer domain.Mailer,
+	templates domain.EmailTemplates,
+	twoFactorService domain.TwoFactorService,
+	loginGuard domain.LoginGuard,
+	sessionService domain.SessionService,
+	apiKeyRepo domain.APIKeyRepository,
+	events domain.EventPublisher,
+	config Config,
 	// validatorService ValidatorService,
 ) domain.UserService {
 	log.Println("user service created")
-	return &service{
+	s := &service{
 		repo:          repo,
 		cryptoService: cryptoService,
 		authService:   authService,
+		auditRepo:     auditRepo,
+		tokenRepo:     tokenRepo,
+		mailer:        mailer,
+		templates:     templates,
+		twoFactor:     twoFactorService,
+		loginGuard:    loginGuard,
+		sessions:      sessionService,
+		apiKeyRepo:    apiKeyRepo,
+		events:        events,
+		config:        config,
 		// validator:     validatorService,
+		passwordResets: make(chan string, passwordResetQueueSize),
 	}
+	go s.sendPasswordResets()
+	return s
 }
 
 func (s *service) Register(req *domain.RegisterRequest) (*domain.User, error) {
-	// check if username & email already exist
+	// check if username is reserved & username & email already exist, ignoring case and width
+	if hasAt(req.Username) {
+		return nil, errUsernameAt
+	}
+	if s.isReservedUsername(req.Username) {
+		return nil, errUsernameReserved
+	}
 	if s.repo.IsUsernameExist(req.Username) {
-		return nil, errors.New("username already exist")
+		return nil, errUsernameTaken
 	}
 	if s.repo.IsEmailExist(req.Email) {
-		return nil, errors.New("email already exist")
+		return nil, errEmailTaken
 	}
 
 	// hash password
@@ -56,29 +137,287 @@ func (s *service) Register(req *domain.RegisterRequest) (*domain.User, error) {
 		Age:      req.Age,
 		Password: hashedPassword,
 	}
-	return s.repo.SaveUser(userToSave)
+	user, err := s.repo.SaveUser(userToSave)
+	if err != nil {
+		return nil, err
+	}
+
+	// the user can ask for a new link when this one is lost
+	if err := s.sendEmailVerification(user, user.Email); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	return user, nil
 }
 
-func (s *service) Login(user *domain.LoginRequest) (*string, error) {
+func (s *service) Login(user *domain.LoginRequest) (*domain.LoginResult, error) {
 	// validate login request
 	// if err := s.validator.ValidateLoginRequest(user); err != nil {
 	// 	return nil, err
 	// }
 
-	// get user by username
-	userFromDB, err := s.repo.GetUserByUsername(user.Username)
+	// check the credentials
+	userFromDB, err := s.authenticate(user)
 	if err != nil {
 		return nil, err
 	}
 
-	// verify password
-	err = s.cryptoService.VerifyPassword(user.Password, userFromDB.Password)
+	// accounts pending deletion can only be used to cancel the deletion
+	if userFromDB.DeletionScheduledAt != nil {
+		return nil, domain.NewForbiddenError("account_pending_deletion", "account is pending deletion, cancel the deletion to log in again")
+	}
+
+	return s.issueLogin(userFromDB, user.IP, user.UserAgent)
+}
+
+func (s *service) LoginWithIdentity(req *domain.IdentityLoginRequest) (*domain.LoginResult, error) {
+	// accounts pending deletion are not returned
+	user, err := s.repo.GetUserByID(req.UserID)
+	if err != nil {
+		return nil, domain.NewUnauthorizedError("account_unavailable", "account not found or pending deletion")
+	}
+
+	return s.issueLogin(user, req.IP, req.UserAgent)
+}
+
+// issueLogin returns the token of an authenticated user, or the challenge token when
+// two-factor authentication is enabled
+func (s *service) issueLogin(user *domain.User, ip string, userAgent string) (*domain.LoginResult, error) {
+	// with two-factor authentication a code is needed to get the token,
+	// failures are only cleared once the code is accepted
+	if s.twoFactor.IsEnabled(user.ID) {
+		challengeToken, err := s.authService.GenerateChallengeToken(user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.LoginResult{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}, nil
+	}
+
+	s.recordLoginSuccess(user.Username)
+
+	token, err := s.startSession(user, ip, userAgent)
 	if err != nil {
 		return nil, err
 	}
 
+	return &domain.LoginResult{
+		Token: token,
+	}, nil
+}
+
+// startSession records a session for the device and returns a token bound to it
+func (s *service) startSession(user *domain.User, ip string, userAgent string) (string, error) {
+	session, err := s.sessions.CreateSession(user.ID, userAgent, ip)
+	if err != nil {
+		return "", err
+	}
+
 	// generate token
-	token, err := s.authService.GenerateToken(userFromDB.ID)
+	return s.authService.GenerateToken(&domain.TokenClaims{
+		UserID:       user.ID,
+		TokenVersion: user.TokenVersion,
+		SessionID:    session.ID,
+	})
+}
+
+// authenticate checks a username or email and a password against the login guard. Unknown identifiers
+// go through a password verification too so response times don't reveal which accounts exist.
+func (s *service) authenticate(req *domain.LoginRequest) (*domain.User, error) {
+	// get user by email or username, ignoring case and width
+	var userFromDB *domain.User
+	var err error
+	if strings.Contains(req.Username, "@") {
+		userFromDB, err = s.repo.GetUserByEmail(req.Username)
+		// usernames registered before "@" was refused can still log in
+		if errors.Is(err, domain.ErrUserNotFound) {
+			userFromDB, err = s.repo.GetUserByUsername(req.Username)
+		}
+	} else {
+		userFromDB, err = s.repo.GetUserByUsername(req.Username)
+	}
+
+	// failures are counted per account, whichever identifier was used
+	guardName := req.Username
+	if err == nil {
+		guardName = userFromDB.Username
+	}
+	if err := s.loginGuard.Check(guardName, req.IP); err != nil {
+		return nil, err
+	}
+
+	if err != nil {
+		s.cryptoService.VerifyPassword(req.Password, s.dummyPasswordHash())
+		s.recordLoginFailure(guardName, req.IP)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	// verify password
+	err = s.cryptoService.VerifyPassword(req.Password, userFromDB.Password)
+	if err != nil {
+		s.recordLoginFailure(guardName, req.IP)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	// upgrade the hash while the plaintext is at hand
+	if s.cryptoService.NeedsRehash(userFromDB.Password) {
+		s.rehashPassword(userFromDB, req.Password)
+	}
+
+	return userFromDB, nil
+}
+
+func (s *service) rehashPassword(user *domain.User, password string) {
+	hashedPassword, err := s.cryptoService.HashPassword(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := s.repo.UpdatePasswordHash(user.ID, hashedPassword); err != nil {
+		log.Printf("failed to store rehashed password of user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// dummyPasswordHash is verified against for unknown usernames. It is made like the hashes of the
+// accounts that didn't log in since the hashing algorithm changed while there are any, since their
+// hashes can take much longer to verify, and like the new hashes once all were upgraded.
+func (s *service) dummyPasswordHash() string {
+	s.dummyMu.Lock()
+	defer s.dummyMu.Unlock()
+
+	if time.Since(s.dummyCheckedAt) >= dummyHashRecheckInterval {
+		algorithms := s.cryptoService.Algorithms()
+		algorithm := algorithms[0]
+		for _, legacy := range algorithms[1:] {
+			remaining, err := s.repo.HasPasswordHashWithPrefix(s.cryptoService.HashPrefixes(legacy))
+			if err != nil {
+				log.Printf("failed to look for %s password hashes: %v", legacy, err)
+				continue
+			}
+			if remaining {
+				algorithm = legacy
+				break
+			}
+		}
+		s.dummyAlgorithm = algorithm
+		s.dummyCheckedAt = time.Now()
+	}
+
+	if _, ok := s.dummyHashes[s.dummyAlgorithm]; !ok {
+		hash, err := s.cryptoService.DummyHash(s.dummyAlgorithm)
+		if err != nil {
+			log.Printf("failed to create dummy password hash: %v", err)
+			return ""
+		}
+		if s.dummyHashes == nil {
+			s.dummyHashes = make(map[string]string)
+		}
+		s.dummyHashes[s.dummyAlgorithm] = hash
+	}
+	return s.dummyHashes[s.dummyAlgorithm]
+}
+
+func (s *service) recordLoginFailure(username string, ip string) {
+	if err := s.loginGuard.RecordFailure(username, ip); err != nil {
+		log.Printf("failed to record login failure of %q: %v", username, err)
+	}
+}
+
+func (s *service) recordLoginSuccess(username string) {
+	if err := s.loginGuard.RecordSuccess(username); err != nil {
+		log.Printf("failed to record login success of %q: %v", username, err)
+	}
+}
+
+// LoginTwoFactor exchanges the challenge token of Login and a TOTP or recovery code for an access token
+func (s *service) LoginTwoFactor(req *domain.TwoFactorLoginRequest) (*string, error) {
+	userID, err := s.authService.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// get user from db by id, accounts deleted in the meantime are rejected
+	userFromDB, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// codes are guessed against the same counters as passwords
+	if err := s.loginGuard.Check(userFromDB.Username, req.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactor.VerifyCode(userID, req.Code); err != nil {
+		s.recordLoginFailure(userFromDB.Username, req.IP)
+		// a wrong code fails the login like a wrong password
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) && domainErr.Kind == domain.ErrorKindValidation {
+			return nil, domain.NewUnauthorizedError(domainErr.Code, domainErr.Message)
+		}
+		return nil, err
+	}
+
+	s.recordLoginSuccess(userFromDB.Username)
+
+	token, err := s.startSession(userFromDB, req.IP, req.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// VerifyTokenClaims rejects tokens of unknown or pending deletion accounts, tokens revoked by a password change
+// and tokens of revoked sessions
+func (s *service) VerifyTokenClaims(claims *domain.TokenClaims) error {
+	user, err := s.repo.GetUserByID(claims.UserID)
+	if err != nil {
+		return domain.NewUnauthorizedError("account_unavailable", "user not found")
+	}
+
+	if user.TokenVersion != claims.TokenVersion {
+		return domain.NewUnauthorizedError("token_revoked", "token has been revoked")
+	}
+
+	// tokens issued before sessions were recorded have no session to check
+	if claims.SessionID != 0 {
+		return s.sessions.ValidateSession(claims.UserID, claims.SessionID)
+	}
+
+	return nil
+}
+
+// ChangePassword sets a new password, revokes all other tokens and the API keys and returns a fresh
+// token for the caller
+func (s *service) ChangePassword(userID uint, req *domain.ChangePasswordRequest) (*string, error) {
+	// get user from db by id
+	userFromDB, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify current password
+	if err := s.cryptoService.VerifyPassword(req.CurrentPassword, userFromDB.Password); err != nil {
+		return nil, domain.NewValidationError("current_password_incorrect", "current password is incorrect",
+			domain.FieldError{Field: "current_password", Message: "is incorrect"})
+	}
+
+	if err := s.setPassword(userID, req.NewPassword, req.SessionID); err != nil {
+		return nil, err
+	}
+
+	// token version was bumped, issue a token for the current session
+	token, err := s.authService.GenerateToken(&domain.TokenClaims{
+		UserID:       userID,
+		TokenVersion: userFromDB.TokenVersion + 1,
+		SessionID:    req.SessionID,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +425,136 @@ func (s *service) Login(user *domain.LoginRequest) (*string, error) {
 	return &token, nil
 }
 
+// RequestPasswordReset queues a reset link for the email. It returns before looking the email up
+// and the email is sent in the background, so neither the response nor its timing tell whether the
+// email is registered.
+func (s *service) RequestPasswordReset(email string) error {
+	select {
+	case s.passwordResets <- email:
+	default:
+		log.Printf("password reset queue is full, dropped a request")
+	}
+	return nil
+}
+
+func (s *service) sendPasswordResets() {
+	for email := range s.passwordResets {
+		if err := s.sendPasswordReset(email); err != nil {
+			log.Printf("failed to send a password reset email: %v", err)
+		}
+	}
+}
+
+// sendPasswordReset emails a reset link, unknown emails are ignored
+func (s *service) sendPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	// only the latest link works
+	if err := s.tokenRepo.ConsumeUserTokens(user.ID, domain.UserTokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token, err := s.issueUserToken(user.ID, domain.UserTokenPurposePasswordReset, "", s.config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	message, err := s.templates.Render("reset_password", map[string]interface{}{
+		"Username":  user.Username,
+		"URL":       s.config.PasswordResetURL + "?token=" + url.QueryEscape(token),
+		"ExpiresIn": s.config.PasswordResetTTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	message.To = user.Email
+	if err := s.mailer.Send(message); err != nil {
+		return fmt.Errorf("user %d: %w", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token, every issued token and API key is revoked
+func (s *service) ResetPassword(req *domain.ResetPasswordRequest) error {
+	token, err := s.useUserToken(domain.UserTokenPurposePasswordReset, req.Token)
+	if err != nil {
+		return err
+	}
+
+	return s.setPassword(token.UserID, req.NewPassword, 0)
+}
+
+// setPassword stores the new password, revokes the API keys and logs out every session but keepSessionID
+func (s *service) setPassword(userID uint, password string, keepSessionID uint) error {
+	hashedPassword, err := s.cryptoService.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+
+	// API keys don't carry the token version, a key leaked with the old password is revoked here
+	if _, err := s.apiKeyRepo.DeleteAPIKeysByUserID(userID); err != nil {
+		return err
+	}
+
+	// the token version bump already rejects the old tokens, this keeps the session list accurate
+	if err := s.sessions.RevokeOtherSessions(userID, keepSessionID); err != nil {
+		log.Printf("failed to revoke sessions of user %d: %v", userID, err)
+	}
+
+	return nil
+}
+
+// issueUserToken saves the hash of a new random token and returns the plaintext to send to the user
+func (s *service) issueUserToken(userID uint, purpose string, data string, ttl time.Duration) (string, error) {
+	token, err := s.cryptoService.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.tokenRepo.SaveUserToken(&domain.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: s.cryptoService.HashToken(token),
+		Data:      data,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useUserToken consumes a token sent to the user, it fails when the token is unknown, expired or already used
+func (s *service) useUserToken(purpose string, plaintext string) (*domain.UserToken, error) {
+	token, err := s.tokenRepo.GetUserTokenByHash(purpose, s.cryptoService.HashToken(plaintext))
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	if token.UsedAt != nil || !token.ExpiresAt.After(time.Now()) {
+		return nil, errInvalidToken
+	}
+
+	consumed, err := s.tokenRepo.ConsumeUserToken(token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errInvalidToken
+	}
+
+	return token, nil
+}
+
 func (s *service) UpdateUser(userID uint, user *domain.UpdateUserRequest) (*domain.User, error) {
 	// get user from db by id
 	userFromDB, err := s.repo.GetUserByID(userID)
@@ -93,19 +562,153 @@ func (s *service) UpdateUser(userID uint, user *domain.UpdateUserRequest) (*doma
 		return nil, err
 	}
 
-	// update user
+	// a new username has to be free, changing only its case is fine
+	if canonical.Username(user.Username) != canonical.Username(userFromDB.Username) {
+		if hasAt(user.Username) {
+			return nil, errUsernameAt
+		}
+		if s.isReservedUsername(user.Username) {
+			return nil, errUsernameReserved
+		}
+		if s.repo.IsUsernameExist(user.Username) {
+			return nil, errUsernameTaken
+		}
+	}
+
+	// update user, a new email only replaces the current one once it is confirmed
 	userFromDB.Username = user.Username
-	userFromDB.Email = user.Email
+	emailChanged := user.Email != userFromDB.Email && user.Email != userFromDB.PendingEmail
+	if user.Email == userFromDB.Email {
+		userFromDB.PendingEmail = ""
+	} else if emailChanged {
+		if s.repo.IsEmailExist(user.Email) {
+			return nil, errEmailTaken
+		}
+		userFromDB.PendingEmail = user.Email
+	}
+
+	updatedUser, err := s.repo.UpdateUser(userFromDB)
+	if err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := s.sendEmailVerification(updatedUser, updatedUser.PendingEmail); err != nil {
+			log.Printf("failed to send verification email to user %d: %v", updatedUser.ID, err)
+		}
+	}
+
+	return updatedUser, nil
+}
 
-	return s.repo.UpdateUser(userFromDB)
+// IsUsernameAvailable tells whether a username is neither reserved nor taken, ignoring case and width
+func (s *service) IsUsernameAvailable(username string) bool {
+	return !s.isReservedUsername(username) && !s.repo.IsUsernameExist(username)
 }
 
 func (s *service) DeleteUser(userID uint) error {
 	// check if user exist
-	if !s.IsUserExist(userID) {
-		return errors.New("user not found")
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+	if err := s.repo.DeleteUserByID(userID); err != nil {
+		return err
+	}
+
+	s.publishDeleted(user)
+	return nil
+}
+
+// RequestDeletion puts the account in pending deletion state, it is purged once the grace period ends
+func (s *service) RequestDeletion(userID uint) (*domain.User, error) {
+	// check if user exist
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// schedule the purge
+	scheduledAt := time.Now().Add(s.config.DeletionGracePeriod)
+	if err := s.repo.SetDeletionSchedule(userID, &scheduledAt); err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = &scheduledAt
+
+	s.audit(domain.AuditActionDeletionRequested, userID, fmt.Sprintf("purge scheduled at %s", scheduledAt.Format(time.RFC3339)))
+
+	return user, nil
+}
+
+// CancelDeletion restores an account pending deletion, the credentials are checked like on login
+func (s *service) CancelDeletion(req *domain.LoginRequest) error {
+	// check the credentials
+	userFromDB, err := s.authenticate(req)
+	if err != nil {
+		return err
+	}
+
+	if userFromDB.DeletionScheduledAt == nil {
+		return domain.NewConflictError("account_not_pending_deletion", "account is not pending deletion")
+	}
+
+	if err := s.repo.SetDeletionSchedule(userFromDB.ID, nil); err != nil {
+		return err
+	}
+
+	s.audit(domain.AuditActionDeletionCancelled, userFromDB.ID, "deletion cancelled by account owner")
+
+	return nil
+}
+
+// PurgeScheduledDeletions deletes every account whose grace period has ended and returns how many were purged
+func (s *service) PurgeScheduledDeletions() (int, error) {
+	now := time.Now()
+	users, err := s.repo.GetUsersScheduledForDeletion(now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range *users {
+		// the owner may cancel the deletion between the lookup and the purge
+		deleted, err := s.repo.PurgeUser(user.ID, now)
+		if err != nil {
+			return purged, err
+		}
+		if !deleted {
+			continue
+		}
+		purged++
+
+		s.audit(domain.AuditActionUserPurged, user.ID, fmt.Sprintf("account purged, deletion was scheduled at %s", user.DeletionScheduledAt.Format(time.RFC3339)))
+		s.publishDeleted(&user)
+	}
+
+	return purged, nil
+}
+
+// publishDeleted raises the event of a deleted account, its own webhooks are gone with it so only
+// the webhooks of every account receive it
+func (s *service) publishDeleted(user *domain.User) {
+	s.events.Publish(&domain.Event{
+		Type:       domain.EventUserDeleted,
+		ActorID:    user.ID,
+		UserIDs:    []uint{user.ID},
+		Data:       user,
+		OccurredAt: time.Now(),
+	})
+}
+
+func (s *service) audit(action string, userID uint, detail string) {
+	_, err := s.auditRepo.SaveAuditLog(&domain.AuditLog{
+		Action: action,
+		UserID: userID,
+		Detail: detail,
+	})
+	if err != nil {
+		log.Printf("failed to save audit log %s for user %d: %v", action, userID, err)
 	}
-	return s.repo.DeleteUserByID(userID)
 }
 
 func (s *service) IsUserExist(id uint) bool {
@@ -116,3 +719,7 @@ func (s *service) IsUserExist(id uint) bool {
 func (s *service) GetUserByID(id uint) (*domain.User, error) {
 	return s.repo.GetUserByID(id)
 }
+
+func (s *service) GetUsersByIDs(ids []uint) (*[]domain.User, error) {
+	return s.repo.GetUsersByIDs(ids)
+}
@@ -0,0 +1,157 @@
+package user
+
+import (
+	"final-project/pkg/crypto"
+	"final-project/pkg/domain"
+	"final-project/pkg/internal/fake"
+	"final-project/pkg/mailer"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeUserRepo struct {
+	domain.UserRepository
+	bcryptHashesRemain bool
+	// scheduled are the accounts pending deletion, the owners of cancelled cancel it before the purge
+	scheduled []domain.User
+	cancelled map[uint]bool
+	purged    []uint
+}
+
+func (r *fakeUserRepo) GetUserByEmail(email string) (*domain.User, error) {
+	if email != "alice@example.com" {
+		return nil, domain.ErrUserNotFound
+	}
+	return &domain.User{ID: 1, Username: "alice", Email: email}, nil
+}
+
+func (r *fakeUserRepo) GetUsersScheduledForDeletion(before time.Time) (*[]domain.User, error) {
+	return &r.scheduled, nil
+}
+
+func (r *fakeUserRepo) PurgeUser(userID uint, scheduledBefore time.Time) (bool, error) {
+	if r.cancelled[userID] {
+		return false, nil
+	}
+	r.purged = append(r.purged, userID)
+	return true, nil
+}
+
+func (r *fakeUserRepo) HasPasswordHashWithPrefix(prefixes []string) (bool, error) {
+	return r.bcryptHashesRemain && strings.HasPrefix(prefixes[0], "$2"), nil
+}
+
+type fakeAuditLogRepo struct {
+	domain.AuditLogRepository
+	actions []string
+}
+
+func (r *fakeAuditLogRepo) SaveAuditLog(auditLog *domain.AuditLog) (*domain.AuditLog, error) {
+	r.actions = append(r.actions, auditLog.Action)
+	return auditLog, nil
+}
+
+type fakeUserTokenRepo struct {
+	domain.UserTokenRepository
+}
+
+func (fakeUserTokenRepo) ConsumeUserTokens(userID uint, purpose string) error {
+	return nil
+}
+
+func (fakeUserTokenRepo) SaveUserToken(token *domain.UserToken) (*domain.UserToken, error) {
+	return token, nil
+}
+
+type fakeEventPublisher struct {
+	events []*domain.Event
+}
+
+func (p *fakeEventPublisher) Publish(event *domain.Event) {
+	p.events = append(p.events, event)
+}
+
+func newDummyTestService(repo domain.UserRepository) *service {
+	cryptoService := crypto.NewCryptoService(crypto.Config{
+		Algorithm:  crypto.AlgorithmArgon2id,
+		BcryptCost: 4,
+		Argon2:     crypto.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	})
+	return &service{repo: repo, cryptoService: cryptoService}
+}
+
+func TestDummyPasswordHashFollowsRemainingLegacyHashes(t *testing.T) {
+	repo := &fakeUserRepo{bcryptHashesRemain: true}
+	s := newDummyTestService(repo)
+
+	if hash := s.dummyPasswordHash(); !strings.HasPrefix(hash, "$2") {
+		t.Errorf("dummy hash should be bcrypt while bcrypt hashes remain, got %q", hash)
+	}
+
+	// Once every account was upgraded the dummy follows the new algorithm
+	repo.bcryptHashesRemain = false
+	s.dummyCheckedAt = s.dummyCheckedAt.Add(-dummyHashRecheckInterval)
+	if hash := s.dummyPasswordHash(); !strings.HasPrefix(hash, "$argon2id$") {
+		t.Errorf("dummy hash should be argon2id once no bcrypt hash remains, got %q", hash)
+	}
+}
+
+func TestRegisterRefusesAtInUsernames(t *testing.T) {
+	s := newDummyTestService(&fakeUserRepo{})
+
+	for _, username := range []string{"alice@example.com", "alice＠home"} {
+		_, err := s.Register(&domain.RegisterRequest{Username: username, Email: "alice@example.com", Age: 20, Password: "secret"})
+		if err != errUsernameAt {
+			t.Errorf("register %q: got %v, want errUsernameAt", username, err)
+		}
+	}
+}
+
+func TestPurgeSkipsCancelledDeletions(t *testing.T) {
+	scheduledAt := time.Now().Add(-time.Hour)
+	repo := &fakeUserRepo{
+		scheduled: []domain.User{{ID: 1, DeletionScheduledAt: &scheduledAt}, {ID: 2, DeletionScheduledAt: &scheduledAt}},
+		cancelled: map[uint]bool{2: true},
+	}
+	auditRepo := &fakeAuditLogRepo{}
+	events := &fakeEventPublisher{}
+	s := &service{repo: repo, auditRepo: auditRepo, events: events}
+
+	purged, err := s.PurgeScheduledDeletions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 || len(repo.purged) != 1 || repo.purged[0] != 1 {
+		t.Errorf("purged %d accounts %v, want only account 1", purged, repo.purged)
+	}
+	if len(auditRepo.actions) != 1 || len(events.events) != 1 || events.events[0].ActorID != 1 {
+		t.Errorf("got audit logs %v and %d events, want only the ones of account 1", auditRepo.actions, len(events.events))
+	}
+}
+
+func TestRequestPasswordResetDoesNotWaitOnTheMailer(t *testing.T) {
+	m := &fake.Mailer{Release: make(chan struct{})}
+	s := newDummyTestService(&fakeUserRepo{})
+	s.tokenRepo = fakeUserTokenRepo{}
+	s.mailer = m
+	s.templates = mailer.NewTemplates()
+	s.passwordResets = make(chan string, 1)
+	go s.sendPasswordResets()
+
+	// the mailer is stuck, yet registered and unknown emails both return right away
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		if err := s.RequestPasswordReset(email); err != nil {
+			t.Errorf("%s: got %v", email, err)
+		}
+	}
+
+	close(m.Release)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(m.Recipients()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if recipients := m.Recipients(); len(recipients) != 1 || recipients[0] != "alice@example.com" {
+		t.Errorf("reset emails sent to %v, want alice@example.com", recipients)
+	}
+}
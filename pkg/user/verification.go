@@ -0,0 +1,82 @@
+package user
+
+import (
+	"final-project/pkg/domain"
+	"net/url"
+)
+
+// VerifyEmail confirms the email a verification token was sent to
+func (s *service) VerifyEmail(token string) (*domain.User, error) {
+	userToken, err := s.useUserToken(domain.UserTokenPurposeEmailVerification, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(userToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch userToken.Data {
+	case user.Email:
+		if err := s.repo.MarkEmailVerified(user.ID); err != nil {
+			return nil, err
+		}
+	case user.PendingEmail:
+		// the email may have been taken since the change was requested
+		if s.repo.IsEmailExist(user.PendingEmail) {
+			return nil, errEmailTaken
+		}
+		if err := s.repo.ReplaceEmail(user.ID, user.PendingEmail); err != nil {
+			return nil, err
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+	default:
+		return nil, domain.NewConflictError("email_changed", "this email is no longer used by the account")
+	}
+
+	user.EmailVerified = true
+	return user, nil
+}
+
+// ResendEmailVerification sends a new link for the pending email, or for the current one when it is not verified
+func (s *service) ResendEmailVerification(userID uint) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case user.PendingEmail != "":
+		return s.sendEmailVerification(user, user.PendingEmail)
+	case !user.EmailVerified:
+		return s.sendEmailVerification(user, user.Email)
+	default:
+		return domain.NewConflictError("email_already_verified", "email is already verified")
+	}
+}
+
+func (s *service) sendEmailVerification(user *domain.User, email string) error {
+	// only the latest link works
+	if err := s.tokenRepo.ConsumeUserTokens(user.ID, domain.UserTokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := s.issueUserToken(user.ID, domain.UserTokenPurposeEmailVerification, email, s.config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	message, err := s.templates.Render("verify_email", map[string]interface{}{
+		"Username":  user.Username,
+		"URL":       s.config.EmailVerificationURL + "?token=" + url.QueryEscape(token),
+		"ExpiresIn": s.config.EmailVerificationTTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	message.To = email
+	return s.mailer.Send(message)
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"final-project/pkg/domain"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// blockedNetworks can't be reached by webhooks on top of the loopback, private, link-local, multicast
+// and unspecified addresses. The cloud metadata services are link-local, or private for IPv6.
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",     // this network
+	"100.64.0.0/10", // carrier-grade NAT, some clouds serve metadata there
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved and broadcast
+	"64:ff9b::/96",  // NAT64, which translates to any IPv4 address
+)
+
+var errPrivateAddress = domain.NewFieldValidationError("url", "must not point to a loopback, private or link-local address")
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// isPublicAddress tells whether webhooks may connect to ip
+func isPublicAddress(ip net.IP) bool {
+	// IPv4-mapped IPv6 addresses are checked as IPv4
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHost refuses the hosts of webhook URLs resolving to an address that isn't public
+func (s *service) checkHost(host string) error {
+	if s.config.AllowPrivateNetworks {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return domain.NewFieldValidationError("url", "has a host that can't be resolved")
+	}
+	for _, addr := range addrs {
+		if !isPublicAddress(addr.IP) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// newClient returns the client of the deliveries. Every connection checks the address it dials, the
+// host may resolve to another address than when the webhook was created, and redirects aren't
+// followed since they could lead anywhere.
+func newClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: config.Timeout, KeepAlive: 30 * time.Second}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+				return fmt.Errorf("refused to connect to %s, not a public address", host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect to the receiver on our behalf, out of reach of the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,415 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"final-project/pkg/domain"
+	eventpkg "final-project/pkg/event"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretPrefix marks the generated secrets
+const secretPrefix = "whsec_"
+
+type Config struct {
+	// MaxWebhooksPerUser caps the number of webhooks of an account
+	MaxWebhooksPerUser int
+	// MaxAttempts is the number of failed attempts after which a delivery is dead, 8 when zero
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt, doubled after each of the next
+	// ones up to MaxBackoff. 30 seconds and 6 hours when zero.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds each attempt, 10 seconds when zero
+	Timeout time.Duration
+	// BatchSize is the number of deliveries sent per run of DeliverDue, 100 when zero
+	BatchSize int
+	// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses, for
+	// development. They are refused when a webhook is created and when a delivery connects otherwise.
+	AllowPrivateNetworks bool
+	// Client sends the deliveries, when nil a client with Timeout which doesn't follow redirects and
+	// only connects to the addresses allowed by AllowPrivateNetworks
+	Client *http.Client
+}
+
+type service struct {
+	repo          domain.WebhookRepository
+	userRepo      domain.UserRepository
+	cryptoService domain.CryptoService
+	config        Config
+	// delivering serializes the runs of DeliverDue of this instance, the claims keep instances apart
+	delivering sync.Mutex
+	// wake asks the delivery worker for a run of DeliverDue, a pending request covers every event
+	// published until the run starts
+	wake chan struct{}
+}
+
+func NewService(repo domain.WebhookRepository, userRepo domain.UserRepository, cryptoService domain.CryptoService, config Config) domain.WebhookService {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 6 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.Client == nil {
+		config.Client = newClient(config)
+	}
+
+	s := &service{
+		repo:          repo,
+		userRepo:      userRepo,
+		cryptoService: cryptoService,
+		config:        config,
+		wake:          make(chan struct{}, 1),
+	}
+	go s.deliverWhenWoken()
+
+	return s
+}
+
+func (s *service) CreateWebhook(userID uint, req *domain.CreateWebhookRequest) (*domain.Webhook, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	// validate parsed the URL already
+	u, _ := url.Parse(req.URL)
+	if err := s.checkHost(u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	if req.AllUsers {
+		user, err := s.userRepo.GetUserByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !user.IsAdmin {
+			return nil, domain.NewForbiddenError("admin_required", "only administrators can subscribe to the events of every account")
+		}
+	}
+
+	webhooks, err := s.repo.GetWebhooksByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.MaxWebhooksPerUser > 0 && len(*webhooks) >= s.config.MaxWebhooksPerUser {
+		return nil, domain.NewConflictError("webhook_limit_reached", fmt.Sprintf("an account can have at most %d webhooks", s.config.MaxWebhooksPerUser))
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		token, err := s.cryptoService.GenerateRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		secret = secretPrefix + token
+	}
+
+	return s.repo.SaveWebhook(&domain.Webhook{
+		UserID:   userID,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   uniqueEvents(req.Events),
+		AllUsers: req.AllUsers,
+	})
+}
+
+func (s *service) GetWebhooks(userID uint) (*[]domain.Webhook, error) {
+	return s.repo.GetWebhooksByUserID(userID)
+}
+
+func (s *service) DeleteWebhook(userID uint, webhookID uint) error {
+	deleted, err := s.repo.DeleteWebhook(userID, webhookID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *service) GetDeadLetters(userID uint, webhookID uint, page domain.PageRequest) (*[]domain.WebhookDelivery, int64, error) {
+	if _, err := s.ownedWebhook(userID, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.repo.GetDeadDeliveries(webhookID, page)
+}
+
+func (s *service) Redeliver(userID uint, webhookID uint, deliveryID uint) (*domain.WebhookDelivery, error) {
+	if _, err := s.ownedWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.GetDeliveryByID(deliveryID)
+	if err != nil || delivery.WebhookID != webhookID {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	if delivery.Status == domain.DeliveryStatusPending {
+		return nil, domain.NewConflictError("delivery_pending", "delivery is still being attempted")
+	}
+
+	// The delivery gets a fresh set of attempts
+	delivery.Status = domain.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.LastStatusCode = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	s.wakeDelivery()
+
+	return delivery, nil
+}
+
+// Publish queues the event for the webhooks subscribed to it and sends it in the background.
+// Failures are logged, the action which raised the event has already happened.
+func (s *service) Publish(event *domain.Event) {
+	webhooks, err := s.repo.GetWebhooksForEvent(event.Type, event.UserIDs)
+	if err != nil {
+		log.Printf("failed to find webhooks of event %s: %v", event.Type, err)
+		return
+	}
+	if len(*webhooks) == 0 {
+		return
+	}
+
+	if event.ID == "" {
+		event.ID = eventpkg.NewID()
+	}
+	payload, err := json.Marshal(eventpkg.NewPayload(event))
+	if err != nil {
+		log.Printf("failed to encode event %s: %v", event.Type, err)
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]domain.WebhookDelivery, len(*webhooks))
+	for i, webhook := range *webhooks {
+		deliveries[i] = domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        domain.DeliveryStatusPending,
+			NextAttemptAt: now,
+		}
+	}
+	if err := s.repo.SaveDeliveries(deliveries); err != nil {
+		log.Printf("failed to queue event %s: %v", event.Type, err)
+		return
+	}
+
+	s.wakeDelivery()
+}
+
+// wakeDelivery asks the delivery worker for a run without waiting for it. When a run is already
+// requested the deliveries just saved are picked up by it.
+func (s *service) wakeDelivery() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverWhenWoken is the delivery worker, it sends the due deliveries each time it is woken up
+func (s *service) deliverWhenWoken() {
+	for range s.wake {
+		if _, err := s.DeliverDue(); err != nil {
+			log.Printf("failed to deliver webhooks: %v", err)
+		}
+	}
+}
+
+func (s *service) DeliverDue() (int, error) {
+	s.delivering.Lock()
+	defer s.delivering.Unlock()
+
+	now := time.Now()
+	deliveries, err := s.repo.GetDueDeliveries(now, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[uint]*domain.Webhook)
+	delivered := 0
+	for i := range *deliveries {
+		delivery := &(*deliveries)[i]
+
+		// Claimed for longer than an attempt lasts, so a crashed attempt is retried
+		claimed, err := s.repo.ClaimDelivery(delivery, now.Add(2*s.config.Timeout))
+		if err != nil {
+			return delivered, err
+		}
+		if !claimed {
+			continue
+		}
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = s.repo.GetWebhookByID(delivery.WebhookID)
+			if err != nil {
+				log.Printf("failed to load webhook %d of delivery %d: %v", delivery.WebhookID, delivery.ID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if s.attempt(webhook, delivery) {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// attempt sends a delivery once, records the outcome and reports whether it was delivered
+func (s *service) attempt(webhook *domain.Webhook, delivery *domain.WebhookDelivery) bool {
+	statusCode, err := s.send(webhook, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	if err == nil {
+		delivery.Status = domain.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.LastError = truncate(err.Error(), 1024)
+		if delivery.Attempts >= s.config.MaxAttempts {
+			delivery.Status = domain.DeliveryStatusDead
+		} else {
+			delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		}
+	}
+
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		log.Printf("failed to record attempt of webhook delivery %d: %v", delivery.ID, err)
+	}
+	return delivery.Status == domain.DeliveryStatusDelivered
+}
+
+// send posts the signed payload and returns the response status, any status but 2xx is an error
+func (s *service) send(webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MyGram-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, signatureHeader(webhook.Secret, time.Now(), body))
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts
+func (s *service) backoff(attempts int) time.Duration {
+	wait := s.config.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= s.config.MaxBackoff {
+			return s.config.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// ownedWebhook returns a webhook of the user, the webhooks of others are reported as not found
+func (s *service) ownedWebhook(userID uint, webhookID uint) (*domain.Webhook, error) {
+	webhook, err := s.repo.GetWebhookByID(webhookID)
+	if err != nil || webhook.UserID != userID {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func validate(req *domain.CreateWebhookRequest) error {
+	if req.URL == "" {
+		return domain.NewFieldValidationError("url", "is required")
+	}
+	if len(req.URL) > 512 {
+		return domain.NewFieldValidationError("url", "must be at most 512 characters")
+	}
+	if u, err := url.ParseRequestURI(req.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return domain.NewFieldValidationError("url", "must be a valid http or https url")
+	}
+
+	if req.Secret != "" && (len(req.Secret) < 16 || len(req.Secret) > 255) {
+		return domain.NewFieldValidationError("secret", "must be between 16 and 255 characters")
+	}
+
+	if len(req.Events) == 0 {
+		return domain.NewFieldValidationError("events", "must have at least one event")
+	}
+	for _, event := range req.Events {
+		if !isKnownEvent(event) {
+			return domain.NewFieldValidationError("events", fmt.Sprintf("has unknown event %q, expected one of %s", event, strings.Join(domain.WebhookEventTypes, ", ")))
+		}
+	}
+
+	return nil
+}
+
+func isKnownEvent(event string) bool {
+	for _, known := range domain.WebhookEventTypes {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueEvents(events []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, event := range events {
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
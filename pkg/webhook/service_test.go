@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"final-project/pkg/domain"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeWebhookRepo keeps the webhooks, all subscribed to every event, but not the deliveries. Each run
+// of DeliverDue is signalled on runs and waits on release when they are set.
+type fakeWebhookRepo struct {
+	domain.WebhookRepository
+	saved   []domain.Webhook
+	runs    chan struct{}
+	release chan struct{}
+}
+
+func (r *fakeWebhookRepo) GetWebhooksByUserID(userID uint) (*[]domain.Webhook, error) {
+	return &r.saved, nil
+}
+
+func (r *fakeWebhookRepo) SaveWebhook(webhook *domain.Webhook) (*domain.Webhook, error) {
+	webhook.ID = uint(len(r.saved) + 1)
+	r.saved = append(r.saved, *webhook)
+	return webhook, nil
+}
+
+func (r *fakeWebhookRepo) GetWebhooksForEvent(eventType string, userIDs []uint) (*[]domain.Webhook, error) {
+	return &r.saved, nil
+}
+
+func (r *fakeWebhookRepo) SaveDeliveries(deliveries []domain.WebhookDelivery) error {
+	return nil
+}
+
+func (r *fakeWebhookRepo) GetDueDeliveries(now time.Time, limit int) (*[]domain.WebhookDelivery, error) {
+	if r.runs != nil {
+		r.runs <- struct{}{}
+		<-r.release
+	}
+	return &[]domain.WebhookDelivery{}, nil
+}
+
+type fakeCryptoService struct {
+	domain.CryptoService
+}
+
+func (fakeCryptoService) GenerateRandomToken() (string, error) {
+	return "random", nil
+}
+
+func newTestService(allowPrivate bool) *service {
+	return NewService(&fakeWebhookRepo{}, nil, fakeCryptoService{}, Config{
+		Timeout:              time.Second,
+		AllowPrivateNetworks: allowPrivate,
+	}).(*service)
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::1":     true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"fe80::1":                false,
+		"fd00:ec2::254":          false,
+		"100.100.100.200":        false,
+		"0.0.0.0":                false,
+		"::":                     false,
+		"224.0.0.1":              false,
+		"255.255.255.255":        false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+		"64:ff9b::a9fe:a9fe":     false,
+	}
+	for address, want := range tests {
+		if got := isPublicAddress(net.ParseIP(address)); got != want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestCreateWebhookRefusesPrivateAddresses(t *testing.T) {
+	s := newTestService(false)
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"http://[::ffff:10.0.0.1]/hook",
+		"http://0x7f000001/hook",
+	} {
+		_, err := s.CreateWebhook(1, &domain.CreateWebhookRequest{URL: url, Events: []string{domain.EventPhotoCreated}})
+		if err == nil {
+			t.Errorf("%s was accepted", url)
+		}
+	}
+
+	webhook, err := s.CreateWebhook(1, &domain.CreateWebhookRequest{URL: "https://93.184.216.34/hook", Events: []string{domain.EventPhotoCreated}})
+	if err != nil {
+		t.Fatalf("public address refused: %v", err)
+	}
+	if webhook.Secret != secretPrefix+"random" {
+		t.Errorf("unexpected secret %q", webhook.Secret)
+	}
+
+	if _, err := newTestService(true).CreateWebhook(1, &domain.CreateWebhookRequest{URL: "http://127.0.0.1:8080/hook", Events: []string{domain.EventPhotoCreated}}); err != nil {
+		t.Errorf("private networks allowed: %v", err)
+	}
+}
+
+// A webhook whose host resolves to a private address after it was created is refused when connecting
+func TestDeliveryRefusesPrivateAddresses(t *testing.T) {
+	var hits int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer receiver.Close()
+
+	s := newTestService(false)
+	_, err := s.send(&domain.Webhook{URL: receiver.URL, Secret: "secret"}, &domain.WebhookDelivery{Payload: "{}"})
+	if err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Errorf("got %v, want the connection refused", err)
+	}
+	if hits != 0 {
+		t.Error("receiver on loopback was reached")
+	}
+}
+
+func TestDeliveryDoesNotFollowRedirects(t *testing.T) {
+	var redirected int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirected, 1)
+	}))
+	defer target.Close()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	s := newTestService(true)
+	statusCode, err := s.send(&domain.Webhook{URL: receiver.URL, Secret: "secret"}, &domain.WebhookDelivery{Payload: "{}"})
+	if err == nil || statusCode != http.StatusTemporaryRedirect {
+		t.Errorf("got %d, %v, want a failed attempt with status 307", statusCode, err)
+	}
+	if redirected != 0 {
+		t.Error("redirect was followed")
+	}
+}
+
+func TestDeliverySigned(t *testing.T) {
+	received := make(chan error, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- VerifySignature("secret", r.Header.Get(SignatureHeader), body, time.Minute)
+	}))
+	defer receiver.Close()
+
+	s := newTestService(true)
+	if _, err := s.send(&domain.Webhook{URL: receiver.URL, Secret: "secret"}, &domain.WebhookDelivery{Payload: `{"id":"evt_1"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-received; err != nil {
+		t.Errorf("signature doesn't verify: %v", err)
+	}
+}
+
+// Events published during a run of DeliverDue are sent by a single next run
+func TestPublishWakesOneDeliveryRun(t *testing.T) {
+	repo := &fakeWebhookRepo{
+		saved:   []domain.Webhook{{ID: 1}},
+		runs:    make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+	s := NewService(repo, nil, fakeCryptoService{}, Config{})
+
+	s.Publish(&domain.Event{Type: "photo.created", UserIDs: []uint{1}})
+	<-repo.runs
+	for i := 0; i < 50; i++ {
+		s.Publish(&domain.Event{Type: "photo.created", UserIDs: []uint{1}})
+	}
+	close(repo.release)
+
+	select {
+	case <-repo.runs:
+	case <-time.After(time.Second):
+		t.Fatal("the events published during the first run weren't delivered")
+	}
+	select {
+	case <-repo.runs:
+		t.Error("more than one run followed the first one")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the timestamp and the signature of a delivery, "t=<unix time>,v1=<hex>"
+	SignatureHeader = "X-MyGram-Signature"
+	EventHeader     = "X-MyGram-Event"
+	EventIDHeader   = "X-MyGram-Event-ID"
+	DeliveryHeader  = "X-MyGram-Delivery"
+)
+
+var errInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the HMAC-SHA256 of "<timestamp>.<body>" keyed by secret, hex encoded. The timestamp
+// is signed so a captured delivery can't be replayed later.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureHeader returns the SignatureHeader value of body sent at now
+func signatureHeader(secret string, now time.Time, body []byte) string {
+	timestamp := now.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(secret, timestamp, body))
+}
+
+// VerifySignature checks the SignatureHeader value of a received delivery, deliveries signed more
+// than tolerance ago are rejected. Receivers written in Go can use it as is.
+func VerifySignature(secret string, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errInvalidSignature
+			}
+			timestamp = t
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return errInvalidSignature
+	}
+
+	age := time.Since(time.Unix(timestamp, 0))
+	if tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return errors.New("webhook signature has expired")
+	}
+
+	expected := Sign(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}